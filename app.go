@@ -165,6 +165,203 @@ func (a *App) SetHealthThresholds(thresholds tcpmonitor.HealthThresholds) {
 	}
 }
 
+// === Hostname Resolution Methods ===
+
+// GetResolverConfig returns the current reverse-DNS configuration
+func (a *App) GetResolverConfig() tcpmonitor.ResolverConfig {
+	if a.service == nil {
+		return tcpmonitor.DefaultResolverConfig()
+	}
+	return a.service.GetResolverConfig()
+}
+
+// SetResolverConfig updates the reverse-DNS configuration and reloads hosts files
+func (a *App) SetResolverConfig(config tcpmonitor.ResolverConfig) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+	return a.service.SetResolverConfig(config)
+}
+
+// ClearHostnameCache discards all cached reverse-DNS results
+func (a *App) ClearHostnameCache() {
+	if a.service != nil {
+		a.service.ClearHostnameCache()
+	}
+}
+
+// === GeoIP Methods ===
+
+// GetGeoIPConfig returns the configured GeoIP database paths
+func (a *App) GetGeoIPConfig() tcpmonitor.GeoIPConfig {
+	if a.service == nil {
+		return tcpmonitor.GeoIPConfig{}
+	}
+	return a.service.GetGeoIPConfig()
+}
+
+// SetGeoIPConfig loads local .mmdb databases for country/city/ASN tagging
+func (a *App) SetGeoIPConfig(config tcpmonitor.GeoIPConfig) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+	return a.service.SetGeoIPConfig(config)
+}
+
+// GetServiceCatalog returns the user-defined service catalog entries
+func (a *App) GetServiceCatalog() []tcpmonitor.ServiceCatalogEntry {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.GetServiceCatalog()
+}
+
+// SetServiceCatalog replaces the user-defined service catalog entries
+func (a *App) SetServiceCatalog(entries []tcpmonitor.ServiceCatalogEntry) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+	return a.service.SetServiceCatalog(entries)
+}
+
+// GetConnectionGroups breaks down live connections by a dimension (country, asn, pid, ...)
+func (a *App) GetConnectionGroups(filter tcpmonitor.FilterOptions, groupBy string) ([]llm.ConnectionGroup, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.GetConnectionGroups(filter, groupBy)
+}
+
+// GetSessionGroups breaks down a recorded session's connections by a dimension
+func (a *App) GetSessionGroups(sessionID int64, groupBy string) ([]llm.ConnectionGroup, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.GetSessionGroups(sessionID, groupBy)
+}
+
+// CompareSessions compares session b against baseline session a, overall and per group
+func (a *App) CompareSessions(sessionA, sessionB int64, groupBy string) (*llm.SessionComparison, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.CompareSessions(sessionA, sessionB, groupBy)
+}
+
+// === Window Analysis Methods ===
+
+// GetWindowAnalysis explains what limits a live connection's throughput
+func (a *App) GetWindowAnalysis(localAddr string, localPort uint16, remoteAddr string, remotePort uint16) (*tcpmonitor.WindowAnalysis, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.GetWindowAnalysis(localAddr, localPort, remoteAddr, remotePort)
+}
+
+// GetSessionWindowAnalysis classifies what limited a connection's throughput over a session
+func (a *App) GetSessionWindowAnalysis(sessionID int64, localAddr string, localPort int, remoteAddr string, remotePort int) (*tcpmonitor.WindowAnalysis, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.GetSessionWindowAnalysis(sessionID, localAddr, localPort, remoteAddr, remotePort)
+}
+
+// === Active Probe Methods ===
+
+// GetProberConfig returns the active prober configuration
+func (a *App) GetProberConfig() tcpmonitor.ProberConfig {
+	if a.service == nil {
+		return tcpmonitor.DefaultProberConfig()
+	}
+	return a.service.GetProberConfig()
+}
+
+// SetProberConfig updates the active prober configuration
+func (a *App) SetProberConfig(config tcpmonitor.ProberConfig) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+	return a.service.SetProberConfig(config)
+}
+
+// ProbeTarget performs an immediate connect-latency probe of a "host:port" target
+func (a *App) ProbeTarget(target string) (*tcpmonitor.ProbeResult, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.ProbeTarget(target)
+}
+
+// GetLatestProbeResults returns the most recent probe result for every target
+func (a *App) GetLatestProbeResults() []tcpmonitor.ProbeResult {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.GetLatestProbeResults()
+}
+
+// GetProbeHistory returns recorded probe results for a session, optionally for one target
+func (a *App) GetProbeHistory(sessionID int64, target string) []tcpmonitor.ProbeResult {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.GetProbeHistory(sessionID, target)
+}
+
+// GetProbeComparison contrasts active connect time with passive RTT per probed endpoint
+func (a *App) GetProbeComparison() []tcpmonitor.ProbeComparison {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.GetProbeComparison()
+}
+
+// === Throughput Test Methods ===
+
+// StartThroughputServer listens for throughput tests from another tcpdoctor instance
+func (a *App) StartThroughputServer(listenAddr string) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+	return a.service.StartThroughputServer(listenAddr)
+}
+
+// StopThroughputServer stops accepting throughput tests
+func (a *App) StopThroughputServer() {
+	if a.service != nil {
+		a.service.StopThroughputServer()
+	}
+}
+
+// GetThroughputServerStatus returns the throughput server state and recent streams
+func (a *App) GetThroughputServerStatus() tcpmonitor.ThroughputServerStatus {
+	if a.service == nil {
+		return tcpmonitor.ThroughputServerStatus{}
+	}
+	return a.service.GetThroughputServerStatus()
+}
+
+// RunThroughputTest runs a throughput test against a remote tcpdoctor server
+func (a *App) RunThroughputTest(config tcpmonitor.ThroughputTestConfig) (*tcpmonitor.ThroughputTestReport, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.RunThroughputTest(config)
+}
+
+// GetThroughputTestReports returns recent client-side throughput test reports
+func (a *App) GetThroughputTestReports() []tcpmonitor.ThroughputTestReport {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.GetThroughputTestReports()
+}
+
+// GetDefaultThroughputTestConfig returns the default throughput test configuration
+func (a *App) GetDefaultThroughputTestConfig() tcpmonitor.ThroughputTestConfig {
+	return tcpmonitor.DefaultThroughputTestConfig()
+}
+
 // ============================================================
 // LLM (AI) Methods - Exposed to Wails frontend
 // ============================================================
@@ -245,11 +442,21 @@ func (a *App) GenerateHealthReportForSession(sessionID int64) (*llm.HealthReport
 
 // === Snapshot Methods ===
 
-// StartRecording begins snapshot capture
-func (a *App) StartRecording() {
-	if a.service != nil {
-		a.service.StartRecording()
+// StartRecording begins snapshot capture limited to a scope; an empty
+// scope records everything
+func (a *App) StartRecording(scope tcpmonitor.RecordingScope) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
 	}
+	return a.service.StartRecording(scope)
+}
+
+// GetRecordingFieldGroups returns the metric groups a recording scope can keep
+func (a *App) GetRecordingFieldGroups() []string {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.GetRecordingFieldGroups()
 }
 
 // StopRecording stops snapshot capture
@@ -259,6 +466,14 @@ func (a *App) StopRecording() {
 	}
 }
 
+// StopSession stops one recording session, leaving any others running
+func (a *App) StopSession(sessionID int64) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+	return a.service.StopSession(sessionID)
+}
+
 // IsRecording returns current recording state
 func (a *App) IsRecording() bool {
 	if a.service == nil {
@@ -291,12 +506,28 @@ func (a *App) GetSnapshot(id int64) *tcpmonitor.Snapshot {
 	return a.service.GetSnapshot(id)
 }
 
-// CompareSnapshots compares two snapshots
-func (a *App) CompareSnapshots(id1, id2 int64) *tcpmonitor.ComparisonResult {
+// CompareSnapshots diffs two snapshots
+func (a *App) CompareSnapshots(id1, id2 int64, options tcpmonitor.DiffOptions) (*tcpmonitor.ComparisonResult, error) {
 	if a.service == nil {
-		return nil
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.CompareSnapshots(id1, id2, options)
+}
+
+// CompareRanges diffs the aggregate of one recorded time range against another
+func (a *App) CompareRanges(rangeA, rangeB tcpmonitor.DiffRange, options tcpmonitor.DiffOptions) (*tcpmonitor.ComparisonResult, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.CompareRanges(rangeA, rangeB, options)
+}
+
+// QueryHistory runs a history query against the recorded sessions
+func (a *App) QueryHistory(query string) (*tcpmonitor.HistoryQueryResult, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
 	}
-	return a.service.CompareSnapshots(id1, id2)
+	return a.service.QueryHistory(query)
 }
 
 // ClearSnapshots removes all stored snapshots
@@ -323,12 +554,52 @@ func (a *App) GetConnectionHistory(localAddr string, localPort int, remoteAddr s
 
 // === Session Methods ===
 
-// GetSessions returns all recording sessions
-func (a *App) GetSessions() []tcpmonitor.RecordingSession {
+// GetSessions returns the recording sessions matching a search query
+func (a *App) GetSessions(query string) []tcpmonitor.RecordingSession {
 	if a.service == nil {
 		return nil
 	}
-	return a.service.GetSessions()
+	return a.service.GetSessions(query)
+}
+
+// SetSessionInfo sets the name, description and tags of a session
+func (a *App) SetSessionInfo(sessionID int64, name, description string, tags []string) (*tcpmonitor.RecordingSession, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.SetSessionInfo(sessionID, name, description, tags)
+}
+
+// DeleteSession removes a session and everything recorded in it
+func (a *App) DeleteSession(sessionID int64) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+	return a.service.DeleteSession(sessionID)
+}
+
+// TrimSession keeps only the part of a session within a time range
+func (a *App) TrimSession(sessionID int64, start, end time.Time) (*tcpmonitor.RecordingSession, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.TrimSession(sessionID, start, end)
+}
+
+// SplitSession splits a session in two at a timestamp
+func (a *App) SplitSession(sessionID int64, at time.Time) (*tcpmonitor.RecordingSession, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.SplitSession(sessionID, at)
+}
+
+// MergeSessions joins two adjacent sessions into the earlier one
+func (a *App) MergeSessions(firstID, secondID int64) (*tcpmonitor.RecordingSession, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.MergeSessions(firstID, secondID)
 }
 
 // GetSessionCount returns number of sessions
@@ -354,3 +625,208 @@ func (a *App) GetConnectionHistoryForSession(sessionID int64, localAddr string,
 	}
 	return a.service.GetConnectionHistoryForSession(sessionID, localAddr, localPort, remoteAddr, remotePort)
 }
+
+// GetSessionTimelineRange returns the timeline rows of a session within a time range
+func (a *App) GetSessionTimelineRange(sessionID int64, start, end time.Time) []tcpmonitor.TimelineConnection {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.GetSessionTimelineRange(sessionID, start, end)
+}
+
+// GetSessionChartTimeline returns the timeline rows of a session within a time range for charting, rolled up when the range is long
+func (a *App) GetSessionChartTimeline(sessionID int64, start, end time.Time) []tcpmonitor.TimelineConnection {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.GetSessionChartTimeline(sessionID, start, end)
+}
+
+// GetStateAt returns the connection table of a session as it was at an instant
+func (a *App) GetStateAt(sessionID int64, at time.Time, filter tcpmonitor.FilterOptions) (*tcpmonitor.SessionState, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.GetStateAt(sessionID, at, filter)
+}
+
+// GetConnectionHistoryRange returns historical data for a connection within a session time range
+func (a *App) GetConnectionHistoryRange(sessionID int64, start, end time.Time, localAddr string, localPort int, remoteAddr string, remotePort int) []tcpmonitor.ConnectionHistoryPoint {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.GetConnectionHistoryRange(sessionID, start, end, localAddr, localPort, remoteAddr, remotePort)
+}
+
+// GetConnectionChartHistory returns historical data for a connection within a session time range for charting, rolled up when the range is long
+func (a *App) GetConnectionChartHistory(sessionID int64, start, end time.Time, localAddr string, localPort int, remoteAddr string, remotePort int) []tcpmonitor.ConnectionHistoryPoint {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.GetConnectionChartHistory(sessionID, start, end, localAddr, localPort, remoteAddr, remotePort)
+}
+
+// AddAnnotation marks a point in time, or a range when end is set, of a session
+func (a *App) AddAnnotation(sessionID int64, start, end time.Time, text, category, author string) (*tcpmonitor.Annotation, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.AddAnnotation(sessionID, start, end, text, category, author)
+}
+
+// DeleteAnnotation removes an annotation from a session
+func (a *App) DeleteAnnotation(sessionID, annotationID int64) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+	return a.service.DeleteAnnotation(sessionID, annotationID)
+}
+
+// GetAnnotations returns the annotations of a session in time order
+func (a *App) GetAnnotations(sessionID int64) []tcpmonitor.Annotation {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.GetAnnotations(sessionID)
+}
+
+// ExportSession writes a recorded session to a portable archive file
+func (a *App) ExportSession(sessionID int64, path string) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+
+	if path == "" {
+		var err error
+		path, err = runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+			Title:           "Export Recording Session",
+			DefaultFilename: fmt.Sprintf("session-%d%s", sessionID, tcpmonitor.ArchiveExtension),
+			Filters: []runtime.FileFilter{
+				{DisplayName: "tcpdoctor Sessions (*.tcpdoctor)", Pattern: "*" + tcpmonitor.ArchiveExtension},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("dialog error: %w", err)
+		}
+		if path == "" {
+			return nil // User cancelled
+		}
+	}
+
+	return a.service.ExportSession(sessionID, path)
+}
+
+// ImportSession loads a session archive recorded on another machine
+func (a *App) ImportSession(path string) (*tcpmonitor.RecordingSession, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+
+	if path == "" {
+		var err error
+		path, err = runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+			Title: "Import Recording Session",
+			Filters: []runtime.FileFilter{
+				{DisplayName: "tcpdoctor Sessions (*.tcpdoctor)", Pattern: "*" + tcpmonitor.ArchiveExtension},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("dialog error: %w", err)
+		}
+		if path == "" {
+			return nil, nil // User cancelled
+		}
+	}
+
+	return a.service.ImportSession(path)
+}
+
+// === Storage Methods ===
+
+// GetFlightRecorderConfig returns the flight-recorder settings in effect
+func (a *App) GetFlightRecorderConfig() tcpmonitor.FlightRecorderConfig {
+	if a.service == nil {
+		return tcpmonitor.FlightRecorderConfig{}
+	}
+	return a.service.GetFlightRecorderConfig()
+}
+
+// SetFlightRecorderConfig switches between continuous and trigger-based recording
+func (a *App) SetFlightRecorderConfig(config tcpmonitor.FlightRecorderConfig) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+	return a.service.SetFlightRecorderConfig(config)
+}
+
+// TriggerFlightRecorder fires a manual flight-recorder trigger
+func (a *App) TriggerFlightRecorder(reason string) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+	return a.service.TriggerFlightRecorder(reason)
+}
+
+// GetFlightRecorderStatus reports what the flight recorder is doing
+func (a *App) GetFlightRecorderStatus() tcpmonitor.FlightRecorderStatus {
+	if a.service == nil {
+		return tcpmonitor.FlightRecorderStatus{}
+	}
+	return a.service.GetFlightRecorderStatus()
+}
+
+// GetSchedules returns the recording schedules
+func (a *App) GetSchedules() []tcpmonitor.RecordingSchedule {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.GetSchedules()
+}
+
+// AddSchedule saves a new recording schedule
+func (a *App) AddSchedule(schedule tcpmonitor.RecordingSchedule) (*tcpmonitor.RecordingSchedule, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.AddSchedule(schedule)
+}
+
+// UpdateSchedule replaces a recording schedule's settings
+func (a *App) UpdateSchedule(schedule tcpmonitor.RecordingSchedule) (*tcpmonitor.RecordingSchedule, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.UpdateSchedule(schedule)
+}
+
+// DeleteSchedule removes a recording schedule
+func (a *App) DeleteSchedule(id int64) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+	return a.service.DeleteSchedule(id)
+}
+
+// GetStorageConfig returns the session persistence settings in effect
+func (a *App) GetStorageConfig() tcpmonitor.StorageConfig {
+	if a.service == nil {
+		return tcpmonitor.StorageConfig{}
+	}
+	return a.service.GetStorageConfig()
+}
+
+// SetStorageConfig updates the retention limits of persisted sessions
+func (a *App) SetStorageConfig(config tcpmonitor.StorageConfig) error {
+	if a.service == nil {
+		return fmt.Errorf("service not initialized")
+	}
+	return a.service.SetStorageConfig(config)
+}
+
+// GetStorageSize returns the bytes used by persisted sessions
+func (a *App) GetStorageSize() int64 {
+	if a.service == nil {
+		return 0
+	}
+	return a.service.GetStorageSize()
+}
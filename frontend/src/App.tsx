@@ -4,6 +4,8 @@ import {
     IsAdministrator,
     GetUpdateInterval,
     ExportToCSV,
+    ExportSession,
+    ImportSession,
     ConfigureLLM,
     IsLLMConfigured,
     DiagnoseConnection,
@@ -11,6 +13,7 @@ import {
     QueryConnections,
     StartRecording,
     StopRecording,
+    StopSession,
     IsRecording,
     GetSessionCount,
     GetSnapshotMeta,
@@ -19,8 +22,12 @@ import {
     ClearSnapshots,
     TakeSnapshot,
     GetConnectionHistory,
-    GetConnectionHistoryForSession,
+    GetConnectionChartHistory,
     GetSessions,
+    SetSessionInfo,
+    DeleteSession,
+    AddAnnotation,
+    TriggerFlightRecorder,
     GetSessionTimeline
 } from "../wailsjs/go/main/App";
 import { tcpmonitor } from "../wailsjs/go/models";
@@ -259,9 +266,9 @@ function App() {
 
     // --- Session / Snapshot Handling ---
 
-    const handleStartRecording = async () => {
+    const handleStartRecording = async (scope?: Partial<tcpmonitor.RecordingScope>) => {
         try {
-            await StartRecording();
+            await StartRecording(new tcpmonitor.RecordingScope(scope || {}));
             setIsRecording(true);
             // Update session count
             const count = await GetSessionCount();
@@ -292,15 +299,63 @@ function App() {
     };
 
     const handleExportSession = async (sessionId: number) => {
-        // Not implemented in backend yet? Or we use ExportToCSV?
-        // Let's assume generic export or placeholder
-        alert("Export started for session " + sessionId);
+        try {
+            // Empty path opens a save dialog
+            await ExportSession(sessionId, "");
+        } catch (e) {
+            console.error("Failed to export session:", e);
+            alert("Export failed: " + e);
+        }
+    };
+
+    const handleImportSession = async () => {
+        try {
+            // Empty path opens a file picker
+            await ImportSession("");
+        } catch (e) {
+            console.error("Failed to import session:", e);
+            alert("Import failed: " + e);
+        }
+    };
+
+    const handleRenameSession = async (session: tcpmonitor.RecordingSession, name: string) => {
+        await SetSessionInfo(session.id, name, session.description || '', session.tags || []);
+    };
+
+    const handleDeleteSession = async (sessionId: number) => {
+        try {
+            await DeleteSession(sessionId);
+        } catch (e) {
+            console.error("Failed to delete session:", e);
+            alert("Delete failed: " + e);
+        }
     };
 
-    const handleImportSession = () => {
-        alert("Import not implemented yet");
+    const handleAnnotateSession = async (sessionId: number, text: string) => {
+        await AddAnnotation(sessionId, new Date().toISOString(), null, text, "note", "");
     };
 
+    const handleStopSession = async (sessionId: number) => {
+        await StopSession(sessionId);
+        setIsRecording(await IsRecording());
+    };
+
+    // Ctrl+Shift+M fires the flight recorder, saving the buffered history as a session
+    useEffect(() => {
+        const onKeyDown = async (e: KeyboardEvent) => {
+            if (!(e.ctrlKey && e.shiftKey && e.key.toLowerCase() === 'm')) return;
+            e.preventDefault();
+            try {
+                await TriggerFlightRecorder("Manual hotkey");
+                setIsRecording(await IsRecording());
+            } catch (err) {
+                console.error("Flight recorder trigger failed:", err);
+            }
+        };
+        window.addEventListener('keydown', onKeyDown);
+        return () => window.removeEventListener('keydown', onKeyDown);
+    }, []);
+
     // Load a historic session
     const handleLoadSession = useCallback(async (sessionId: number, timeline: any[]) => {
         setViewingSnapshotId(sessionId);
@@ -313,6 +368,7 @@ function App() {
         if (timeline && timeline.length > 0) {
             const uniqueConns = new Map<string, tcpmonitor.ConnectionInfo>();
             timeline.forEach(t => {
+                if (t.annotation) return; // Annotation markers carry no connection
                 const conn = t.connection;
                 // Use camelCase properties from CompactConnection
                 const key = `${conn.localAddr}:${conn.localPort}-${conn.remoteAddr}:${conn.remotePort}`;
@@ -379,9 +435,11 @@ function App() {
 
     const getHistoryForConnection = async () => {
         if (viewingSnapshotRef.current !== null && selectedConnection) {
-            // We are in session mode
-            return await GetConnectionHistoryForSession(
+            // We are in session mode; long sessions are charted from rollups
+            return await GetConnectionChartHistory(
                 viewingSnapshotRef.current,
+                null,
+                null,
                 selectedConnection.LocalAddr,
                 selectedConnection.LocalPort,
                 selectedConnection.RemoteAddr,
@@ -405,7 +463,7 @@ function App() {
                 <AIAgentView
                     isConfigured={isAIConfigured}
                     onConfigure={() => setIsSettingsOpen(true)}
-                    getSessions={GetSessions}
+                    getSessions={() => GetSessions('')}
                     getSessionTimeline={GetSessionTimeline}
                     selectedConnection={selectedConnection}
                     onDiagnoseConnection={async (conn: tcpmonitor.ConnectionInfo | null) => {
@@ -464,7 +522,7 @@ function App() {
                         <SnapshotControls
                             isRecording={isRecording}
                             sessionCount={snapshotCount}
-                            onStartRecording={handleStartRecording}
+                            onStartRecording={() => handleStartRecording()}
                             onStopRecording={handleStopRecording}
                             getSessions={GetSessions}
                             getSessionTimeline={GetSessionTimeline}
@@ -472,6 +530,10 @@ function App() {
                             onClear={handleClearSnapshots}
                             onExportSession={handleExportSession}
                             onImportSession={handleImportSession}
+                            onRenameSession={handleRenameSession}
+                            onDeleteSession={handleDeleteSession}
+                            onAnnotateSession={handleAnnotateSession}
+                            onStopSession={handleStopSession}
                         />
                     </div>
                 </div>
@@ -18,6 +18,7 @@ interface RecordingSession {
     startTime: string;
     endTime: string;
     snapshotCount: number;
+    name?: string;
 }
 
 interface AIAgentViewProps {
@@ -89,7 +90,7 @@ const AIAgentView: React.FC<AIAgentViewProps> = ({
 
     // Get display name for a session
     const getSessionDisplayName = (s: RecordingSession): string => {
-        return sessionNames[s.id] || `Session #${s.id}`;
+        return s.name || sessionNames[s.id] || `Session #${s.id}`;
     };
 
     const formatTime = (ts: string) => {
@@ -141,7 +142,7 @@ const AIAgentView: React.FC<AIAgentViewProps> = ({
                 <div className="chat-header-bar">
                     <div className="current-context">
                         {selectedContext ? (
-                            <>Analyzing: <strong>{sessionNames[parseInt(selectedContext)] || `Session #${selectedContext}`}</strong></>
+                            <>Analyzing: <strong>{sessions.find(s => s.id.toString() === selectedContext)?.name || sessionNames[parseInt(selectedContext)] || `Session #${selectedContext}`}</strong></>
                         ) : (
                             <span className="no-context">Select a session to begin analysis</span>
                         )}
@@ -192,6 +193,7 @@ const AIAgentView: React.FC<AIAgentViewProps> = ({
                                     const connections: any[] = [];
 
                                     for (let i = timeline.length - 1; i >= 0; i--) {
+                                        if (timeline[i].annotation) continue; // Marker rows carry no connection
                                         const c = timeline[i].connection;
                                         if (!c) continue;
                                         const key = `${c.localAddr}:${c.localPort}-${c.remoteAddr}:${c.remotePort}-${c.pid}`;
@@ -31,7 +31,7 @@ const ConnectionDetailView: React.FC<ConnectionDetailViewProps> = ({
                 return new Set(JSON.parse(saved));
             } catch (e) { }
         }
-        return new Set(['charts', 'data', 'retrans', 'rtt', 'congestion', 'bandwidth', 'window', 'segments', 'dups']);
+        return new Set(['charts', 'data', 'retrans', 'rtt', 'congestion', 'bandwidth', 'window', 'segments', 'dups', 'buffers']);
     });
 
     // View menu state
@@ -72,6 +72,7 @@ const ConnectionDetailView: React.FC<ConnectionDetailViewProps> = ({
         { id: 'window', label: 'Window & Scaling' },
         { id: 'segments', label: 'Segment Info & MSS' },
         { id: 'dups', label: 'Duplicate ACKs & SACKs' },
+        { id: 'buffers', label: 'Socket Buffers' },
     ];
 
     return (
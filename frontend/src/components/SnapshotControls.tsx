@@ -6,6 +6,9 @@ interface RecordingSession {
     startTime: string;
     endTime: string;
     snapshotCount: number;
+    name?: string;
+    description?: string;
+    tags?: string[];
 }
 
 interface TimelineConnection {
@@ -18,15 +21,19 @@ interface SnapshotControlsProps {
     sessionCount: number;
     onStartRecording: () => void;
     onStopRecording: () => void;
-    getSessions: () => Promise<RecordingSession[]>;
+    getSessions: (query: string) => Promise<RecordingSession[]>;
     getSessionTimeline: (sessionId: number) => Promise<TimelineConnection[]>;
     onLoadSession: (sessionId: number, timeline: TimelineConnection[]) => void;
     onClear: () => void;
     onExportSession: (sessionId: number) => void;
-    onImportSession: () => void;
+    onImportSession: () => Promise<void> | void;
+    onRenameSession: (session: RecordingSession, name: string) => Promise<void>;
+    onDeleteSession: (sessionId: number) => Promise<void>;
+    onAnnotateSession: (sessionId: number, text: string) => Promise<void>;
+    onStopSession: (sessionId: number) => Promise<void>;
 }
 
-// Helper to get/set session names from localStorage
+// Names given before sessions stored their own metadata
 const getSessionNames = (): Record<number, string> => {
     try {
         return JSON.parse(localStorage.getItem('session_names') || '{}');
@@ -35,12 +42,6 @@ const getSessionNames = (): Record<number, string> => {
     }
 };
 
-const setSessionName = (id: number, name: string) => {
-    const names = getSessionNames();
-    names[id] = name;
-    localStorage.setItem('session_names', JSON.stringify(names));
-};
-
 const SnapshotControls: React.FC<SnapshotControlsProps> = ({
     isRecording,
     sessionCount,
@@ -52,6 +53,10 @@ const SnapshotControls: React.FC<SnapshotControlsProps> = ({
     onClear,
     onExportSession,
     onImportSession,
+    onRenameSession,
+    onDeleteSession,
+    onAnnotateSession,
+    onStopSession,
 }) => {
     const [isOpen, setIsOpen] = useState(false);
     const [sessions, setSessions] = useState<RecordingSession[]>([]);
@@ -60,6 +65,7 @@ const SnapshotControls: React.FC<SnapshotControlsProps> = ({
     const [sessionNames, setSessionNames] = useState<Record<number, string>>({});
     const [editingId, setEditingId] = useState<number | null>(null);
     const [editName, setEditName] = useState('');
+    const [query, setQuery] = useState('');
     const popoverRef = useRef<HTMLDivElement>(null);
     const inputRef = useRef<HTMLInputElement>(null);
 
@@ -68,7 +74,7 @@ const SnapshotControls: React.FC<SnapshotControlsProps> = ({
             loadSessions();
             setSessionNames(getSessionNames());
         }
-    }, [isOpen]);
+    }, [isOpen, query]);
 
     useEffect(() => {
         const handleClickOutside = (event: MouseEvent) => {
@@ -83,7 +89,7 @@ const SnapshotControls: React.FC<SnapshotControlsProps> = ({
     }, [isOpen]);
 
     const loadSessions = async () => {
-        const data = await getSessions();
+        const data = await getSessions(query);
         setSessions(data || []);
     };
 
@@ -133,17 +139,23 @@ const SnapshotControls: React.FC<SnapshotControlsProps> = ({
 
     const startEditing = (session: RecordingSession) => {
         setEditingId(session.id);
-        setEditName(sessionNames[session.id] || `Session ${session.id}`);
+        setEditName(getSessionDisplayName(session));
         setTimeout(() => inputRef.current?.focus(), 50);
     };
 
-    const saveEdit = () => {
-        if (editingId !== null && editName.trim()) {
-            setSessionName(editingId, editName.trim());
-            setSessionNames(prev => ({ ...prev, [editingId]: editName.trim() }));
-        }
+    const saveEdit = async () => {
+        const session = sessions.find(s => s.id === editingId);
+        const name = editName.trim();
         setEditingId(null);
         setEditName('');
+        if (session && name && name !== getSessionDisplayName(session)) {
+            try {
+                await onRenameSession(session, name);
+            } catch (e) {
+                console.error('Failed to rename session:', e);
+            }
+            loadSessions();
+        }
     };
 
     const cancelEdit = () => {
@@ -152,7 +164,40 @@ const SnapshotControls: React.FC<SnapshotControlsProps> = ({
     };
 
     const getSessionDisplayName = (session: RecordingSession) => {
-        return sessionNames[session.id] || `Session ${session.id}`;
+        return session.name || sessionNames[session.id] || `Session ${session.id}`;
+    };
+
+    const handleMark = async (session: RecordingSession) => {
+        const text = window.prompt('Annotation for this moment (e.g. "deployed v2.3"):');
+        if (!text || !text.trim()) {
+            return;
+        }
+        try {
+            await onAnnotateSession(session.id, text.trim());
+        } catch (e) {
+            console.error('Failed to annotate session:', e);
+        }
+    };
+
+    const handleStop = async (session: RecordingSession) => {
+        try {
+            await onStopSession(session.id);
+        } catch (e) {
+            console.error('Failed to stop session:', e);
+        }
+        loadSessions();
+    };
+
+    const handleDelete = async (session: RecordingSession) => {
+        if (!window.confirm(`Delete "${getSessionDisplayName(session)}" and all of its snapshots?`)) {
+            return;
+        }
+        try {
+            await onDeleteSession(session.id);
+        } catch (e) {
+            console.error('Failed to delete session:', e);
+        }
+        loadSessions();
     };
 
     const handleRecordToggle = () => {
@@ -208,7 +253,13 @@ const SnapshotControls: React.FC<SnapshotControlsProps> = ({
 
                     {/* Action Buttons */}
                     <div className="action-row">
-                        <button className="action-btn" onClick={onImportSession}>
+                        <button
+                            className="action-btn"
+                            onClick={async () => {
+                                await onImportSession();
+                                loadSessions();
+                            }}
+                        >
                             Import
                         </button>
                         <button
@@ -220,9 +271,22 @@ const SnapshotControls: React.FC<SnapshotControlsProps> = ({
                         </button>
                     </div>
 
+                    {/* Search by name, description or tag:name */}
+                    <input
+                        type="text"
+                        className="session-search"
+                        placeholder="Search sessions (tag:name)"
+                        value={query}
+                        onChange={(e) => setQuery(e.target.value)}
+                    />
+
                     {/* Sessions List */}
                     <div className="sessions-list">
-                        {sessions.length === 0 ? (
+                        {sessions.length === 0 && query ? (
+                            <div className="empty-state">
+                                No sessions match "{query}".
+                            </div>
+                        ) : sessions.length === 0 ? (
                             <div className="empty-state">
                                 No captures yet.<br />
                                 Click "Start Capture" to begin.
@@ -269,6 +333,13 @@ const SnapshotControls: React.FC<SnapshotControlsProps> = ({
                                             <div className="session-meta">
                                                 {formatDuration(session.startTime, session.endTime)} · {session.snapshotCount} snapshots
                                             </div>
+                                            {session.tags && session.tags.length > 0 && (
+                                                <div className="session-tags">
+                                                    {session.tags.map(tag => (
+                                                        <span key={tag} className="session-tag">{tag}</span>
+                                                    ))}
+                                                </div>
+                                            )}
                                         </div>
                                         <div className="session-buttons">
                                             <button
@@ -277,6 +348,31 @@ const SnapshotControls: React.FC<SnapshotControlsProps> = ({
                                             >
                                                 Export
                                             </button>
+                                            {ongoing ? (
+                                                <>
+                                                    <button
+                                                        className="session-action-btn"
+                                                        onClick={() => handleMark(session)}
+                                                        title="Annotate the current moment"
+                                                    >
+                                                        Mark
+                                                    </button>
+                                                    <button
+                                                        className="session-action-btn danger"
+                                                        onClick={() => handleStop(session)}
+                                                        title="Stop this capture, leaving others running"
+                                                    >
+                                                        Stop
+                                                    </button>
+                                                </>
+                                            ) : (
+                                                <button
+                                                    className="session-action-btn danger"
+                                                    onClick={() => handleDelete(session)}
+                                                >
+                                                    Delete
+                                                </button>
+                                            )}
                                             <button
                                                 className="session-action-btn primary"
                                                 onClick={() => handleLoadSession(session.id)}
@@ -31,6 +31,7 @@ interface ConnectionDiff {
     deltaIn: number;
     deltaOut: number;
     deltaRtt: number;
+    changes: string[];
 }
 
 interface ComparisonResult {
@@ -180,6 +181,9 @@ const SnapshotTimeline: React.FC<SnapshotTimelineProps> = ({
                                                     {diff.deltaIn > 0 && <span className="delta-in">+{formatBytes(diff.deltaIn)}</span>}
                                                     {diff.deltaOut > 0 && <span className="delta-out">↑{formatBytes(diff.deltaOut)}</span>}
                                                 </span>
+                                                <span className="diff-changes">
+                                                    {diff.changes?.filter(c => c !== 'traffic').join(', ').replace(/_/g, ' ')}
+                                                </span>
                                             </div>
                                         ))}
                                     </div>
@@ -51,6 +51,7 @@ const ALL_SECTIONS = [
   { id: 'window', label: 'Window & Scaling' },
   { id: 'segments', label: 'Segment Info & MSS' },
   { id: 'dups', label: 'Duplicate ACKs & SACKs' },
+  { id: 'buffers', label: 'Socket Buffers' },
 ];
 
 const StatsPanel: React.FC<StatsPanelProps> = ({
@@ -309,6 +310,32 @@ const StatsPanel: React.FC<StatsPanelProps> = ({
                   label="Timeouts"
                   value={formatCount(ExtendedStats.TimeoutEpisodes)}
                 />
+                {connection.Timer && (
+                  <>
+                    <StatItem
+                      label="Timer"
+                      value={connection.Timer.Kind.replace(/_/g, ' ')}
+                      subValue={`fires in ${(connection.Timer.ExpiresMs / 1000).toFixed(1)}s`}
+                    />
+                    <StatItem
+                      label="Backoff"
+                      value={formatCount(connection.Timer.Backoff)}
+                      subValue={`RTO ${formatRTT(connection.Timer.RTOMs).formatted}`}
+                    />
+                    <StatItem
+                      label="Unacked Retries"
+                      value={formatCount(connection.Timer.Retransmits)}
+                      subValue={connection.Timer.Probes > 0 ? `${connection.Timer.Probes} probes` : undefined}
+                    />
+                  </>
+                )}
+                {connection.Stall && (
+                  <StatItem
+                    label="Stalled"
+                    value={connection.Stall.Condition.replace(/_/g, ' ')}
+                    subValue={connection.Stall.Description}
+                  />
+                )}
               </Section>
             )}
 
@@ -353,6 +380,50 @@ const StatsPanel: React.FC<StatsPanelProps> = ({
                   label="Avoidance"
                   value={formatCount(ExtendedStats.CongAvoidCount)}
                 />
+                {ExtendedStats.CongestionAlgorithm && (
+                  <>
+                    <StatItem
+                      label="Algorithm"
+                      value={ExtendedStats.CongestionAlgorithm}
+                      subValue={ExtendedStats.CAState}
+                    />
+                    <StatItem
+                      label="Pacing Rate"
+                      value={ExtendedStats.PacingRate ? formatBandwidth(ExtendedStats.PacingRate).formatted : '—'}
+                    />
+                  </>
+                )}
+                {ExtendedStats.BBR && (
+                  <>
+                    <StatItem
+                      label="BBR BW"
+                      value={formatBandwidth(ExtendedStats.BBR.BandwidthBps).formatted}
+                    />
+                    <StatItem
+                      label="BBR Min RTT"
+                      value={formatRTT(ExtendedStats.BBR.MinRTTUs / 1000).formatted}
+                    />
+                    <StatItem
+                      label="BBR Gains"
+                      value={`${ExtendedStats.BBR.PacingGain.toFixed(2)} / ${ExtendedStats.BBR.CwndGain.toFixed(2)}`}
+                      subValue="Pacing / CWND"
+                    />
+                  </>
+                )}
+                {ExtendedStats.DCTCP && (
+                  <StatItem
+                    label="DCTCP α"
+                    value={(ExtendedStats.DCTCP.Alpha / 1024).toFixed(3)}
+                    subValue="ECN-marked"
+                  />
+                )}
+                {ExtendedStats.Vegas && (
+                  <StatItem
+                    label="Vegas RTT"
+                    value={formatRTT(ExtendedStats.Vegas.RTTUs / 1000).formatted}
+                    subValue={`min ${formatRTT(ExtendedStats.Vegas.MinRTTUs / 1000).formatted}`}
+                  />
+                )}
               </Section>
             )}
 
@@ -442,6 +513,46 @@ const StatsPanel: React.FC<StatsPanelProps> = ({
                 />
               </Section>
             )}
+
+            {visibleSections.has('buffers') && (
+              <Section title="Socket Buffers" icon="🧺" delay="0.5s">
+                <StatItem
+                  label="App Write Queue"
+                  value={formatBytes(ExtendedStats.CurAppWQueue).formatted}
+                  subValue={`max ${formatBytes(ExtendedStats.MaxAppWQueue).formatted}`}
+                />
+                <StatItem
+                  label="Retx Queue"
+                  value={formatBytes(ExtendedStats.CurRetxQueue).formatted}
+                  subValue={`max ${formatBytes(ExtendedStats.MaxRetxQueue).formatted}`}
+                />
+                {(ExtendedStats.RcvBuf > 0 || ExtendedStats.SndBuf > 0) && (
+                  <>
+                    <StatItem
+                      label="Recv Buffer"
+                      value={formatBytes(ExtendedStats.RmemAlloc).formatted}
+                      subValue={`of ${formatBytes(ExtendedStats.RcvBuf).formatted}`}
+                    />
+                    <StatItem
+                      label="Send Buffer"
+                      value={formatBytes(ExtendedStats.WmemQueued).formatted}
+                      subValue={`of ${formatBytes(ExtendedStats.SndBuf).formatted}`}
+                    />
+                    <StatItem
+                      label="Socket Drops"
+                      value={formatCount(ExtendedStats.SockDrops)}
+                    />
+                  </>
+                )}
+                {connection.BufferPressure && (
+                  <StatItem
+                    label="Buffer Pressure"
+                    value={connection.BufferPressure.Condition}
+                    subValue={connection.BufferPressure.Description}
+                  />
+                )}
+              </Section>
+            )}
           </>
         ) : isAdmin ? (
           <div className="stats-message">
@@ -3,39 +3,97 @@
 import { tcpmonitor } from '../models';
 import { llm } from '../models';
 
+export function AddAnnotation(arg1: number, arg2: any, arg3: any, arg4: string, arg5: string, arg6: string): Promise<tcpmonitor.Annotation>;
+
+export function AddSchedule(arg1: tcpmonitor.RecordingSchedule): Promise<tcpmonitor.RecordingSchedule>;
+
+export function ClearHostnameCache(): Promise<void>;
+
 export function ClearSelection(): Promise<void>;
 
 export function ClearSnapshots(): Promise<void>;
 
-export function CompareSnapshots(arg1: number, arg2: number): Promise<tcpmonitor.ComparisonResult>;
+export function CompareRanges(arg1: tcpmonitor.DiffRange, arg2: tcpmonitor.DiffRange, arg3: tcpmonitor.DiffOptions): Promise<tcpmonitor.ComparisonResult>;
+
+export function CompareSessions(arg1: number, arg2: number, arg3: string): Promise<llm.SessionComparison>;
+
+export function CompareSnapshots(arg1: number, arg2: number, arg3: tcpmonitor.DiffOptions): Promise<tcpmonitor.ComparisonResult>;
 
 export function ConfigureLLM(arg1: string): Promise<void>;
 
+export function DeleteAnnotation(arg1: number, arg2: number): Promise<void>;
+
+export function DeleteSchedule(arg1: number): Promise<void>;
+
+export function DeleteSession(arg1: number): Promise<void>;
+
 export function DiagnoseConnection(arg1: string, arg2: number, arg3: string, arg4: number): Promise<llm.DiagnosticResult>;
 
+export function ExportSession(arg1: number, arg2: string): Promise<void>;
+
 export function ExportToCSV(arg1: string): Promise<void>;
 
 export function GenerateHealthReport(): Promise<llm.HealthReport>;
 
 export function GenerateHealthReportForSession(arg1: number): Promise<llm.HealthReport>;
 
+export function GetAnnotations(arg1: number): Promise<Array<tcpmonitor.Annotation>>;
+
+export function GetConnectionChartHistory(arg1: number, arg2: any, arg3: any, arg4: string, arg5: number, arg6: string, arg7: number): Promise<Array<tcpmonitor.ConnectionHistoryPoint>>;
+
 export function GetConnectionCount(): Promise<number>;
 
+export function GetConnectionGroups(arg1: tcpmonitor.FilterOptions, arg2: string): Promise<Array<llm.ConnectionGroup>>;
+
 export function GetConnectionHistory(arg1: string, arg2: number, arg3: string, arg4: number): Promise<Array<tcpmonitor.ConnectionHistoryPoint>>;
 
 export function GetConnectionHistoryForSession(arg1: number, arg2: string, arg3: number, arg4: string, arg5: number): Promise<Array<tcpmonitor.ConnectionHistoryPoint>>;
 
+export function GetConnectionHistoryRange(arg1: number, arg2: any, arg3: any, arg4: string, arg5: number, arg6: string, arg7: number): Promise<Array<tcpmonitor.ConnectionHistoryPoint>>;
+
 export function GetConnectionStats(arg1: string, arg2: number, arg3: string, arg4: number): Promise<tcpmonitor.ExtendedStats>;
 
 export function GetConnections(arg1: tcpmonitor.FilterOptions): Promise<Array<tcpmonitor.ConnectionInfo>>;
 
+export function GetDefaultThroughputTestConfig(): Promise<tcpmonitor.ThroughputTestConfig>;
+
+export function GetFlightRecorderConfig(): Promise<tcpmonitor.FlightRecorderConfig>;
+
+export function GetFlightRecorderStatus(): Promise<tcpmonitor.FlightRecorderStatus>;
+
+export function GetGeoIPConfig(): Promise<tcpmonitor.GeoIPConfig>;
+
 export function GetHealthThresholds(): Promise<tcpmonitor.HealthThresholds>;
 
+export function GetLatestProbeResults(): Promise<Array<tcpmonitor.ProbeResult>>;
+
+export function GetProbeComparison(): Promise<Array<tcpmonitor.ProbeComparison>>;
+
+export function GetProbeHistory(arg1: number, arg2: string): Promise<Array<tcpmonitor.ProbeResult>>;
+
+export function GetProberConfig(): Promise<tcpmonitor.ProberConfig>;
+
+export function GetRecordingFieldGroups(): Promise<Array<string>>;
+
+export function GetResolverConfig(): Promise<tcpmonitor.ResolverConfig>;
+
+export function GetSchedules(): Promise<Array<tcpmonitor.RecordingSchedule>>;
+
+export function GetServiceCatalog(): Promise<Array<tcpmonitor.ServiceCatalogEntry>>;
+
+export function GetSessionChartTimeline(arg1: number, arg2: any, arg3: any): Promise<Array<tcpmonitor.TimelineConnection>>;
+
 export function GetSessionCount(): Promise<number>;
 
+export function GetSessionGroups(arg1: number, arg2: string): Promise<Array<llm.ConnectionGroup>>;
+
 export function GetSessionTimeline(arg1: number): Promise<Array<tcpmonitor.TimelineConnection>>;
 
-export function GetSessions(): Promise<Array<tcpmonitor.RecordingSession>>;
+export function GetSessionTimelineRange(arg1: number, arg2: any, arg3: any): Promise<Array<tcpmonitor.TimelineConnection>>;
+
+export function GetSessionWindowAnalysis(arg1: number, arg2: string, arg3: number, arg4: string, arg5: number): Promise<tcpmonitor.WindowAnalysis>;
+
+export function GetSessions(arg1: string): Promise<Array<tcpmonitor.RecordingSession>>;
 
 export function GetSnapshot(arg1: number): Promise<tcpmonitor.Snapshot>;
 
@@ -43,30 +101,80 @@ export function GetSnapshotCount(): Promise<number>;
 
 export function GetSnapshotMeta(): Promise<Array<tcpmonitor.SnapshotMeta>>;
 
+export function GetStateAt(arg1: number, arg2: any, arg3: tcpmonitor.FilterOptions): Promise<tcpmonitor.SessionState>;
+
+export function GetStorageConfig(): Promise<tcpmonitor.StorageConfig>;
+
+export function GetStorageSize(): Promise<number>;
+
+export function GetThroughputServerStatus(): Promise<tcpmonitor.ThroughputServerStatus>;
+
+export function GetThroughputTestReports(): Promise<Array<tcpmonitor.ThroughputTestReport>>;
+
 export function GetUpdateInterval(): Promise<number>;
 
+export function GetWindowAnalysis(arg1: string, arg2: number, arg3: string, arg4: number): Promise<tcpmonitor.WindowAnalysis>;
+
+export function ImportSession(arg1: string): Promise<tcpmonitor.RecordingSession>;
+
 export function IsAdministrator(): Promise<boolean>;
 
 export function IsLLMConfigured(): Promise<boolean>;
 
 export function IsRecording(): Promise<boolean>;
 
+export function MergeSessions(arg1: number, arg2: number): Promise<tcpmonitor.RecordingSession>;
+
+export function ProbeTarget(arg1: string): Promise<tcpmonitor.ProbeResult>;
+
 export function QueryConnections(arg1: string): Promise<llm.QueryResult>;
 
 export function QueryConnectionsForSession(arg1: string, arg2: number): Promise<llm.QueryResult>;
 
 export function QueryConnectionsWithHistory(arg1: string, arg2: Array<{ role: string, content: string }>): Promise<llm.QueryResult>;
 
+export function QueryHistory(arg1: string): Promise<tcpmonitor.HistoryQueryResult>;
+
+export function RunThroughputTest(arg1: tcpmonitor.ThroughputTestConfig): Promise<tcpmonitor.ThroughputTestReport>;
+
+export function SetFlightRecorderConfig(arg1: tcpmonitor.FlightRecorderConfig): Promise<void>;
+
+export function SetGeoIPConfig(arg1: tcpmonitor.GeoIPConfig): Promise<void>;
+
 export function SetHealthThresholds(arg1: tcpmonitor.HealthThresholds): Promise<void>;
 
+export function SetProberConfig(arg1: tcpmonitor.ProberConfig): Promise<void>;
+
 export function SetRTTThreshold(arg1: number): Promise<void>;
 
+export function SetResolverConfig(arg1: tcpmonitor.ResolverConfig): Promise<void>;
+
 export function SetRetransmissionThreshold(arg1: number): Promise<void>;
 
+export function SetServiceCatalog(arg1: Array<tcpmonitor.ServiceCatalogEntry>): Promise<void>;
+
+export function SetSessionInfo(arg1: number, arg2: string, arg3: string, arg4: Array<string>): Promise<tcpmonitor.RecordingSession>;
+
+export function SetStorageConfig(arg1: tcpmonitor.StorageConfig): Promise<void>;
+
 export function SetUpdateInterval(arg1: number): Promise<void>;
 
-export function StartRecording(): Promise<void>;
+export function SplitSession(arg1: number, arg2: any): Promise<tcpmonitor.RecordingSession>;
+
+export function StartRecording(arg1: tcpmonitor.RecordingScope): Promise<void>;
+
+export function StartThroughputServer(arg1: string): Promise<void>;
 
 export function StopRecording(): Promise<void>;
 
+export function StopSession(arg1: number): Promise<void>;
+
+export function StopThroughputServer(): Promise<void>;
+
 export function TakeSnapshot(arg1: tcpmonitor.FilterOptions): Promise<void>;
+
+export function TriggerFlightRecorder(arg1: string): Promise<void>;
+
+export function TrimSession(arg1: number, arg2: any, arg3: any): Promise<tcpmonitor.RecordingSession>;
+
+export function UpdateSchedule(arg1: tcpmonitor.RecordingSchedule): Promise<tcpmonitor.RecordingSchedule>;
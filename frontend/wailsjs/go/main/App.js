@@ -2,6 +2,18 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddAnnotation(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['AddAnnotation'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function AddSchedule(arg1) {
+  return window['go']['main']['App']['AddSchedule'](arg1);
+}
+
+export function ClearHostnameCache() {
+  return window['go']['main']['App']['ClearHostnameCache']();
+}
+
 export function ClearSelection() {
   return window['go']['main']['App']['ClearSelection']();
 }
@@ -10,18 +22,42 @@ export function ClearSnapshots() {
   return window['go']['main']['App']['ClearSnapshots']();
 }
 
-export function CompareSnapshots(arg1, arg2) {
-  return window['go']['main']['App']['CompareSnapshots'](arg1, arg2);
+export function CompareRanges(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CompareRanges'](arg1, arg2, arg3);
+}
+
+export function CompareSessions(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CompareSessions'](arg1, arg2, arg3);
+}
+
+export function CompareSnapshots(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CompareSnapshots'](arg1, arg2, arg3);
 }
 
 export function ConfigureLLM(arg1) {
   return window['go']['main']['App']['ConfigureLLM'](arg1);
 }
 
+export function DeleteAnnotation(arg1, arg2) {
+  return window['go']['main']['App']['DeleteAnnotation'](arg1, arg2);
+}
+
+export function DeleteSchedule(arg1) {
+  return window['go']['main']['App']['DeleteSchedule'](arg1);
+}
+
+export function DeleteSession(arg1) {
+  return window['go']['main']['App']['DeleteSession'](arg1);
+}
+
 export function DiagnoseConnection(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['DiagnoseConnection'](arg1, arg2, arg3, arg4);
 }
 
+export function ExportSession(arg1, arg2) {
+  return window['go']['main']['App']['ExportSession'](arg1, arg2);
+}
+
 export function ExportToCSV(arg1) {
   return window['go']['main']['App']['ExportToCSV'](arg1);
 }
@@ -34,10 +70,22 @@ export function GenerateHealthReportForSession(arg1) {
   return window['go']['main']['App']['GenerateHealthReportForSession'](arg1);
 }
 
+export function GetAnnotations(arg1) {
+  return window['go']['main']['App']['GetAnnotations'](arg1);
+}
+
+export function GetConnectionChartHistory(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['GetConnectionChartHistory'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
 export function GetConnectionCount() {
   return window['go']['main']['App']['GetConnectionCount']();
 }
 
+export function GetConnectionGroups(arg1, arg2) {
+  return window['go']['main']['App']['GetConnectionGroups'](arg1, arg2);
+}
+
 export function GetConnectionHistory(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['GetConnectionHistory'](arg1, arg2, arg3, arg4);
 }
@@ -46,6 +94,10 @@ export function GetConnectionHistoryForSession(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['main']['App']['GetConnectionHistoryForSession'](arg1, arg2, arg3, arg4, arg5);
 }
 
+export function GetConnectionHistoryRange(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['GetConnectionHistoryRange'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
 export function GetConnectionStats(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['GetConnectionStats'](arg1, arg2, arg3, arg4);
 }
@@ -54,20 +106,84 @@ export function GetConnections(arg1) {
   return window['go']['main']['App']['GetConnections'](arg1);
 }
 
+export function GetDefaultThroughputTestConfig() {
+  return window['go']['main']['App']['GetDefaultThroughputTestConfig']();
+}
+
+export function GetFlightRecorderConfig() {
+  return window['go']['main']['App']['GetFlightRecorderConfig']();
+}
+
+export function GetFlightRecorderStatus() {
+  return window['go']['main']['App']['GetFlightRecorderStatus']();
+}
+
+export function GetGeoIPConfig() {
+  return window['go']['main']['App']['GetGeoIPConfig']();
+}
+
 export function GetHealthThresholds() {
   return window['go']['main']['App']['GetHealthThresholds']();
 }
 
+export function GetLatestProbeResults() {
+  return window['go']['main']['App']['GetLatestProbeResults']();
+}
+
+export function GetProbeComparison() {
+  return window['go']['main']['App']['GetProbeComparison']();
+}
+
+export function GetProbeHistory(arg1, arg2) {
+  return window['go']['main']['App']['GetProbeHistory'](arg1, arg2);
+}
+
+export function GetProberConfig() {
+  return window['go']['main']['App']['GetProberConfig']();
+}
+
+export function GetRecordingFieldGroups() {
+  return window['go']['main']['App']['GetRecordingFieldGroups']();
+}
+
+export function GetResolverConfig() {
+  return window['go']['main']['App']['GetResolverConfig']();
+}
+
+export function GetSchedules() {
+  return window['go']['main']['App']['GetSchedules']();
+}
+
+export function GetServiceCatalog() {
+  return window['go']['main']['App']['GetServiceCatalog']();
+}
+
+export function GetSessionChartTimeline(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetSessionChartTimeline'](arg1, arg2, arg3);
+}
+
 export function GetSessionCount() {
   return window['go']['main']['App']['GetSessionCount']();
 }
 
+export function GetSessionGroups(arg1, arg2) {
+  return window['go']['main']['App']['GetSessionGroups'](arg1, arg2);
+}
+
 export function GetSessionTimeline(arg1) {
   return window['go']['main']['App']['GetSessionTimeline'](arg1);
 }
 
-export function GetSessions() {
-  return window['go']['main']['App']['GetSessions']();
+export function GetSessionTimelineRange(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetSessionTimelineRange'](arg1, arg2, arg3);
+}
+
+export function GetSessionWindowAnalysis(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['GetSessionWindowAnalysis'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function GetSessions(arg1) {
+  return window['go']['main']['App']['GetSessions'](arg1);
 }
 
 export function GetSnapshot(arg1) {
@@ -82,10 +198,38 @@ export function GetSnapshotMeta() {
   return window['go']['main']['App']['GetSnapshotMeta']();
 }
 
+export function GetStateAt(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetStateAt'](arg1, arg2, arg3);
+}
+
+export function GetStorageConfig() {
+  return window['go']['main']['App']['GetStorageConfig']();
+}
+
+export function GetStorageSize() {
+  return window['go']['main']['App']['GetStorageSize']();
+}
+
+export function GetThroughputServerStatus() {
+  return window['go']['main']['App']['GetThroughputServerStatus']();
+}
+
+export function GetThroughputTestReports() {
+  return window['go']['main']['App']['GetThroughputTestReports']();
+}
+
 export function GetUpdateInterval() {
   return window['go']['main']['App']['GetUpdateInterval']();
 }
 
+export function GetWindowAnalysis(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['GetWindowAnalysis'](arg1, arg2, arg3, arg4);
+}
+
+export function ImportSession(arg1) {
+  return window['go']['main']['App']['ImportSession'](arg1);
+}
+
 export function IsAdministrator() {
   return window['go']['main']['App']['IsAdministrator']();
 }
@@ -98,6 +242,14 @@ export function IsRecording() {
   return window['go']['main']['App']['IsRecording']();
 }
 
+export function MergeSessions(arg1, arg2) {
+  return window['go']['main']['App']['MergeSessions'](arg1, arg2);
+}
+
+export function ProbeTarget(arg1) {
+  return window['go']['main']['App']['ProbeTarget'](arg1);
+}
+
 export function QueryConnections(arg1) {
   return window['go']['main']['App']['QueryConnections'](arg1);
 }
@@ -110,30 +262,94 @@ export function QueryConnectionsWithHistory(arg1, arg2) {
   return window['go']['main']['App']['QueryConnectionsWithHistory'](arg1, arg2);
 }
 
+export function QueryHistory(arg1) {
+  return window['go']['main']['App']['QueryHistory'](arg1);
+}
+
+export function RunThroughputTest(arg1) {
+  return window['go']['main']['App']['RunThroughputTest'](arg1);
+}
+
+export function SetFlightRecorderConfig(arg1) {
+  return window['go']['main']['App']['SetFlightRecorderConfig'](arg1);
+}
+
+export function SetGeoIPConfig(arg1) {
+  return window['go']['main']['App']['SetGeoIPConfig'](arg1);
+}
+
 export function SetHealthThresholds(arg1) {
   return window['go']['main']['App']['SetHealthThresholds'](arg1);
 }
 
+export function SetProberConfig(arg1) {
+  return window['go']['main']['App']['SetProberConfig'](arg1);
+}
+
 export function SetRTTThreshold(arg1) {
   return window['go']['main']['App']['SetRTTThreshold'](arg1);
 }
 
+export function SetResolverConfig(arg1) {
+  return window['go']['main']['App']['SetResolverConfig'](arg1);
+}
+
 export function SetRetransmissionThreshold(arg1) {
   return window['go']['main']['App']['SetRetransmissionThreshold'](arg1);
 }
 
+export function SetServiceCatalog(arg1) {
+  return window['go']['main']['App']['SetServiceCatalog'](arg1);
+}
+
+export function SetSessionInfo(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SetSessionInfo'](arg1, arg2, arg3, arg4);
+}
+
+export function SetStorageConfig(arg1) {
+  return window['go']['main']['App']['SetStorageConfig'](arg1);
+}
+
 export function SetUpdateInterval(arg1) {
   return window['go']['main']['App']['SetUpdateInterval'](arg1);
 }
 
-export function StartRecording() {
-  return window['go']['main']['App']['StartRecording']();
+export function SplitSession(arg1, arg2) {
+  return window['go']['main']['App']['SplitSession'](arg1, arg2);
+}
+
+export function StartRecording(arg1) {
+  return window['go']['main']['App']['StartRecording'](arg1);
+}
+
+export function StartThroughputServer(arg1) {
+  return window['go']['main']['App']['StartThroughputServer'](arg1);
 }
 
 export function StopRecording() {
   return window['go']['main']['App']['StopRecording']();
 }
 
+export function StopSession(arg1) {
+  return window['go']['main']['App']['StopSession'](arg1);
+}
+
+export function StopThroughputServer() {
+  return window['go']['main']['App']['StopThroughputServer']();
+}
+
 export function TakeSnapshot(arg1) {
   return window['go']['main']['App']['TakeSnapshot'](arg1);
 }
+
+export function TriggerFlightRecorder(arg1) {
+  return window['go']['main']['App']['TriggerFlightRecorder'](arg1);
+}
+
+export function TrimSession(arg1, arg2, arg3) {
+  return window['go']['main']['App']['TrimSession'](arg1, arg2, arg3);
+}
+
+export function UpdateSchedule(arg1) {
+  return window['go']['main']['App']['UpdateSchedule'](arg1);
+}
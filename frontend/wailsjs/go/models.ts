@@ -54,6 +54,204 @@ export namespace llm {
 	        this.success = source["success"];
 	    }
 	}
+	export class SignificanceResult {
+	    test: string;
+	    samplesA: number;
+	    samplesB: number;
+	    u: number;
+	    z: number;
+	    pValue: number;
+	    significant: boolean;
+	    probabilityLower: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SignificanceResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.test = source["test"];
+	        this.samplesA = source["samplesA"];
+	        this.samplesB = source["samplesB"];
+	        this.u = source["u"];
+	        this.z = source["z"];
+	        this.pValue = source["pValue"];
+	        this.significant = source["significant"];
+	        this.probabilityLower = source["probabilityLower"];
+	    }
+	}
+	export class MetricDelta {
+	    metric: string;
+	    a: number;
+	    b: number;
+	    delta: number;
+	    percentChange: number;
+	    change?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MetricDelta(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.metric = source["metric"];
+	        this.a = source["a"];
+	        this.b = source["b"];
+	        this.delta = source["delta"];
+	        this.percentChange = source["percentChange"];
+	        this.change = source["change"];
+	    }
+	}
+	export class SessionGroupStats {
+	    connections: number;
+	    rttSamples: number;
+	    rttMeanMs: number;
+	    rttP50Ms: number;
+	    rttP90Ms: number;
+	    rttP99Ms: number;
+	    lossRate: number;
+	    throughputInBps: number;
+	    throughputOutBps: number;
+	    opened: number;
+	    closed: number;
+	    churnPerMinute: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionGroupStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connections = source["connections"];
+	        this.rttSamples = source["rttSamples"];
+	        this.rttMeanMs = source["rttMeanMs"];
+	        this.rttP50Ms = source["rttP50Ms"];
+	        this.rttP90Ms = source["rttP90Ms"];
+	        this.rttP99Ms = source["rttP99Ms"];
+	        this.lossRate = source["lossRate"];
+	        this.throughputInBps = source["throughputInBps"];
+	        this.throughputOutBps = source["throughputOutBps"];
+	        this.opened = source["opened"];
+	        this.closed = source["closed"];
+	        this.churnPerMinute = source["churnPerMinute"];
+	    }
+	}
+	export class GroupComparison {
+	    key: string;
+	    label: string;
+	    a: SessionGroupStats;
+	    b: SessionGroupStats;
+	    deltas: MetricDelta[];
+	    rttTest?: SignificanceResult;
+	    verdict: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GroupComparison(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.label = source["label"];
+	        this.a = this.convertValues(source["a"], SessionGroupStats);
+	        this.b = this.convertValues(source["b"], SessionGroupStats);
+	        this.deltas = this.convertValues(source["deltas"], MetricDelta);
+	        this.rttTest = this.convertValues(source["rttTest"], SignificanceResult);
+	        this.verdict = source["verdict"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SessionComparison {
+	    sessionA: number;
+	    sessionB: number;
+	    groupBy?: string;
+	    overall: GroupComparison;
+	    groups?: GroupComparison[];
+	    summary: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionComparison(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sessionA = source["sessionA"];
+	        this.sessionB = source["sessionB"];
+	        this.groupBy = source["groupBy"];
+	        this.overall = this.convertValues(source["overall"], GroupComparison);
+	        this.groups = this.convertValues(source["groups"], GroupComparison);
+	        this.summary = source["summary"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ConnectionGroup {
+	    groupBy: string;
+	    key: string;
+	    label: string;
+	    connections: number;
+	    established: number;
+	    withWarning: number;
+	    bytesIn: number;
+	    bytesOut: number;
+	    avgRttMs: number;
+	    maxRttMs: number;
+	    avgRetransmissionRate: number;
+	    remoteAddrs?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ConnectionGroup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.groupBy = source["groupBy"];
+	        this.key = source["key"];
+	        this.label = source["label"];
+	        this.connections = source["connections"];
+	        this.established = source["established"];
+	        this.withWarning = source["withWarning"];
+	        this.bytesIn = source["bytesIn"];
+	        this.bytesOut = source["bytesOut"];
+	        this.avgRttMs = source["avgRttMs"];
+	        this.maxRttMs = source["maxRttMs"];
+	        this.avgRetransmissionRate = source["avgRetransmissionRate"];
+	        this.remoteAddrs = source["remoteAddrs"];
+	    }
+	}
 
 }
 
@@ -84,10 +282,23 @@ export namespace tcpmonitor {
 	    remotePort: number;
 	    state: number;
 	    pid: number;
+	    remoteHostname?: string;
+	    remoteCountry?: string;
+	    remoteCity?: string;
+	    remoteAsn?: number;
+	    remoteAsOrg?: string;
+	    serviceName?: string;
+	    role?: string;
 	    bytesIn: number;
 	    bytesOut: number;
 	    segmentsIn: number;
 	    segmentsOut: number;
+	    timerKind?: string;
+	    timerExpiresMs?: number;
+	    timerRetransmits?: number;
+	    timerProbes?: number;
+	    timerBackoff?: number;
+	    rtoMs?: number;
 	    rtt: number;
 	    rttVariance: number;
 	    minRtt: number;
@@ -97,6 +308,26 @@ export namespace tcpmonitor {
 	    congestionWin: number;
 	    inBandwidth: number;
 	    outBandwidth: number;
+	    rmemAlloc?: number;
+	    rcvBuf?: number;
+	    wmemAlloc?: number;
+	    wmemQueued?: number;
+	    sndBuf?: number;
+	    fwdAlloc?: number;
+	    sockDrops?: number;
+	    sndLimTimeRwin?: number;
+	    sndLimTimeCwnd?: number;
+	    sndLimTimeSnd?: number;
+	    congestionAlgorithm?: string;
+	    caState?: string;
+	    pacingRate?: number;
+	    bbrBandwidth?: number;
+	    bbrMinRtt?: number;
+	    bbrPacingGain?: number;
+	    bbrCwndGain?: number;
+	    dctcpAlpha?: number;
+	    vegasRtt?: number;
+	    vegasMinRtt?: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new CompactConnection(source);
@@ -110,10 +341,23 @@ export namespace tcpmonitor {
 	        this.remotePort = source["remotePort"];
 	        this.state = source["state"];
 	        this.pid = source["pid"];
+	        this.remoteHostname = source["remoteHostname"];
+	        this.remoteCountry = source["remoteCountry"];
+	        this.remoteCity = source["remoteCity"];
+	        this.remoteAsn = source["remoteAsn"];
+	        this.remoteAsOrg = source["remoteAsOrg"];
+	        this.serviceName = source["serviceName"];
+	        this.role = source["role"];
 	        this.bytesIn = source["bytesIn"];
 	        this.bytesOut = source["bytesOut"];
 	        this.segmentsIn = source["segmentsIn"];
 	        this.segmentsOut = source["segmentsOut"];
+	        this.timerKind = source["timerKind"];
+	        this.timerExpiresMs = source["timerExpiresMs"];
+	        this.timerRetransmits = source["timerRetransmits"];
+	        this.timerProbes = source["timerProbes"];
+	        this.timerBackoff = source["timerBackoff"];
+	        this.rtoMs = source["rtoMs"];
 	        this.rtt = source["rtt"];
 	        this.rttVariance = source["rttVariance"];
 	        this.minRtt = source["minRtt"];
@@ -123,13 +367,75 @@ export namespace tcpmonitor {
 	        this.congestionWin = source["congestionWin"];
 	        this.inBandwidth = source["inBandwidth"];
 	        this.outBandwidth = source["outBandwidth"];
+	        this.rmemAlloc = source["rmemAlloc"];
+	        this.rcvBuf = source["rcvBuf"];
+	        this.wmemAlloc = source["wmemAlloc"];
+	        this.wmemQueued = source["wmemQueued"];
+	        this.sndBuf = source["sndBuf"];
+	        this.fwdAlloc = source["fwdAlloc"];
+	        this.sockDrops = source["sockDrops"];
+	        this.sndLimTimeRwin = source["sndLimTimeRwin"];
+	        this.sndLimTimeCwnd = source["sndLimTimeCwnd"];
+	        this.sndLimTimeSnd = source["sndLimTimeSnd"];
+	        this.congestionAlgorithm = source["congestionAlgorithm"];
+	        this.caState = source["caState"];
+	        this.pacingRate = source["pacingRate"];
+	        this.bbrBandwidth = source["bbrBandwidth"];
+	        this.bbrMinRtt = source["bbrMinRtt"];
+	        this.bbrPacingGain = source["bbrPacingGain"];
+	        this.bbrCwndGain = source["bbrCwndGain"];
+	        this.dctcpAlpha = source["dctcpAlpha"];
+	        this.vegasRtt = source["vegasRtt"];
+	        this.vegasMinRtt = source["vegasMinRtt"];
+	    }
+	}
+	export class FieldChange {
+	    field: string;
+	    from: string;
+	    to: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FieldChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.field = source["field"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	    }
+	}
+	export class MetricChange {
+	    metric: string;
+	    kind: string;
+	    from: number;
+	    to: number;
+	    delta: number;
+	    percentChange: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new MetricChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.metric = source["metric"];
+	        this.kind = source["kind"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.delta = source["delta"];
+	        this.percentChange = source["percentChange"];
 	    }
 	}
 	export class ConnectionDiff {
 	    connection: CompactConnection;
+	    previous: CompactConnection;
 	    deltaIn: number;
 	    deltaOut: number;
 	    deltaRtt: number;
+	    changes: string[];
+	    metrics: MetricChange[];
+	    fields?: FieldChange[];
 	
 	    static createFrom(source: any = {}) {
 	        return new ConnectionDiff(source);
@@ -138,9 +444,51 @@ export namespace tcpmonitor {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.connection = this.convertValues(source["connection"], CompactConnection);
+	        this.previous = this.convertValues(source["previous"], CompactConnection);
 	        this.deltaIn = source["deltaIn"];
 	        this.deltaOut = source["deltaOut"];
 	        this.deltaRtt = source["deltaRtt"];
+	        this.changes = source["changes"];
+	        this.metrics = this.convertValues(source["metrics"], MetricChange);
+	        this.fields = this.convertValues(source["fields"], FieldChange);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DiffRange {
+	    sessionId: number;
+	    // Go type: time
+	    start: any;
+	    // Go type: time
+	    end: any;
+	    snapshots: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiffRange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sessionId = source["sessionId"];
+	        this.start = this.convertValues(source["start"], null);
+	        this.end = this.convertValues(source["end"], null);
+	        this.snapshots = source["snapshots"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -164,9 +512,13 @@ export namespace tcpmonitor {
 	export class ComparisonResult {
 	    snapshot1: number;
 	    snapshot2: number;
+	    rangeA?: DiffRange;
+	    rangeB?: DiffRange;
 	    added: CompactConnection[];
 	    removed: CompactConnection[];
 	    changed: ConnectionDiff[];
+	    unchanged: number;
+	    changeCounts: {[key: string]: number};
 	
 	    static createFrom(source: any = {}) {
 	        return new ComparisonResult(source);
@@ -176,9 +528,13 @@ export namespace tcpmonitor {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.snapshot1 = source["snapshot1"];
 	        this.snapshot2 = source["snapshot2"];
+	        this.rangeA = this.convertValues(source["rangeA"], DiffRange);
+	        this.rangeB = this.convertValues(source["rangeB"], DiffRange);
 	        this.added = this.convertValues(source["added"], CompactConnection);
 	        this.removed = this.convertValues(source["removed"], CompactConnection);
 	        this.changed = this.convertValues(source["changed"], ConnectionDiff);
+	        this.unchanged = source["unchanged"];
+	        this.changeCounts = source["changeCounts"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -199,10 +555,11 @@ export namespace tcpmonitor {
 		    return a;
 		}
 	}
-	
+
 	export class ConnectionHistoryPoint {
 	    // Go type: time
 	    timestamp: any;
+	    resolution?: number;
 	    state: number;
 	    bytesIn: number;
 	    bytesOut: number;
@@ -225,6 +582,7 @@ export namespace tcpmonitor {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.resolution = source["resolution"];
 	        this.state = source["state"];
 	        this.bytesIn = source["bytesIn"];
 	        this.bytesOut = source["bytesOut"];
@@ -277,10 +635,23 @@ export namespace tcpmonitor {
 	    CurrentSsthresh: number;
 	    SlowStartCount: number;
 	    CongAvoidCount: number;
+	    CongestionAlgorithm: string;
+	    CAState: string;
+	    PacingRate: number;
+	    BBR?: BBRInfo;
+	    DCTCP?: DCTCPInfo;
+	    Vegas?: VegasInfo;
 	    CurRetxQueue: number;
 	    MaxRetxQueue: number;
 	    CurAppWQueue: number;
 	    MaxAppWQueue: number;
+	    RmemAlloc: number;
+	    RcvBuf: number;
+	    WmemAlloc: number;
+	    WmemQueued: number;
+	    SndBuf: number;
+	    FwdAlloc: number;
+	    SockDrops: number;
 	    OutboundBandwidth: number;
 	    InboundBandwidth: number;
 	
@@ -307,13 +678,44 @@ export namespace tcpmonitor {
 	        this.CurrentSsthresh = source["CurrentSsthresh"];
 	        this.SlowStartCount = source["SlowStartCount"];
 	        this.CongAvoidCount = source["CongAvoidCount"];
+	        this.CongestionAlgorithm = source["CongestionAlgorithm"];
+	        this.CAState = source["CAState"];
+	        this.PacingRate = source["PacingRate"];
+	        this.BBR = this.convertValues(source["BBR"], BBRInfo);
+	        this.DCTCP = this.convertValues(source["DCTCP"], DCTCPInfo);
+	        this.Vegas = this.convertValues(source["Vegas"], VegasInfo);
 	        this.CurRetxQueue = source["CurRetxQueue"];
 	        this.MaxRetxQueue = source["MaxRetxQueue"];
 	        this.CurAppWQueue = source["CurAppWQueue"];
 	        this.MaxAppWQueue = source["MaxAppWQueue"];
+	        this.RmemAlloc = source["RmemAlloc"];
+	        this.RcvBuf = source["RcvBuf"];
+	        this.WmemAlloc = source["WmemAlloc"];
+	        this.WmemQueued = source["WmemQueued"];
+	        this.SndBuf = source["SndBuf"];
+	        this.FwdAlloc = source["FwdAlloc"];
+	        this.SockDrops = source["SockDrops"];
 	        this.OutboundBandwidth = source["OutboundBandwidth"];
 	        this.InboundBandwidth = source["InboundBandwidth"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class ConnectionInfo {
 	    LocalAddr: string;
@@ -327,6 +729,22 @@ export namespace tcpmonitor {
 	    LastSeen: any;
 	    BasicStats?: BasicStats;
 	    ExtendedStats?: ExtendedStats;
+	    RemoteHostname: string;
+	    RemoteCountry: string;
+	    RemoteCity: string;
+	    RemoteASN: number;
+	    RemoteASOrg: string;
+	    ServiceName: string;
+	    Role: string;
+	    Timer?: TimerInfo;
+	    MSSWarning: boolean;
+	    MSSAnomaly?: MSSFinding;
+	    WindowScaleWarning: boolean;
+	    BufferWarning: boolean;
+	    BufferPressure?: BufferFinding;
+	    StallWarning: boolean;
+	    Stall?: StallFinding;
+	    WindowAnalysis?: WindowAnalysis;
 	
 	    static createFrom(source: any = {}) {
 	        return new ConnectionInfo(source);
@@ -344,6 +762,22 @@ export namespace tcpmonitor {
 	        this.LastSeen = this.convertValues(source["LastSeen"], null);
 	        this.BasicStats = this.convertValues(source["BasicStats"], BasicStats);
 	        this.ExtendedStats = this.convertValues(source["ExtendedStats"], ExtendedStats);
+	        this.RemoteHostname = source["RemoteHostname"];
+	        this.RemoteCountry = source["RemoteCountry"];
+	        this.RemoteCity = source["RemoteCity"];
+	        this.RemoteASN = source["RemoteASN"];
+	        this.RemoteASOrg = source["RemoteASOrg"];
+	        this.ServiceName = source["ServiceName"];
+	        this.Role = source["Role"];
+	        this.Timer = this.convertValues(source["Timer"], TimerInfo);
+	        this.MSSWarning = source["MSSWarning"];
+	        this.MSSAnomaly = this.convertValues(source["MSSAnomaly"], MSSFinding);
+	        this.WindowScaleWarning = source["WindowScaleWarning"];
+	        this.BufferWarning = source["BufferWarning"];
+	        this.BufferPressure = this.convertValues(source["BufferPressure"], BufferFinding);
+	        this.StallWarning = source["StallWarning"];
+	        this.Stall = this.convertValues(source["Stall"], StallFinding);
+	        this.WindowAnalysis = this.convertValues(source["WindowAnalysis"], WindowAnalysis);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -373,6 +807,11 @@ export namespace tcpmonitor {
 	    IPv6Only: boolean;
 	    ExcludeInternal: boolean;
 	    SearchText: string;
+	    Country: string;
+	    ASN?: number;
+	    Service: string;
+	    Role: string;
+	    Limitation: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new FilterOptions(source);
@@ -387,7 +826,54 @@ export namespace tcpmonitor {
 	        this.IPv6Only = source["IPv6Only"];
 	        this.ExcludeInternal = source["ExcludeInternal"];
 	        this.SearchText = source["SearchText"];
+	        this.Country = source["Country"];
+	        this.ASN = source["ASN"];
+	        this.Service = source["Service"];
+	        this.Role = source["Role"];
+	        this.Limitation = source["Limitation"];
+	    }
+	}
+	export class DiffOptions {
+	    Filter: FilterOptions;
+	    Changes: string[];
+	    SortBy: string;
+	    Ascending: boolean;
+	    Limit: number;
+	    RTTShiftMs: number;
+	    RTTShiftPercent: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiffOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Filter = this.convertValues(source["Filter"], FilterOptions);
+	        this.Changes = source["Changes"];
+	        this.SortBy = source["SortBy"];
+	        this.Ascending = source["Ascending"];
+	        this.Limit = source["Limit"];
+	        this.RTTShiftMs = source["RTTShiftMs"];
+	        this.RTTShiftPercent = source["RTTShiftPercent"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class HealthThresholds {
 	    RetransmissionRatePercent: number;
@@ -403,24 +889,19 @@ export namespace tcpmonitor {
 	        this.HighRTTMilliseconds = source["HighRTTMilliseconds"];
 	    }
 	}
-	export class RecordingSession {
-	    id: number;
-	    // Go type: time
-	    startTime: any;
+	export class HistoryQueryPoint {
 	    // Go type: time
-	    endTime: any;
-	    snapshotCount: number;
+	    timestamp: any;
+	    value: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new RecordingSession(source);
+	        return new HistoryQueryPoint(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.startTime = this.convertValues(source["startTime"], null);
-	        this.endTime = this.convertValues(source["endTime"], null);
-	        this.snapshotCount = source["snapshotCount"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.value = source["value"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -441,21 +922,32 @@ export namespace tcpmonitor {
 		    return a;
 		}
 	}
-	export class Snapshot {
-	    id: number;
-	    // Go type: time
-	    timestamp: any;
-	    connections: CompactConnection[];
+	export class HistoryQueryRow {
+	    labels: {[key: string]: string};
+	    value: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new Snapshot(source);
+	        return new HistoryQueryRow(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.timestamp = this.convertValues(source["timestamp"], null);
-	        this.connections = this.convertValues(source["connections"], CompactConnection);
+	        this.labels = source["labels"];
+	        this.value = source["value"];
+	    }
+	}
+	export class HistoryQuerySeries {
+	    labels: {[key: string]: string};
+	    points: HistoryQueryPoint[];
+	
+	    static createFrom(source: any = {}) {
+	        return new HistoryQuerySeries(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.labels = source["labels"];
+	        this.points = this.convertValues(source["points"], HistoryQueryPoint);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -476,21 +968,36 @@ export namespace tcpmonitor {
 		    return a;
 		}
 	}
-	export class SnapshotMeta {
-	    id: number;
+	export class HistoryQueryResult {
+	    query: string;
+	    kind: string;
+	    sessionId: number;
 	    // Go type: time
-	    timestamp: any;
-	    connectionCount: number;
+	    start: any;
+	    // Go type: time
+	    end: any;
+	    step?: number;
+	    labels: string[];
+	    value: string;
+	    rows?: HistoryQueryRow[];
+	    series?: HistoryQuerySeries[];
 	
 	    static createFrom(source: any = {}) {
-	        return new SnapshotMeta(source);
+	        return new HistoryQueryResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.timestamp = this.convertValues(source["timestamp"], null);
-	        this.connectionCount = source["connectionCount"];
+	        this.query = source["query"];
+	        this.kind = source["kind"];
+	        this.sessionId = source["sessionId"];
+	        this.start = this.convertValues(source["start"], null);
+	        this.end = this.convertValues(source["end"], null);
+	        this.step = source["step"];
+	        this.labels = source["labels"];
+	        this.value = source["value"];
+	        this.rows = this.convertValues(source["rows"], HistoryQueryRow);
+	        this.series = this.convertValues(source["series"], HistoryQuerySeries);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -511,19 +1018,68 @@ export namespace tcpmonitor {
 		    return a;
 		}
 	}
-	export class TimelineConnection {
+	export class RecordingScope {
+	    processes?: string[];
+	    ports?: number[];
+	    cidrs?: string[];
+	    states?: number[];
+	    fields?: string[];
+	    intervalMs?: number;
+	    retentionDays?: number;
+	    fullResolutionHours?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RecordingScope(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.processes = source["processes"];
+	        this.ports = source["ports"];
+	        this.cidrs = source["cidrs"];
+	        this.states = source["states"];
+	        this.fields = source["fields"];
+	        this.intervalMs = source["intervalMs"];
+	        this.retentionDays = source["retentionDays"];
+	        this.fullResolutionHours = source["fullResolutionHours"];
+	    }
+	}
+	export class RecordingSchedule {
+	    id: number;
+	    name: string;
+	    enabled: boolean;
+	    cron?: string;
+	    durationMinutes?: number;
 	    // Go type: time
-	    timestamp: any;
-	    connection: CompactConnection;
+	    start?: any;
+	    // Go type: time
+	    end?: any;
+	    scope: RecordingScope;
+	    nameTemplate?: string;
+	    // Go type: time
+	    lastRun?: any;
+	    // Go type: time
+	    nextRun?: any;
+	    sessionId?: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new TimelineConnection(source);
+	        return new RecordingSchedule(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.timestamp = this.convertValues(source["timestamp"], null);
-	        this.connection = this.convertValues(source["connection"], CompactConnection);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.enabled = source["enabled"];
+	        this.cron = source["cron"];
+	        this.durationMinutes = source["durationMinutes"];
+	        this.start = this.convertValues(source["start"], null);
+	        this.end = this.convertValues(source["end"], null);
+	        this.scope = this.convertValues(source["scope"], RecordingScope);
+	        this.nameTemplate = source["nameTemplate"];
+	        this.lastRun = this.convertValues(source["lastRun"], null);
+	        this.nextRun = this.convertValues(source["nextRun"], null);
+	        this.sessionId = source["sessionId"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -544,6 +1100,1055 @@ export namespace tcpmonitor {
 		    return a;
 		}
 	}
+	export class ThresholdChange {
+	    // Go type: time
+	    from: any;
+	    thresholds: HealthThresholds;
+	
+	    static createFrom(source: any = {}) {
+	        return new ThresholdChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.from = this.convertValues(source["from"], null);
+	        this.thresholds = this.convertValues(source["thresholds"], HealthThresholds);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ArchiveInterface {
+	    name: string;
+	    mtu: number;
+	    up: boolean;
+	    loopback?: boolean;
+	    addresses?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ArchiveInterface(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.mtu = source["mtu"];
+	        this.up = source["up"];
+	        this.loopback = source["loopback"];
+	        this.addresses = source["addresses"];
+	    }
+	}
+	export class ArchiveHost {
+	    hostname: string;
+	    os: string;
+	    arch: string;
+	    extendedStats: boolean;
+	    interfaces?: ArchiveInterface[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ArchiveHost(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hostname = source["hostname"];
+	        this.os = source["os"];
+	        this.arch = source["arch"];
+	        this.extendedStats = source["extendedStats"];
+	        this.interfaces = this.convertValues(source["interfaces"], ArchiveInterface);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RecordingSession {
+	    id: number;
+	    // Go type: time
+	    startTime: any;
+	    // Go type: time
+	    endTime: any;
+	    snapshotCount: number;
+	    recovered?: boolean;
+	    importedFrom?: string;
+	    importedHost?: ArchiveHost;
+	    name?: string;
+	    description?: string;
+	    tags?: string[];
+	    scope?: RecordingScope;
+	    // Go type: time
+	    downsampledUntil?: any;
+	    healthThresholds?: ThresholdChange[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RecordingSession(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.startTime = this.convertValues(source["startTime"], null);
+	        this.endTime = this.convertValues(source["endTime"], null);
+	        this.snapshotCount = source["snapshotCount"];
+	        this.recovered = source["recovered"];
+	        this.importedFrom = source["importedFrom"];
+	        this.importedHost = this.convertValues(source["importedHost"], ArchiveHost);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.tags = source["tags"];
+	        this.scope = this.convertValues(source["scope"], RecordingScope);
+	        this.downsampledUntil = this.convertValues(source["downsampledUntil"], null);
+	        this.healthThresholds = this.convertValues(source["healthThresholds"], ThresholdChange);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SessionState {
+	    sessionId: number;
+	    // Go type: time
+	    requested: any;
+	    snapshotId: number;
+	    // Go type: time
+	    timestamp: any;
+	    thresholds: HealthThresholds;
+	    total: number;
+	    connections: ConnectionInfo[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionState(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sessionId = source["sessionId"];
+	        this.requested = this.convertValues(source["requested"], null);
+	        this.snapshotId = source["snapshotId"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.thresholds = this.convertValues(source["thresholds"], HealthThresholds);
+	        this.total = source["total"];
+	        this.connections = this.convertValues(source["connections"], ConnectionInfo);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Snapshot {
+	    id: number;
+	    // Go type: time
+	    timestamp: any;
+	    connections: CompactConnection[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Snapshot(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.connections = this.convertValues(source["connections"], CompactConnection);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SnapshotMeta {
+	    id: number;
+	    // Go type: time
+	    timestamp: any;
+	    connectionCount: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SnapshotMeta(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.connectionCount = source["connectionCount"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Annotation {
+	    id: number;
+	    sessionId: number;
+	    // Go type: time
+	    timestamp: any;
+	    // Go type: time
+	    endTime?: any;
+	    text: string;
+	    category: string;
+	    author?: string;
+	    // Go type: time
+	    createdAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Annotation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.sessionId = source["sessionId"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.endTime = this.convertValues(source["endTime"], null);
+	        this.text = source["text"];
+	        this.category = source["category"];
+	        this.author = source["author"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TimelineConnection {
+	    // Go type: time
+	    timestamp: any;
+	    resolution?: number;
+	    connection: CompactConnection;
+	    annotation?: Annotation;
+	
+	    static createFrom(source: any = {}) {
+	        return new TimelineConnection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.resolution = source["resolution"];
+	        this.connection = this.convertValues(source["connection"], CompactConnection);
+	        this.annotation = this.convertValues(source["annotation"], Annotation);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+	export class FlightRecorderConfig {
+	    enabled: boolean;
+	    preTriggerSeconds: number;
+	    postTriggerSeconds: number;
+	    healthWarnings: number;
+	    connectionSpike: number;
+	    processes?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FlightRecorderConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.preTriggerSeconds = source["preTriggerSeconds"];
+	        this.postTriggerSeconds = source["postTriggerSeconds"];
+	        this.healthWarnings = source["healthWarnings"];
+	        this.connectionSpike = source["connectionSpike"];
+	        this.processes = source["processes"];
+	    }
+	}
+	export class FlightTrigger {
+	    kind: string;
+	    reason: string;
+	    // Go type: time
+	    time: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new FlightTrigger(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.reason = source["reason"];
+	        this.time = this.convertValues(source["time"], null);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FlightRecorderStatus {
+	    enabled: boolean;
+	    recording: boolean;
+	    sessionId?: number;
+	    // Go type: time
+	    stopsAt?: any;
+	    bufferedSnapshots: number;
+	    // Go type: time
+	    bufferedSince?: any;
+	    lastTrigger?: FlightTrigger;
+	
+	    static createFrom(source: any = {}) {
+	        return new FlightRecorderStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.recording = source["recording"];
+	        this.sessionId = source["sessionId"];
+	        this.stopsAt = this.convertValues(source["stopsAt"], null);
+	        this.bufferedSnapshots = source["bufferedSnapshots"];
+	        this.bufferedSince = this.convertValues(source["bufferedSince"], null);
+	        this.lastTrigger = this.convertValues(source["lastTrigger"], FlightTrigger);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ResolverConfig {
+	    Enabled: boolean;
+	    TTLSeconds: number;
+	    NegativeTTL: number;
+	    LookupBudget: number;
+	    HostsFiles: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ResolverConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Enabled = source["Enabled"];
+	        this.TTLSeconds = source["TTLSeconds"];
+	        this.NegativeTTL = source["NegativeTTL"];
+	        this.LookupBudget = source["LookupBudget"];
+	        this.HostsFiles = source["HostsFiles"];
+	    }
+	}
+	export class GeoIPConfig {
+	    CityDBPath: string;
+	    ASNDBPath: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GeoIPConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.CityDBPath = source["CityDBPath"];
+	        this.ASNDBPath = source["ASNDBPath"];
+	    }
+	}
+	export class ServiceCatalogEntry {
+	    Pattern: string;
+	    Name: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ServiceCatalogEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Pattern = source["Pattern"];
+	        this.Name = source["Name"];
+	    }
+	}
+	export class ProbeResult {
+	    sessionId?: number;
+	    // Go type: time
+	    timestamp: any;
+	    target: string;
+	    connectMs: number;
+	    outcome: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProbeResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sessionId = source["sessionId"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.target = source["target"];
+	        this.connectMs = source["connectMs"];
+	        this.outcome = source["outcome"];
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProbeComparison {
+	    target: string;
+	    samples: number;
+	    connectP50Ms: number;
+	    connectP95Ms: number;
+	    failureRate: number;
+	    passiveRttMs: number;
+	    connections: number;
+	    assessment: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProbeComparison(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.target = source["target"];
+	        this.samples = source["samples"];
+	        this.connectP50Ms = source["connectP50Ms"];
+	        this.connectP95Ms = source["connectP95Ms"];
+	        this.failureRate = source["failureRate"];
+	        this.passiveRttMs = source["passiveRttMs"];
+	        this.connections = source["connections"];
+	        this.assessment = source["assessment"];
+	    }
+	}
+	export class ProberConfig {
+	    Enabled: boolean;
+	    IntervalSeconds: number;
+	    TimeoutMs: number;
+	    Targets: string[];
+	    ProbeObserved: boolean;
+	    MaxObservedTargets: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProberConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Enabled = source["Enabled"];
+	        this.IntervalSeconds = source["IntervalSeconds"];
+	        this.TimeoutMs = source["TimeoutMs"];
+	        this.Targets = source["Targets"];
+	        this.ProbeObserved = source["ProbeObserved"];
+	        this.MaxObservedTargets = source["MaxObservedTargets"];
+	    }
+	}
+	export class ThroughputTestConfig {
+	    Target: string;
+	    Direction: string;
+	    DurationSeconds: number;
+	    Streams: number;
+	    BufferSize: number;
+	    SocketBufferSize: number;
+	    SampleIntervalMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ThroughputTestConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Target = source["Target"];
+	        this.Direction = source["Direction"];
+	        this.DurationSeconds = source["DurationSeconds"];
+	        this.Streams = source["Streams"];
+	        this.BufferSize = source["BufferSize"];
+	        this.SocketBufferSize = source["SocketBufferSize"];
+	        this.SampleIntervalMs = source["SampleIntervalMs"];
+	    }
+	}
+	export class ThroughputSample {
+	    // Go type: time
+	    timestamp: any;
+	    elapsedMs: number;
+	    stream: number;
+	    bytes: number;
+	    throughputBps: number;
+	    rtt: number;
+	    rttVariance: number;
+	    cwnd: number;
+	    ssthresh: number;
+	    segsRetrans: number;
+	    bytesRetrans: number;
+	    fastRetrans: number;
+	    timeouts: number;
+	    curRwinRcvd: number;
+	    curRwinSent: number;
+	    curMss: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ThroughputSample(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.elapsedMs = source["elapsedMs"];
+	        this.stream = source["stream"];
+	        this.bytes = source["bytes"];
+	        this.throughputBps = source["throughputBps"];
+	        this.rtt = source["rtt"];
+	        this.rttVariance = source["rttVariance"];
+	        this.cwnd = source["cwnd"];
+	        this.ssthresh = source["ssthresh"];
+	        this.segsRetrans = source["segsRetrans"];
+	        this.bytesRetrans = source["bytesRetrans"];
+	        this.fastRetrans = source["fastRetrans"];
+	        this.timeouts = source["timeouts"];
+	        this.curRwinRcvd = source["curRwinRcvd"];
+	        this.curRwinSent = source["curRwinSent"];
+	        this.curMss = source["curMss"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ThroughputStreamResult {
+	    stream: number;
+	    localAddr: string;
+	    localPort: number;
+	    remoteAddr: string;
+	    remotePort: number;
+	    bytes: number;
+	    durationMs: number;
+	    throughputBps: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ThroughputStreamResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.stream = source["stream"];
+	        this.localAddr = source["localAddr"];
+	        this.localPort = source["localPort"];
+	        this.remoteAddr = source["remoteAddr"];
+	        this.remotePort = source["remotePort"];
+	        this.bytes = source["bytes"];
+	        this.durationMs = source["durationMs"];
+	        this.throughputBps = source["throughputBps"];
+	        this.error = source["error"];
+	    }
+	}
+	export class ThroughputTestReport {
+	    id: number;
+	    role: string;
+	    config: ThroughputTestConfig;
+	    // Go type: time
+	    startTime: any;
+	    // Go type: time
+	    endTime: any;
+	    totalBytes: number;
+	    throughputBps: number;
+	    streams: ThroughputStreamResult[];
+	    samples: ThroughputSample[];
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ThroughputTestReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.role = source["role"];
+	        this.config = this.convertValues(source["config"], ThroughputTestConfig);
+	        this.startTime = this.convertValues(source["startTime"], null);
+	        this.endTime = this.convertValues(source["endTime"], null);
+	        this.totalBytes = source["totalBytes"];
+	        this.throughputBps = source["throughputBps"];
+	        this.streams = this.convertValues(source["streams"], ThroughputStreamResult);
+	        this.samples = this.convertValues(source["samples"], ThroughputSample);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ThroughputServerStatus {
+	    running: boolean;
+	    listenAddr: string;
+	    activeStreams: number;
+	    recent: ThroughputTestReport[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ThroughputServerStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.listenAddr = source["listenAddr"];
+	        this.activeStreams = source["activeStreams"];
+	        this.recent = this.convertValues(source["recent"], ThroughputTestReport);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MSSFinding {
+	    anomaly: string;
+	    severity: string;
+	    description: string;
+	    fromMss?: number;
+	    toMss?: number;
+	    // Go type: time
+	    timestamp?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new MSSFinding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.anomaly = source["anomaly"];
+	        this.severity = source["severity"];
+	        this.description = source["description"];
+	        this.fromMss = source["fromMss"];
+	        this.toMss = source["toMss"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class WindowAnalysis {
+	    limitation: string;
+	    direction: string;
+	    throughputBps: number;
+	    rttMs: number;
+	    bdpBytes: number;
+	    pathBdpBytes: number;
+	    cwnd: number;
+	    peerRwnd: number;
+	    localRwnd: number;
+	    winScaleSent: number;
+	    winScaleRcvd: number;
+	    windowScaleIssue?: string;
+	    explanation: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new WindowAnalysis(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.limitation = source["limitation"];
+	        this.direction = source["direction"];
+	        this.throughputBps = source["throughputBps"];
+	        this.rttMs = source["rttMs"];
+	        this.bdpBytes = source["bdpBytes"];
+	        this.pathBdpBytes = source["pathBdpBytes"];
+	        this.cwnd = source["cwnd"];
+	        this.peerRwnd = source["peerRwnd"];
+	        this.localRwnd = source["localRwnd"];
+	        this.winScaleSent = source["winScaleSent"];
+	        this.winScaleRcvd = source["winScaleRcvd"];
+	        this.windowScaleIssue = source["windowScaleIssue"];
+	        this.explanation = source["explanation"];
+	    }
+	}
+	export class BBRInfo {
+	    BandwidthBps: number;
+	    MinRTTUs: number;
+	    PacingGain: number;
+	    CwndGain: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BBRInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.BandwidthBps = source["BandwidthBps"];
+	        this.MinRTTUs = source["MinRTTUs"];
+	        this.PacingGain = source["PacingGain"];
+	        this.CwndGain = source["CwndGain"];
+	    }
+	}
+	export class DCTCPInfo {
+	    Enabled: boolean;
+	    CEState: number;
+	    Alpha: number;
+	    ABECN: number;
+	    ABTotal: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DCTCPInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Enabled = source["Enabled"];
+	        this.CEState = source["CEState"];
+	        this.Alpha = source["Alpha"];
+	        this.ABECN = source["ABECN"];
+	        this.ABTotal = source["ABTotal"];
+	    }
+	}
+	export class VegasInfo {
+	    Enabled: boolean;
+	    RTTCount: number;
+	    RTTUs: number;
+	    MinRTTUs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new VegasInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Enabled = source["Enabled"];
+	        this.RTTCount = source["RTTCount"];
+	        this.RTTUs = source["RTTUs"];
+	        this.MinRTTUs = source["MinRTTUs"];
+	    }
+	}
+	export class BufferFinding {
+	    condition: string;
+	    severity: string;
+	    description: string;
+	    used?: number;
+	    limit?: number;
+	    drops?: number;
+	    // Go type: time
+	    timestamp?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new BufferFinding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.condition = source["condition"];
+	        this.severity = source["severity"];
+	        this.description = source["description"];
+	        this.used = source["used"];
+	        this.limit = source["limit"];
+	        this.drops = source["drops"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TimerInfo {
+	    Kind: string;
+	    ExpiresMs: number;
+	    Retransmits: number;
+	    Probes: number;
+	    Backoff: number;
+	    RTOMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TimerInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Kind = source["Kind"];
+	        this.ExpiresMs = source["ExpiresMs"];
+	        this.Retransmits = source["Retransmits"];
+	        this.Probes = source["Probes"];
+	        this.Backoff = source["Backoff"];
+	        this.RTOMs = source["RTOMs"];
+	    }
+	}
+	export class StallFinding {
+	    condition: string;
+	    severity: string;
+	    description: string;
+	    backoff?: number;
+	    retransmits?: number;
+	    probes?: number;
+	    durationMs: number;
+	    expiresMs?: number;
+	    // Go type: time
+	    timestamp?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new StallFinding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.condition = source["condition"];
+	        this.severity = source["severity"];
+	        this.description = source["description"];
+	        this.backoff = source["backoff"];
+	        this.retransmits = source["retransmits"];
+	        this.probes = source["probes"];
+	        this.durationMs = source["durationMs"];
+	        this.expiresMs = source["expiresMs"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class StorageConfig {
+	    Enabled: boolean;
+	    Dir: string;
+	    RetentionDays: number;
+	    MaxSizeMB: number;
+	    FullResolutionHours: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new StorageConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Enabled = source["Enabled"];
+	        this.Dir = source["Dir"];
+	        this.RetentionDays = source["RetentionDays"];
+	        this.MaxSizeMB = source["MaxSizeMB"];
+	        this.FullResolutionHours = source["FullResolutionHours"];
+	    }
+	}
 
 }
 
@@ -4,7 +4,12 @@ go 1.24
 
 toolchain go1.24.2
 
-require github.com/wailsapp/wails/v2 v2.11.0
+require (
+	github.com/oschwald/maxminddb-golang v1.13.1
+	github.com/wailsapp/wails/v2 v2.11.0
+	golang.org/x/sys v0.31.0
+	google.golang.org/genai v1.38.0
+)
 
 require (
 	cloud.google.com/go v0.116.0 // indirect
@@ -40,9 +45,7 @@ require (
 	go.opencensus.io v0.24.0 // indirect
 	golang.org/x/crypto v0.36.0 // indirect
 	golang.org/x/net v0.38.0 // indirect
-	golang.org/x/sys v0.31.0 // indirect
 	golang.org/x/text v0.23.0 // indirect
-	google.golang.org/genai v1.38.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240903143218-8af14fe29dc1 // indirect
 	google.golang.org/grpc v1.66.2 // indirect
 	google.golang.org/protobuf v1.34.2 // indirect
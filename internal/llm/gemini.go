@@ -223,6 +223,59 @@ func (g *GeminiService) QueryConnectionsWithHistory(ctx context.Context, query s
 						Required: []string{"sessionID", "localAddr", "localPort", "remoteAddr", "remotePort", "metric"},
 					},
 				},
+				{
+					Name:        "group_connections",
+					Description: "Break down a session's connections by a dimension (country, autonomous system, service, process, port, ...) with per-group RTT and retransmission aggregates. Use this to check whether a problem is confined to one network or destination.",
+					Parameters: &genai.Schema{
+						Type: genai.TypeObject,
+						Properties: map[string]*genai.Schema{
+							"sessionID": {Type: genai.TypeInteger, Description: "The recording session ID"},
+							"groupBy":   {Type: genai.TypeString, Description: "Dimension to group by", Enum: []string{"remoteAddr", "hostname", "remotePort", "pid", "state", "country", "asn", "service", "role", "limitation", "congestion"}},
+						},
+						Required: []string{"sessionID", "groupBy"},
+					},
+				},
+				{
+					Name:        "compare_sessions",
+					Description: "Compare two recorded sessions (A = before, B = after) overall and per group: RTT percentiles, loss rate, throughput and connection churn with deltas, plus a Mann-Whitney U significance test on RTT. Use this to answer whether the network got better or worse after a change.",
+					Parameters: &genai.Schema{
+						Type: genai.TypeObject,
+						Properties: map[string]*genai.Schema{
+							"sessionA": {Type: genai.TypeInteger, Description: "The baseline (before) session ID"},
+							"sessionB": {Type: genai.TypeInteger, Description: "The session compared against the baseline (after)"},
+							"groupBy":  {Type: genai.TypeString, Description: "Dimension to compare per group; omit for overall only", Enum: []string{"remoteAddr", "hostname", "remotePort", "pid", "state", "country", "asn", "service", "role", "limitation", "congestion"}},
+						},
+						Required: []string{"sessionA", "sessionB"},
+					},
+				},
+				{
+					Name:        "get_state_at",
+					Description: "Get the connection table of a recorded session as it was at one instant, built from the nearest snapshot, with health warnings judged by the thresholds in effect at the time. Use this for questions like 'what did the table look like at 14:03:12'.",
+					Parameters: &genai.Schema{
+						Type: genai.TypeObject,
+						Properties: map[string]*genai.Schema{
+							"sessionID": {Type: genai.TypeInteger, Description: "The session ID"},
+							"timestamp": {Type: genai.TypeString, Description: "The instant in ISO8601 format"},
+							"state":     {Type: genai.TypeString, Description: "Only connections in this TCP state, e.g. ESTABLISHED or TIME_WAIT"},
+							"port":      {Type: genai.TypeInteger, Description: "Only connections with this local or remote port"},
+							"pid":       {Type: genai.TypeInteger, Description: "Only connections of this process"},
+							"search":    {Type: genai.TypeString, Description: "Only connections whose address, hostname or service contains this text"},
+							"limit":     {Type: genai.TypeInteger, Description: "Maximum connections to list (default and maximum 30); warnings come first"},
+						},
+						Required: []string{"sessionID", "timestamp"},
+					},
+				},
+				{
+					Name:        "query_history",
+					Description: "Run an ad-hoc query over a recorded session, returning a table or, with step, time series. Syntax: [topk|bottomk(k, ] [avg|sum|min|max|count|stddev|p50|p90|p95|p99(] metric | rate(counter) | delta(counter) [)] [by label, ...] [where cond] [over session N|latest [from 'time' to 'time' | last 10m]] [step 30s] [limit N]. Labels: localAddr, localPort, remoteAddr, remotePort, connection, state, pid, hostname, country, city, asn, asOrg, service, role, timerKind, congestion, caState. Conditions use =, !=, <, <=, >, >=, =~ (regex) and !~ with and, or, not. Metrics are CompactConnection fields such as rtt, segsRetrans, bytesIn, bytesOut, congestionWin. Examples: \"avg(rtt) by remoteAddr where state = 'ESTABLISHED' and remotePort = 443 over session 12 step 10s\", \"topk(5, rate(segsRetrans)) by pid\".",
+					Parameters: &genai.Schema{
+						Type: genai.TypeObject,
+						Properties: map[string]*genai.Schema{
+							"query": {Type: genai.TypeString, Description: "The query"},
+						},
+						Required: []string{"query"},
+					},
+				},
 				{
 					Name:        "plot_graph",
 					Description: "Suggest a graph visualization to show data to the user. Use this whenever you want to visualize distributions or trends.",
@@ -58,6 +58,14 @@ type ConnectionSummary struct {
 	LocalPort            uint16  `json:"localPort"`
 	RemoteAddr           string  `json:"remoteAddr"`
 	RemotePort           uint16  `json:"remotePort"`
+	RemoteHostname       string  `json:"remoteHostname,omitempty"`
+	RemoteCountry        string  `json:"remoteCountry,omitempty"`
+	RemoteCity           string  `json:"remoteCity,omitempty"`
+	RemoteASN            uint32  `json:"remoteAsn,omitempty"`
+	RemoteASOrg          string  `json:"remoteAsOrg,omitempty"`
+	PID                  uint32  `json:"pid,omitempty"`
+	ServiceName          string  `json:"serviceName,omitempty"`
+	Role                 string  `json:"role,omitempty"` // client, server or listener
 	State                string  `json:"state"`
 	BytesIn              uint64  `json:"bytesIn"`
 	BytesOut             uint64  `json:"bytesOut"`
@@ -68,8 +76,15 @@ type ConnectionSummary struct {
 	HasWarning           bool    `json:"hasWarning"`
 
 	// Congestion Control
-	CongestionWindow   uint64 `json:"congestionWindow"`
-	SlowStartThreshold uint64 `json:"slowStartThreshold"`
+	CongestionWindow    uint64  `json:"congestionWindow"`
+	SlowStartThreshold  uint64  `json:"slowStartThreshold"`
+	CongestionAlgorithm string  `json:"congestionAlgorithm,omitempty"` // e.g. cubic, bbr (Linux only)
+	CongestionState     string  `json:"congestionState,omitempty"`     // open, disorder, cwr, recovery, loss
+	PacingRateBps       uint64  `json:"pacingRateBps,omitempty"`
+	BBRBandwidthBps     uint64  `json:"bbrBandwidthBps,omitempty"` // BBR bottleneck bandwidth estimate
+	BBRMinRTTMs         float64 `json:"bbrMinRttMs,omitempty"`
+	BBRPacingGain       float64 `json:"bbrPacingGain,omitempty"`
+	DCTCPAlpha          float64 `json:"dctcpAlpha,omitempty"` // Fraction of ECN-marked bytes, 0-1
 
 	// Retransmission Details
 	FastRetransmissions uint64 `json:"fastRetransmissions"`
@@ -83,6 +98,15 @@ type ConnectionSummary struct {
 	WindowScaleSent     int    `json:"windowScaleSent"`
 	WindowScaleReceived int    `json:"windowScaleReceived"`
 
+	// Socket buffers (Linux): memory in use vs. limit
+	AppWriteQueue      uint64 `json:"appWriteQueue,omitempty"`     // Unsent bytes queued by the application
+	RetransmitQueue    uint64 `json:"retransmitQueue,omitempty"`   // Unacknowledged bytes in flight
+	ReceiveBufferUsed  uint64 `json:"receiveBufferUsed,omitempty"` // Unread data in the receive queue
+	ReceiveBufferLimit uint64 `json:"receiveBufferLimit,omitempty"`
+	SendBufferUsed     uint64 `json:"sendBufferUsed,omitempty"`
+	SendBufferLimit    uint64 `json:"sendBufferLimit,omitempty"`
+	SocketDrops        uint64 `json:"socketDrops,omitempty"`
+
 	// MSS
 	CurrentMSS uint64 `json:"currentMSS"`
 	MaxMSS     uint64 `json:"maxMSS"`
@@ -92,6 +116,15 @@ type ConnectionSummary struct {
 	RTTVarianceMs float64 `json:"rttVarianceMs"`
 	MinRTTMs      float64 `json:"minRTTMs"`
 	MaxRTTMs      float64 `json:"maxRTTMs"`
+
+	// Pending TCP timer, e.g. "retransmit in 3.2s (backoff 3)" (Linux)
+	Timer string `json:"timer,omitempty"`
+
+	// Throughput limitation: rwnd_limited, cwnd_limited, app_limited, network_limited, unknown or idle
+	Limitation string `json:"limitation,omitempty"`
+
+	// Findings from local detectors (MSS/PMTU, window/BDP, ...)
+	Findings []string `json:"findings,omitempty"`
 }
 
 // LLMConfig holds configuration for the LLM service
@@ -130,12 +163,17 @@ type PerformancePeriod struct {
 
 // ConnectionRanking ranks a connection by a specific metric
 type ConnectionRanking struct {
-	LocalAddr  string  `json:"localAddr"`
-	RemoteAddr string  `json:"remoteAddr"`
-	LocalPort  uint16  `json:"localPort"`
-	RemotePort uint16  `json:"remotePort"`
-	Score      float64 `json:"score"`    // The metric value
-	Severity   string  `json:"severity"` // "high", "medium", "low"
+	LocalAddr      string  `json:"localAddr"`
+	RemoteAddr     string  `json:"remoteAddr"`
+	RemoteHostname string  `json:"remoteHostname,omitempty"`
+	RemoteCountry  string  `json:"remoteCountry,omitempty"`
+	RemoteASN      uint32  `json:"remoteAsn,omitempty"`
+	RemoteASOrg    string  `json:"remoteAsOrg,omitempty"`
+	ServiceName    string  `json:"serviceName,omitempty"`
+	LocalPort      uint16  `json:"localPort"`
+	RemotePort     uint16  `json:"remotePort"`
+	Score          float64 `json:"score"`    // The metric value
+	Severity       string  `json:"severity"` // "high", "medium", "low"
 }
 
 // MajorEvent represents a significant event affecting multiple connections
@@ -147,6 +185,22 @@ type MajorEvent struct {
 	Severity    string    `json:"severity"`
 }
 
+// ConnectionGroup aggregates connections sharing a dimension value (country, ASN, ...)
+type ConnectionGroup struct {
+	GroupBy     string   `json:"groupBy"`
+	Key         string   `json:"key"`
+	Label       string   `json:"label"`
+	Connections int      `json:"connections"`
+	Established int      `json:"established"`
+	WithWarning int      `json:"withWarning"`
+	BytesIn     uint64   `json:"bytesIn"`
+	BytesOut    uint64   `json:"bytesOut"`
+	AvgRTTMs    float64  `json:"avgRttMs"`
+	MaxRTTMs    float64  `json:"maxRttMs"`
+	AvgRetrans  float64  `json:"avgRetransmissionRate"`
+	RemoteAddrs []string `json:"remoteAddrs,omitempty"` // Sample of distinct remote addresses
+}
+
 // SessionHighlights contains preprocessed session analysis
 type SessionHighlights struct {
 	// Session overview
@@ -173,6 +227,137 @@ type SessionHighlights struct {
 	// Temporal patterns
 	TimeOfWorstPerformance time.Time `json:"timeOfWorstPerformance,omitempty"`
 	TimeOfBestPerformance  time.Time `json:"timeOfBestPerformance,omitempty"`
+
+	// User annotations with the metrics around them
+	Annotations []AnnotationImpact `json:"annotations,omitempty"`
+}
+
+// AnnotationImpact compares the metrics before a user annotation with those
+// after it, or during it for an annotation covering a time range
+type AnnotationImpact struct {
+	Timestamp time.Time        `json:"timestamp"`
+	EndTime   time.Time        `json:"endTime,omitempty"`
+	Text      string           `json:"text"`
+	Category  string           `json:"category"`
+	Author    string           `json:"author,omitempty"`
+	Before    AnnotationWindow `json:"before"`
+	After     AnnotationWindow `json:"after"`
+	Summary   string           `json:"summary"` // e.g. "RTT 42.0 → 81.3 ms (+94%)"
+}
+
+// AnnotationWindow aggregates the connections seen in a time window
+type AnnotationWindow struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Samples     int       `json:"samples"`
+	Connections int       `json:"connections"`
+	AvgRTTMs    float64   `json:"avgRttMs"`
+	RetransRate float64   `json:"retransRate"` // Percentage of segments sent
+}
+
+// SessionComparison compares two recorded sessions, overall and per group
+// of connections, e.g. before and after a change
+type SessionComparison struct {
+	SessionA int64             `json:"sessionA"`
+	SessionB int64             `json:"sessionB"`
+	GroupBy  string            `json:"groupBy,omitempty"`
+	Overall  GroupComparison   `json:"overall"`
+	Groups   []GroupComparison `json:"groups,omitempty"` // Most connections first
+	Summary  string            `json:"summary"`
+}
+
+// GroupComparison compares the connections of one group in two sessions
+type GroupComparison struct {
+	Key     string              `json:"key"`
+	Label   string              `json:"label"`
+	A       SessionGroupStats   `json:"a"`
+	B       SessionGroupStats   `json:"b"`
+	Deltas  []MetricDelta       `json:"deltas"`
+	RTTTest *SignificanceResult `json:"rttTest,omitempty"` // B's RTT samples against A's
+	Verdict string              `json:"verdict"`           // "better", "worse", "mixed", "unchanged", "only_a", "only_b"
+}
+
+// SessionGroupStats aggregates a group's connections over one session
+type SessionGroupStats struct {
+	Connections    int     `json:"connections"`
+	RTTSamples     int     `json:"rttSamples"`
+	RTTMeanMs      float64 `json:"rttMeanMs"`
+	RTTP50Ms       float64 `json:"rttP50Ms"`
+	RTTP90Ms       float64 `json:"rttP90Ms"`
+	RTTP99Ms       float64 `json:"rttP99Ms"`
+	LossRate       float64 `json:"lossRate"`         // Percentage of segments sent that were retransmitted
+	ThroughputIn   float64 `json:"throughputInBps"`  // Bytes per second over the session
+	ThroughputOut  float64 `json:"throughputOutBps"` // Bytes per second over the session
+	Opened         int     `json:"opened"`           // Connections first seen after the session started
+	Closed         int     `json:"closed"`           // Connections gone before the session ended
+	ChurnPerMinute float64 `json:"churnPerMinute"`
+}
+
+// MetricDelta is the change of one metric from session A to session B
+type MetricDelta struct {
+	Metric        string  `json:"metric"`
+	A             float64 `json:"a"`
+	B             float64 `json:"b"`
+	Delta         float64 `json:"delta"`
+	PercentChange float64 `json:"percentChange"`
+	Change        string  `json:"change,omitempty"` // "better", "worse" or "unchanged"; empty when neither direction is better
+}
+
+// SignificanceResult is the outcome of a two-sample significance test
+type SignificanceResult struct {
+	Test        string  `json:"test"` // "mann-whitney-u"
+	SamplesA    int     `json:"samplesA"`
+	SamplesB    int     `json:"samplesB"`
+	U           float64 `json:"u"`
+	Z           float64 `json:"z"`
+	PValue      float64 `json:"pValue"` // Two-sided
+	Significant bool    `json:"significant"`
+	// Probability that a B sample is lower than an A sample, ties counting
+	// half; above 0.5 means B tends to be lower
+	ProbabilityLower float64 `json:"probabilityLower"`
+}
+
+// SessionStateAt is the connection table of a recorded session at an instant
+type SessionStateAt struct {
+	SessionID    int64               `json:"sessionId"`
+	Requested    string              `json:"requested"`
+	SnapshotTime string              `json:"snapshotTime"` // Nearest snapshot, which the table is built from
+	OffsetSec    float64             `json:"offsetSec"`    // Snapshot time minus requested time
+	Total        int                 `json:"total"`        // Connections in the snapshot
+	Matched      int                 `json:"matched"`      // Connections matching the filter
+	Warnings     int                 `json:"warnings"`     // Matched connections with health warnings
+	Connections  []ConnectionSummary `json:"connections"`  // Warnings first, then most bytes; may be truncated
+}
+
+// HistoryQueryAnswer is a history query result condensed for the AI
+type HistoryQueryAnswer struct {
+	Query     string               `json:"query"`
+	SessionID int64                `json:"sessionId"`
+	Start     string               `json:"start"`
+	End       string               `json:"end"`
+	Step      int64                `json:"step,omitempty"` // Seconds between points
+	Value     string               `json:"value"`
+	Rows      []HistoryQueryRow    `json:"rows,omitempty"`
+	Series    []HistoryQuerySeries `json:"series,omitempty"`
+	Total     int                  `json:"total"` // Rows or series before truncation
+}
+
+// HistoryQueryRow is one group of a table answer
+type HistoryQueryRow struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// HistoryQuerySeries is one group of a series answer, possibly thinned
+type HistoryQuerySeries struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Points []TimeValue       `json:"points"`
+}
+
+// TimeValue is a value at an instant
+type TimeValue struct {
+	Time  string  `json:"t"`
+	Value float64 `json:"v"`
 }
 
 // ConnectionIdentifier uniquely identifies a connection
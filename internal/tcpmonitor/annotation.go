@@ -0,0 +1,209 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Annotation categories offered by the UI; any other category is kept as given
+const (
+	AnnotationNote     = "note"
+	AnnotationDeploy   = "deploy"
+	AnnotationNetwork  = "network"
+	AnnotationIncident = "incident"
+)
+
+// Annotation marks a moment or a time range of a session with a note, such
+// as "deployed v2.3" or "user reported freeze"
+type Annotation struct {
+	ID        int64     `json:"id"`
+	SessionID int64     `json:"sessionId"`
+	Timestamp time.Time `json:"timestamp"`
+	EndTime   time.Time `json:"endTime,omitempty"` // Zero for a point in time
+	Text      string    `json:"text"`
+	Category  string    `json:"category"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// IsRange reports whether the annotation covers a time range
+func (a *Annotation) IsRange() bool {
+	return a.EndTime.After(a.Timestamp)
+}
+
+// === Annotation Methods ===
+
+// AddAnnotation attaches an annotation to a session and returns it with its
+// ID assigned. A zero EndTime marks a single point in time.
+func (s *SnapshotStore) AddAnnotation(annotation Annotation) (*Annotation, error) {
+	annotation.Text = strings.TrimSpace(annotation.Text)
+	annotation.Category = strings.ToLower(strings.TrimSpace(annotation.Category))
+	annotation.Author = strings.TrimSpace(annotation.Author)
+	if annotation.Text == "" {
+		return nil, fmt.Errorf("annotation text is required")
+	}
+	if annotation.Timestamp.IsZero() {
+		return nil, fmt.Errorf("annotation timestamp is required")
+	}
+	if !annotation.EndTime.IsZero() && annotation.EndTime.Before(annotation.Timestamp) {
+		return nil, fmt.Errorf("annotation ends before it starts")
+	}
+	if annotation.Category == "" {
+		annotation.Category = AnnotationNote
+	}
+	if annotation.CreatedAt.IsZero() {
+		annotation.CreatedAt = time.Now()
+	}
+
+	s.editMu.Lock()
+	defer s.editMu.Unlock()
+
+	annotations, err := s.loadAnnotations(annotation.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	annotation.ID = 1
+	for _, a := range annotations {
+		if a.ID >= annotation.ID {
+			annotation.ID = a.ID + 1
+		}
+	}
+	annotations = append(annotations, annotation)
+	sortAnnotations(annotations)
+	if err := s.saveAnnotations(annotation.SessionID, annotations); err != nil {
+		return nil, err
+	}
+	return &annotation, nil
+}
+
+// DeleteAnnotation removes an annotation from a session
+func (s *SnapshotStore) DeleteAnnotation(sessionID, annotationID int64) error {
+	s.editMu.Lock()
+	defer s.editMu.Unlock()
+
+	annotations, err := s.loadAnnotations(sessionID)
+	if err != nil {
+		return err
+	}
+	for i := range annotations {
+		if annotations[i].ID == annotationID {
+			annotations = append(annotations[:i], annotations[i+1:]...)
+			return s.saveAnnotations(sessionID, annotations)
+		}
+	}
+	return fmt.Errorf("annotation %d not found in session %d", annotationID, sessionID)
+}
+
+// GetAnnotations returns the annotations of a session in time order
+func (s *SnapshotStore) GetAnnotations(sessionID int64) []Annotation {
+	annotations, err := s.loadAnnotations(sessionID)
+	if err != nil {
+		return nil
+	}
+	return annotations
+}
+
+// importAnnotations attaches annotations carried by an archive to a newly
+// imported session, renumbering them
+func (s *SnapshotStore) importAnnotations(sessionID int64, annotations []Annotation) error {
+	if len(annotations) == 0 {
+		return nil
+	}
+	s.editMu.Lock()
+	defer s.editMu.Unlock()
+
+	imported := make([]Annotation, len(annotations))
+	copy(imported, annotations)
+	sortAnnotations(imported)
+	for i := range imported {
+		imported[i].ID = int64(i + 1)
+		imported[i].SessionID = sessionID
+	}
+	return s.saveAnnotations(sessionID, imported)
+}
+
+// loadAnnotations reads the annotations of a session from disk or memory
+func (s *SnapshotStore) loadAnnotations(sessionID int64) ([]Annotation, error) {
+	if s.disk != nil {
+		return s.disk.LoadAnnotations(sessionID)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := false
+	for i := range s.sessions {
+		if s.sessions[i].ID == sessionID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("session %d not found", sessionID)
+	}
+	return append([]Annotation(nil), s.annotations[sessionID]...), nil
+}
+
+// saveAnnotations replaces the annotations of a session.
+// Must be called with s.editMu held.
+func (s *SnapshotStore) saveAnnotations(sessionID int64, annotations []Annotation) error {
+	if s.disk != nil {
+		return s.disk.SaveAnnotations(sessionID, annotations)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(annotations) == 0 {
+		delete(s.annotations, sessionID)
+	} else {
+		s.annotations[sessionID] = annotations
+	}
+	return nil
+}
+
+// sortAnnotations orders annotations by time, then by ID
+func sortAnnotations(annotations []Annotation) {
+	sort.SliceStable(annotations, func(i, j int) bool {
+		if !annotations[i].Timestamp.Equal(annotations[j].Timestamp) {
+			return annotations[i].Timestamp.Before(annotations[j].Timestamp)
+		}
+		return annotations[i].ID < annotations[j].ID
+	})
+}
+
+// annotationsBetween returns the annotations overlapping [start, end]; a
+// zero time leaves that end of the range open
+func annotationsBetween(annotations []Annotation, start, end time.Time) []Annotation {
+	var result []Annotation
+	for _, a := range annotations {
+		last := a.Timestamp
+		if a.IsRange() {
+			last = a.EndTime
+		}
+		if (start.IsZero() || !last.Before(start)) && (end.IsZero() || !a.Timestamp.After(end)) {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// annotateTimeline interleaves annotation markers with timeline rows, each
+// marker placed after the rows recorded at or before its timestamp. Marker
+// rows carry only a timestamp and the annotation.
+func annotateTimeline(rows []TimelineConnection, annotations []Annotation) []TimelineConnection {
+	if len(annotations) == 0 {
+		return rows
+	}
+	result := make([]TimelineConnection, 0, len(rows)+len(annotations))
+	next := 0
+	for i := range annotations {
+		a := &annotations[i]
+		for next < len(rows) && !rows[next].Timestamp.After(a.Timestamp) {
+			result = append(result, rows[next])
+			next++
+		}
+		result = append(result, TimelineConnection{Timestamp: a.Timestamp, Annotation: a})
+	}
+	return append(result, rows[next:]...)
+}
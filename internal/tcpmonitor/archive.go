@@ -0,0 +1,204 @@
+package tcpmonitor
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Session archive format: a gzip-compressed stream of JSON lines. The first
+// line is the ArchiveManifest; every following line is an archiveRecord
+// holding exactly one item. Readers ignore fields they do not know, so new
+// optional fields do not need a version bump; incompatible changes do.
+const (
+	ArchiveFormat    = "tcpdoctor-session"
+	ArchiveVersion   = 1
+	ArchiveExtension = ".tcpdoctor"
+)
+
+// ArchiveManifest describes an exported session
+type ArchiveManifest struct {
+	Format          string           `json:"format"`
+	Version         int              `json:"version"`
+	CreatedAt       time.Time        `json:"createdAt"`
+	Tool            ArchiveTool      `json:"tool"`
+	Host            ArchiveHost      `json:"host"`
+	Session         RecordingSession `json:"session"`
+	SnapshotCount   int              `json:"snapshotCount"`
+	ProbeCount      int              `json:"probeCount"`
+	RollupCount     int              `json:"rollupCount,omitempty"`
+	AnnotationCount int              `json:"annotationCount,omitempty"`
+}
+
+// ArchiveTool identifies the tcpdoctor build that wrote an archive
+type ArchiveTool struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+}
+
+// ArchiveHost describes the machine a session was recorded on
+type ArchiveHost struct {
+	Hostname      string             `json:"hostname"`
+	OS            string             `json:"os"`
+	Arch          string             `json:"arch"`
+	ExtendedStats bool               `json:"extendedStats"` // Whether per-connection extended stats were available
+	Interfaces    []ArchiveInterface `json:"interfaces,omitempty"`
+}
+
+// ArchiveInterface is a network interface of the recording host
+type ArchiveInterface struct {
+	Name      string   `json:"name"`
+	MTU       int      `json:"mtu"`
+	Up        bool     `json:"up"`
+	Loopback  bool     `json:"loopback,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// archiveRecord is one line of the archive body
+type archiveRecord struct {
+	Snapshot   *Snapshot         `json:"snapshot,omitempty"`
+	Probe      *ProbeResult      `json:"probe,omitempty"`
+	Rollup     *ConnectionRollup `json:"rollup,omitempty"`
+	Annotation *Annotation       `json:"annotation,omitempty"`
+}
+
+// SessionArchive is the decoded content of an archive
+type SessionArchive struct {
+	Manifest    ArchiveManifest
+	Snapshots   []Snapshot
+	Probes      []ProbeResult
+	Rollups     []ConnectionRollup // All tiers, including those covering dropped snapshots
+	Annotations []Annotation
+}
+
+// currentArchiveTool describes the running build
+func currentArchiveTool() ArchiveTool {
+	tool := ArchiveTool{Name: "tcpdoctor", Version: "unknown", GoVersion: runtime.Version()}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		tool.Version = info.Main.Version
+	}
+	return tool
+}
+
+// currentArchiveHost describes the machine tcpdoctor is running on
+func currentArchiveHost(extendedStats bool) ArchiveHost {
+	host := ArchiveHost{
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		ExtendedStats: extendedStats,
+	}
+	host.Hostname, _ = os.Hostname()
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return host
+	}
+	for _, iface := range ifaces {
+		entry := ArchiveInterface{
+			Name:     iface.Name,
+			MTU:      iface.MTU,
+			Up:       iface.Flags&net.FlagUp != 0,
+			Loopback: iface.Flags&net.FlagLoopback != 0,
+		}
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				entry.Addresses = append(entry.Addresses, addr.String())
+			}
+		}
+		host.Interfaces = append(host.Interfaces, entry)
+	}
+	return host
+}
+
+// WriteSessionArchive writes a session archive to w
+func WriteSessionArchive(w io.Writer, archive *SessionArchive) error {
+	manifest := archive.Manifest
+	manifest.Format = ArchiveFormat
+	manifest.Version = ArchiveVersion
+	manifest.SnapshotCount = len(archive.Snapshots)
+	manifest.ProbeCount = len(archive.Probes)
+	manifest.RollupCount = len(archive.Rollups)
+	manifest.AnnotationCount = len(archive.Annotations)
+
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	if err := enc.Encode(&manifest); err != nil {
+		return err
+	}
+	for i := range archive.Snapshots {
+		if err := enc.Encode(archiveRecord{Snapshot: &archive.Snapshots[i]}); err != nil {
+			return err
+		}
+	}
+	for i := range archive.Probes {
+		if err := enc.Encode(archiveRecord{Probe: &archive.Probes[i]}); err != nil {
+			return err
+		}
+	}
+	for i := range archive.Rollups {
+		if err := enc.Encode(archiveRecord{Rollup: &archive.Rollups[i]}); err != nil {
+			return err
+		}
+	}
+	for i := range archive.Annotations {
+		if err := enc.Encode(archiveRecord{Annotation: &archive.Annotations[i]}); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ReadSessionArchive decodes a session archive from r
+func ReadSessionArchive(r io.Reader) (*SessionArchive, error) {
+	zr, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("not a tcpdoctor session archive: %w", err)
+	}
+	defer zr.Close()
+
+	dec := json.NewDecoder(zr)
+	archive := &SessionArchive{}
+	if err := dec.Decode(&archive.Manifest); err != nil {
+		return nil, fmt.Errorf("not a tcpdoctor session archive: %w", err)
+	}
+	if archive.Manifest.Format != ArchiveFormat {
+		return nil, fmt.Errorf("not a tcpdoctor session archive (format %q)", archive.Manifest.Format)
+	}
+	if archive.Manifest.Version > ArchiveVersion {
+		return nil, fmt.Errorf("archive version %d is newer than supported version %d, update tcpdoctor",
+			archive.Manifest.Version, ArchiveVersion)
+	}
+
+	for {
+		var record archiveRecord
+		if err := dec.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("corrupt session archive: %w", err)
+		}
+		switch {
+		case record.Snapshot != nil:
+			archive.Snapshots = append(archive.Snapshots, *record.Snapshot)
+		case record.Probe != nil:
+			archive.Probes = append(archive.Probes, *record.Probe)
+		case record.Rollup != nil:
+			archive.Rollups = append(archive.Rollups, *record.Rollup)
+		case record.Annotation != nil:
+			archive.Annotations = append(archive.Annotations, *record.Annotation)
+		}
+	}
+
+	if len(archive.Snapshots) != archive.Manifest.SnapshotCount {
+		return nil, fmt.Errorf("truncated session archive: %d of %d snapshots",
+			len(archive.Snapshots), archive.Manifest.SnapshotCount)
+	}
+	return archive, nil
+}
@@ -0,0 +1,273 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Socket buffer conditions
+const (
+	BufferRecvFull   = "rcvbuf_full"    // Receive buffer full: the application is not reading
+	BufferSendPinned = "sndbuf_limited" // Send buffer pinned at its limit: autotuning capped
+	BufferDrops      = "socket_drops"   // Packets dropped at the socket
+)
+
+const (
+	// A buffer counts as full at this fraction of its limit
+	bufferFullRatio = 0.9
+
+	// Consecutive full samples before a condition is reported
+	bufferPressureSamples = 3
+)
+
+// BufferFinding describes socket buffer pressure
+type BufferFinding struct {
+	Condition   string    `json:"condition"`
+	Severity    string    `json:"severity"` // "high", "medium", "low"
+	Description string    `json:"description"`
+	Used        uint32    `json:"used,omitempty"`      // Bytes in use
+	Limit       uint32    `json:"limit,omitempty"`     // Buffer limit in bytes
+	Drops       uint32    `json:"drops,omitempty"`     // New socket drops
+	Timestamp   time.Time `json:"timestamp,omitempty"` // When replaying recorded sessions
+}
+
+// bufferSample is the subset of stats the analyzer looks at
+type bufferSample struct {
+	RmemAlloc  uint32
+	RcvBuf     uint32
+	WmemQueued uint32
+	SndBuf     uint32
+	AppWQueue  uint32
+	Drops      uint32
+}
+
+// bufferSampleFromStats extracts analyzer inputs from ExtendedStats
+func bufferSampleFromStats(stats *ExtendedStats) bufferSample {
+	return bufferSample{
+		RmemAlloc:  stats.RmemAlloc,
+		RcvBuf:     stats.RcvBuf,
+		WmemQueued: stats.WmemQueued,
+		SndBuf:     stats.SndBuf,
+		AppWQueue:  stats.CurAppWQueue,
+		Drops:      stats.SockDrops,
+	}
+}
+
+// bufferSampleFromCompact extracts analyzer inputs from a recorded snapshot
+func bufferSampleFromCompact(c *CompactConnection) bufferSample {
+	return bufferSample{
+		RmemAlloc:  uint32(c.RmemAlloc),
+		RcvBuf:     uint32(c.RcvBuf),
+		WmemQueued: uint32(c.WmemQueued),
+		SndBuf:     uint32(c.SndBuf),
+		AppWQueue:  uint32(c.CurAppWQueue),
+		Drops:      uint32(c.SockDrops),
+	}
+}
+
+// hasMemInfo reports whether socket memory was collected for the sample
+func (s bufferSample) hasMemInfo() bool {
+	return s.RcvBuf > 0 || s.SndBuf > 0
+}
+
+// recvFull checks whether unread data fills the receive buffer
+func (s bufferSample) recvFull() bool {
+	return s.RcvBuf > 0 && float64(s.RmemAlloc) >= float64(s.RcvBuf)*bufferFullRatio
+}
+
+// sendFull checks whether queued data fills the send buffer while the
+// application still has data waiting to be sent
+func (s bufferSample) sendFull() bool {
+	return s.SndBuf > 0 && s.AppWQueue > 0 && float64(s.WmemQueued) >= float64(s.SndBuf)*bufferFullRatio
+}
+
+// bufferTracker follows one connection's buffers across samples.
+// It is used both live (BufferAnalyzer) and when replaying recorded sessions.
+type bufferTracker struct {
+	initialized bool
+	recvFullRun int    // Consecutive samples with a full receive buffer
+	sendFullRun int    // Consecutive samples with a full send buffer at an unchanged limit
+	lastSndBuf  uint32 // Send buffer limit at the previous sample
+	lastDrops   uint32
+	newDrops    uint32 // Drops seen at the latest sample
+}
+
+// observe advances the tracker and returns conditions that started at this sample
+func (t *bufferTracker) observe(s bufferSample) []BufferFinding {
+	var started []BufferFinding
+
+	if !t.initialized {
+		t.initialized = true
+		t.lastSndBuf = s.SndBuf
+		t.lastDrops = s.Drops
+	}
+
+	if s.recvFull() {
+		t.recvFullRun++
+		if t.recvFullRun == bufferPressureSamples {
+			started = append(started, recvFullFinding(s))
+		}
+	} else {
+		t.recvFullRun = 0
+	}
+
+	// A growing limit means autotuning is still raising the buffer
+	if s.sendFull() && s.SndBuf <= t.lastSndBuf {
+		t.sendFullRun++
+		if t.sendFullRun == bufferPressureSamples {
+			started = append(started, sendPinnedFinding(s))
+		}
+	} else {
+		t.sendFullRun = 0
+	}
+	t.lastSndBuf = s.SndBuf
+
+	t.newDrops = counterDelta32(t.lastDrops, s.Drops)
+	t.lastDrops = s.Drops
+	if t.newDrops > 0 {
+		started = append(started, socketDropsFinding(t.newDrops))
+	}
+
+	return started
+}
+
+// condition returns the most severe standing condition for the latest sample
+func (t *bufferTracker) condition(s bufferSample) *BufferFinding {
+	if t.recvFullRun >= bufferPressureSamples {
+		f := recvFullFinding(s)
+		return &f
+	}
+	if t.sendFullRun >= bufferPressureSamples {
+		f := sendPinnedFinding(s)
+		return &f
+	}
+	if t.newDrops > 0 {
+		f := socketDropsFinding(t.newDrops)
+		return &f
+	}
+	return nil
+}
+
+// recvFullFinding describes a receive buffer the application is not draining
+func recvFullFinding(s bufferSample) BufferFinding {
+	return BufferFinding{
+		Condition: BufferRecvFull,
+		Severity:  "high",
+		Description: fmt.Sprintf("Receive buffer full (%s of %s): the application is not reading",
+			formatByteSize(uint64(s.RmemAlloc)), formatByteSize(uint64(s.RcvBuf))),
+		Used:  s.RmemAlloc,
+		Limit: s.RcvBuf,
+	}
+}
+
+// sendPinnedFinding describes a send buffer stuck at its limit
+func sendPinnedFinding(s bufferSample) BufferFinding {
+	return BufferFinding{
+		Condition: BufferSendPinned,
+		Severity:  "medium",
+		Description: fmt.Sprintf("Send buffer pinned at its %s limit with %s still queued by the application (autotuning capped, raise SO_SNDBUF or tcp_wmem)",
+			formatByteSize(uint64(s.SndBuf)), formatByteSize(uint64(s.AppWQueue))),
+		Used:  s.WmemQueued,
+		Limit: s.SndBuf,
+	}
+}
+
+// socketDropsFinding describes packets dropped at the socket
+func socketDropsFinding(drops uint32) BufferFinding {
+	return BufferFinding{
+		Condition:   BufferDrops,
+		Severity:    "medium",
+		Description: fmt.Sprintf("%d packets dropped at the socket (receive buffer overflow or socket filter)", drops),
+		Drops:       drops,
+	}
+}
+
+// DetectBufferPressure replays a recorded connection timeline and returns
+// every buffer condition, stamped with the time of the sample where it started
+func DetectBufferPressure(snapshots []TimelineConnection) []BufferFinding {
+	var tracker bufferTracker
+	var findings []BufferFinding
+
+	for i := range snapshots {
+		conn := &snapshots[i].Connection
+		if TCPState(conn.State) != StateEstablished {
+			continue
+		}
+		s := bufferSampleFromCompact(conn)
+		if !s.hasMemInfo() {
+			continue
+		}
+		for _, f := range tracker.observe(s) {
+			f.Timestamp = snapshots[i].Timestamp
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// BufferAnalyzer flags socket buffer pressure on live connections
+type BufferAnalyzer struct {
+	trackers map[ConnectionKey]*bufferTracker
+	mu       sync.Mutex
+	logger   *Logger
+}
+
+// NewBufferAnalyzer creates a new analyzer
+func NewBufferAnalyzer() *BufferAnalyzer {
+	return &BufferAnalyzer{
+		trackers: make(map[ConnectionKey]*bufferTracker),
+		logger:   GetLogger(),
+	}
+}
+
+// Analyze sets BufferWarning and BufferPressure on every connection with
+// socket memory stats and forgets connections that are gone
+func (b *BufferAnalyzer) Analyze(connections []ConnectionInfo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	seen := make(map[ConnectionKey]bool, len(connections))
+	for i := range connections {
+		conn := &connections[i]
+		conn.BufferWarning = false
+		conn.BufferPressure = nil
+
+		if conn.ExtendedStats == nil || conn.State != StateEstablished {
+			continue
+		}
+		s := bufferSampleFromStats(conn.ExtendedStats)
+		if !s.hasMemInfo() {
+			continue
+		}
+
+		key := ConnectionKey{
+			LocalAddr:  conn.LocalAddr,
+			LocalPort:  conn.LocalPort,
+			RemoteAddr: conn.RemoteAddr,
+			RemotePort: conn.RemotePort,
+			IsIPv6:     conn.IsIPv6,
+		}
+		seen[key] = true
+
+		tracker := b.trackers[key]
+		if tracker == nil {
+			tracker = &bufferTracker{}
+			b.trackers[key] = tracker
+		}
+
+		for _, f := range tracker.observe(s) {
+			b.logger.Info("%s on %s", f.Description, key.String())
+		}
+		if f := tracker.condition(s); f != nil {
+			conn.BufferWarning = true
+			conn.BufferPressure = f
+		}
+	}
+
+	for key := range b.trackers {
+		if !seen[key] {
+			delete(b.trackers, key)
+		}
+	}
+}
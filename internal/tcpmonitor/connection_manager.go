@@ -104,6 +104,24 @@ func (cm *ConnectionManager) Update(connections []ConnectionInfo) []ConnectionEv
 			existing.LastSeen = now
 			existing.BasicStats = conn.BasicStats
 			existing.ExtendedStats = conn.ExtendedStats
+			existing.RemoteHostname = conn.RemoteHostname
+			existing.RemoteCountry = conn.RemoteCountry
+			existing.RemoteCity = conn.RemoteCity
+			existing.RemoteASN = conn.RemoteASN
+			existing.RemoteASOrg = conn.RemoteASOrg
+			existing.ServiceName = conn.ServiceName
+			existing.Role = conn.Role
+			existing.HighRetransmissionWarning = conn.HighRetransmissionWarning
+			existing.HighRTTWarning = conn.HighRTTWarning
+			existing.MSSWarning = conn.MSSWarning
+			existing.MSSAnomaly = conn.MSSAnomaly
+			existing.WindowScaleWarning = conn.WindowScaleWarning
+			existing.WindowAnalysis = conn.WindowAnalysis
+			existing.BufferWarning = conn.BufferWarning
+			existing.BufferPressure = conn.BufferPressure
+			existing.Timer = conn.Timer
+			existing.StallWarning = conn.StallWarning
+			existing.Stall = conn.Stall
 
 			events = append(events, ConnectionEvent{
 				Type:       ConnectionUpdated,
@@ -0,0 +1,140 @@
+package tcpmonitor
+
+import (
+	"testing"
+	"time"
+)
+
+// at returns a UTC time on 2026-10-day (the 16th is a Friday)
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseCron(t *testing.T) {
+	weekdays := uint64(1<<1 | 1<<2 | 1<<3 | 1<<4 | 1<<5)
+	tests := []struct {
+		expr   string
+		minute uint64
+		dow    uint64
+	}{
+		{"0 9 * * mon-fri", 1, weekdays},
+		{"0 9 * * 1-5", 1, weekdays},
+		{"*/15 * * * *", 1<<0 | 1<<15 | 1<<30 | 1<<45, 1<<7 - 1},
+		{"5/10 * * * *", 1<<5 | 1<<15 | 1<<25 | 1<<35 | 1<<45 | 1<<55, 1<<7 - 1},
+		{"0-30/10 * * * *", 1<<0 | 1<<10 | 1<<20 | 1<<30, 1<<7 - 1},
+		{"0 0 * * 7", 1, 1},
+		{"0 0 * * SUN", 1, 1},
+		{"0 0 * * 5-7", 1, 1<<0 | 1<<5 | 1<<6},
+		{"0,30 0 * * sat,sun", 1<<0 | 1<<30, 1<<0 | 1<<6},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if c.minute != tt.minute {
+			t.Errorf("parseCron(%q) minutes = %b, want %b", tt.expr, c.minute, tt.minute)
+		}
+		if c.dow != tt.dow {
+			t.Errorf("parseCron(%q) weekdays = %b, want %b", tt.expr, c.dow, tt.dow)
+		}
+	}
+
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"* * * * funday",
+		"1-2-3 * * * *",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		// Weekdays at 9: Friday after 9 rolls over the weekend
+		{"0 9 * * mon-fri", at(16, 8, 0), at(16, 9, 0)},
+		{"0 9 * * mon-fri", at(16, 9, 0), at(19, 9, 0)},
+		{"0 9 * * mon-fri", at(17, 12, 0), at(19, 9, 0)},
+
+		{"*/15 * * * *", at(16, 10, 7), at(16, 10, 15)},
+		{"*/15 * * * *", at(16, 10, 45), at(16, 11, 0)},
+		{"*/15 * * * *", at(16, 23, 50), at(17, 0, 0)},
+		{"5/10 * * * *", at(16, 10, 5), at(16, 10, 15)},
+		{"5/10 * * * *", at(16, 10, 56), at(16, 11, 5)},
+
+		// Sunday as 7 and as 0
+		{"0 0 * * 7", at(16, 12, 0), at(18, 0, 0)},
+		{"0 0 * * 0", at(16, 12, 0), at(18, 0, 0)},
+
+		// Day of month and weekday both restricted: either matches
+		{"0 0 13 * fri", at(9, 1, 0), at(13, 0, 0)},
+		{"0 0 13 * fri", at(13, 1, 0), at(16, 0, 0)},
+		// Only one restricted: that one alone decides
+		{"0 0 13 * *", at(13, 1, 0), time.Date(2026, time.November, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * fri", at(9, 1, 0), at(16, 0, 0)},
+
+		// Seconds are ignored
+		{"* * * * *", at(16, 10, 0).Add(30 * time.Second), at(16, 10, 1)},
+		// February 30th never comes
+		{"0 0 30 feb *", at(16, 0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		if got := c.next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q next after %v = %v, want %v", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestCronLatest(t *testing.T) {
+	tests := []struct {
+		expr   string
+		now    time.Time
+		window time.Duration
+		want   time.Time // Zero when no window holds now
+	}{
+		{"0 9 * * *", at(16, 9, 0), time.Hour, at(16, 9, 0)},
+		{"0 9 * * *", at(16, 9, 59).Add(59 * time.Second), time.Hour, at(16, 9, 0)},
+		{"0 9 * * *", at(16, 10, 0), time.Hour, time.Time{}},
+		{"0 9 * * *", at(16, 8, 59), time.Hour, time.Time{}},
+
+		// A late-evening window runs on past midnight
+		{"30 23 * * *", at(17, 0, 15), time.Hour, at(16, 23, 30)},
+		{"30 23 * * *", at(17, 0, 31), time.Hour, time.Time{}},
+		// ... into a day the expression itself doesn't match
+		{"30 23 * * fri", at(17, 0, 15), time.Hour, at(16, 23, 30)},
+
+		// The most recent of several openings wins
+		{"*/15 * * * *", at(16, 10, 20), time.Hour, at(16, 10, 15)},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		got, ok := c.latest(tt.now, tt.window)
+		if ok != !tt.want.IsZero() || !got.Equal(tt.want) {
+			t.Errorf("%q latest at %v = %v, %v; want %v", tt.expr, tt.now, got, ok, tt.want)
+		}
+	}
+}
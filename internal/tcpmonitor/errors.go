@@ -1,4 +1,5 @@
-// +build windows
+//go:build windows || linux
+// +build windows linux
 
 package tcpmonitor
 
@@ -24,7 +25,7 @@ var (
 	ErrInvalidInterval = errors.New("invalid update interval")
 )
 
-// APIError wraps Windows API errors with additional context
+// APIError wraps platform API errors with additional context
 type APIError struct {
 	Operation string
 	Err       error
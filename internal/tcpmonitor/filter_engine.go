@@ -13,7 +13,12 @@ type FilterOptions struct {
 	IPv4Only        bool      // Show only IPv4 connections
 	IPv6Only        bool      // Show only IPv6 connections
 	ExcludeInternal bool      // Hide connections where both endpoints are internal/private IPs
-	SearchText      string    // Text search for addresses (empty means no filter)
+	SearchText      string    // Text search for addresses and hostnames (empty means no filter)
+	Country         string    // Remote ISO country code (empty means no filter)
+	ASN             *uint32   // Remote autonomous system number (nil means no filter)
+	Service         string    // Service name from the catalog (empty means no filter)
+	Role            string    // Socket role: client, server or listener (empty means no filter)
+	Limitation      string    // Throughput limitation class, e.g. rwnd_limited (empty means no filter)
 }
 
 // FilterEngine applies filters to connection lists
@@ -58,7 +63,12 @@ func (fe *FilterEngine) hasActiveFilters(filter FilterOptions) bool {
 		filter.IPv4Only ||
 		filter.IPv6Only ||
 		filter.ExcludeInternal ||
-		filter.SearchText != ""
+		filter.SearchText != "" ||
+		filter.Country != "" ||
+		filter.ASN != nil ||
+		filter.Service != "" ||
+		filter.Role != "" ||
+		filter.Limitation != ""
 }
 
 // matchesFilter checks if a connection matches all filter criteria
@@ -95,18 +105,43 @@ func (fe *FilterEngine) matchesFilter(conn ConnectionInfo, filter FilterOptions)
 		}
 	}
 
-	// Text search filter (searches in local and remote addresses)
+	// Text search filter (searches in addresses, the resolved hostname and the service name)
 	if filter.SearchText != "" {
 		searchLower := strings.ToLower(filter.SearchText)
 		localAddrLower := strings.ToLower(conn.LocalAddr)
 		remoteAddrLower := strings.ToLower(conn.RemoteAddr)
+		hostnameLower := strings.ToLower(conn.RemoteHostname)
+		serviceLower := strings.ToLower(conn.ServiceName)
 
 		if !strings.Contains(localAddrLower, searchLower) &&
-			!strings.Contains(remoteAddrLower, searchLower) {
+			!strings.Contains(remoteAddrLower, searchLower) &&
+			!strings.Contains(hostnameLower, searchLower) &&
+			!strings.Contains(serviceLower, searchLower) {
 			return false
 		}
 	}
 
+	// GeoIP filters
+	if filter.Country != "" && !strings.EqualFold(conn.RemoteCountry, filter.Country) {
+		return false
+	}
+	if filter.ASN != nil && conn.RemoteASN != *filter.ASN {
+		return false
+	}
+
+	// Service classification filters
+	if filter.Service != "" && !strings.EqualFold(conn.ServiceName, filter.Service) {
+		return false
+	}
+	if filter.Role != "" && conn.Role != filter.Role {
+		return false
+	}
+
+	// Window/BDP limitation filter
+	if filter.Limitation != "" && (conn.WindowAnalysis == nil || conn.WindowAnalysis.Limitation != filter.Limitation) {
+		return false
+	}
+
 	// All filters passed
 	return true
 }
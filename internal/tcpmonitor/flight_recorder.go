@@ -0,0 +1,382 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Flight-recorder trigger kinds
+const (
+	TriggerManual          = "manual"
+	TriggerHealth          = "health"
+	TriggerConnectionSpike = "connection_spike"
+	TriggerProcess         = "process"
+)
+
+const (
+	// Samples averaged before the connection spike trigger is armed
+	spikeWarmupSamples = 10
+	// Weight of the newest sample in the connection count average
+	spikeAverageWeight = 0.05
+)
+
+// FlightRecorderConfig controls trigger-based recording. While armed, the
+// snapshots of the last PreTriggerSeconds are kept without creating a
+// session; a trigger starts a session holding that history and recording
+// continues until PostTriggerSeconds have passed without another trigger.
+// Enabling it replaces continuous recording.
+type FlightRecorderConfig struct {
+	Enabled            bool `json:"enabled"`
+	PreTriggerSeconds  int  `json:"preTriggerSeconds"`  // History kept before a trigger
+	PostTriggerSeconds int  `json:"postTriggerSeconds"` // Recording continues this long after the last trigger
+
+	// Triggers; a zero value disables one. Manual triggers always fire.
+	HealthWarnings  int      `json:"healthWarnings"`      // Connections with health warnings at the same time
+	ConnectionSpike int      `json:"connectionSpike"`     // Connections above the recent average
+	Processes       []string `json:"processes,omitempty"` // Process names or PIDs whose new connections fire
+}
+
+// DefaultFlightRecorderConfig returns the default flight-recorder configuration
+func DefaultFlightRecorderConfig() FlightRecorderConfig {
+	return FlightRecorderConfig{
+		Enabled:            false,
+		PreTriggerSeconds:  300,
+		PostTriggerSeconds: 120,
+		HealthWarnings:     5,
+		ConnectionSpike:    100,
+	}
+}
+
+// PreTrigger returns the history kept before a trigger
+func (c FlightRecorderConfig) PreTrigger() time.Duration {
+	return time.Duration(c.PreTriggerSeconds) * time.Second
+}
+
+// PostTrigger returns how long recording continues after the last trigger
+func (c FlightRecorderConfig) PostTrigger() time.Duration {
+	return time.Duration(c.PostTriggerSeconds) * time.Second
+}
+
+// FlightTrigger describes why the flight recorder fired
+type FlightTrigger struct {
+	Kind   string    `json:"kind"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// FlightRecorderStatus describes what the flight recorder is doing
+type FlightRecorderStatus struct {
+	Enabled           bool           `json:"enabled"`
+	Recording         bool           `json:"recording"`           // A triggered session is being written
+	SessionID         int64          `json:"sessionId,omitempty"` // The triggered session
+	StopsAt           time.Time      `json:"stopsAt,omitempty"`
+	BufferedSnapshots int            `json:"bufferedSnapshots"`
+	BufferedSince     time.Time      `json:"bufferedSince,omitempty"`
+	LastTrigger       *FlightTrigger `json:"lastTrigger,omitempty"`
+}
+
+// flightRecorder starts and stops recording sessions around triggers
+type flightRecorder struct {
+	mu     sync.Mutex
+	config FlightRecorderConfig
+	store  *SnapshotStore
+
+	sessionID   int64 // Session started by a trigger, 0 while armed
+	continuous  int64 // Session recorded while disabled, 0 if none
+	stopAt      time.Time
+	lastTrigger *FlightTrigger
+
+	// Trigger state
+	active   map[string]bool        // Level triggers that held last sample
+	avgConns float64                // Moving average of the connection count
+	samples  int                    // Samples in the average
+	watched  map[ConnectionKey]bool // Connections of watched processes seen last sample
+	primed   bool                   // watched holds a sample
+	names    map[uint32]string      // Process names by PID, for the last sample's PIDs
+	pids     map[uint32]bool        // Watched PIDs from the configuration
+	procs    map[string]bool        // Watched process names, lower case
+	logger   *Logger
+}
+
+// newFlightRecorder creates a flight recorder for a store
+func newFlightRecorder(store *SnapshotStore, config FlightRecorderConfig) *flightRecorder {
+	fr := &flightRecorder{
+		store:  store,
+		logger: GetLogger(),
+	}
+	fr.setConfigLocked(config)
+	return fr
+}
+
+// Configure applies a new configuration. Enabling stops the continuous
+// session and arms the recorder; disabling it ends a triggered session and
+// returns to continuous recording. Scoped and scheduled sessions are left
+// running either way.
+func (fr *flightRecorder) Configure(config FlightRecorderConfig) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	wasEnabled := fr.config.Enabled
+	fr.setConfigLocked(config)
+
+	switch {
+	case config.Enabled && !wasEnabled:
+		if fr.continuous != 0 && fr.store.IsSessionRecording(fr.continuous) {
+			fr.store.StopSession(fr.continuous)
+		}
+		fr.continuous = 0
+		fr.logger.Info("Flight recorder armed with %v of history", config.PreTrigger())
+	case !config.Enabled && wasEnabled:
+		if fr.sessionID != 0 {
+			fr.store.StopSession(fr.sessionID)
+			fr.sessionID = 0
+		}
+		fr.recordContinuouslyLocked()
+		fr.logger.Info("Flight recorder disabled, recording continuously")
+	}
+}
+
+// RecordContinuously starts the continuous session unless the recorder is
+// enabled, which replaces it
+func (fr *flightRecorder) RecordContinuously() {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if !fr.config.Enabled {
+		fr.recordContinuouslyLocked()
+	}
+}
+
+// recordContinuouslyLocked starts the continuous session if it is not
+// already running. Must be called with fr.mu held.
+func (fr *flightRecorder) recordContinuouslyLocked() {
+	if fr.continuous != 0 && fr.store.IsSessionRecording(fr.continuous) {
+		return
+	}
+	fr.continuous = fr.store.StartRecording()
+}
+
+// setConfigLocked stores a configuration and resets the trigger state.
+// Must be called with fr.mu held.
+func (fr *flightRecorder) setConfigLocked(config FlightRecorderConfig) {
+	fr.config = config
+	fr.active = make(map[string]bool)
+	fr.watched = make(map[ConnectionKey]bool)
+	fr.primed = false
+	fr.names = make(map[uint32]string)
+	fr.pids = make(map[uint32]bool)
+	fr.procs = make(map[string]bool)
+	for _, p := range config.Processes {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if pid, err := strconv.ParseUint(p, 10, 32); err == nil {
+			fr.pids[uint32(pid)] = true
+		} else {
+			fr.procs[strings.TrimSuffix(p, ".exe")] = true
+		}
+	}
+
+	if config.Enabled {
+		fr.store.SetPreTriggerBuffer(config.PreTrigger())
+	} else {
+		fr.store.SetPreTriggerBuffer(0)
+	}
+}
+
+// Config returns the configuration in effect
+func (fr *flightRecorder) Config() FlightRecorderConfig {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.config
+}
+
+// Status reports the recorder's state
+func (fr *flightRecorder) Status() FlightRecorderStatus {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	status := FlightRecorderStatus{
+		Enabled:     fr.config.Enabled,
+		Recording:   fr.sessionID != 0,
+		SessionID:   fr.sessionID,
+		LastTrigger: fr.lastTrigger,
+	}
+	if fr.sessionID != 0 {
+		status.StopsAt = fr.stopAt
+	}
+	status.BufferedSnapshots, status.BufferedSince = fr.store.PreTriggerBuffer()
+	return status
+}
+
+// Trigger fires a manual trigger
+func (fr *flightRecorder) Trigger(reason string) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	if !fr.config.Enabled {
+		return fmt.Errorf("flight recorder is not enabled")
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		reason = "Manual trigger"
+	}
+	fr.fireLocked(FlightTrigger{Kind: TriggerManual, Reason: reason, Time: time.Now()})
+	return nil
+}
+
+// Observe evaluates the triggers against one poll's connections, after the
+// store has taken its snapshot, and ends the triggered session once its
+// post-trigger window has passed
+func (fr *flightRecorder) Observe(connections []ConnectionInfo) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	if !fr.config.Enabled {
+		return
+	}
+	now := time.Now()
+
+	// The triggered session may have been stopped from outside
+	if fr.sessionID != 0 && !fr.store.IsSessionRecording(fr.sessionID) {
+		fr.sessionID = 0
+	}
+
+	for _, trigger := range fr.evaluateLocked(connections, now) {
+		fr.fireLocked(trigger)
+	}
+
+	if fr.sessionID != 0 && now.After(fr.stopAt) {
+		fr.store.StopSession(fr.sessionID)
+		fr.logger.Info("Flight recorder finished session %d", fr.sessionID)
+		fr.sessionID = 0
+	}
+}
+
+// evaluateLocked returns the triggers that fire on a sample. Conditions
+// that keep holding extend a triggered session without firing again.
+// Must be called with fr.mu held.
+func (fr *flightRecorder) evaluateLocked(connections []ConnectionInfo, now time.Time) []FlightTrigger {
+	var fired []FlightTrigger
+	level := func(kind string, holds bool, reason string) {
+		if holds && !fr.active[kind] {
+			fired = append(fired, FlightTrigger{Kind: kind, Reason: reason, Time: now})
+		} else if holds && fr.sessionID != 0 {
+			fr.stopAt = now.Add(fr.config.PostTrigger())
+		}
+		fr.active[kind] = holds
+	}
+
+	if fr.config.HealthWarnings > 0 {
+		warnings := 0
+		for i := range connections {
+			if HasHealthWarnings(&connections[i]) {
+				warnings++
+			}
+		}
+		level(TriggerHealth, warnings >= fr.config.HealthWarnings,
+			fmt.Sprintf("%d connections with health warnings", warnings))
+	}
+
+	if fr.config.ConnectionSpike > 0 {
+		count := float64(len(connections))
+		average := fr.avgConns
+		level(TriggerConnectionSpike,
+			fr.samples >= spikeWarmupSamples && count-average >= float64(fr.config.ConnectionSpike),
+			fmt.Sprintf("%d connections, %.0f above the recent average", len(connections), count-average))
+		if fr.samples == 0 {
+			fr.avgConns = count
+		} else {
+			fr.avgConns += (count - fr.avgConns) * spikeAverageWeight
+		}
+		fr.samples++
+	}
+
+	if len(fr.pids) > 0 || len(fr.procs) > 0 {
+		fired = append(fired, fr.processTriggersLocked(connections, now)...)
+	}
+	return fired
+}
+
+// processTriggersLocked fires once per watched process that opened a
+// connection since the last sample. Must be called with fr.mu held.
+func (fr *flightRecorder) processTriggersLocked(connections []ConnectionInfo, now time.Time) []FlightTrigger {
+	names := make(map[uint32]string)
+	watched := make(map[ConnectionKey]bool)
+	seen := make(map[uint32]bool)
+	var fired []FlightTrigger
+
+	for i := range connections {
+		conn := &connections[i]
+		if conn.PID == 0 || conn.State == StateListen {
+			continue
+		}
+		name, ok := names[conn.PID]
+		if !ok && len(fr.procs) > 0 {
+			if name, ok = fr.names[conn.PID]; !ok {
+				name = processName(conn.PID)
+			}
+			names[conn.PID] = name
+		}
+		if !fr.pids[conn.PID] && !fr.procs[strings.TrimSuffix(strings.ToLower(name), ".exe")] {
+			continue
+		}
+
+		key := ConnectionKey{
+			LocalAddr:  conn.LocalAddr,
+			LocalPort:  conn.LocalPort,
+			RemoteAddr: conn.RemoteAddr,
+			RemotePort: conn.RemotePort,
+			IsIPv6:     conn.IsIPv6,
+		}
+		watched[key] = true
+		if fr.primed && !fr.watched[key] && !seen[conn.PID] {
+			seen[conn.PID] = true
+			label := name
+			if label == "" {
+				label = "PID " + strconv.FormatUint(uint64(conn.PID), 10)
+			}
+			fired = append(fired, FlightTrigger{
+				Kind:   TriggerProcess,
+				Reason: fmt.Sprintf("%s connected to %s:%d", label, conn.RemoteAddr, conn.RemotePort),
+				Time:   now,
+			})
+		}
+	}
+
+	fr.names = names
+	fr.watched = watched
+	fr.primed = true
+	return fired
+}
+
+// fireLocked starts a session with the pre-trigger history, or extends the
+// one being recorded, and marks the trigger with an annotation.
+// Must be called with fr.mu held.
+func (fr *flightRecorder) fireLocked(trigger FlightTrigger) {
+	fr.lastTrigger = &trigger
+
+	if fr.sessionID == 0 {
+		fr.sessionID = fr.store.StartRecordingWithBuffer()
+		fr.logger.Info("Flight recorder triggered (%s), recording session %d", trigger.Reason, fr.sessionID)
+		if _, err := fr.store.SetSessionInfo(fr.sessionID, "Flight recorder: "+trigger.Reason, "",
+			[]string{"flight-recorder", trigger.Kind}); err != nil {
+			fr.logger.Warn("Failed to name flight recorder session %d: %v", fr.sessionID, err)
+		}
+	}
+	fr.stopAt = trigger.Time.Add(fr.config.PostTrigger())
+
+	_, err := fr.store.AddAnnotation(Annotation{
+		SessionID: fr.sessionID,
+		Timestamp: trigger.Time,
+		Text:      trigger.Reason,
+		Category:  "trigger",
+		Author:    "flight recorder",
+	})
+	if err != nil {
+		fr.logger.Warn("Failed to annotate flight recorder trigger: %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// GeoIPConfig points at local MaxMind-format (.mmdb) databases.
+// Any GeoLite2/DB-IP City or Country database works for CityDBPath,
+// and any GeoLite2/DB-IP ASN database for ASNDBPath. Empty paths disable
+// that part of the enrichment. No network lookups are ever made.
+type GeoIPConfig struct {
+	CityDBPath string
+	ASNDBPath  string
+}
+
+// GeoInfo holds the location and network owner of an address
+type GeoInfo struct {
+	CountryCode string
+	City        string
+	ASN         uint32
+	ASOrg       string
+}
+
+// mmdbCityRecord is the subset of a City/Country database record we decode
+type mmdbCityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// mmdbASNRecord is an ASN database record
+type mmdbASNRecord struct {
+	ASN uint32 `maxminddb:"autonomous_system_number"`
+	Org string `maxminddb:"autonomous_system_organization"`
+}
+
+// geoCacheLimit bounds the number of cached lookups
+const geoCacheLimit = 10000
+
+// GeoIPResolver tags remote addresses with country, city and ASN data
+type GeoIPResolver struct {
+	config GeoIPConfig
+	cityDB *maxminddb.Reader
+	asnDB  *maxminddb.Reader
+	cache  map[string]GeoInfo
+	mu     sync.RWMutex
+	logger *Logger
+}
+
+// NewGeoIPResolver creates a resolver with no databases loaded
+func NewGeoIPResolver() *GeoIPResolver {
+	return &GeoIPResolver{
+		cache:  make(map[string]GeoInfo),
+		logger: GetLogger(),
+	}
+}
+
+// SetConfig opens the configured databases, replacing any previously loaded ones
+func (g *GeoIPResolver) SetConfig(config GeoIPConfig) error {
+	var cityDB, asnDB *maxminddb.Reader
+	var err error
+
+	if config.CityDBPath != "" {
+		cityDB, err = maxminddb.Open(config.CityDBPath)
+		if err != nil {
+			return fmt.Errorf("failed to open city database: %w", err)
+		}
+	}
+	if config.ASNDBPath != "" {
+		asnDB, err = maxminddb.Open(config.ASNDBPath)
+		if err != nil {
+			if cityDB != nil {
+				cityDB.Close()
+			}
+			return fmt.Errorf("failed to open ASN database: %w", err)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.closeLocked()
+	g.config = config
+	g.cityDB = cityDB
+	g.asnDB = asnDB
+	g.cache = make(map[string]GeoInfo)
+	return nil
+}
+
+// GetConfig returns the current database configuration
+func (g *GeoIPResolver) GetConfig() GeoIPConfig {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.config
+}
+
+// Close releases the loaded databases
+func (g *GeoIPResolver) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closeLocked()
+}
+
+// closeLocked closes open readers. Caller must hold the write lock.
+func (g *GeoIPResolver) closeLocked() {
+	if g.cityDB != nil {
+		g.cityDB.Close()
+		g.cityDB = nil
+	}
+	if g.asnDB != nil {
+		g.asnDB.Close()
+		g.asnDB = nil
+	}
+}
+
+// Lookup returns geo data for an address. Private and wildcard addresses
+// return an empty GeoInfo.
+func (g *GeoIPResolver) Lookup(addr string) GeoInfo {
+	g.mu.RLock()
+	info, cached := g.cache[addr]
+	loaded := g.cityDB != nil || g.asnDB != nil
+	g.mu.RUnlock()
+
+	if cached || !loaded {
+		return info
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	info = g.lookupLocked(addr)
+	if len(g.cache) >= geoCacheLimit {
+		g.cache = make(map[string]GeoInfo)
+	}
+	g.cache[addr] = info
+	return info
+}
+
+// lookupLocked reads both databases. Caller must hold the lock.
+func (g *GeoIPResolver) lookupLocked(addr string) GeoInfo {
+	var info GeoInfo
+
+	ip := net.ParseIP(addr)
+	if ip == nil || isInternalIP(addr) {
+		return info
+	}
+
+	if g.cityDB != nil {
+		var record mmdbCityRecord
+		if err := g.cityDB.Lookup(ip, &record); err != nil {
+			g.logger.Debug("City lookup failed for %s: %v", addr, err)
+		} else {
+			info.CountryCode = record.Country.ISOCode
+			info.City = record.City.Names["en"]
+		}
+	}
+
+	if g.asnDB != nil {
+		var record mmdbASNRecord
+		if err := g.asnDB.Lookup(ip, &record); err != nil {
+			g.logger.Debug("ASN lookup failed for %s: %v", addr, err)
+		} else {
+			info.ASN = record.ASN
+			info.ASOrg = record.Org
+		}
+	}
+
+	return info
+}
+
+// Enrich tags each connection's remote address with geo and ASN data
+func (g *GeoIPResolver) Enrich(connections []ConnectionInfo) {
+	for i := range connections {
+		conn := &connections[i]
+		info := g.Lookup(conn.RemoteAddr)
+		conn.RemoteCountry = info.CountryCode
+		conn.RemoteCity = info.City
+		conn.RemoteASN = info.ASN
+		conn.RemoteASOrg = info.ASOrg
+	}
+}
@@ -0,0 +1,134 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"tcpdoctor/internal/llm"
+)
+
+// Group-by dimensions for connection breakdowns
+const (
+	GroupByRemoteAddr = "remoteAddr"
+	GroupByHostname   = "hostname"
+	GroupByRemotePort = "remotePort"
+	GroupByPID        = "pid"
+	GroupByState      = "state"
+	GroupByCountry    = "country"
+	GroupByASN        = "asn"
+	GroupByService    = "service"
+	GroupByRole       = "role"
+	GroupByLimitation = "limitation"
+	GroupByCongestion = "congestion"
+)
+
+// maxGroupSampleAddrs limits how many remote addresses are listed per group
+const maxGroupSampleAddrs = 5
+
+// summaryGroupKey returns the group key and display label of a connection for a dimension
+func summaryGroupKey(c *llm.ConnectionSummary, groupBy string) (string, string, error) {
+	switch groupBy {
+	case GroupByRemoteAddr:
+		return c.RemoteAddr, c.RemoteAddr, nil
+	case GroupByHostname:
+		return c.RemoteHostname, c.RemoteHostname, nil
+	case GroupByRemotePort:
+		port := strconv.Itoa(int(c.RemotePort))
+		return port, port, nil
+	case GroupByPID:
+		pid := strconv.Itoa(int(c.PID))
+		return pid, "PID " + pid, nil
+	case GroupByState:
+		return c.State, c.State, nil
+	case GroupByCountry:
+		return c.RemoteCountry, c.RemoteCountry, nil
+	case GroupByService:
+		return c.ServiceName, c.ServiceName, nil
+	case GroupByRole:
+		return c.Role, c.Role, nil
+	case GroupByLimitation:
+		return c.Limitation, c.Limitation, nil
+	case GroupByCongestion:
+		return c.CongestionAlgorithm, c.CongestionAlgorithm, nil
+	case GroupByASN:
+		if c.RemoteASN == 0 {
+			return "", "", nil
+		}
+		return strconv.Itoa(int(c.RemoteASN)), fmt.Sprintf("AS%d %s", c.RemoteASN, c.RemoteASOrg), nil
+	}
+	return "", "", fmt.Errorf("unsupported group-by dimension: %s", groupBy)
+}
+
+// groupConnectionSummaries aggregates connections by the given dimension.
+// Groups are sorted by connection count, largest first.
+func groupConnectionSummaries(summaries []llm.ConnectionSummary, groupBy string) ([]llm.ConnectionGroup, error) {
+	type accumulator struct {
+		group     llm.ConnectionGroup
+		rttSum    float64
+		rttCount  int
+		retranSum float64
+		addrs     map[string]bool
+	}
+
+	accs := make(map[string]*accumulator)
+	for i := range summaries {
+		c := &summaries[i]
+		key, label, err := summaryGroupKey(c, groupBy)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			label = "unknown"
+		}
+
+		acc, exists := accs[key]
+		if !exists {
+			acc = &accumulator{
+				group: llm.ConnectionGroup{GroupBy: groupBy, Key: key, Label: label},
+				addrs: make(map[string]bool),
+			}
+			accs[key] = acc
+		}
+
+		acc.group.Connections++
+		if c.State == StateEstablished.String() {
+			acc.group.Established++
+		}
+		if c.HasWarning {
+			acc.group.WithWarning++
+		}
+		acc.group.BytesIn += c.BytesIn
+		acc.group.BytesOut += c.BytesOut
+		if c.RTTMs > 0 {
+			acc.rttSum += c.RTTMs
+			acc.rttCount++
+			if c.RTTMs > acc.group.MaxRTTMs {
+				acc.group.MaxRTTMs = c.RTTMs
+			}
+		}
+		acc.retranSum += c.RetransmissionRate
+		if len(acc.addrs) < maxGroupSampleAddrs && !acc.addrs[c.RemoteAddr] {
+			acc.addrs[c.RemoteAddr] = true
+			acc.group.RemoteAddrs = append(acc.group.RemoteAddrs, c.RemoteAddr)
+		}
+	}
+
+	groups := make([]llm.ConnectionGroup, 0, len(accs))
+	for _, acc := range accs {
+		if acc.rttCount > 0 {
+			acc.group.AvgRTTMs = acc.rttSum / float64(acc.rttCount)
+		}
+		acc.group.AvgRetrans = acc.retranSum / float64(acc.group.Connections)
+		groups = append(groups, acc.group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Connections != groups[j].Connections {
+			return groups[i].Connections > groups[j].Connections
+		}
+		return groups[i].Key < groups[j].Key
+	})
+
+	return groups, nil
+}
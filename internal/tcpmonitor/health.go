@@ -28,5 +28,5 @@ func CalculateHealth(conn *ConnectionInfo, thresholds HealthThresholds) {
 
 // HasHealthWarnings returns true if the connection has any health warnings
 func HasHealthWarnings(conn *ConnectionInfo) bool {
-	return conn.HighRetransmissionWarning || conn.HighRTTWarning
+	return conn.HighRetransmissionWarning || conn.HighRTTWarning || conn.MSSWarning || conn.WindowScaleWarning || conn.BufferWarning || conn.StallWarning
 }
@@ -0,0 +1,331 @@
+//go:build linux
+
+package tcpmonitor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// inet_diag attribute types (linux/inet_diag.h)
+const (
+	inetDiagInfo      = 2
+	inetDiagVegasInfo = 3
+	inetDiagCong      = 4
+	inetDiagSKMemInfo = 7
+	inetDiagDCTCPInfo = 9
+	inetDiagBBRInfo   = 16
+)
+
+const (
+	sizeofInetDiagReqV2 = 56
+	sizeofInetDiagMsg   = 72
+
+	// Requesting VEGASINFO also makes the kernel return DCTCP and BBR info,
+	// whose attribute numbers do not fit in the 8-bit extension mask
+	inetDiagExtensions = 1<<(inetDiagInfo-1) | 1<<(inetDiagVegasInfo-1) | 1<<(inetDiagCong-1) |
+		1<<(inetDiagSKMemInfo-1)
+
+	// All TCP states
+	inetDiagAllStates = 0xFFFFFFFF
+
+	// Cookie value that disables cookie matching
+	inetDiagNoCookieValue = 0xFFFFFFFF
+
+	// SK_MEMINFO_* indexes of the INET_DIAG_SKMEMINFO u32 array
+	skMemInfoRmemAlloc  = 0
+	skMemInfoRcvBuf     = 1
+	skMemInfoWmemAlloc  = 2
+	skMemInfoSndBuf     = 3
+	skMemInfoFwdAlloc   = 4
+	skMemInfoWmemQueued = 5
+	skMemInfoDrops      = 8
+	skMemInfoVars       = 9
+
+	// tcp_info options bit for negotiated window scaling
+	tcpiOptWscale = 4
+
+	// tcp_info byte holding snd_wscale:4 and rcv_wscale:4
+	tcpInfoWscaleOffset = 6
+
+	netlinkRecvBufferSize = 64 * 1024
+)
+
+// inetDiagSockID identifies a socket (struct inet_diag_sockid)
+type inetDiagSockID struct {
+	SrcPort uint16 // Network byte order
+	DstPort uint16 // Network byte order
+	Src     [16]byte
+	Dst     [16]byte
+	If      uint32
+	Cookie  [2]uint32
+}
+
+// inetDiagNoCookie matches sockets regardless of their cookie
+var inetDiagNoCookie = [2]uint32{inetDiagNoCookieValue, inetDiagNoCookieValue}
+
+// diagSocket is one parsed inet_diag response
+type diagSocket struct {
+	Family  uint8
+	State   uint8
+	Timer   uint8
+	Retrans uint8
+	ID      inetDiagSockID
+	Expires uint32
+	RQueue  uint32
+	WQueue  uint32
+	UID     uint32
+	Inode   uint32
+
+	Info       *unix.TCPInfo // nil for TIME_WAIT and request sockets
+	SndWscale  uint8
+	RcvWscale  uint8
+	Congestion string
+	MemInfo    []uint32 // SK_MEMINFO_* values; nil if not reported
+	Vegas      *unix.TCPVegasInfo
+	DCTCP      *unix.TCPDCTCPInfo
+	BBR        *unix.TCPBBRInfo
+}
+
+// localAddr returns the local address as a net.IP
+func (d *diagSocket) localAddr() net.IP {
+	if d.Family == unix.AF_INET {
+		return net.IP(d.ID.Src[:4])
+	}
+	return net.IP(d.ID.Src[:])
+}
+
+// remoteAddr returns the remote address as a net.IP
+func (d *diagSocket) remoteAddr() net.IP {
+	if d.Family == unix.AF_INET {
+		return net.IP(d.ID.Dst[:4])
+	}
+	return net.IP(d.ID.Dst[:])
+}
+
+// inetDiagDump lists all TCP sockets of an address family
+func inetDiagDump(family uint8) ([]diagSocket, error) {
+	return inetDiagRequest(family, nil)
+}
+
+// inetDiagQuery looks up a single TCP socket by its 4-tuple
+func inetDiagQuery(family uint8, id inetDiagSockID) (*diagSocket, error) {
+	sockets, err := inetDiagRequest(family, &id)
+	if err != nil {
+		return nil, err
+	}
+	if len(sockets) == 0 {
+		return nil, fmt.Errorf("socket not found")
+	}
+	return &sockets[0], nil
+}
+
+// inetDiagRequest sends a SOCK_DIAG_BY_FAMILY request. A nil id dumps all sockets.
+func inetDiagRequest(family uint8, id *inetDiagSockID) ([]diagSocket, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_SOCK_DIAG)
+	if err != nil {
+		return nil, NewAPIError("socket(NETLINK_SOCK_DIAG)", err)
+	}
+	defer unix.Close(fd)
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, NewAPIError("bind(netlink)", err)
+	}
+
+	dump := id == nil
+	flags := uint16(unix.NLM_F_REQUEST)
+	if dump {
+		flags |= unix.NLM_F_DUMP
+	}
+
+	req := make([]byte, unix.SizeofNlMsghdr+sizeofInetDiagReqV2)
+	hdr := (*unix.NlMsghdr)(unsafe.Pointer(&req[0]))
+	hdr.Len = uint32(len(req))
+	hdr.Type = unix.SOCK_DIAG_BY_FAMILY
+	hdr.Flags = flags
+	hdr.Seq = 1
+
+	if id == nil {
+		id = &inetDiagSockID{Cookie: inetDiagNoCookie}
+	}
+
+	body := req[unix.SizeofNlMsghdr:]
+	body[0] = family
+	body[1] = unix.IPPROTO_TCP
+	body[2] = inetDiagExtensions
+	nativeEndian().PutUint32(body[4:8], inetDiagAllStates)
+	putInetDiagSockID(body[8:], id)
+
+	if err := unix.Sendto(fd, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, NewAPIError("sendto(netlink)", err)
+	}
+
+	var sockets []diagSocket
+	buf := make([]byte, netlinkRecvBufferSize)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, NewAPIError("recvfrom(netlink)", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, NewAPIError("parse netlink message", err)
+		}
+
+		for _, msg := range msgs {
+			switch msg.Header.Type {
+			case syscall.NLMSG_DONE:
+				return sockets, nil
+			case unix.NLMSG_ERROR:
+				if len(msg.Data) >= 4 {
+					errno := -int32(nativeEndian().Uint32(msg.Data[:4]))
+					if errno == 0 {
+						return sockets, nil
+					}
+					if unix.Errno(errno) == unix.ENOENT {
+						return nil, ErrConnectionNotFound
+					}
+					return nil, NewAPIError("inet_diag", unix.Errno(errno))
+				}
+				return nil, NewAPIError("inet_diag", fmt.Errorf("malformed error message"))
+			case unix.SOCK_DIAG_BY_FAMILY:
+				if sock, ok := parseInetDiagMsg(msg.Data); ok {
+					sockets = append(sockets, sock)
+				}
+			}
+		}
+
+		// A single-socket query is answered without NLMSG_DONE
+		if !dump {
+			return sockets, nil
+		}
+	}
+}
+
+// putInetDiagSockID encodes a socket id into a request
+func putInetDiagSockID(b []byte, id *inetDiagSockID) {
+	binary.BigEndian.PutUint16(b[0:2], id.SrcPort)
+	binary.BigEndian.PutUint16(b[2:4], id.DstPort)
+	copy(b[4:20], id.Src[:])
+	copy(b[20:36], id.Dst[:])
+	nativeEndian().PutUint32(b[36:40], id.If)
+	nativeEndian().PutUint32(b[40:44], id.Cookie[0])
+	nativeEndian().PutUint32(b[44:48], id.Cookie[1])
+}
+
+// parseInetDiagMsg decodes struct inet_diag_msg and its attributes
+func parseInetDiagMsg(data []byte) (diagSocket, bool) {
+	var d diagSocket
+	if len(data) < sizeofInetDiagMsg {
+		return d, false
+	}
+
+	ne := nativeEndian()
+	d.Family = data[0]
+	d.State = data[1]
+	d.Timer = data[2]
+	d.Retrans = data[3]
+	d.ID.SrcPort = binary.BigEndian.Uint16(data[4:6])
+	d.ID.DstPort = binary.BigEndian.Uint16(data[6:8])
+	copy(d.ID.Src[:], data[8:24])
+	copy(d.ID.Dst[:], data[24:40])
+	d.ID.If = ne.Uint32(data[40:44])
+	d.ID.Cookie[0] = ne.Uint32(data[44:48])
+	d.ID.Cookie[1] = ne.Uint32(data[48:52])
+	d.Expires = ne.Uint32(data[52:56])
+	d.RQueue = ne.Uint32(data[56:60])
+	d.WQueue = ne.Uint32(data[60:64])
+	d.UID = ne.Uint32(data[64:68])
+	d.Inode = ne.Uint32(data[68:72])
+
+	attrs := data[sizeofInetDiagMsg:]
+	for len(attrs) >= unix.SizeofRtAttr {
+		attrLen := int(ne.Uint16(attrs[0:2]))
+		attrType := ne.Uint16(attrs[2:4])
+		if attrLen < unix.SizeofRtAttr || attrLen > len(attrs) {
+			break
+		}
+		payload := attrs[unix.SizeofRtAttr:attrLen]
+
+		switch attrType {
+		case inetDiagInfo:
+			d.Info = new(unix.TCPInfo)
+			copyStruct(unsafe.Pointer(d.Info), unix.SizeofTCPInfo, payload)
+			if len(payload) > tcpInfoWscaleOffset && d.Info.Options&tcpiOptWscale != 0 {
+				// Bitfields are allocated from the low bits on little-endian hosts
+				b := payload[tcpInfoWscaleOffset]
+				d.SndWscale, d.RcvWscale = b&0x0F, b>>4
+				if !hostIsLittleEndian() {
+					d.SndWscale, d.RcvWscale = b>>4, b&0x0F
+				}
+			} else {
+				d.SndWscale, d.RcvWscale = 0xFF, 0xFF
+			}
+		case inetDiagCong:
+			d.Congestion = cString(payload)
+		case inetDiagSKMemInfo:
+			// Older kernels report fewer values; the missing ones stay zero
+			d.MemInfo = make([]uint32, skMemInfoVars)
+			for i := 0; i < skMemInfoVars && 4*i+4 <= len(payload); i++ {
+				d.MemInfo[i] = ne.Uint32(payload[4*i:])
+			}
+		case inetDiagVegasInfo:
+			d.Vegas = new(unix.TCPVegasInfo)
+			copyStruct(unsafe.Pointer(d.Vegas), int(unsafe.Sizeof(*d.Vegas)), payload)
+		case inetDiagDCTCPInfo:
+			d.DCTCP = new(unix.TCPDCTCPInfo)
+			copyStruct(unsafe.Pointer(d.DCTCP), int(unsafe.Sizeof(*d.DCTCP)), payload)
+		case inetDiagBBRInfo:
+			d.BBR = new(unix.TCPBBRInfo)
+			copyStruct(unsafe.Pointer(d.BBR), int(unsafe.Sizeof(*d.BBR)), payload)
+		}
+
+		// Attributes are 4-byte aligned
+		next := (attrLen + unix.RTA_ALIGNTO - 1) &^ (unix.RTA_ALIGNTO - 1)
+		if next > len(attrs) {
+			break
+		}
+		attrs = attrs[next:]
+	}
+
+	return d, true
+}
+
+// copyStruct copies a kernel structure into a Go struct. Older kernels send
+// shorter structures; the missing tail stays zero.
+func copyStruct(dst unsafe.Pointer, size int, src []byte) {
+	if len(src) > size {
+		src = src[:size]
+	}
+	copy(unsafe.Slice((*byte)(dst), size), src)
+}
+
+// cString converts a NUL-terminated byte slice to a string
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// nativeEndian returns the host byte order used by netlink
+func nativeEndian() binary.ByteOrder {
+	if hostIsLittleEndian() {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// hostIsLittleEndian reports the host byte order
+func hostIsLittleEndian() bool {
+	var x uint16 = 1
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}
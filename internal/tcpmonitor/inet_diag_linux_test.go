@@ -0,0 +1,290 @@
+//go:build linux
+
+package tcpmonitor
+
+import (
+	"net"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// diagAttr encodes an inet_diag attribute, padded to 4 bytes
+func diagAttr(attrType uint16, payload []byte) []byte {
+	n := unix.SizeofRtAttr + len(payload)
+	b := make([]byte, (n+unix.RTA_ALIGNTO-1)&^(unix.RTA_ALIGNTO-1))
+	nativeEndian().PutUint16(b[0:2], uint16(n))
+	nativeEndian().PutUint16(b[2:4], attrType)
+	copy(b[unix.SizeofRtAttr:], payload)
+	return b
+}
+
+// tcpInfoAttr encodes tcp_info with the snd_wscale:4/rcv_wscale:4 byte
+func tcpInfoAttr(info unix.TCPInfo, sndWscale, rcvWscale uint8) []byte {
+	payload := make([]byte, unix.SizeofTCPInfo)
+	copy(payload, unsafe.Slice((*byte)(unsafe.Pointer(&info)), unix.SizeofTCPInfo))
+	if hostIsLittleEndian() {
+		payload[tcpInfoWscaleOffset] = sndWscale | rcvWscale<<4
+	} else {
+		payload[tcpInfoWscaleOffset] = sndWscale<<4 | rcvWscale
+	}
+	return diagAttr(inetDiagInfo, payload)
+}
+
+// diagMsg encodes struct inet_diag_msg followed by attributes
+func diagMsg(family, state uint8, id inetDiagSockID, inode uint32, attrs ...[]byte) []byte {
+	data := make([]byte, sizeofInetDiagMsg)
+	data[0], data[1] = family, state
+	putInetDiagSockID(data[4:], &id)
+	nativeEndian().PutUint32(data[68:72], inode)
+	for _, attr := range attrs {
+		data = append(data, attr...)
+	}
+	return data
+}
+
+func mustSockID(t *testing.T, conn ConnectionInfo) inetDiagSockID {
+	t.Helper()
+	id, err := diagSockIDOf(&conn)
+	if err != nil {
+		t.Fatalf("diagSockIDOf: %v", err)
+	}
+	return id
+}
+
+func TestParseInetDiagMsg(t *testing.T) {
+	v4 := mustSockID(t, ConnectionInfo{LocalAddr: "10.0.0.5", LocalPort: 51000, RemoteAddr: "93.184.216.34", RemotePort: 443})
+	v6 := mustSockID(t, ConnectionInfo{LocalAddr: "fd00::5", LocalPort: 8080, RemoteAddr: "2001:db8::1", RemotePort: 60000, IsIPv6: true})
+
+	tests := []struct {
+		name       string
+		data       []byte
+		ok         bool
+		local      string
+		remote     string
+		localPort  uint16
+		remotePort uint16
+		hasInfo    bool
+		sndWscale  uint8
+		rcvWscale  uint8
+	}{
+		{
+			name:  "ipv4 with tcp_info and window scaling",
+			data:  diagMsg(unix.AF_INET, 1, v4, 4242, tcpInfoAttr(unix.TCPInfo{Options: tcpiOptWscale, Snd_mss: 1448}, 7, 9)),
+			ok:    true,
+			local: "10.0.0.5", remote: "93.184.216.34", localPort: 51000, remotePort: 443,
+			hasInfo: true, sndWscale: 7, rcvWscale: 9,
+		},
+		{
+			name:  "ipv6 without window scaling",
+			data:  diagMsg(unix.AF_INET6, 1, v6, 1, tcpInfoAttr(unix.TCPInfo{Snd_mss: 1428}, 7, 9)),
+			ok:    true,
+			local: "fd00::5", remote: "2001:db8::1", localPort: 8080, remotePort: 60000,
+			hasInfo: true, sndWscale: 0xFF, rcvWscale: 0xFF,
+		},
+		{
+			name:  "time-wait socket has no tcp_info",
+			data:  diagMsg(unix.AF_INET, 6, v4, 0),
+			ok:    true,
+			local: "10.0.0.5", remote: "93.184.216.34", localPort: 51000, remotePort: 443,
+		},
+		{
+			name:  "unknown attributes are skipped",
+			data:  diagMsg(unix.AF_INET, 1, v4, 0, diagAttr(200, []byte{1, 2, 3}), tcpInfoAttr(unix.TCPInfo{Options: tcpiOptWscale}, 2, 3)),
+			ok:    true,
+			local: "10.0.0.5", remote: "93.184.216.34", localPort: 51000, remotePort: 443,
+			hasInfo: true, sndWscale: 2, rcvWscale: 3,
+		},
+		{
+			name:  "attribute longer than the message stops parsing",
+			data:  append(diagMsg(unix.AF_INET, 1, v4, 0), 0xFF, 0x00, inetDiagInfo, 0x00),
+			ok:    true,
+			local: "10.0.0.5", remote: "93.184.216.34", localPort: 51000, remotePort: 443,
+		},
+		{
+			name: "truncated message",
+			data: make([]byte, sizeofInetDiagMsg-1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := parseInetDiagMsg(tt.data)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if got := d.localAddr().String(); got != tt.local {
+				t.Errorf("local address = %s, want %s", got, tt.local)
+			}
+			if got := d.remoteAddr().String(); got != tt.remote {
+				t.Errorf("remote address = %s, want %s", got, tt.remote)
+			}
+			if d.ID.SrcPort != tt.localPort || d.ID.DstPort != tt.remotePort {
+				t.Errorf("ports = %d -> %d, want %d -> %d", d.ID.SrcPort, d.ID.DstPort, tt.localPort, tt.remotePort)
+			}
+			if (d.Info != nil) != tt.hasInfo {
+				t.Fatalf("has tcp_info = %v, want %v", d.Info != nil, tt.hasInfo)
+			}
+			if tt.hasInfo && (d.SndWscale != tt.sndWscale || d.RcvWscale != tt.rcvWscale) {
+				t.Errorf("window scale = %d/%d, want %d/%d", d.SndWscale, d.RcvWscale, tt.sndWscale, tt.rcvWscale)
+			}
+		})
+	}
+}
+
+func TestDiagSockIDOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		conn    ConnectionInfo
+		wantErr bool
+		src     net.IP
+	}{
+		{name: "ipv4", conn: ConnectionInfo{LocalAddr: "127.0.0.1", LocalPort: 80, RemoteAddr: "127.0.0.2", RemotePort: 5000}, src: net.IPv4(127, 0, 0, 1).To4()},
+		{name: "ipv6", conn: ConnectionInfo{LocalAddr: "::1", LocalPort: 80, RemoteAddr: "::1", RemotePort: 5000, IsIPv6: true}, src: net.IPv6loopback},
+		{name: "invalid address", conn: ConnectionInfo{LocalAddr: "localhost", RemoteAddr: "::1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := diagSockIDOf(&tt.conn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if id.Cookie != inetDiagNoCookie {
+				t.Errorf("cookie = %v, want no-cookie", id.Cookie)
+			}
+			if got := id.Src[:len(tt.src)]; !net.IP(got).Equal(tt.src) {
+				t.Errorf("source = %v, want %v", net.IP(got), tt.src)
+			}
+			if id.SrcPort != tt.conn.LocalPort || id.DstPort != tt.conn.RemotePort {
+				t.Errorf("ports = %d -> %d", id.SrcPort, id.DstPort)
+			}
+		})
+	}
+}
+
+func TestExtendedStatsFromDiag(t *testing.T) {
+	info := &unix.TCPInfo{
+		Snd_mss:       1000,
+		Snd_cwnd:      10,
+		Snd_ssthresh:  linuxInfiniteSsthresh,
+		Rtt:           20500,
+		Rttvar:        3000,
+		Min_rtt:       0xFFFFFFFF,
+		Unacked:       3,
+		Snd_wnd:       65535,
+		Rcv_space:     29200,
+		Segs_out:      120,
+		Total_retrans: 4,
+	}
+	sock := &diagSocket{Info: info, SndWscale: 7, RcvWscale: 8}
+
+	first := extendedStatsFromDiag(sock, nil)
+	checks := []struct {
+		name      string
+		got, want uint64
+	}{
+		{"cwnd in bytes", uint64(first.CurrentCwnd), 10000},
+		{"infinite ssthresh", uint64(first.CurrentSsthresh), 0xFFFFFFFF},
+		{"rtt rounded up to ms", uint64(first.SmoothedRTT), 21},
+		{"min rtt before first sample", uint64(first.MinRTT), 0},
+		{"retransmit queue in bytes", uint64(first.CurRetxQueue), 3000},
+		{"receive window falls back to rcv_space", uint64(first.CurRwinSent), 29200},
+		{"window scale received", uint64(first.WinScaleRcvd), 7},
+		{"segments out", first.TotalSegsOut, 120},
+		{"retransmitted segments", uint64(first.SegsRetrans), 4},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %d, want %d", c.name, c.got, c.want)
+		}
+	}
+
+	// Maxima and minima are tracked across samples
+	info.Rtt, info.Snd_mss, info.Snd_ssthresh, info.Min_rtt = 5000, 1200, 20, 4000
+	second := extendedStatsFromDiag(sock, first)
+	if second.MaxRTT != 21 || second.SmoothedRTT != 5 {
+		t.Errorf("rtt = %d (max %d), want 5 (max 21)", second.SmoothedRTT, second.MaxRTT)
+	}
+	if second.MinMss != 1000 || second.MaxMss != 1200 {
+		t.Errorf("mss range = %d-%d, want 1000-1200", second.MinMss, second.MaxMss)
+	}
+	if second.CurrentSsthresh != 24000 || second.MinRTT != 4 {
+		t.Errorf("ssthresh = %d, min rtt = %d; want 24000 and 4", second.CurrentSsthresh, second.MinRTT)
+	}
+}
+
+func TestCongestionFromDiag(t *testing.T) {
+	id := mustSockID(t, ConnectionInfo{LocalAddr: "10.0.0.5", LocalPort: 51000, RemoteAddr: "93.184.216.34", RemotePort: 443})
+	bbr := unix.TCPBBRInfo{Bw_lo: 1250000, Min_rtt: 12000, Pacing_gain: 739, Cwnd_gain: 512}
+	dctcp := unix.TCPDCTCPInfo{Enabled: 1, Alpha: 256, Ab_ecn: 10, Ab_tot: 40}
+	info := tcpInfoAttr(unix.TCPInfo{Ca_state: 3, Snd_mss: 1448, Pacing_rate: 2500000}, 0, 0)
+
+	tests := []struct {
+		name      string
+		attrs     [][]byte
+		algorithm string
+		check     func(t *testing.T, stats *ExtendedStats)
+	}{
+		{
+			name:      "cubic has no model",
+			attrs:     [][]byte{info, diagAttr(inetDiagCong, []byte("cubic\x00"))},
+			algorithm: "cubic",
+			check: func(t *testing.T, stats *ExtendedStats) {
+				if stats.BBR != nil || stats.DCTCP != nil || stats.Vegas != nil {
+					t.Errorf("unexpected algorithm info: %+v %+v %+v", stats.BBR, stats.DCTCP, stats.Vegas)
+				}
+			},
+		},
+		{
+			name:      "bbr model",
+			attrs:     [][]byte{info, diagAttr(inetDiagCong, []byte("bbr\x00")), diagAttr(inetDiagBBRInfo, unsafe.Slice((*byte)(unsafe.Pointer(&bbr)), unsafe.Sizeof(bbr)))},
+			algorithm: "bbr",
+			check: func(t *testing.T, stats *ExtendedStats) {
+				if stats.BBR == nil {
+					t.Fatal("BBR info missing")
+				}
+				if stats.BBR.BandwidthBps != 10000000 || stats.BBR.MinRTTUs != 12000 || stats.BBR.CwndGain != 2 {
+					t.Errorf("BBR = %+v", *stats.BBR)
+				}
+			},
+		},
+		{
+			name:      "dctcp alpha",
+			attrs:     [][]byte{info, diagAttr(inetDiagCong, []byte("dctcp\x00")), diagAttr(inetDiagDCTCPInfo, unsafe.Slice((*byte)(unsafe.Pointer(&dctcp)), unsafe.Sizeof(dctcp)))},
+			algorithm: "dctcp",
+			check: func(t *testing.T, stats *ExtendedStats) {
+				if stats.DCTCP == nil || stats.DCTCP.Alpha != 256 || stats.DCTCP.ABTotal != 40 {
+					t.Errorf("DCTCP = %+v", stats.DCTCP)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := parseInetDiagMsg(diagMsg(unix.AF_INET, 1, id, 0, tt.attrs...))
+			if !ok || d.Info == nil {
+				t.Fatal("message not parsed")
+			}
+			stats := extendedStatsFromDiag(&d, nil)
+			if stats.CongestionAlgorithm != tt.algorithm {
+				t.Errorf("algorithm = %q, want %q", stats.CongestionAlgorithm, tt.algorithm)
+			}
+			if stats.CAState != "recovery" {
+				t.Errorf("state = %q, want recovery", stats.CAState)
+			}
+			if stats.PacingRate != 20000000 {
+				t.Errorf("pacing rate = %d, want 20000000", stats.PacingRate)
+			}
+			tt.check(t, stats)
+		})
+	}
+}
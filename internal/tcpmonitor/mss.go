@@ -0,0 +1,299 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MSS / path-MTU anomaly types
+const (
+	MSSAnomalyDrop      = "mss_drop"        // MSS reduced mid-connection (PMTU discovery event)
+	MSSAnomalySmall     = "small_mss"       // Stuck at an unusually small MSS (tunnel/VPN overhead)
+	MSSAnomalyBlackHole = "pmtu_black_hole" // Repeated timeouts without progress on a large-MSS path
+)
+
+const (
+	// Below these the path carries noticeable encapsulation overhead
+	// (Ethernet MSS is 1460 for IPv4 and 1440 for IPv6)
+	smallMSSIPv4 = 1400
+	smallMSSIPv6 = 1380
+
+	// Very small MSS, e.g. nested tunnels or a 576-byte MTU path
+	tinyMSS = 1000
+
+	// MSS at or above which full-size segments are being sent
+	largeMSS = 1400
+
+	// Timeout episodes without acked progress that indicate a black hole
+	blackHoleTimeouts = 2
+
+	// How long an MSS drop stays reported once the MSS holds steady
+	mssDropWindow = 2 * time.Minute
+)
+
+// MSSFinding describes a detected MSS/PMTU anomaly
+type MSSFinding struct {
+	Anomaly     string    `json:"anomaly"`
+	Severity    string    `json:"severity"` // "high", "medium", "low"
+	Description string    `json:"description"`
+	FromMSS     uint32    `json:"fromMss,omitempty"` // For drops
+	ToMSS       uint32    `json:"toMss,omitempty"`
+	Timestamp   time.Time `json:"timestamp,omitempty"` // When replaying recorded sessions
+}
+
+// mssSample is the subset of stats the detector looks at
+type mssSample struct {
+	CurMss    uint32
+	MaxMss    uint32
+	Timeouts  uint32
+	RetxQueue uint32
+	Acked     uint64
+}
+
+// mssSampleFromStats extracts detector inputs from ExtendedStats
+func mssSampleFromStats(stats *ExtendedStats) mssSample {
+	return mssSample{
+		CurMss:    stats.CurMss,
+		MaxMss:    stats.MaxMss,
+		Timeouts:  stats.TimeoutEpisodes,
+		RetxQueue: stats.CurRetxQueue,
+		Acked:     stats.ThruBytesAcked,
+	}
+}
+
+// mssSampleFromCompact extracts detector inputs from a recorded snapshot
+func mssSampleFromCompact(c *CompactConnection) mssSample {
+	return mssSample{
+		CurMss:    uint32(c.CurMss),
+		MaxMss:    uint32(c.MaxMss),
+		Timeouts:  uint32(c.TimeoutEpisodes),
+		RetxQueue: uint32(c.CurRetxQueue),
+		Acked:     uint64(c.ThruBytesAcked),
+	}
+}
+
+// mssTracker follows one connection's MSS and acked progress across samples.
+// It is used both live (PMTUDetector) and when replaying recorded sessions.
+type mssTracker struct {
+	initialized      bool
+	lastMss          uint32
+	progressAcked    uint64 // ThruBytesAcked at the last observed progress
+	progressTimeouts uint32 // TimeoutEpisodes at the last observed progress
+	blackHole        bool   // Black hole currently reported
+
+	// Last observed MSS decrease, reported until the MSS has held for
+	// mssDropWindow. MaxMss can't tell this: it never comes back down.
+	dropFrom, dropTo uint32
+	dropAt           time.Time
+}
+
+// observe advances the tracker with a sample taken at now and returns
+// anomalies that started at this sample
+func (t *mssTracker) observe(s mssSample, now time.Time) []MSSFinding {
+	var started []MSSFinding
+
+	if !t.initialized {
+		t.initialized = true
+		t.lastMss = s.CurMss
+		t.progressAcked = s.Acked
+		t.progressTimeouts = s.Timeouts
+		// Nothing beyond the handshake acked yet: count timeouts from the start
+		if s.Acked < uint64(s.CurMss) {
+			t.progressTimeouts = 0
+		}
+		// Dropped before the connection was first seen
+		if s.CurMss > 0 && s.MaxMss > s.CurMss {
+			t.dropFrom, t.dropTo, t.dropAt = s.MaxMss, s.CurMss, now
+		}
+	}
+
+	if s.CurMss > 0 && t.lastMss > 0 && s.CurMss < t.lastMss {
+		started = append(started, mssDropFinding(t.lastMss, s.CurMss))
+		t.dropFrom, t.dropTo, t.dropAt = t.lastMss, s.CurMss, now
+	}
+	if s.CurMss > 0 {
+		t.lastMss = s.CurMss
+	}
+
+	if s.Acked > t.progressAcked {
+		t.progressAcked = s.Acked
+		t.progressTimeouts = s.Timeouts
+		t.blackHole = false
+	}
+
+	if t.isBlackHole(s) {
+		if !t.blackHole {
+			started = append(started, blackHoleFinding(s, s.Timeouts-t.progressTimeouts))
+		}
+		t.blackHole = true
+	}
+
+	return started
+}
+
+// isBlackHole checks for stalled progress with repeated timeouts on a large-MSS connection
+func (t *mssTracker) isBlackHole(s mssSample) bool {
+	return s.CurMss >= largeMSS &&
+		s.RetxQueue > 0 &&
+		s.Acked == t.progressAcked &&
+		s.Timeouts >= t.progressTimeouts+blackHoleTimeouts
+}
+
+// condition returns the most severe standing anomaly for the latest sample,
+// taken at now. A drop stands until the MSS has held for mssDropWindow.
+func (t *mssTracker) condition(s mssSample, isIPv6 bool, now time.Time) *MSSFinding {
+	if t.blackHole {
+		f := blackHoleFinding(s, s.Timeouts-t.progressTimeouts)
+		return &f
+	}
+	if !t.dropAt.IsZero() && now.Sub(t.dropAt) < mssDropWindow {
+		f := mssDropFinding(t.dropFrom, t.dropTo)
+		return &f
+	}
+	if f := smallMSSFinding(s, isIPv6); f != nil {
+		return f
+	}
+	return nil
+}
+
+// smallMSSFinding flags a connection that never ran with a full-size MSS
+func smallMSSFinding(s mssSample, isIPv6 bool) *MSSFinding {
+	limit := uint32(smallMSSIPv4)
+	if isIPv6 {
+		limit = smallMSSIPv6
+	}
+	maxMss := s.MaxMss
+	if maxMss < s.CurMss {
+		maxMss = s.CurMss
+	}
+	if s.CurMss == 0 || maxMss >= limit {
+		return nil
+	}
+
+	severity := "low"
+	if s.CurMss < tinyMSS {
+		severity = "medium"
+	}
+	return &MSSFinding{
+		Anomaly:     MSSAnomalySmall,
+		Severity:    severity,
+		Description: fmt.Sprintf("MSS stuck at %d bytes (tunnel/VPN overhead or reduced path MTU)", s.CurMss),
+		ToMSS:       s.CurMss,
+	}
+}
+
+// mssDropFinding describes an MSS reduction
+func mssDropFinding(from, to uint32) MSSFinding {
+	return MSSFinding{
+		Anomaly:     MSSAnomalyDrop,
+		Severity:    "medium",
+		Description: fmt.Sprintf("MSS dropped from %d to %d bytes (path MTU discovery event)", from, to),
+		FromMSS:     from,
+		ToMSS:       to,
+	}
+}
+
+// blackHoleFinding describes a suspected PMTU black hole
+func blackHoleFinding(s mssSample, timeouts uint32) MSSFinding {
+	return MSSFinding{
+		Anomaly:  MSSAnomalyBlackHole,
+		Severity: "high",
+		Description: fmt.Sprintf("Suspected PMTU black hole: %d timeouts without progress while sending %d-byte segments",
+			timeouts, s.CurMss),
+		ToMSS: s.CurMss,
+	}
+}
+
+// DetectMSSAnomalies replays a recorded connection timeline and returns
+// every anomaly, stamped with the time of the sample where it started
+func DetectMSSAnomalies(snapshots []TimelineConnection) []MSSFinding {
+	var tracker mssTracker
+	var findings []MSSFinding
+	reportedSmall := false
+
+	for i := range snapshots {
+		conn := &snapshots[i].Connection
+		if TCPState(conn.State) != StateEstablished {
+			continue
+		}
+		s := mssSampleFromCompact(conn)
+		for _, f := range tracker.observe(s, snapshots[i].Timestamp) {
+			f.Timestamp = snapshots[i].Timestamp
+			findings = append(findings, f)
+		}
+		if !reportedSmall {
+			if f := smallMSSFinding(s, strings.Contains(conn.RemoteAddr, ":")); f != nil {
+				f.Timestamp = snapshots[i].Timestamp
+				findings = append(findings, *f)
+				reportedSmall = true
+			}
+		}
+	}
+	return findings
+}
+
+// PMTUDetector flags MSS/PMTU anomalies on live connections
+type PMTUDetector struct {
+	trackers map[ConnectionKey]*mssTracker
+	mu       sync.Mutex
+	logger   *Logger
+}
+
+// NewPMTUDetector creates a new detector
+func NewPMTUDetector() *PMTUDetector {
+	return &PMTUDetector{
+		trackers: make(map[ConnectionKey]*mssTracker),
+		logger:   GetLogger(),
+	}
+}
+
+// Analyze sets MSSWarning and MSSAnomaly on every connection with extended
+// stats and forgets connections that are gone
+func (d *PMTUDetector) Analyze(connections []ConnectionInfo) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	seen := make(map[ConnectionKey]bool, len(connections))
+	for i := range connections {
+		conn := &connections[i]
+		conn.MSSWarning = false
+		conn.MSSAnomaly = nil
+
+		if conn.ExtendedStats == nil || conn.State != StateEstablished {
+			continue
+		}
+
+		key := ConnectionKey{
+			LocalAddr:  conn.LocalAddr,
+			LocalPort:  conn.LocalPort,
+			RemoteAddr: conn.RemoteAddr,
+			RemotePort: conn.RemotePort,
+			IsIPv6:     conn.IsIPv6,
+		}
+		seen[key] = true
+
+		tracker := d.trackers[key]
+		if tracker == nil {
+			tracker = &mssTracker{}
+			d.trackers[key] = tracker
+		}
+
+		s := mssSampleFromStats(conn.ExtendedStats)
+		for _, f := range tracker.observe(s, now) {
+			d.logger.Info("%s on %s", f.Description, key.String())
+		}
+		if f := tracker.condition(s, conn.IsIPv6, now); f != nil {
+			conn.MSSWarning = true
+			conn.MSSAnomaly = f
+		}
+	}
+
+	for key := range d.trackers {
+		if !seen[key] {
+			delete(d.trackers, key)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build linux
+
+package tcpmonitor
+
+// newPlatformStatsCollector creates the inet_diag statistics collector.
+// The kernel reports tcp_info for TCP sockets to unprivileged users, so
+// extended statistics are always available and no API layer is needed.
+func newPlatformStatsCollector() (*StatsCollector, *WindowsAPILayer, bool) {
+	GetLogger().Info("Using inet_diag - extended statistics available")
+	return NewStatsCollector(), nil, true
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package tcpmonitor
+
+import "tcpdoctor/internal/tcpmonitor/winapi"
+
+// newPlatformStatsCollector creates the Windows API layer and statistics collector.
+// Extended statistics (ESTATS) require administrator privileges.
+func newPlatformStatsCollector() (*StatsCollector, *WindowsAPILayer, bool) {
+	logger := GetLogger()
+
+	// Create Windows API layer
+	apiLayer := winapi.NewWindowsAPILayer()
+
+	// Check administrator privileges
+	isAdmin := apiLayer.IsAdministrator()
+	if isAdmin {
+		logger.Info("Running with Administrator privileges - extended statistics available")
+	} else {
+		logger.Info("Running without Administrator privileges - extended statistics unavailable")
+	}
+
+	return NewStatsCollector(apiLayer, isAdmin), apiLayer, isAdmin
+}
@@ -0,0 +1,458 @@
+package tcpmonitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Probe outcomes
+const (
+	ProbeOK          = "ok"
+	ProbeRefused     = "refused"     // RST in response to SYN
+	ProbeReset       = "reset"       // Connection reset during/after the handshake
+	ProbeTimeout     = "timeout"     // No SYN/ACK within the timeout
+	ProbeUnreachable = "unreachable" // Host or network unreachable
+	ProbeError       = "error"       // Anything else (DNS failure, ...)
+)
+
+// ProberConfig controls active TCP connect probing
+type ProberConfig struct {
+	Enabled            bool
+	IntervalSeconds    int      // Time between probe rounds
+	TimeoutMs          int      // Per-probe connect timeout
+	Targets            []string // Explicit "host:port" targets
+	ProbeObserved      bool     // Also probe remote endpoints of established client connections
+	MaxObservedTargets int      // Cap on observed endpoints probed per round
+}
+
+// DefaultProberConfig returns the default prober configuration (disabled)
+func DefaultProberConfig() ProberConfig {
+	return ProberConfig{
+		Enabled:            false,
+		IntervalSeconds:    10,
+		TimeoutMs:          3000,
+		ProbeObserved:      false,
+		MaxObservedTargets: 20,
+	}
+}
+
+// ProbeResult is a single connect-latency measurement
+type ProbeResult struct {
+	SessionID int64     `json:"sessionId,omitempty"` // Set when recorded into a session
+	Timestamp time.Time `json:"timestamp"`
+	Target    string    `json:"target"`
+	ConnectMs float64   `json:"connectMs"` // SYN -> SYN/ACK time, 0 on failure
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// ProbeComparison contrasts active connect time with passive RTT for one endpoint
+type ProbeComparison struct {
+	Target       string  `json:"target"`
+	Samples      int     `json:"samples"`
+	ConnectP50Ms float64 `json:"connectP50Ms"`
+	ConnectP95Ms float64 `json:"connectP95Ms"`
+	FailureRate  float64 `json:"failureRate"` // Percent of probes that failed
+	PassiveRTTMs float64 `json:"passiveRttMs"`
+	Connections  int     `json:"connections"` // Established connections to the endpoint
+	Assessment   string  `json:"assessment"`
+}
+
+// proberHistorySize is the number of recent results kept per target for the live view
+const proberHistorySize = 120
+
+// proberConcurrency bounds the number of simultaneous probes
+const proberConcurrency = 8
+
+// probeResetWait is how long a completed handshake is watched for a reset.
+// Peers and middleboxes that refuse a connection after accepting it, such as
+// an overflowing accept queue or a filtering firewall, answer within it.
+const probeResetWait = 100 * time.Millisecond
+
+// Prober periodically measures TCP connect latency to a set of targets
+type Prober struct {
+	config  ProberConfig
+	history map[string][]ProbeResult // Recent results per target
+
+	// observed returns endpoints seen in the connection table
+	observed func() []string
+	// record receives each round of results
+	record func([]ProbeResult)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	logger *Logger
+}
+
+// NewProber creates a prober. observed supplies remote endpoints from the
+// connection table and record stores results; either may be nil.
+func NewProber(config ProberConfig, observed func() []string, record func([]ProbeResult)) *Prober {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Prober{
+		config:   config,
+		history:  make(map[string][]ProbeResult),
+		observed: observed,
+		record:   record,
+		ctx:      ctx,
+		cancel:   cancel,
+		logger:   GetLogger(),
+	}
+}
+
+// Start begins the probing loop
+func (p *Prober) Start() {
+	p.wg.Add(1)
+	go p.loop()
+}
+
+// Stop ends the probing loop and waits for in-flight probes
+func (p *Prober) Stop() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// loop runs a probe round every interval while enabled
+func (p *Prober) loop() {
+	defer p.wg.Done()
+
+	for {
+		config := p.GetConfig()
+		interval := time.Duration(config.IntervalSeconds) * time.Second
+		if interval <= 0 {
+			interval = 10 * time.Second
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-time.After(interval):
+			if config.Enabled {
+				p.runRound(config)
+			}
+		}
+	}
+}
+
+// runRound probes all current targets concurrently
+func (p *Prober) runRound(config ProberConfig) {
+	targets := p.targets(config)
+	if len(targets) == 0 {
+		return
+	}
+
+	results := make([]ProbeResult, len(targets))
+	sem := make(chan struct{}, proberConcurrency)
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, target string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = p.probe(p.ctx, target, time.Duration(config.TimeoutMs)*time.Millisecond)
+		}(i, target)
+	}
+	wg.Wait()
+
+	// Results from a cancelled round are meaningless
+	if p.ctx.Err() != nil {
+		return
+	}
+
+	p.storeHistory(results)
+	if p.record != nil {
+		p.record(results)
+	}
+	p.logger.Debug("Probe round completed: %d targets", len(targets))
+}
+
+// targets merges the configured targets with observed endpoints, without duplicates
+func (p *Prober) targets(config ProberConfig) []string {
+	seen := make(map[string]bool)
+	var targets []string
+
+	for _, t := range config.Targets {
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+
+	if config.ProbeObserved && p.observed != nil {
+		added := 0
+		for _, t := range p.observed() {
+			if added >= config.MaxObservedTargets {
+				break
+			}
+			if !seen[t] {
+				seen[t] = true
+				targets = append(targets, t)
+				added++
+			}
+		}
+	}
+
+	return targets
+}
+
+// ProbeOnce performs a single immediate probe of a target
+func (p *Prober) ProbeOnce(target string) ProbeResult {
+	config := p.GetConfig()
+	result := p.probe(p.ctx, target, time.Duration(config.TimeoutMs)*time.Millisecond)
+	p.storeHistory([]ProbeResult{result})
+	return result
+}
+
+// probe opens and immediately closes a TCP connection, timing the handshake
+func (p *Prober) probe(ctx context.Context, target string, timeout time.Duration) ProbeResult {
+	result := ProbeResult{Timestamp: time.Now(), Target: target}
+
+	// Resolve first so DNS time isn't counted as connect time
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		result.Outcome = ProbeError
+		result.Error = err.Error()
+		return result
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	addrs, err := net.DefaultResolver.LookupIPAddr(lookupCtx, host)
+	cancel()
+	if err != nil || len(addrs) == 0 {
+		result.Outcome = ProbeError
+		result.Error = fmt.Sprintf("lookup failed: %v", err)
+		return result
+	}
+	addr := net.JoinHostPort(addrs[0].IP.String(), port)
+
+	dialer := net.Dialer{Timeout: timeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		result.Outcome = classifyProbeError(err)
+		result.Error = err.Error()
+		return result
+	}
+	result.ConnectMs = float64(elapsed.Microseconds()) / 1000.0
+	result.Outcome = ProbeOK
+
+	// A reset shows up on the first read; data, EOF or silence mean the
+	// connection was accepted
+	conn.SetReadDeadline(time.Now().Add(min(probeResetWait, timeout)))
+	if _, err := conn.Read(make([]byte, 1)); err != nil && isConnReset(err) {
+		result.Outcome = ProbeReset
+		result.Error = err.Error()
+	}
+
+	// Close with RST (linger 0) so probes don't pile up in TIME_WAIT
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+	return result
+}
+
+// classifyProbeError maps a dial error to a probe outcome
+func classifyProbeError(err error) string {
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return ProbeTimeout
+	case isConnRefused(err):
+		return ProbeRefused
+	case isConnReset(err):
+		return ProbeReset
+	case isUnreachable(err):
+		return ProbeUnreachable
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ProbeTimeout
+	}
+	return ProbeError
+}
+
+// storeHistory appends results to the per-target live history
+func (p *Prober) storeHistory(results []ProbeResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, r := range results {
+		h := append(p.history[r.Target], r)
+		if len(h) > proberHistorySize {
+			h = h[len(h)-proberHistorySize:]
+		}
+		p.history[r.Target] = h
+	}
+}
+
+// GetLatest returns the most recent result for every probed target
+func (p *Prober) GetLatest() []ProbeResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make([]ProbeResult, 0, len(p.history))
+	for _, h := range p.history {
+		if len(h) > 0 {
+			result = append(result, h[len(h)-1])
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Target < result[j].Target
+	})
+	return result
+}
+
+// GetHistory returns the recent results for a target
+func (p *Prober) GetHistory(target string) []ProbeResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	result := make([]ProbeResult, len(p.history[target]))
+	copy(result, p.history[target])
+	return result
+}
+
+// SetConfig replaces the prober configuration
+func (p *Prober) SetConfig(config ProberConfig) error {
+	if config.IntervalSeconds < 1 {
+		return fmt.Errorf("probe interval must be at least 1 second")
+	}
+	if config.TimeoutMs < 1 {
+		return fmt.Errorf("probe timeout must be positive")
+	}
+	for _, t := range config.Targets {
+		if _, port, err := net.SplitHostPort(t); err != nil {
+			return fmt.Errorf("invalid probe target %q: %w", t, err)
+		} else if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid probe target %q: bad port", t)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+	return nil
+}
+
+// GetConfig returns the current prober configuration
+func (p *Prober) GetConfig() ProberConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config
+}
+
+// Compare contrasts recent probe results with passive RTT of established
+// connections to the same endpoints.
+func (p *Prober) Compare(connections []ConnectionInfo, thresholds HealthThresholds) []ProbeComparison {
+	// Passive RTT per remote endpoint
+	type passive struct {
+		rttSum float64
+		count  int
+		conns  int
+	}
+	passiveByTarget := make(map[string]*passive)
+	for i := range connections {
+		c := &connections[i]
+		if c.State != StateEstablished {
+			continue
+		}
+		target := net.JoinHostPort(c.RemoteAddr, strconv.Itoa(int(c.RemotePort)))
+		pa, ok := passiveByTarget[target]
+		if !ok {
+			pa = &passive{}
+			passiveByTarget[target] = pa
+		}
+		pa.conns++
+		if c.ExtendedStats != nil && c.ExtendedStats.SmoothedRTT > 0 {
+			pa.rttSum += float64(c.ExtendedStats.SmoothedRTT)
+			pa.count++
+		}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	comparisons := make([]ProbeComparison, 0, len(p.history))
+	for target, h := range p.history {
+		cmp := ProbeComparison{Target: target, Samples: len(h)}
+
+		var connectTimes []float64
+		failures := 0
+		for _, r := range h {
+			if r.Outcome == ProbeOK {
+				connectTimes = append(connectTimes, r.ConnectMs)
+			} else {
+				failures++
+			}
+		}
+		if len(h) > 0 {
+			cmp.FailureRate = float64(failures) / float64(len(h)) * 100
+		}
+		cmp.ConnectP50Ms = percentileFloat64(connectTimes, 50)
+		cmp.ConnectP95Ms = percentileFloat64(connectTimes, 95)
+
+		if pa, ok := passiveByTarget[target]; ok {
+			cmp.Connections = pa.conns
+			if pa.count > 0 {
+				cmp.PassiveRTTMs = pa.rttSum / float64(pa.count)
+			}
+		}
+
+		cmp.Assessment = assessProbe(cmp, thresholds)
+		comparisons = append(comparisons, cmp)
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool {
+		return comparisons[i].Target < comparisons[j].Target
+	})
+	return comparisons
+}
+
+// assessProbe classifies where latency comes from for one endpoint.
+// Connect time is answered by the remote kernel, so it reflects the network
+// path; passive RTT well above it points at the endpoint or queueing.
+func assessProbe(cmp ProbeComparison, thresholds HealthThresholds) string {
+	switch {
+	case cmp.Samples == 0:
+		return "no_data"
+	case cmp.FailureRate >= 50:
+		return "failing"
+	case cmp.FailureRate > 0:
+		return "intermittent_failures"
+	case cmp.ConnectP50Ms >= float64(thresholds.HighRTTMilliseconds):
+		return "path_slow"
+	case cmp.PassiveRTTMs > 20 && cmp.PassiveRTTMs > 2*cmp.ConnectP50Ms:
+		return "endpoint_or_queueing_delay"
+	}
+	return "healthy"
+}
+
+// percentileFloat64 returns the p-th percentile (nearest rank) of values
+func percentileFloat64(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
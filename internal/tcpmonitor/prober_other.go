@@ -0,0 +1,23 @@
+//go:build !windows
+
+package tcpmonitor
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isConnRefused reports whether a dial failed because the target answered with RST
+func isConnRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// isConnReset reports whether a connection was reset by the peer
+func isConnReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET)
+}
+
+// isUnreachable reports whether the host or network could not be reached
+func isUnreachable(err error) bool {
+	return errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH)
+}
@@ -0,0 +1,75 @@
+package tcpmonitor
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestProbeClassification(t *testing.T) {
+	// Accepts and keeps connections open
+	open, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer open.Close()
+	go func() {
+		for {
+			conn, err := open.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	// Accepts, then resets every connection
+	resetting, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resetting.Close()
+	go func() {
+		for {
+			conn, err := resetting.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*net.TCPConn).SetLinger(0)
+			conn.Close()
+		}
+	}()
+
+	// Nothing listens on a port just released
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := closedListener.Addr().String()
+	closedListener.Close()
+
+	tests := []struct {
+		name    string
+		target  string
+		outcome string
+	}{
+		{"listening", open.Addr().String(), ProbeOK},
+		{"closed port", closed, ProbeRefused},
+		{"reset after accept", resetting.Addr().String(), ProbeReset},
+		{"bad target", "127.0.0.1", ProbeError},
+	}
+
+	p := NewProber(DefaultProberConfig(), nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := p.probe(context.Background(), tt.target, 2*time.Second)
+			if result.Outcome != tt.outcome {
+				t.Fatalf("outcome = %q (%s), want %q", result.Outcome, result.Error, tt.outcome)
+			}
+			if (result.ConnectMs > 0) != (tt.outcome == ProbeOK || tt.outcome == ProbeReset) {
+				t.Errorf("ConnectMs = %v for outcome %q", result.ConnectMs, result.Outcome)
+			}
+		})
+	}
+}
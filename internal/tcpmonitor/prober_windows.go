@@ -0,0 +1,31 @@
+//go:build windows
+
+package tcpmonitor
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Winsock error codes; syscall.ECONNREFUSED and friends are invented values on Windows
+const (
+	wsaeConnRefused = syscall.Errno(10061)
+	wsaeConnReset   = syscall.WSAECONNRESET
+	wsaeNetUnreach  = syscall.Errno(10051)
+	wsaeHostUnreach = syscall.Errno(10065)
+)
+
+// isConnRefused reports whether a dial failed because the target answered with RST
+func isConnRefused(err error) bool {
+	return errors.Is(err, wsaeConnRefused)
+}
+
+// isConnReset reports whether a connection was reset by the peer
+func isConnReset(err error) bool {
+	return errors.Is(err, wsaeConnReset)
+}
+
+// isUnreachable reports whether the host or network could not be reached
+func isUnreachable(err error) bool {
+	return errors.Is(err, wsaeHostUnreach) || errors.Is(err, wsaeNetUnreach)
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package tcpmonitor
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// processName returns the executable name of a process, or "" if unknown
+func processName(pid uint32) string {
+	data, err := os.ReadFile("/proc/" + strconv.FormatUint(uint64(pid), 10) + "/comm")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package tcpmonitor
+
+import (
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+var procQueryFullProcessImageNameW = syscall.NewLazyDLL("kernel32.dll").NewProc("QueryFullProcessImageNameW")
+
+// PROCESS_QUERY_LIMITED_INFORMATION is enough to read the image name of
+// processes owned by other users
+const processQueryLimitedInformation = 0x1000
+
+// processName returns the executable name of a process, or "" if unknown
+func processName(pid uint32) string {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer syscall.CloseHandle(handle)
+
+	buf := make([]uint16, syscall.MAX_PATH)
+	size := uint32(len(buf))
+	ret, _, _ := procQueryFullProcessImageNameW.Call(
+		uintptr(handle),
+		0,
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	if ret == 0 {
+		return ""
+	}
+	return filepath.Base(syscall.UTF16ToString(buf[:size]))
+}
@@ -0,0 +1,467 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tcpdoctor/internal/llm"
+)
+
+// maxQueryPoints limits the steps a history query may span
+const maxQueryPoints = 10000
+
+// HistoryQueryResult is the outcome of a history query: a table when the
+// query has no step, otherwise one series per group
+type HistoryQueryResult struct {
+	Query     string               `json:"query"`
+	Kind      string               `json:"kind"` // "table" or "series"
+	SessionID int64                `json:"sessionId"`
+	Start     time.Time            `json:"start"`
+	End       time.Time            `json:"end"`
+	Step      int64                `json:"step,omitempty"` // Seconds between series points
+	Labels    []string             `json:"labels"`         // Grouping labels in query order
+	Value     string               `json:"value"`          // The selection, e.g. "avg(rtt)"
+	Rows      []HistoryQueryRow    `json:"rows,omitempty"`
+	Series    []HistoryQuerySeries `json:"series,omitempty"`
+}
+
+// HistoryQueryRow is one group of a table result
+type HistoryQueryRow struct {
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
+// HistoryQuerySeries is one group of a series result
+type HistoryQuerySeries struct {
+	Labels map[string]string   `json:"labels"`
+	Points []HistoryQueryPoint `json:"points"`
+}
+
+// HistoryQueryPoint is the value of a group over one step
+type HistoryQueryPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// queryConnKey identifies a connection within a query, as in a range diff
+type queryConnKey struct {
+	tuple connTuple
+	pid   int
+}
+
+// queryCell accumulates the samples of one connection within one step
+type queryCell struct {
+	conn CompactConnection // Latest matching row, for labels
+	sum  float64           // Gauge total, or counter increase
+	secs float64           // Time the increase was measured over
+	last float64
+	n    int
+}
+
+// value reduces the cell to the connection's value for the step
+func (c *queryCell) value(q *historyQuery) (float64, bool) {
+	switch {
+	case q.metric == nil:
+		return 1, true
+	case q.fn == "rate":
+		return c.sum / c.secs, c.secs > 0
+	case q.fn == "delta":
+		return c.sum, c.n > 0
+	case q.metric.counter:
+		return c.last, true
+	default:
+		return c.sum / float64(c.n), true
+	}
+}
+
+// queryGroup collects the connection values of one group within one step
+type queryGroup struct {
+	labels map[string]string
+	values []float64
+}
+
+// Query runs a history query (see query_parse.go) against a recorded
+// session. It serves the UI, the query_history AI tool and any Go caller
+// holding the store.
+func (s *SnapshotStore) Query(query string) (*HistoryQueryResult, error) {
+	q, err := parseHistoryQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	session, err := s.querySession(q.sessionID)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := q.resolveRange(session)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &HistoryQueryResult{
+		Query:     query,
+		Kind:      "table",
+		SessionID: session.ID,
+		Start:     start,
+		End:       end,
+		Labels:    make([]string, len(q.by)),
+		Value:     q.valueName(),
+	}
+	for i, l := range q.by {
+		result.Labels[i] = l.name
+	}
+	if q.step > 0 {
+		if end.Sub(start)/q.step >= maxQueryPoints {
+			return nil, fmt.Errorf("step %s is too small for the range, which would need more than %d points", q.step, maxQueryPoints)
+		}
+		result.Kind = "series"
+		result.Step = int64(q.step / time.Second)
+	}
+
+	buckets := s.queryBuckets(q, session.ID, start, end)
+	if q.step == 0 {
+		result.Rows = q.tableRows(buckets[0])
+	} else {
+		result.Series = q.series(buckets, start)
+	}
+	return result, nil
+}
+
+// querySession returns the session with the given ID, or the most recently
+// started one for 0
+func (s *SnapshotStore) querySession(sessionID int64) (*RecordingSession, error) {
+	if sessionID != 0 {
+		session := s.GetSessionByID(sessionID)
+		if session == nil {
+			return nil, fmt.Errorf("session %d not found", sessionID)
+		}
+		return session, nil
+	}
+
+	var latest *RecordingSession
+	sessions := s.GetSessions()
+	for i := range sessions {
+		if latest == nil || sessions[i].StartTime.After(latest.StartTime) {
+			latest = &sessions[i]
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no recording sessions")
+	}
+	return latest, nil
+}
+
+// resolveRange turns the over clause into a time range within the session
+func (q *historyQuery) resolveRange(session *RecordingSession) (time.Time, time.Time, error) {
+	start, end := session.StartTime, session.EndTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	switch {
+	case q.last > 0:
+		if from := end.Add(-q.last); from.After(start) {
+			start = from
+		}
+	case q.from != "":
+		from, err := parseQueryTime(q.from, session.StartTime)
+		if err != nil {
+			return start, end, err
+		}
+		to, err := parseQueryTime(q.to, session.StartTime)
+		if err != nil {
+			return start, end, err
+		}
+		// Times of day past midnight belong to the next day
+		if !isRFC3339(q.from) && from.Before(start.Truncate(time.Second)) && !from.Add(24*time.Hour).After(end) {
+			from = from.Add(24 * time.Hour)
+		}
+		if !isRFC3339(q.to) && to.Before(from) {
+			to = to.Add(24 * time.Hour)
+		}
+		if to.Before(from) {
+			return start, end, fmt.Errorf("range ends before it starts")
+		}
+		// Clip to the session so only the segments it needs are read
+		if from.After(start) {
+			start = from
+		}
+		if to.Before(end) {
+			end = to
+		}
+		if end.Before(start) {
+			return start, end, fmt.Errorf("range is outside session %d", session.ID)
+		}
+	}
+	return start, end, nil
+}
+
+func isRFC3339(text string) bool {
+	_, err := time.Parse(time.RFC3339, text)
+	return err == nil
+}
+
+// parseQueryTime parses an RFC3339 time, or a time of day on the date of day
+func parseQueryTime(text string, day time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if clock, err := time.Parse(layout, text); err == nil {
+			y, m, d := day.Date()
+			return time.Date(y, m, d, clock.Hour(), clock.Minute(), clock.Second(), 0, day.Location()), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use RFC3339 or HH:MM[:SS]", text)
+}
+
+// queryBuckets reads the session's raw snapshots in the range and
+// accumulates the matching rows per step and connection. Without a step everything falls
+// in bucket 0.
+func (s *SnapshotStore) queryBuckets(q *historyQuery, sessionID int64, start, end time.Time) map[int]map[queryConnKey]*queryCell {
+	type previous struct {
+		value     float64
+		timestamp time.Time
+	}
+	prev := make(map[queryConnKey]previous)
+	buckets := map[int]map[queryConnKey]*queryCell{0: {}}
+	table := newStringTable()
+
+	view, _ := s.sessionView(sessionID, start, end, func(log *snapshotLog) *logView {
+		return log.view(log.clipTime(log.sessionRuns(sessionID), start, end))
+	})
+	view.Each(func(snap *Snapshot) bool {
+		for i := range snap.Connections {
+			c := &snap.Connections[i]
+			var packed packedRow
+			table.packRow(c, &packed)
+
+			key := queryConnKey{tupleOf(c), c.PID}
+			var value float64
+			if q.metric != nil {
+				value = q.metric.value(&packed)
+			}
+			last, seen := prev[key]
+			prev[key] = previous{value, snap.Timestamp}
+			if q.where != nil && !q.where.match(c, &packed) {
+				continue
+			}
+
+			bucket := 0
+			if q.step > 0 && snap.Timestamp.After(start) {
+				bucket = int(snap.Timestamp.Sub(start) / q.step)
+			}
+			cells := buckets[bucket]
+			if cells == nil {
+				cells = make(map[queryConnKey]*queryCell)
+				buckets[bucket] = cells
+			}
+			cell := cells[key]
+			if cell == nil {
+				cell = &queryCell{}
+				cells[key] = cell
+			}
+			cell.conn = *c
+
+			switch {
+			case q.metric == nil:
+			case q.fn != "":
+				if !seen {
+					continue
+				}
+				// A drop means the counter was reset
+				increase := value
+				if value >= last.value {
+					increase = value - last.value
+				}
+				cell.sum += increase
+				cell.secs += snap.Timestamp.Sub(last.timestamp).Seconds()
+				cell.n++
+			default:
+				cell.sum += value
+				cell.last = value
+				cell.n++
+			}
+		}
+		return true
+	})
+	return buckets
+}
+
+// groups reduces the cells of one step to the values of each group
+func (q *historyQuery) groups(cells map[queryConnKey]*queryCell) map[string]*queryGroup {
+	groups := make(map[string]*queryGroup)
+	for _, cell := range cells {
+		v, ok := cell.value(q)
+		if !ok {
+			continue
+		}
+		labels := make(map[string]string, len(q.by))
+		parts := make([]string, len(q.by))
+		for i, l := range q.by {
+			labels[l.name] = l.value(&cell.conn)
+			parts[i] = labels[l.name]
+		}
+		key := strings.Join(parts, "\x00")
+		g := groups[key]
+		if g == nil {
+			g = &queryGroup{labels: labels}
+			groups[key] = g
+		}
+		g.values = append(g.values, v)
+	}
+	return groups
+}
+
+// aggregate combines the connection values of a group
+func (q *historyQuery) aggregate(values []float64) float64 {
+	switch q.agg {
+	case "sum":
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum
+	case "min", "max":
+		result := values[0]
+		for _, v := range values[1:] {
+			if (q.agg == "min") == (v < result) {
+				result = v
+			}
+		}
+		return result
+	case "count":
+		return float64(len(values))
+	case "stddev":
+		return stdDevFloat64(values)
+	case "p50", "p90", "p95", "p99":
+		p, _ := strconv.ParseFloat(q.agg[1:], 64)
+		return percentileFloat64(values, p)
+	default:
+		return avgFloat64(values)
+	}
+}
+
+// keep returns how many of n ranked results the query keeps
+func (q *historyQuery) keep(n int) int {
+	if q.rank != "" && q.k < n {
+		n = q.k
+	}
+	if q.limit > 0 && q.limit < n {
+		n = q.limit
+	}
+	return n
+}
+
+// ranksBefore orders results by value, highest first except for bottomk,
+// breaking ties by labels so results are stable
+func (q *historyQuery) ranksBefore(a, b float64, aKey, bKey string) bool {
+	if a != b {
+		return (a > b) != (q.rank == "bottomk")
+	}
+	return aKey < bKey
+}
+
+// tableRows builds the rows of a table result
+func (q *historyQuery) tableRows(cells map[queryConnKey]*queryCell) []HistoryQueryRow {
+	groups := q.groups(cells)
+	keys := make([]string, 0, len(groups))
+	values := make(map[string]float64, len(groups))
+	for key, g := range groups {
+		keys = append(keys, key)
+		values[key] = q.aggregate(g.values)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return q.ranksBefore(values[keys[i]], values[keys[j]], keys[i], keys[j])
+	})
+
+	rows := make([]HistoryQueryRow, q.keep(len(keys)))
+	for i := range rows {
+		rows[i] = HistoryQueryRow{Labels: groups[keys[i]].labels, Value: values[keys[i]]}
+	}
+	return rows
+}
+
+// series builds the series of a stepped result, ranked by their mean
+func (q *historyQuery) series(buckets map[int]map[queryConnKey]*queryCell, start time.Time) []HistoryQuerySeries {
+	steps := make([]int, 0, len(buckets))
+	for bucket := range buckets {
+		steps = append(steps, bucket)
+	}
+	sort.Ints(steps)
+
+	byKey := make(map[string]*HistoryQuerySeries)
+	var keys []string
+	for _, bucket := range steps {
+		timestamp := start.Add(time.Duration(bucket) * q.step)
+		for key, g := range q.groups(buckets[bucket]) {
+			series := byKey[key]
+			if series == nil {
+				series = &HistoryQuerySeries{Labels: g.labels}
+				byKey[key] = series
+				keys = append(keys, key)
+			}
+			series.Points = append(series.Points, HistoryQueryPoint{Timestamp: timestamp, Value: q.aggregate(g.values)})
+		}
+	}
+
+	means := make(map[string]float64, len(keys))
+	for _, key := range keys {
+		var sum float64
+		for _, p := range byKey[key].Points {
+			sum += p.Value
+		}
+		means[key] = sum / float64(len(byKey[key].Points))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return q.ranksBefore(means[keys[i]], means[keys[j]], keys[i], keys[j])
+	})
+
+	result := make([]HistoryQuerySeries, q.keep(len(keys)))
+	for i := range result {
+		result[i] = *byKey[keys[i]]
+	}
+	return result
+}
+
+// Limits on what a query_history result shows the AI
+const (
+	maxToolQueryRows   = 50
+	maxToolQuerySeries = 10
+	maxToolQueryPoints = 60
+)
+
+// queryAnswer condenses a history query result for the AI, keeping the top
+// rows and series and thinning long series to every n-th point
+func queryAnswer(result *HistoryQueryResult) *llm.HistoryQueryAnswer {
+	answer := &llm.HistoryQueryAnswer{
+		Query:     result.Query,
+		SessionID: result.SessionID,
+		Start:     result.Start.Format(time.RFC3339),
+		End:       result.End.Format(time.RFC3339),
+		Step:      result.Step,
+		Value:     result.Value,
+		Total:     len(result.Rows) + len(result.Series),
+	}
+	for i, row := range result.Rows {
+		if i == maxToolQueryRows {
+			break
+		}
+		answer.Rows = append(answer.Rows, llm.HistoryQueryRow{Labels: row.Labels, Value: row.Value})
+	}
+	for i, series := range result.Series {
+		if i == maxToolQuerySeries {
+			break
+		}
+		stride := (len(series.Points) + maxToolQueryPoints - 1) / maxToolQueryPoints
+		thinned := llm.HistoryQuerySeries{Labels: series.Labels}
+		for j := 0; j < len(series.Points); j += stride {
+			p := series.Points[j]
+			thinned.Points = append(thinned.Points, llm.TimeValue{Time: p.Timestamp.Format(time.RFC3339), Value: p.Value})
+		}
+		answer.Series = append(answer.Series, thinned)
+	}
+	return answer
+}
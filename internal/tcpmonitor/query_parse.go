@@ -0,0 +1,661 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// History queries select a value per group of connections from a recorded
+// session, either over the whole range (a table) or per step (time series):
+//
+//	avg(rtt) by remoteAddr where state = 'ESTABLISHED' and remotePort = 443 over session 12 step 10s
+//	topk(5, rate(segsRetrans)) by pid
+//	count(*) by state over session latest last 15m step 1m
+//
+// The selection is an optional topk/bottomk(k, ...) around an optional
+// aggregation (avg, sum, min, max, count, stddev, p50, p90, p95, p99) of a
+// value: a CompactConnection metric, rate(counter) per second or
+// delta(counter), or * inside count. A bare value is summed for counters,
+// rates and deltas and averaged for gauges. Clauses may come in any order:
+//
+//	by label, ...              group by connection labels (see queryLabels)
+//	where cond                 =, !=, <, <=, >, >=, =~ and !~ on labels and
+//	                           metrics, combined with and, or, not and parentheses
+//	over session N|latest      the session queried (default latest)
+//	     [from T to T|last D]  part of it; T is RFC3339 or a time of day
+//	step D                     return a series with one point per D
+//	limit N                    at most N rows or series
+//
+// Each connection contributes one value per step: the mean of a gauge, the
+// last value of a counter, or the increase of a counter (per second for
+// rate) since the previous sample. The aggregation then combines the
+// connections of a group.
+
+// queryAggregations lists the aggregation functions
+var queryAggregations = []string{"avg", "sum", "min", "max", "count", "stddev", "p50", "p90", "p95", "p99"}
+
+// historyQuery is a parsed history query
+type historyQuery struct {
+	rank   string // "topk", "bottomk" or ""
+	k      int
+	agg    string      // Aggregation across the connections of a group
+	fn     string      // "rate", "delta" or "" for the metric's value
+	metric *diffColumn // nil for count(*)
+
+	by    []queryLabel
+	where queryCond // nil matches every row
+
+	sessionID int64  // 0 for the latest session
+	from, to  string // Range within the session, unresolved
+	last      time.Duration
+	step      time.Duration
+	limit     int
+}
+
+// valueName describes the selection, e.g. "topk(5, sum(rate(segsRetrans)))"
+func (q *historyQuery) valueName() string {
+	value := "*"
+	if q.metric != nil {
+		value = q.metric.name
+	}
+	if q.fn != "" {
+		value = q.fn + "(" + value + ")"
+	}
+	value = q.agg + "(" + value + ")"
+	if q.rank != "" {
+		value = fmt.Sprintf("%s(%d, %s)", q.rank, q.k, value)
+	}
+	return value
+}
+
+// queryLabel is a descriptive field connections can be grouped and
+// filtered by
+type queryLabel struct {
+	name  string
+	value func(c *CompactConnection) string
+}
+
+// queryLabelAliases maps short label names to CompactConnection JSON names
+var queryLabelAliases = map[string]string{
+	"hostname":   "remoteHostname",
+	"country":    "remoteCountry",
+	"city":       "remoteCity",
+	"asn":        "remoteAsn",
+	"asorg":      "remoteAsOrg",
+	"service":    "serviceName",
+	"congestion": "congestionAlgorithm",
+}
+
+// queryLabels lists the labels: the 4-tuple, "connection" for all of it,
+// and the descriptive fields of a diff
+func queryLabels() []queryLabel {
+	labels := []queryLabel{
+		{"localAddr", func(c *CompactConnection) string { return c.LocalAddr }},
+		{"localPort", func(c *CompactConnection) string { return strconv.Itoa(c.LocalPort) }},
+		{"remoteAddr", func(c *CompactConnection) string { return c.RemoteAddr }},
+		{"remotePort", func(c *CompactConnection) string { return strconv.Itoa(c.RemotePort) }},
+		{"connection", func(c *CompactConnection) string {
+			return fmt.Sprintf("%s:%d->%s:%d", c.LocalAddr, c.LocalPort, c.RemoteAddr, c.RemotePort)
+		}},
+	}
+	for _, f := range diffFields {
+		labels = append(labels, queryLabel{f.name, f.value})
+	}
+	return labels
+}
+
+// lookupQueryLabel finds a label by name or alias, ignoring case
+func lookupQueryLabel(name string) (queryLabel, bool) {
+	if alias, ok := queryLabelAliases[strings.ToLower(name)]; ok {
+		name = alias
+	}
+	for _, l := range queryLabels() {
+		if strings.EqualFold(l.name, name) {
+			return l, true
+		}
+	}
+	return queryLabel{}, false
+}
+
+// queryCond is a where condition on one timeline row
+type queryCond interface {
+	match(c *CompactConnection, row *packedRow) bool
+}
+
+type andCond []queryCond
+
+func (a andCond) match(c *CompactConnection, row *packedRow) bool {
+	for _, cond := range a {
+		if !cond.match(c, row) {
+			return false
+		}
+	}
+	return true
+}
+
+type orCond []queryCond
+
+func (o orCond) match(c *CompactConnection, row *packedRow) bool {
+	for _, cond := range o {
+		if cond.match(c, row) {
+			return true
+		}
+	}
+	return false
+}
+
+type notCond struct{ cond queryCond }
+
+func (n notCond) match(c *CompactConnection, row *packedRow) bool {
+	return !n.cond.match(c, row)
+}
+
+// compareCond compares a label or metric with a literal
+type compareCond struct {
+	label  *queryLabel
+	metric *diffColumn
+	op     string
+	text   string
+	number float64
+	isNum  bool
+	re     *regexp.Regexp // For =~ and !~
+}
+
+func (cmp *compareCond) match(c *CompactConnection, row *packedRow) bool {
+	if cmp.metric != nil {
+		return compareNumbers(cmp.metric.value(row), cmp.op, cmp.number)
+	}
+
+	value := cmp.label.value(c)
+	switch cmp.op {
+	case "=~":
+		return cmp.re.MatchString(value)
+	case "!~":
+		return !cmp.re.MatchString(value)
+	}
+	if cmp.isNum {
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return compareNumbers(n, cmp.op, cmp.number)
+		}
+	}
+	switch cmp.op {
+	case "=":
+		return strings.EqualFold(value, cmp.text)
+	case "!=":
+		return !strings.EqualFold(value, cmp.text)
+	case "<":
+		return value < cmp.text
+	case "<=":
+		return value <= cmp.text
+	case ">":
+		return value > cmp.text
+	default:
+		return value >= cmp.text
+	}
+}
+
+func compareNumbers(a float64, op string, b float64) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+// Token kinds of the query lexer
+const (
+	tokEOF = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokOp // ( ) , * and comparison operators
+)
+
+type queryToken struct {
+	kind int
+	text string
+	pos  int
+}
+
+// lexQuery splits a query into tokens
+func lexQuery(input string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '\'' || r == '"':
+			i++
+			for i < len(runes) && runes[i] != r {
+				i++
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			i++
+			tokens = append(tokens, queryToken{tokString, string(runes[start+1 : i-1]), start})
+			continue
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			kind := tokNumber
+			if i < len(runes) && unicode.IsLetter(runes[i]) {
+				kind = tokDuration
+				for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '.') {
+					i++
+				}
+			}
+			tokens = append(tokens, queryToken{kind, string(runes[start:i]), start})
+			continue
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, queryToken{tokIdent, string(runes[start:i]), start})
+			continue
+		}
+
+		op := string(r)
+		if i+1 < len(runes) {
+			switch two := string(runes[i : i+2]); two {
+			case "!=", "<=", ">=", "=~", "!~":
+				op = two
+			}
+		}
+		if !strings.Contains("(),*=<>", op) && len(op) == 1 {
+			return nil, fmt.Errorf("unexpected %q at %d", r, start)
+		}
+		i += len([]rune(op))
+		tokens = append(tokens, queryToken{tokOp, op, start})
+	}
+	return append(tokens, queryToken{tokEOF, "", len(runes)}), nil
+}
+
+// queryParser is a recursive-descent parser over the tokens of a query
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+// parseHistoryQuery parses a history query
+func parseHistoryQuery(input string) (*historyQuery, error) {
+	tokens, err := lexQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	q := &historyQuery{}
+	if err := p.parseSelection(q); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for p.peek().kind != tokEOF {
+		t := p.next()
+		clause := strings.ToLower(t.text)
+		if t.kind != tokIdent {
+			return nil, p.errorAt(t, "expected by, where, over, step or limit")
+		}
+		if seen[clause] {
+			return nil, p.errorAt(t, "duplicate %s clause", clause)
+		}
+		seen[clause] = true
+
+		switch clause {
+		case "by":
+			err = p.parseBy(q)
+		case "where":
+			q.where, err = p.parseOr()
+		case "over":
+			err = p.parseOver(q)
+		case "step":
+			q.step, err = p.parseDuration()
+			if err == nil && q.step < time.Second {
+				err = fmt.Errorf("step must be at least 1s")
+			}
+		case "limit":
+			q.limit, err = p.parseCount("limit")
+		default:
+			err = p.errorAt(t, "expected by, where, over, step or limit")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// topk without grouping ranks single connections
+	if q.rank != "" && len(q.by) == 0 {
+		label, _ := lookupQueryLabel("connection")
+		q.by = []queryLabel{label}
+	}
+	return q, nil
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword consumes the next token if it is the given keyword
+func (p *queryParser) keyword(word string) bool {
+	if t := p.peek(); t.kind == tokIdent && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(op string) error {
+	if t := p.next(); t.kind != tokOp || t.text != op {
+		return p.errorAt(t, "expected %q", op)
+	}
+	return nil
+}
+
+func (p *queryParser) errorAt(t queryToken, format string, args ...interface{}) error {
+	found := t.text
+	if t.kind == tokEOF {
+		found = "end of query"
+	}
+	return fmt.Errorf("%s at %d (found %q)", fmt.Sprintf(format, args...), t.pos, found)
+}
+
+// parseSelection parses [topk|bottomk(k, ] [agg(] value [)] [)]
+func (p *queryParser) parseSelection(q *historyQuery) error {
+	t := p.peek()
+	if t.kind == tokIdent && (strings.EqualFold(t.text, "topk") || strings.EqualFold(t.text, "bottomk")) {
+		p.next()
+		q.rank = strings.ToLower(t.text)
+		if err := p.expect("("); err != nil {
+			return err
+		}
+		k, err := p.parseCount(q.rank)
+		if err != nil {
+			return err
+		}
+		q.k = k
+		if err := p.expect(","); err != nil {
+			return err
+		}
+		if err := p.parseAggregation(q); err != nil {
+			return err
+		}
+		return p.expect(")")
+	}
+	return p.parseAggregation(q)
+}
+
+// parseAggregation parses agg(value) or a bare value
+func (p *queryParser) parseAggregation(q *historyQuery) error {
+	t := p.peek()
+	if t.kind == tokIdent && p.tokens[p.pos+1].text == "(" {
+		for _, agg := range queryAggregations {
+			if strings.EqualFold(t.text, agg) {
+				p.pos += 2
+				q.agg = agg
+				if err := p.parseValue(q); err != nil {
+					return err
+				}
+				return p.expect(")")
+			}
+		}
+	}
+
+	if err := p.parseValue(q); err != nil {
+		return err
+	}
+	q.agg = "avg"
+	if q.fn != "" || (q.metric != nil && q.metric.counter) {
+		q.agg = "sum"
+	}
+	return nil
+}
+
+// parseValue parses metric, rate(metric), delta(metric) or * (in count)
+func (p *queryParser) parseValue(q *historyQuery) error {
+	t := p.next()
+	if t.kind == tokOp && t.text == "*" {
+		if q.agg != "count" {
+			return p.errorAt(t, "* is only allowed in count")
+		}
+		return nil
+	}
+	if t.kind != tokIdent {
+		return p.errorAt(t, "expected a metric")
+	}
+
+	if fn := strings.ToLower(t.text); (fn == "rate" || fn == "delta") && p.peek().text == "(" {
+		p.next()
+		q.fn = fn
+		t = p.next()
+		if t.kind != tokIdent {
+			return p.errorAt(t, "expected a metric")
+		}
+		if err := p.expect(")"); err != nil {
+			return err
+		}
+	}
+
+	col, ok := lookupDiffColumn(t.text)
+	if !ok {
+		return p.errorAt(t, "unknown metric")
+	}
+	if q.fn != "" && !col.counter {
+		return p.errorAt(t, "%s() needs a counter, %s is a gauge", q.fn, col.name)
+	}
+	q.metric = &col
+	return nil
+}
+
+// parseBy parses a comma-separated label list
+func (p *queryParser) parseBy(q *historyQuery) error {
+	for {
+		t := p.next()
+		label, ok := lookupQueryLabel(t.text)
+		if t.kind != tokIdent || !ok {
+			return p.errorAt(t, "unknown label")
+		}
+		q.by = append(q.by, label)
+		if p.peek().text != "," || p.peek().kind != tokOp {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// parseOr parses conditions joined by "or"
+func (p *queryParser) parseOr() (queryCond, error) {
+	var conds orCond
+	for {
+		cond, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+		if !p.keyword("or") {
+			break
+		}
+	}
+	if len(conds) == 1 {
+		return conds[0], nil
+	}
+	return conds, nil
+}
+
+// parseAnd parses conditions joined by "and"
+func (p *queryParser) parseAnd() (queryCond, error) {
+	var conds andCond
+	for {
+		cond, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+		if !p.keyword("and") {
+			break
+		}
+	}
+	if len(conds) == 1 {
+		return conds[0], nil
+	}
+	return conds, nil
+}
+
+// parseUnary parses not, a parenthesized condition or a comparison
+func (p *queryParser) parseUnary() (queryCond, error) {
+	if p.keyword("not") {
+		cond, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notCond{cond}, nil
+	}
+	if t := p.peek(); t.kind == tokOp && t.text == "(" {
+		p.next()
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return cond, p.expect(")")
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses field op literal
+func (p *queryParser) parseComparison() (queryCond, error) {
+	field := p.next()
+	if field.kind != tokIdent {
+		return nil, p.errorAt(field, "expected a label or metric")
+	}
+	cmp := &compareCond{}
+	if label, ok := lookupQueryLabel(field.text); ok {
+		cmp.label = &label
+	} else if col, ok := lookupDiffColumn(field.text); ok {
+		cmp.metric = &col
+	} else {
+		return nil, p.errorAt(field, "unknown label or metric")
+	}
+
+	op := p.next()
+	if op.kind != tokOp || !strings.Contains(" = != < <= > >= =~ !~ ", " "+op.text+" ") {
+		return nil, p.errorAt(op, "expected a comparison operator")
+	}
+	cmp.op = op.text
+
+	value := p.next()
+	switch value.kind {
+	case tokString, tokIdent:
+		cmp.text = value.text
+	case tokNumber:
+		cmp.text = value.text
+		if n, err := strconv.ParseFloat(value.text, 64); err == nil {
+			cmp.number, cmp.isNum = n, true
+		}
+	default:
+		return nil, p.errorAt(value, "expected a value")
+	}
+
+	if cmp.op == "=~" || cmp.op == "!~" {
+		if cmp.metric != nil {
+			return nil, p.errorAt(op, "%s only applies to labels", cmp.op)
+		}
+		re, err := regexp.Compile("(?i)" + cmp.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", cmp.text, err)
+		}
+		cmp.re = re
+	} else if cmp.metric != nil && !cmp.isNum {
+		return nil, p.errorAt(value, "%s is compared with a number", cmp.metric.name)
+	}
+	return cmp, nil
+}
+
+// parseOver parses session N|latest [from T to T | last D]
+func (p *queryParser) parseOver(q *historyQuery) error {
+	if p.keyword("session") {
+		t := p.next()
+		switch {
+		case t.kind == tokIdent && strings.EqualFold(t.text, "latest"):
+		case t.kind == tokNumber:
+			id, err := strconv.ParseInt(t.text, 10, 64)
+			if err != nil || id <= 0 {
+				return p.errorAt(t, "invalid session ID")
+			}
+			q.sessionID = id
+		default:
+			return p.errorAt(t, "expected a session ID or latest")
+		}
+	}
+
+	switch {
+	case p.keyword("from"):
+		from := p.next()
+		if from.kind != tokString {
+			return p.errorAt(from, "expected a quoted time")
+		}
+		if !p.keyword("to") {
+			return p.errorAt(p.peek(), "expected to")
+		}
+		to := p.next()
+		if to.kind != tokString {
+			return p.errorAt(to, "expected a quoted time")
+		}
+		q.from, q.to = from.text, to.text
+	case p.keyword("last"):
+		d, err := p.parseDuration()
+		if err != nil {
+			return err
+		}
+		q.last = d
+	}
+	return nil
+}
+
+// parseDuration parses a duration such as 10s, 5m or 1h30m
+func (p *queryParser) parseDuration() (time.Duration, error) {
+	t := p.next()
+	if t.kind != tokDuration {
+		return 0, p.errorAt(t, "expected a duration such as 10s or 5m")
+	}
+	d, err := time.ParseDuration(t.text)
+	if err != nil || d <= 0 {
+		return 0, p.errorAt(t, "invalid duration")
+	}
+	return d, nil
+}
+
+// parseCount parses a positive integer
+func (p *queryParser) parseCount(what string) (int, error) {
+	t := p.next()
+	n, err := strconv.Atoi(t.text)
+	if t.kind != tokNumber || err != nil || n <= 0 {
+		return 0, p.errorAt(t, "%s needs a positive integer", what)
+	}
+	return n, nil
+}
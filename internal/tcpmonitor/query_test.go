@@ -0,0 +1,257 @@
+package tcpmonitor
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseHistoryQuery(t *testing.T) {
+	labels := func(q *historyQuery) []string {
+		var names []string
+		for _, l := range q.by {
+			names = append(names, l.name)
+		}
+		return names
+	}
+
+	q, err := parseHistoryQuery("avg(rtt) by remoteAddr where state = 'ESTABLISHED' and remotePort = 443 over session 12 step 10s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.agg != "avg" || q.fn != "" || q.metric.name != "rtt" || q.sessionID != 12 || q.step != 10*time.Second {
+		t.Errorf("parsed %+v", q)
+	}
+	if got := labels(q); len(got) != 1 || got[0] != "remoteAddr" {
+		t.Errorf("by = %v, want [remoteAddr]", got)
+	}
+	if and, ok := q.where.(andCond); !ok || len(and) != 2 {
+		t.Errorf("where = %#v, want two conditions joined by and", q.where)
+	}
+
+	q, err = parseHistoryQuery("topk(5, rate(segsRetrans)) by pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.rank != "topk" || q.k != 5 || q.fn != "rate" || q.metric.name != "segsRetrans" || q.sessionID != 0 {
+		t.Errorf("parsed %+v", q)
+	}
+	if got := q.valueName(); got != "topk(5, sum(rate(segsRetrans)))" {
+		t.Errorf("valueName = %q", got)
+	}
+
+	q, err = parseHistoryQuery("count(*) by state, pid over session latest last 15m step 1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.agg != "count" || q.metric != nil || q.last != 15*time.Minute || q.step != time.Minute {
+		t.Errorf("parsed %+v", q)
+	}
+	if got := labels(q); len(got) != 2 || got[0] != "state" || got[1] != "pid" {
+		t.Errorf("by = %v, want [state pid]", got)
+	}
+
+	// Clauses in any order, aliases, not and parentheses, time-of-day ranges
+	q, err = parseHistoryQuery("P95(RTT) limit 3 where not (remotePort = 443 or hostname =~ 'example') BY service over session 2 from '09:00' to '10:30'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.agg != "p95" || q.limit != 3 || q.from != "09:00" || q.to != "10:30" || q.sessionID != 2 {
+		t.Errorf("parsed %+v", q)
+	}
+	if got := labels(q); len(got) != 1 || got[0] != "serviceName" {
+		t.Errorf("by = %v, want [serviceName]", got)
+	}
+	if _, ok := q.where.(notCond); !ok {
+		t.Errorf("where = %#v, want not", q.where)
+	}
+
+	// Bare values: counters are summed, gauges averaged; topk alone ranks connections
+	defaults := []struct {
+		query string
+		agg   string
+		by    string
+	}{
+		{"rtt", "avg", ""},
+		{"bytesIn", "sum", ""},
+		{"delta(bytesIn)", "sum", ""},
+		{"topk(3, rtt)", "avg", "connection"},
+		{"bottomk(3, max(rtt)) by pid", "max", "pid"},
+	}
+	for _, tt := range defaults {
+		q, err := parseHistoryQuery(tt.query)
+		if err != nil {
+			t.Errorf("%q: %v", tt.query, err)
+			continue
+		}
+		got := labels(q)
+		if q.agg != tt.agg || (tt.by == "") != (len(got) == 0) || (tt.by != "" && got[0] != tt.by) {
+			t.Errorf("%q: agg %q by %v, want %q by %q", tt.query, q.agg, got, tt.agg, tt.by)
+		}
+	}
+
+	for _, query := range []string{
+		"",
+		"avg(rtt",
+		"avg(rtt))",
+		"avg(*)",
+		"nosuchmetric",
+		"rate(rtt)",
+		"topk(0, rtt)",
+		"topk(5 rtt)",
+		"avg(rtt) by nosuchlabel",
+		"avg(rtt) by pid by pid",
+		"avg(rtt) sorted",
+		"avg(rtt) step 500ms",
+		"avg(rtt) step 10",
+		"avg(rtt) limit 0",
+		"avg(rtt) limit -1",
+		"avg(rtt) where rtt =~ '1'",
+		"avg(rtt) where rtt > 'high'",
+		"avg(rtt) where hostname =~ '('",
+		"avg(rtt) where state = 'ESTABLISHED",
+		"avg(rtt) where state",
+		"avg(rtt) where (state = 'LISTEN'",
+		"avg(rtt) over session 0",
+		"avg(rtt) over session first",
+		"avg(rtt) over session 1 from '09:00'",
+		"avg(rtt) over session 1 from 09 to 10",
+		"avg(rtt) over last",
+		"avg(rtt) where pid = 1 ; drop",
+	} {
+		if _, err := parseHistoryQuery(query); err == nil {
+			t.Errorf("parseHistoryQuery(%q) succeeded, want an error", query)
+		}
+	}
+}
+
+// queryTestStore holds one imported session of three connections sampled
+// every 10s for 50s. Port 1's retransmission counter resets at 30s.
+func queryTestStore(t *testing.T) (*SnapshotStore, int64, time.Time) {
+	t.Helper()
+	start := time.Date(2026, time.October, 16, 9, 0, 0, 0, time.UTC)
+	retrans := map[int][]int64{
+		1: {0, 10, 20, 5, 15, 25}, // 45 over 50s
+		2: {0, 2, 4, 6, 8, 10},    // 10 over 50s
+		3: {0, 5, 10, 15, 20, 25}, // 25 over 50s
+	}
+
+	var snapshots []Snapshot
+	for i := 0; i < 6; i++ {
+		snap := Snapshot{ID: int64(i + 1), SessionID: 1, Timestamp: start.Add(time.Duration(i) * 10 * time.Second)}
+		for port := 1; port <= 3; port++ {
+			state := StateEstablished
+			if port == 3 && i >= 4 {
+				state = StateTimeWait
+			}
+			snap.Connections = append(snap.Connections, CompactConnection{
+				LocalAddr:   "192.168.1.20",
+				LocalPort:   port,
+				RemoteAddr:  "203.0.113.1",
+				RemotePort:  443,
+				State:       int(state),
+				PID:         100 + port,
+				RTT:         int64(10 * port),
+				SegsRetrans: retrans[port][i],
+			})
+		}
+		snapshots = append(snapshots, snap)
+	}
+
+	store := NewSnapshotStore(100)
+	session := RecordingSession{StartTime: start, EndTime: start.Add(50 * time.Second)}
+	id, err := store.ImportSession(session, snapshots, nil, nil)
+	if err != nil {
+		t.Fatalf("ImportSession: %v", err)
+	}
+	return store, id, start
+}
+
+func TestQueryTable(t *testing.T) {
+	store, id, _ := queryTestStore(t)
+	session := " over session " + strconv.FormatInt(id, 10)
+
+	tests := []struct {
+		query string
+		ports []string
+		want  []float64
+	}{
+		// The counter reset adds the post-reset value, not a negative step
+		{"rate(segsRetrans) by localPort", []string{"1", "3", "2"}, []float64{0.9, 0.5, 0.2}},
+		{"delta(segsRetrans) by localPort", []string{"1", "3", "2"}, []float64{45, 25, 10}},
+		{"topk(2, rate(segsRetrans)) by localPort", []string{"1", "3"}, []float64{0.9, 0.5}},
+		{"bottomk(2, rate(segsRetrans)) by localPort", []string{"2", "3"}, []float64{0.2, 0.5}},
+		{"topk(3, rate(segsRetrans)) by localPort limit 1", []string{"1"}, []float64{0.9}},
+		{"avg(rtt) by localPort limit 2", []string{"3", "2"}, []float64{30, 20}},
+		{"avg(rtt) by localPort where state = 'ESTABLISHED' and localPort != 2", []string{"3", "1"}, []float64{30, 10}},
+		// A counter's value is its last sample
+		{"segsRetrans by localPort where localPort = 1", []string{"1"}, []float64{25}},
+	}
+	for _, tt := range tests {
+		result, err := store.Query(tt.query + session)
+		if err != nil {
+			t.Errorf("%q: %v", tt.query, err)
+			continue
+		}
+		if result.Kind != "table" || len(result.Rows) != len(tt.want) {
+			t.Errorf("%q: %s with %d rows, want a table of %d", tt.query, result.Kind, len(result.Rows), len(tt.want))
+			continue
+		}
+		for i, row := range result.Rows {
+			if row.Labels["localPort"] != tt.ports[i] || math.Abs(row.Value-tt.want[i]) > 1e-9 {
+				t.Errorf("%q row %d = port %s %v, want port %s %v", tt.query, i, row.Labels["localPort"], row.Value, tt.ports[i], tt.want[i])
+			}
+		}
+	}
+
+	// The latest session is queried by default; port 3 ends in TIME_WAIT
+	result, err := store.Query("count(*) by state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SessionID != id || len(result.Rows) != 2 || result.Rows[0].Labels["state"] != "ESTABLISHED" || result.Rows[0].Value != 2 {
+		t.Errorf("count(*) by state = %+v", result.Rows)
+	}
+
+	if _, err := store.Query("avg(rtt) over session 999"); err == nil {
+		t.Errorf("query of a missing session succeeded")
+	}
+}
+
+func TestQuerySeries(t *testing.T) {
+	store, id, start := queryTestStore(t)
+
+	// Samples at 0-10s, 20-30s and 40-50s fall in three 20s steps; the
+	// first sample of a connection only sets the baseline of its rate
+	result, err := store.Query("sum(rate(segsRetrans)) by localPort where localPort = 1 over session " + strconv.FormatInt(id, 10) + " step 20s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Kind != "series" || result.Step != 20 || len(result.Series) != 1 {
+		t.Fatalf("result = %+v, want one series with 20s steps", result)
+	}
+	want := []HistoryQueryPoint{
+		{start, 1},
+		{start.Add(20 * time.Second), 0.75}, // 10, then 5 after the reset
+		{start.Add(40 * time.Second), 1},
+	}
+	points := result.Series[0].Points
+	if len(points) != len(want) {
+		t.Fatalf("points = %+v, want %+v", points, want)
+	}
+	for i := range want {
+		if !points[i].Timestamp.Equal(want[i].Timestamp) || math.Abs(points[i].Value-want[i].Value) > 1e-9 {
+			t.Errorf("point %d = %+v, want %+v", i, points[i], want[i])
+		}
+	}
+
+	// Series are ranked by their mean and cut by topk
+	result, err = store.Query("topk(2, avg(rtt)) by localPort over session " + strconv.FormatInt(id, 10) + " step 20s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Series) != 2 || result.Series[0].Labels["localPort"] != "3" || result.Series[1].Labels["localPort"] != "2" {
+		t.Errorf("topk series = %+v, want ports 3 and 2", result.Series)
+	}
+}
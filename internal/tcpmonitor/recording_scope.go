@@ -0,0 +1,341 @@
+package tcpmonitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metric groups a recording scope can keep. Addresses, ports, state and PID
+// are always recorded.
+const (
+	FieldsTraffic    = "traffic"    // Bytes and segments in and out
+	FieldsRTT        = "rtt"        // RTT samples, variance and RTO
+	FieldsLoss       = "loss"       // Retransmissions, timeouts, duplicate ACKs and SACKs
+	FieldsCongestion = "congestion" // Congestion window, algorithm state and pacing
+	FieldsWindow     = "window"     // Receive windows, window scaling, MSS and sender limits
+	FieldsBandwidth  = "bandwidth"  // Bandwidth estimates and bytes acknowledged
+	FieldsBuffers    = "buffers"    // Retransmit and application queues, socket memory
+	FieldsTimers     = "timers"     // Pending TCP timer
+	FieldsEnrichment = "enrichment" // Hostname, GeoIP, ASN, service and role
+)
+
+// recordingFieldGroups lists every metric group with the fields it clears
+// when a scope leaves it out
+var recordingFieldGroups = map[string]func(c *CompactConnection){
+	FieldsTraffic: func(c *CompactConnection) {
+		c.BytesIn, c.BytesOut, c.SegmentsIn, c.SegmentsOut = 0, 0, 0, 0
+		c.TotalSegsIn, c.TotalSegsOut = 0, 0
+	},
+	FieldsRTT: func(c *CompactConnection) {
+		c.SampleRTT, c.RTT, c.RTTVariance, c.MinRTT, c.MaxRTT, c.RTOMs = 0, 0, 0, 0, 0, 0
+	},
+	FieldsLoss: func(c *CompactConnection) {
+		c.FastRetrans, c.TimeoutEpisodes, c.Retrans, c.SegsRetrans = 0, 0, 0, 0
+		c.DupAcksIn, c.DupAcksOut, c.SacksRcvd, c.SackBlocksRcvd, c.DsackDups = 0, 0, 0, 0, 0
+	},
+	FieldsCongestion: func(c *CompactConnection) {
+		c.CongestionWin, c.CurrentSsthresh, c.SlowStartCount, c.CongAvoidCount = 0, 0, 0, 0
+		c.CongestionAlgorithm, c.CAState, c.PacingRate = "", "", 0
+		c.BBRBandwidth, c.BBRMinRTT, c.BBRPacingGain, c.BBRCwndGain = 0, 0, 0, 0
+		c.DCTCPAlpha, c.VegasRTT, c.VegasMinRTT = 0, 0, 0
+	},
+	FieldsWindow: func(c *CompactConnection) {
+		c.WinScaleRcvd, c.WinScaleSent = 0, 0
+		c.CurRwinRcvd, c.MaxRwinRcvd, c.CurRwinSent, c.MaxRwinSent = 0, 0, 0, 0
+		c.CurMss, c.MaxMss, c.MinMss = 0, 0, 0
+		c.SndLimTimeRwin, c.SndLimTimeCwnd, c.SndLimTimeSnd = 0, 0, 0
+	},
+	FieldsBandwidth: func(c *CompactConnection) {
+		c.InBandwidth, c.OutBandwidth, c.ThruBytesAcked, c.ThruBytesReceived = 0, 0, 0, 0
+	},
+	FieldsBuffers: func(c *CompactConnection) {
+		c.CurRetxQueue, c.MaxRetxQueue, c.CurAppWQueue, c.MaxAppWQueue = 0, 0, 0, 0
+		c.RmemAlloc, c.RcvBuf, c.WmemAlloc, c.WmemQueued, c.SndBuf, c.FwdAlloc, c.SockDrops = 0, 0, 0, 0, 0, 0, 0
+	},
+	FieldsTimers: func(c *CompactConnection) {
+		c.TimerKind, c.TimerExpiresMs, c.TimerRetransmits, c.TimerProbes, c.TimerBackoff = "", 0, 0, 0, 0
+	},
+	FieldsEnrichment: func(c *CompactConnection) {
+		c.RemoteHostname, c.RemoteCountry, c.RemoteCity, c.RemoteASOrg = "", "", "", ""
+		c.RemoteASN, c.ServiceName, c.Role = 0, "", ""
+	},
+}
+
+// RecordingFieldGroups returns the names of the metric groups a recording
+// scope can select
+func RecordingFieldGroups() []string {
+	groups := make([]string, 0, len(recordingFieldGroups))
+	for name := range recordingFieldGroups {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// RecordingScope narrows what a recording session captures. Each list that
+// is set must match; an empty scope records every connection with every
+// metric at the polling interval.
+type RecordingScope struct {
+	Processes  []string `json:"processes,omitempty"`  // Process names or PIDs
+	Ports      []int    `json:"ports,omitempty"`      // Local or remote ports
+	CIDRs      []string `json:"cidrs,omitempty"`      // Local or remote address ranges, or single addresses
+	States     []int    `json:"states,omitempty"`     // TCP states
+	Fields     []string `json:"fields,omitempty"`     // Metric groups kept; empty keeps them all
+	IntervalMs int64    `json:"intervalMs,omitempty"` // Time between samples; 0 samples every poll
+
+	// Retention of the stored session; 0 follows the storage settings
+	RetentionDays       int `json:"retentionDays,omitempty"`       // Days kept after the session ends
+	FullResolutionHours int `json:"fullResolutionHours,omitempty"` // Hours before snapshots are reduced to rollups
+}
+
+// IsEmpty reports whether the scope records everything with the default
+// interval and retention
+func (rs *RecordingScope) IsEmpty() bool {
+	return !rs.filters() && rs.IntervalMs == 0 && rs.RetentionDays == 0 && rs.FullResolutionHours == 0
+}
+
+// filters reports whether the scope leaves out connections or metrics
+func (rs *RecordingScope) filters() bool {
+	return len(rs.Processes) > 0 || len(rs.Ports) > 0 || len(rs.CIDRs) > 0 ||
+		len(rs.States) > 0 || len(rs.Fields) > 0
+}
+
+// sameScope reports whether two sessions were recorded with the same
+// scope. A nil scope records everything, like an empty one.
+func sameScope(a, b *RecordingScope) bool {
+	if a == nil {
+		a = &RecordingScope{}
+	}
+	if b == nil {
+		b = &RecordingScope{}
+	}
+	// Encoded, nil and empty lists compare equal
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// Interval returns the time between samples, 0 for every poll
+func (rs *RecordingScope) Interval() time.Duration {
+	return time.Duration(rs.IntervalMs) * time.Millisecond
+}
+
+// String describes the scope, e.g. "processes nginx; ports 443; every 500ms"
+func (rs *RecordingScope) String() string {
+	var parts []string
+	if len(rs.Processes) > 0 {
+		parts = append(parts, "processes "+strings.Join(rs.Processes, ", "))
+	}
+	if len(rs.Ports) > 0 {
+		ports := make([]string, len(rs.Ports))
+		for i, port := range rs.Ports {
+			ports[i] = strconv.Itoa(port)
+		}
+		parts = append(parts, "ports "+strings.Join(ports, ", "))
+	}
+	if len(rs.CIDRs) > 0 {
+		parts = append(parts, "addresses "+strings.Join(rs.CIDRs, ", "))
+	}
+	if len(rs.States) > 0 {
+		states := make([]string, len(rs.States))
+		for i, state := range rs.States {
+			states[i] = TCPState(state).String()
+		}
+		parts = append(parts, "states "+strings.Join(states, ", "))
+	}
+	if len(rs.Fields) > 0 {
+		parts = append(parts, "metrics "+strings.Join(rs.Fields, ", "))
+	}
+	if rs.IntervalMs > 0 {
+		parts = append(parts, "every "+rs.Interval().String())
+	}
+	if rs.RetentionDays > 0 {
+		parts = append(parts, "kept "+strconv.Itoa(rs.RetentionDays)+" days")
+	}
+	if len(parts) == 0 {
+		return "everything"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// recordingFilter is a RecordingScope compiled for matching
+type recordingFilter struct {
+	pids   map[uint32]bool
+	procs  map[string]bool // Lower case, without .exe
+	ports  map[uint16]bool
+	nets   []*net.IPNet
+	states map[TCPState]bool
+	strip  []func(c *CompactConnection) // Clear the groups left out
+}
+
+// compileRecordingScope validates a scope and prepares it for matching.
+// A scope that keeps every connection and metric compiles to nil.
+func compileRecordingScope(scope RecordingScope) (*recordingFilter, error) {
+	if scope.IntervalMs < 0 {
+		return nil, fmt.Errorf("sample interval must not be negative")
+	}
+	if scope.RetentionDays < 0 || scope.FullResolutionHours < 0 {
+		return nil, fmt.Errorf("retention limits must not be negative")
+	}
+	if !scope.filters() {
+		return nil, nil
+	}
+
+	f := &recordingFilter{}
+	if len(scope.Processes) > 0 {
+		f.pids = make(map[uint32]bool)
+		f.procs = make(map[string]bool)
+		for _, p := range scope.Processes {
+			p = strings.ToLower(strings.TrimSpace(p))
+			if p == "" {
+				continue
+			}
+			if pid, err := strconv.ParseUint(p, 10, 32); err == nil {
+				f.pids[uint32(pid)] = true
+			} else {
+				f.procs[strings.TrimSuffix(p, ".exe")] = true
+			}
+		}
+	}
+	if len(scope.Ports) > 0 {
+		f.ports = make(map[uint16]bool)
+		for _, port := range scope.Ports {
+			if port < 0 || port > 65535 {
+				return nil, fmt.Errorf("invalid port %d", port)
+			}
+			f.ports[uint16(port)] = true
+		}
+	}
+	for _, cidr := range scope.CIDRs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", cidr)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			cidr = cidr + "/" + strconv.Itoa(bits)
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", cidr)
+		}
+		f.nets = append(f.nets, ipNet)
+	}
+	if len(scope.States) > 0 {
+		f.states = make(map[TCPState]bool)
+		for _, state := range scope.States {
+			if state < int(StateClosed) || state > int(StateDeleteTCB) {
+				return nil, fmt.Errorf("invalid TCP state %d", state)
+			}
+			f.states[TCPState(state)] = true
+		}
+	}
+	if len(scope.Fields) > 0 {
+		keep := make(map[string]bool)
+		for _, name := range scope.Fields {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if _, ok := recordingFieldGroups[name]; !ok {
+				return nil, fmt.Errorf("unknown field group %q", name)
+			}
+			keep[name] = true
+		}
+		for _, name := range RecordingFieldGroups() {
+			if !keep[name] {
+				f.strip = append(f.strip, recordingFieldGroups[name])
+			}
+		}
+	}
+	return f, nil
+}
+
+// matches reports whether a connection is in scope. names caches process
+// names by PID for the duration of one sample.
+func (f *recordingFilter) matches(conn *ConnectionInfo, names map[uint32]string) bool {
+	if f.pids != nil && !f.pids[conn.PID] {
+		if len(f.procs) == 0 || conn.PID == 0 {
+			return false
+		}
+		name, ok := names[conn.PID]
+		if !ok {
+			name = strings.TrimSuffix(strings.ToLower(processName(conn.PID)), ".exe")
+			names[conn.PID] = name
+		}
+		if !f.procs[name] {
+			return false
+		}
+	}
+	if f.ports != nil && !f.ports[conn.LocalPort] && !f.ports[conn.RemotePort] {
+		return false
+	}
+	if f.nets != nil && !f.containsAddr(conn.LocalAddr) && !f.containsAddr(conn.RemoteAddr) {
+		return false
+	}
+	if f.states != nil && !f.states[conn.State] {
+		return false
+	}
+	return true
+}
+
+// containsAddr reports whether an address falls in one of the scope's ranges
+func (f *recordingFilter) containsAddr(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range f.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the recorded form of the connections in scope
+func (f *recordingFilter) apply(connections []ConnectionInfo) []CompactConnection {
+	if f == nil {
+		return compactConnections(connections)
+	}
+
+	names := make(map[uint32]string)
+	matched := make([]ConnectionInfo, 0, len(connections))
+	for i := range connections {
+		if f.matches(&connections[i], names) {
+			matched = append(matched, connections[i])
+		}
+	}
+	compact := compactConnections(matched)
+	for i := range compact {
+		for _, strip := range f.strip {
+			strip(&compact[i])
+		}
+	}
+	return compact
+}
+
+// retentionDays returns the days a session is kept after it ends: its
+// scope's limit, or else the storage-wide one
+func (session *RecordingSession) retentionDays(storage int) int {
+	if session.Scope != nil && session.Scope.RetentionDays > 0 {
+		return session.Scope.RetentionDays
+	}
+	return storage
+}
+
+// fullResolutionHours returns the hours a session's snapshots are kept
+// before only rollups remain: its scope's limit, or else the storage-wide one
+func (session *RecordingSession) fullResolutionHours(storage int) int {
+	if session.Scope != nil && session.Scope.FullResolutionHours > 0 {
+		return session.Scope.FullResolutionHours
+	}
+	return storage
+}
@@ -0,0 +1,330 @@
+package tcpmonitor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResolverConfig controls reverse-DNS enrichment of remote addresses
+type ResolverConfig struct {
+	Enabled      bool     // Perform reverse-DNS lookups (hosts files are always honored)
+	TTLSeconds   int      // How long a resolved name is cached
+	NegativeTTL  int      // How long a failed lookup is cached, in seconds
+	LookupBudget int      // Maximum number of new lookups started per update cycle
+	HostsFiles   []string // Optional /etc/hosts-style mapping files
+}
+
+// DefaultResolverConfig returns the default resolver configuration
+func DefaultResolverConfig() ResolverConfig {
+	return ResolverConfig{
+		Enabled:      true,
+		TTLSeconds:   600,
+		NegativeTTL:  120,
+		LookupBudget: 32,
+	}
+}
+
+// hostEntry is a cached reverse-DNS result
+type hostEntry struct {
+	name    string
+	expires time.Time
+}
+
+// HostResolver resolves remote addresses to hostnames asynchronously.
+// Lookups never block the caller: Enrich only reads the cache and queues
+// misses for background workers, so names appear on a later update cycle.
+type HostResolver struct {
+	config  ResolverConfig
+	hosts   map[string]string    // Static mappings from hosts files
+	cache   map[string]hostEntry // Reverse-DNS results (empty name = negative entry)
+	pending map[string]bool      // Addresses queued or being looked up
+	queue   chan string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	logger *Logger
+}
+
+const (
+	resolverWorkers       = 4
+	resolverQueueSize     = 256
+	resolverLookupTimeout = 2 * time.Second
+	resolverEvictInterval = time.Minute
+)
+
+// NewHostResolver creates a new resolver with the given configuration
+func NewHostResolver(config ResolverConfig) *HostResolver {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &HostResolver{
+		config:  config,
+		hosts:   make(map[string]string),
+		cache:   make(map[string]hostEntry),
+		pending: make(map[string]bool),
+		queue:   make(chan string, resolverQueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		logger:  GetLogger(),
+	}
+	if err := r.loadHostsFiles(config.HostsFiles); err != nil {
+		r.logger.Warn("Failed to load hosts files: %v", err)
+	}
+	return r
+}
+
+// Start launches the background lookup workers and the cache janitor
+func (r *HostResolver) Start() {
+	for i := 0; i < resolverWorkers; i++ {
+		r.wg.Add(1)
+		go r.worker()
+	}
+	r.wg.Add(1)
+	go r.janitor()
+}
+
+// Stop cancels outstanding lookups and waits for the workers to exit
+func (r *HostResolver) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// worker performs queued reverse-DNS lookups
+func (r *HostResolver) worker() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case addr := <-r.queue:
+			r.resolve(addr)
+		}
+	}
+}
+
+// janitor periodically drops long-expired cache entries so the cache
+// doesn't grow without bound, keeping the scan off the Enrich path
+func (r *HostResolver) janitor() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(resolverEvictInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.mu.Lock()
+			r.evictExpired(now)
+			r.mu.Unlock()
+		}
+	}
+}
+
+// resolve looks up a single address and stores the result in the cache
+func (r *HostResolver) resolve(addr string) {
+	ctx, cancel := context.WithTimeout(r.ctx, resolverLookupTimeout)
+	defer cancel()
+
+	name := ""
+	names, err := net.DefaultResolver.LookupAddr(ctx, addr)
+	if err == nil && len(names) > 0 {
+		name = strings.TrimSuffix(names[0], ".")
+	} else if err != nil {
+		r.logger.Debug("Reverse lookup failed for %s: %v", addr, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ttl := time.Duration(r.config.TTLSeconds) * time.Second
+	if name == "" {
+		ttl = time.Duration(r.config.NegativeTTL) * time.Second
+	}
+	r.cache[addr] = hostEntry{name: name, expires: time.Now().Add(ttl)}
+	delete(r.pending, addr)
+}
+
+// Lookup returns the known hostname for an address without blocking.
+// Returns an empty string if the name is not (yet) known.
+func (r *HostResolver) Lookup(addr string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name, ok := r.hosts[addr]; ok {
+		return name
+	}
+	if entry, ok := r.cache[addr]; ok {
+		return entry.name
+	}
+	return ""
+}
+
+// Enrich sets RemoteHostname on each connection from the cache and queues
+// lookups for unknown or expired addresses, up to the per-cycle budget
+func (r *HostResolver) Enrich(connections []ConnectionInfo) {
+	misses := r.enrichFromCache(connections)
+	if len(misses) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, addr := range misses {
+		if r.pending[addr] {
+			continue
+		}
+		select {
+		case r.queue <- addr:
+			r.pending[addr] = true
+		default:
+			// Queue full, retry on a later cycle
+			return
+		}
+	}
+}
+
+// enrichFromCache fills in known hostnames under the read lock and returns
+// the addresses that need a lookup, at most LookupBudget of them
+func (r *HostResolver) enrichFromCache(connections []ConnectionInfo) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	budget := r.config.LookupBudget
+	var misses []string
+	seen := make(map[string]bool)
+
+	for i := range connections {
+		conn := &connections[i]
+		addr := conn.RemoteAddr
+
+		if name, ok := r.hosts[addr]; ok {
+			conn.RemoteHostname = name
+			continue
+		}
+
+		entry, cached := r.cache[addr]
+		if cached {
+			// Keep showing a stale name until the refresh completes
+			conn.RemoteHostname = entry.name
+			if now.Before(entry.expires) {
+				continue
+			}
+		}
+
+		if !r.config.Enabled || len(misses) >= budget || seen[addr] || r.pending[addr] || !isResolvableIP(addr) {
+			continue
+		}
+		seen[addr] = true
+		misses = append(misses, addr)
+	}
+	return misses
+}
+
+// evictExpired drops cache entries that expired long ago so the cache
+// doesn't grow without bound. Caller must hold the write lock.
+func (r *HostResolver) evictExpired(now time.Time) {
+	grace := time.Duration(r.config.TTLSeconds) * time.Second
+	for addr, entry := range r.cache {
+		if now.Sub(entry.expires) > grace {
+			delete(r.cache, addr)
+		}
+	}
+}
+
+// SetConfig replaces the resolver configuration and reloads hosts files
+func (r *HostResolver) SetConfig(config ResolverConfig) error {
+	if err := r.loadHostsFiles(config.HostsFiles); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = config
+	return nil
+}
+
+// GetConfig returns the current resolver configuration
+func (r *HostResolver) GetConfig() ResolverConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
+// ClearCache removes all cached reverse-DNS results
+func (r *HostResolver) ClearCache() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = make(map[string]hostEntry)
+}
+
+// loadHostsFiles parses the given hosts files and replaces the static mappings.
+// When an address appears in several files, the first mapping wins.
+func (r *HostResolver) loadHostsFiles(paths []string) error {
+	hosts := make(map[string]string)
+	for _, path := range paths {
+		if err := parseHostsFile(path, hosts); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hosts = hosts
+	return nil
+}
+
+// parseHostsFile reads an /etc/hosts-style file into the mapping
+func parseHostsFile(path string, hosts map[string]string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open hosts file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		if ip == nil {
+			continue
+		}
+
+		// Normalize so "::ffff:10.0.0.1"-style and canonical forms match
+		addr := ip.String()
+		if _, exists := hosts[addr]; !exists {
+			hosts[addr] = fields[1]
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read hosts file %s: %w", path, err)
+	}
+	return nil
+}
+
+// isResolvableIP reports whether an address is worth a reverse lookup.
+// Wildcard addresses (LISTEN sockets) are skipped.
+func isResolvableIP(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && !ip.IsUnspecified()
+}
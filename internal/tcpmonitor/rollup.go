@@ -0,0 +1,349 @@
+package tcpmonitor
+
+import (
+	"time"
+)
+
+// Rollups summarize each connection over fixed time buckets so that long
+// recordings stay browsable after their full-resolution snapshots have been
+// dropped. Every snapshot feeds all tiers; a bucket is closed by the first
+// snapshot that falls past its end and is then stored with the session.
+
+// rollupTier is one rollup resolution and how long it is kept
+type rollupTier struct {
+	resolution time.Duration
+	keep       time.Duration // Age after the session ended (0 = as long as the session)
+}
+
+// rollupTiers lists the rollup resolutions, finest first
+var rollupTiers = []rollupTier{
+	{resolution: 10 * time.Second, keep: 7 * 24 * time.Hour},
+	{resolution: time.Minute, keep: 30 * 24 * time.Hour},
+	{resolution: 10 * time.Minute},
+}
+
+// maxHistoryPoints is the most points per connection a history or timeline
+// query aims to return. Raw snapshots are assumed to be a second apart, so
+// ranges up to an hour are served at full resolution.
+const maxHistoryPoints = 3600
+
+// GaugeRollup summarizes a gauge over a bucket
+type GaugeRollup struct {
+	Min  int64   `json:"min"`
+	Max  int64   `json:"max"`
+	Avg  float64 `json:"avg"`
+	Last int64   `json:"last"`
+}
+
+// CounterRollup summarizes a cumulative counter over a bucket
+type CounterRollup struct {
+	Delta int64 `json:"delta"` // Increase within the bucket
+	Last  int64 `json:"last"`
+}
+
+// ConnectionRollup summarizes one connection over one bucket
+type ConnectionRollup struct {
+	SessionID  int64     `json:"sessionId"`
+	Start      time.Time `json:"start"`
+	Resolution int64     `json:"resolution"` // Bucket width in seconds
+	Samples    int       `json:"samples"`
+
+	LocalAddr  string `json:"localAddr"`
+	LocalPort  int    `json:"localPort"`
+	RemoteAddr string `json:"remoteAddr"`
+	RemotePort int    `json:"remotePort"`
+
+	// As of the last sample in the bucket
+	State          int    `json:"state"`
+	PID            int    `json:"pid"`
+	RemoteHostname string `json:"remoteHostname,omitempty"`
+	ServiceName    string `json:"serviceName,omitempty"`
+
+	BytesIn     CounterRollup `json:"bytesIn"`
+	BytesOut    CounterRollup `json:"bytesOut"`
+	SegmentsIn  CounterRollup `json:"segmentsIn"`
+	SegmentsOut CounterRollup `json:"segmentsOut"`
+	Retrans     CounterRollup `json:"retrans"`
+	SegsRetrans CounterRollup `json:"segsRetrans"`
+
+	RTT           GaugeRollup `json:"rtt"`
+	RTTVariance   GaugeRollup `json:"rttVariance"`
+	CongestionWin GaugeRollup `json:"congestionWin"`
+	InBandwidth   GaugeRollup `json:"inBandwidth"`
+	OutBandwidth  GaugeRollup `json:"outBandwidth"`
+}
+
+// add folds the n-th sample into the gauge
+func (g *GaugeRollup) add(v int64, n int) {
+	if n == 1 || v < g.Min {
+		g.Min = v
+	}
+	if n == 1 || v > g.Max {
+		g.Max = v
+	}
+	g.Avg += (float64(v) - g.Avg) / float64(n)
+	g.Last = v
+}
+
+// add folds a sample and its increase since the previous sample into the counter
+func (c *CounterRollup) add(v, delta int64) {
+	c.Delta += delta
+	c.Last = v
+}
+
+// historyPoint returns the rollup as a chart point: counters as of the end
+// of the bucket, gauges averaged and RTT extremes over the bucket
+func (r *ConnectionRollup) historyPoint() ConnectionHistoryPoint {
+	return ConnectionHistoryPoint{
+		Timestamp:     r.Start,
+		Resolution:    r.Resolution,
+		State:         r.State,
+		BytesIn:       r.BytesIn.Last,
+		BytesOut:      r.BytesOut.Last,
+		SegmentsIn:    r.SegmentsIn.Last,
+		SegmentsOut:   r.SegmentsOut.Last,
+		RTT:           int64(r.RTT.Avg),
+		RTTVariance:   int64(r.RTTVariance.Avg),
+		MinRTT:        r.RTT.Min,
+		MaxRTT:        r.RTT.Max,
+		Retrans:       r.Retrans.Last,
+		SegsRetrans:   r.SegsRetrans.Last,
+		CongestionWin: int64(r.CongestionWin.Avg),
+		InBandwidth:   int64(r.InBandwidth.Avg),
+		OutBandwidth:  int64(r.OutBandwidth.Avg),
+	}
+}
+
+// connection returns the rollup as a timeline row with the same values as
+// historyPoint; fields that are not rolled up are left empty
+func (r *ConnectionRollup) connection() CompactConnection {
+	p := r.historyPoint()
+	return CompactConnection{
+		LocalAddr:      r.LocalAddr,
+		LocalPort:      r.LocalPort,
+		RemoteAddr:     r.RemoteAddr,
+		RemotePort:     r.RemotePort,
+		State:          r.State,
+		PID:            r.PID,
+		RemoteHostname: r.RemoteHostname,
+		ServiceName:    r.ServiceName,
+		BytesIn:        p.BytesIn,
+		BytesOut:       p.BytesOut,
+		SegmentsIn:     p.SegmentsIn,
+		SegmentsOut:    p.SegmentsOut,
+		RTT:            p.RTT,
+		RTTVariance:    p.RTTVariance,
+		MinRTT:         p.MinRTT,
+		MaxRTT:         p.MaxRTT,
+		Retrans:        p.Retrans,
+		SegsRetrans:    p.SegsRetrans,
+		CongestionWin:  p.CongestionWin,
+		InBandwidth:    p.InBandwidth,
+		OutBandwidth:   p.OutBandwidth,
+	}
+}
+
+// matches reports whether the rollup belongs to a connection
+func (r *ConnectionRollup) matches(localAddr string, localPort int, remoteAddr string, remotePort int) bool {
+	return r.LocalAddr == localAddr && r.LocalPort == localPort &&
+		r.RemoteAddr == remoteAddr && r.RemotePort == remotePort
+}
+
+// rollupKey identifies a connection while building rollups
+type rollupKey struct {
+	localAddr  string
+	localPort  int
+	remoteAddr string
+	remotePort int
+}
+
+// rollupCounters holds the counters of one sample, in ConnectionRollup order
+type rollupCounters [6]int64
+
+// countersOf extracts the rolled-up counters of a connection
+func countersOf(c *CompactConnection) rollupCounters {
+	return rollupCounters{c.BytesIn, c.BytesOut, c.SegmentsIn, c.SegmentsOut, c.Retrans, c.SegsRetrans}
+}
+
+// rollupBucket accumulates the open bucket of one tier
+type rollupBucket struct {
+	resolution time.Duration
+	start      time.Time
+	rollups    map[rollupKey]*ConnectionRollup
+	order      []*ConnectionRollup // In order of first appearance
+}
+
+// rollupBuilder turns a stream of snapshots into closed rollup buckets
+type rollupBuilder struct {
+	buckets []rollupBucket
+	prev    map[rollupKey]rollupCounters // Counters of the previous sample
+}
+
+// newRollupBuilder creates a builder for the given resolutions
+func newRollupBuilder(resolutions ...time.Duration) *rollupBuilder {
+	b := &rollupBuilder{prev: make(map[rollupKey]rollupCounters)}
+	for _, resolution := range resolutions {
+		b.buckets = append(b.buckets, rollupBucket{
+			resolution: resolution,
+			rollups:    make(map[rollupKey]*ConnectionRollup),
+		})
+	}
+	return b
+}
+
+// newTieredRollupBuilder creates a builder for every rollup tier
+func newTieredRollupBuilder() *rollupBuilder {
+	resolutions := make([]time.Duration, len(rollupTiers))
+	for i, tier := range rollupTiers {
+		resolutions[i] = tier.resolution
+	}
+	return newRollupBuilder(resolutions...)
+}
+
+// add folds a snapshot into the open buckets and returns the buckets it closed
+func (b *rollupBuilder) add(snap *Snapshot) []ConnectionRollup {
+	var closed []ConnectionRollup
+	for i := range b.buckets {
+		bucket := &b.buckets[i]
+		start := snap.Timestamp.Truncate(bucket.resolution)
+		if !bucket.start.Equal(start) {
+			closed = bucket.close(closed)
+			bucket.start = start
+		}
+	}
+
+	// Counter deltas are taken between consecutive samples, so a connection
+	// seen for the first time starts at zero and a counter that went
+	// backwards (a reused 4-tuple) restarts from its new value
+	prev := make(map[rollupKey]rollupCounters, len(snap.Connections))
+	for i := range snap.Connections {
+		c := &snap.Connections[i]
+		key := rollupKey{c.LocalAddr, c.LocalPort, c.RemoteAddr, c.RemotePort}
+		if _, dup := prev[key]; dup {
+			continue
+		}
+		cur := countersOf(c)
+		prev[key] = cur
+
+		var delta rollupCounters
+		if last, ok := b.prev[key]; ok {
+			for j := range cur {
+				if delta[j] = cur[j] - last[j]; delta[j] < 0 {
+					delta[j] = cur[j]
+				}
+			}
+		}
+		for j := range b.buckets {
+			b.buckets[j].add(key, c, &cur, &delta)
+		}
+	}
+	b.prev = prev
+	return closed
+}
+
+// flush closes every open bucket
+func (b *rollupBuilder) flush() []ConnectionRollup {
+	var closed []ConnectionRollup
+	for i := range b.buckets {
+		closed = b.buckets[i].close(closed)
+	}
+	return closed
+}
+
+// open returns copies of the open bucket of one resolution
+func (b *rollupBuilder) open(resolution time.Duration) []ConnectionRollup {
+	for i := range b.buckets {
+		if b.buckets[i].resolution == resolution {
+			var result []ConnectionRollup
+			for _, r := range b.buckets[i].order {
+				result = append(result, *r)
+			}
+			return result
+		}
+	}
+	return nil
+}
+
+// add folds one connection sample into the bucket
+func (b *rollupBucket) add(key rollupKey, c *CompactConnection, cur, delta *rollupCounters) {
+	r := b.rollups[key]
+	if r == nil {
+		r = &ConnectionRollup{
+			Start:      b.start,
+			Resolution: int64(b.resolution / time.Second),
+			LocalAddr:  c.LocalAddr,
+			LocalPort:  c.LocalPort,
+			RemoteAddr: c.RemoteAddr,
+			RemotePort: c.RemotePort,
+		}
+		b.rollups[key] = r
+		b.order = append(b.order, r)
+	}
+
+	r.Samples++
+	r.State = c.State
+	r.PID = c.PID
+	r.RemoteHostname = c.RemoteHostname
+	r.ServiceName = c.ServiceName
+
+	r.BytesIn.add(cur[0], delta[0])
+	r.BytesOut.add(cur[1], delta[1])
+	r.SegmentsIn.add(cur[2], delta[2])
+	r.SegmentsOut.add(cur[3], delta[3])
+	r.Retrans.add(cur[4], delta[4])
+	r.SegsRetrans.add(cur[5], delta[5])
+
+	r.RTT.add(c.RTT, r.Samples)
+	r.RTTVariance.add(c.RTTVariance, r.Samples)
+	r.CongestionWin.add(c.CongestionWin, r.Samples)
+	r.InBandwidth.add(c.InBandwidth, r.Samples)
+	r.OutBandwidth.add(c.OutBandwidth, r.Samples)
+}
+
+// close appends the bucket's rollups to dst and empties it
+func (b *rollupBucket) close(dst []ConnectionRollup) []ConnectionRollup {
+	for _, r := range b.order {
+		dst = append(dst, *r)
+	}
+	clear(b.rollups)
+	b.order = b.order[:0]
+	return dst
+}
+
+// rollupView computes the rollups of one resolution from full-resolution
+// snapshots, for sessions recorded without rollups
+func rollupView(view *logView, resolution time.Duration) []ConnectionRollup {
+	b := newRollupBuilder(resolution)
+	var result []ConnectionRollup
+	view.Each(func(snap *Snapshot) bool {
+		result = append(result, b.add(snap)...)
+		return true
+	})
+	return append(result, b.flush()...)
+}
+
+// historyResolution picks the resolution of a query over [start, end]: raw
+// snapshots (0) when the range is short enough and starts after any dropped
+// snapshots, otherwise the finest rollup tier that keeps within maxHistoryPoints
+func historyResolution(start, end, droppedUntil time.Time) time.Duration {
+	span := end.Sub(start)
+	if span <= maxHistoryPoints*time.Second && (droppedUntil.IsZero() || start.After(droppedUntil)) {
+		return 0
+	}
+	for _, tier := range rollupTiers {
+		if span <= maxHistoryPoints*tier.resolution {
+			return tier.resolution
+		}
+	}
+	return rollupTiers[len(rollupTiers)-1].resolution
+}
+
+// rollupTierIndex returns the tier of a resolution, or -1
+func rollupTierIndex(resolution time.Duration) int {
+	for i, tier := range rollupTiers {
+		if tier.resolution == resolution {
+			return i
+		}
+	}
+	return -1
+}
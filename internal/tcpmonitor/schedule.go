@@ -0,0 +1,394 @@
+package tcpmonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Session name used when a schedule has no template of its own
+	defaultScheduleNameTemplate = "{schedule} {date} {time}"
+
+	// Longest window a recurring schedule may record
+	maxScheduleWindow = 24 * time.Hour
+)
+
+// RecordingSchedule records a session during set time windows: each time
+// Cron matches, for DurationMinutes, or once from Start to End
+type RecordingSchedule struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+
+	// Recurring windows open when this five-field cron expression (minute
+	// hour day month weekday, local time) matches
+	Cron            string `json:"cron,omitempty"`
+	DurationMinutes int    `json:"durationMinutes,omitempty"`
+
+	// One-off window, used when Cron is empty
+	Start time.Time `json:"start,omitempty"`
+	End   time.Time `json:"end,omitempty"`
+
+	Scope RecordingScope `json:"scope"`
+
+	// Name of the sessions created; {schedule}, {date}, {time} and
+	// {weekday} are replaced
+	NameTemplate string `json:"nameTemplate,omitempty"`
+
+	LastRun time.Time `json:"lastRun,omitempty"` // When the last window opened
+
+	// Filled in when schedules are listed
+	NextRun   time.Time `json:"nextRun,omitempty"`
+	SessionID int64     `json:"sessionId,omitempty"` // Session being recorded now
+}
+
+// defaultScheduleFile returns the per-user file holding recording schedules
+func defaultScheduleFile() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "tcpdoctor", "schedules.json"), nil
+}
+
+// scheduledRun is a window a schedule is recording
+type scheduledRun struct {
+	sessionID int64
+	opened    time.Time
+	closes    time.Time
+}
+
+// scheduler starts and stops recording sessions on schedule
+type scheduler struct {
+	mu        sync.Mutex
+	store     *SnapshotStore
+	path      string // Where schedules are saved, empty to keep them in memory
+	schedules []RecordingSchedule
+	crons     map[int64]*cronSchedule
+	running   map[int64]*scheduledRun // By schedule ID, until the window closes
+	nextID    int64
+	logger    *Logger
+}
+
+// newScheduler loads the schedules saved at path. On error the scheduler
+// still works but keeps schedules in memory only.
+func newScheduler(store *SnapshotStore, path string) (*scheduler, error) {
+	sc := &scheduler{
+		store:   store,
+		crons:   make(map[int64]*cronSchedule),
+		running: make(map[int64]*scheduledRun),
+		nextID:  1,
+		logger:  GetLogger(),
+	}
+	if path == "" {
+		return sc, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		sc.path = path
+		return sc, nil
+	}
+	if err != nil {
+		return sc, fmt.Errorf("failed to read schedules: %w", err)
+	}
+	var schedules []RecordingSchedule
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return sc, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, sch := range schedules {
+		cron, err := validateSchedule(&sch)
+		if err != nil {
+			sc.logger.Warn("Skipping schedule %d (%s): %v", sch.ID, sch.Name, err)
+			continue
+		}
+		sc.schedules = append(sc.schedules, sch)
+		if cron != nil {
+			sc.crons[sch.ID] = cron
+		}
+		sc.nextID = max(sc.nextID, sch.ID+1)
+	}
+	sc.path = path
+	return sc, nil
+}
+
+// validateSchedule cleans up a schedule and parses its cron expression,
+// which is nil for a one-off window
+func validateSchedule(sch *RecordingSchedule) (*cronSchedule, error) {
+	sch.Name = strings.TrimSpace(sch.Name)
+	sch.Cron = strings.TrimSpace(sch.Cron)
+	sch.NameTemplate = strings.TrimSpace(sch.NameTemplate)
+	sch.NextRun = time.Time{}
+	sch.SessionID = 0
+
+	if sch.Name == "" {
+		return nil, fmt.Errorf("schedule needs a name")
+	}
+	if _, err := compileRecordingScope(sch.Scope); err != nil {
+		return nil, err
+	}
+	if sch.Scope.Interval() > 0 && sch.Scope.Interval() < minUpdateInterval {
+		return nil, fmt.Errorf("sample interval must be at least %v", minUpdateInterval)
+	}
+
+	if sch.Cron == "" {
+		if sch.Start.IsZero() || sch.End.IsZero() {
+			return nil, fmt.Errorf("schedule needs a cron expression or a start and end time")
+		}
+		if !sch.End.After(sch.Start) {
+			return nil, fmt.Errorf("schedule ends before it starts")
+		}
+		return nil, nil
+	}
+
+	cron, err := parseCron(sch.Cron)
+	if err != nil {
+		return nil, err
+	}
+	window := time.Duration(sch.DurationMinutes) * time.Minute
+	if window <= 0 || window > maxScheduleWindow {
+		return nil, fmt.Errorf("recurring windows must last between 1 minute and %v", maxScheduleWindow)
+	}
+	sch.Start, sch.End = time.Time{}, time.Time{}
+	return cron, nil
+}
+
+// Schedules returns every schedule with its next window and the session
+// it is recording, if any
+func (sc *scheduler) Schedules() []RecordingSchedule {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	now := time.Now()
+	result := make([]RecordingSchedule, len(sc.schedules))
+	for i, sch := range sc.schedules {
+		if sch.Enabled {
+			if cron := sc.crons[sch.ID]; cron != nil {
+				sch.NextRun = cron.next(now)
+			} else if now.Before(sch.Start) {
+				sch.NextRun = sch.Start
+			}
+		}
+		if run := sc.running[sch.ID]; run != nil && sc.store.IsSessionRecording(run.sessionID) {
+			sch.SessionID = run.sessionID
+		}
+		result[i] = sch
+	}
+	return result
+}
+
+// Add saves a new schedule and returns it with its ID
+func (sc *scheduler) Add(sch RecordingSchedule) (*RecordingSchedule, error) {
+	cron, err := validateSchedule(&sch)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sch.ID = sc.nextID
+	sch.LastRun = time.Time{}
+	sc.nextID++
+	sc.schedules = append(sc.schedules, sch)
+	if cron != nil {
+		sc.crons[sch.ID] = cron
+	}
+	if err := sc.saveLocked(); err != nil {
+		return nil, err
+	}
+	sc.logger.Info("Added recording schedule %d (%s)", sch.ID, sch.Name)
+	return &sch, nil
+}
+
+// Update replaces a schedule. A window it is recording ends, and reopens
+// on the next poll if the new settings still cover it.
+func (sc *scheduler) Update(sch RecordingSchedule) (*RecordingSchedule, error) {
+	cron, err := validateSchedule(&sch)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	i := sc.indexLocked(sch.ID)
+	if i < 0 {
+		return nil, fmt.Errorf("schedule %d not found", sch.ID)
+	}
+	sch.LastRun = sc.schedules[i].LastRun
+	sc.schedules[i] = sch
+	delete(sc.crons, sch.ID)
+	if cron != nil {
+		sc.crons[sch.ID] = cron
+	}
+	sc.endRunLocked(sch.ID)
+	if err := sc.saveLocked(); err != nil {
+		return nil, err
+	}
+	return &sch, nil
+}
+
+// Delete removes a schedule, ending the window it is recording
+func (sc *scheduler) Delete(id int64) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	i := sc.indexLocked(id)
+	if i < 0 {
+		return fmt.Errorf("schedule %d not found", id)
+	}
+	sc.endRunLocked(id)
+	sc.schedules = append(sc.schedules[:i], sc.schedules[i+1:]...)
+	delete(sc.crons, id)
+	if err := sc.saveLocked(); err != nil {
+		return err
+	}
+	sc.logger.Info("Deleted recording schedule %d", id)
+	return nil
+}
+
+// Tick opens and closes recording windows. It is called on every poll,
+// before the poll is sampled.
+func (sc *scheduler) Tick(now time.Time) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	changed := false
+	for i := range sc.schedules {
+		sch := &sc.schedules[i]
+
+		// A window stopped by hand stays closed until it would have ended
+		if run := sc.running[sch.ID]; run != nil {
+			if now.Before(run.closes) {
+				continue
+			}
+			sc.endRunLocked(sch.ID)
+		}
+		if !sch.Enabled {
+			continue
+		}
+
+		opened, closes, ok := sc.windowLocked(sch, now)
+		if !ok {
+			continue
+		}
+		if sc.startLocked(sch, opened, closes) {
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := sc.saveLocked(); err != nil {
+			sc.logger.Warn("Failed to save recording schedules: %v", err)
+		}
+	}
+}
+
+// windowLocked returns the window of a schedule that holds now.
+// Must be called with sc.mu held.
+func (sc *scheduler) windowLocked(sch *RecordingSchedule, now time.Time) (time.Time, time.Time, bool) {
+	cron := sc.crons[sch.ID]
+	if cron == nil {
+		if now.Before(sch.Start) || !now.Before(sch.End) {
+			return time.Time{}, time.Time{}, false
+		}
+		return sch.Start, sch.End, true
+	}
+
+	window := time.Duration(sch.DurationMinutes) * time.Minute
+	opened, ok := cron.latest(now, window)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return opened, opened.Add(window), true
+}
+
+// startLocked starts the session recording a window, named from the
+// schedule's template and tagged with the schedule.
+// Must be called with sc.mu held.
+func (sc *scheduler) startLocked(sch *RecordingSchedule, opened, closes time.Time) bool {
+	sessionID, err := sc.store.StartScopedRecording(sch.Scope)
+	if err != nil {
+		sc.logger.Error("Schedule %d (%s) failed to start recording: %v", sch.ID, sch.Name, err)
+		return false
+	}
+	sc.running[sch.ID] = &scheduledRun{sessionID: sessionID, opened: opened, closes: closes}
+	sch.LastRun = opened
+
+	template := sch.NameTemplate
+	if template == "" {
+		template = defaultScheduleNameTemplate
+	}
+	name := strings.NewReplacer(
+		"{schedule}", sch.Name,
+		"{date}", opened.Format("2006-01-02"),
+		"{time}", opened.Format("15:04"),
+		"{weekday}", opened.Weekday().String(),
+	).Replace(template)
+	description := fmt.Sprintf("Recorded by schedule %q until %s", sch.Name, closes.Format("2006-01-02 15:04"))
+	if _, err := sc.store.SetSessionInfo(sessionID, name, description, []string{"scheduled", sch.Name}); err != nil {
+		sc.logger.Warn("Failed to name scheduled session %d: %v", sessionID, err)
+	}
+
+	sc.logger.Info("Schedule %d (%s) recording session %d until %s",
+		sch.ID, sch.Name, sessionID, closes.Format("15:04"))
+	return true
+}
+
+// endRunLocked stops the session a schedule is recording, if still running.
+// Must be called with sc.mu held.
+func (sc *scheduler) endRunLocked(id int64) {
+	run := sc.running[id]
+	if run == nil {
+		return
+	}
+	delete(sc.running, id)
+	if sc.store.IsSessionRecording(run.sessionID) {
+		if err := sc.store.StopSession(run.sessionID); err != nil {
+			sc.logger.Warn("Failed to stop scheduled session %d: %v", run.sessionID, err)
+			return
+		}
+		sc.logger.Info("Schedule %d finished session %d", id, run.sessionID)
+	}
+}
+
+// indexLocked returns the position of a schedule, or -1.
+// Must be called with sc.mu held.
+func (sc *scheduler) indexLocked(id int64) int {
+	for i := range sc.schedules {
+		if sc.schedules[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// saveLocked writes the schedules to disk.
+// Must be called with sc.mu held.
+func (sc *scheduler) saveLocked() error {
+	if sc.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(sc.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create schedule directory: %w", err)
+	}
+	schedules := sc.schedules
+	if schedules == nil {
+		schedules = []RecordingSchedule{}
+	}
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := sc.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write schedules: %w", err)
+	}
+	return os.Rename(tmp, sc.path)
+}
@@ -0,0 +1,64 @@
+package tcpmonitor
+
+import "testing"
+
+func TestSchedulerKeepsStoppedWindowClosed(t *testing.T) {
+	store := NewSnapshotStore(100)
+	sc, err := newScheduler(store, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sch, err := sc.Add(RecordingSchedule{Name: "mornings", Enabled: true, Cron: "0 9 * * *", DurationMinutes: 60})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	recording := func() []int64 { return store.RecordingSessionIDs() }
+
+	sc.Tick(at(16, 8, 59))
+	if ids := recording(); len(ids) != 0 {
+		t.Fatalf("recording %v before the window opened", ids)
+	}
+
+	sc.Tick(at(16, 9, 0))
+	ids := recording()
+	if len(ids) != 1 {
+		t.Fatalf("recording %v when the window opened, want one session", ids)
+	}
+	first := ids[0]
+	if got := sc.Schedules()[0]; got.SessionID != first || !got.LastRun.Equal(at(16, 9, 0)) {
+		t.Errorf("schedule = %+v, want session %d opened at 9:00", got, first)
+	}
+
+	// Stopped by hand, the window stays closed for the rest of its hour
+	if err := store.StopSession(first); err != nil {
+		t.Fatal(err)
+	}
+	for _, now := range []int{1, 30, 59} {
+		sc.Tick(at(16, 9, now))
+		if ids := recording(); len(ids) != 0 {
+			t.Fatalf("window reopened at 9:%02d after a manual stop: recording %v", now, ids)
+		}
+	}
+
+	sc.Tick(at(16, 10, 0))
+	if ids := recording(); len(ids) != 0 {
+		t.Fatalf("recording %v after the window closed", ids)
+	}
+
+	// The next window opens as usual
+	sc.Tick(at(17, 9, 5))
+	ids = recording()
+	if len(ids) != 1 || ids[0] == first {
+		t.Fatalf("recording %v in the next window, want a new session", ids)
+	}
+	if got := sc.Schedules()[0]; got.ID != sch.ID || !got.LastRun.Equal(at(17, 9, 0)) {
+		t.Errorf("schedule = %+v, want the window opened at 9:00 on the 17th", got)
+	}
+
+	// Left running, the session ends with its window
+	sc.Tick(at(17, 10, 0))
+	if ids := recording(); len(ids) != 0 {
+		t.Errorf("recording %v after the window closed", ids)
+	}
+}
@@ -1,5 +1,5 @@
-//go:build windows
-// +build windows
+//go:build windows || linux
+// +build windows linux
 
 package tcpmonitor
 
@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"tcpdoctor/internal/llm"
-	"tcpdoctor/internal/tcpmonitor/winapi"
 )
 
 // NewService creates a new TCP monitoring service
@@ -26,22 +25,64 @@ func NewService(config ServiceConfig) (*Service, error) {
 		return nil, ErrInvalidInterval
 	}
 
-	// Create Windows API layer
-	apiLayer := winapi.NewWindowsAPILayer()
-
-	// Check administrator privileges
-	isAdmin := apiLayer.IsAdministrator()
-	if isAdmin {
-		logger.Info("Running with Administrator privileges - extended statistics available")
-	} else {
-		logger.Info("Running without Administrator privileges - extended statistics unavailable")
-	}
+	// Create the platform statistics source (Windows API or Linux inet_diag)
+	statsCollector, apiLayer, isAdmin := newPlatformStatsCollector()
 
 	// Create components
 	connectionManager := NewConnectionManager()
-	statsCollector := NewStatsCollector(apiLayer, isAdmin)
 	filterEngine := NewFilterEngine()
 
+	// Settings edited in the UI survive restarts unless their file is unusable
+	settingsFile := config.SettingsFile
+	if settingsFile == "" {
+		if path, err := defaultSettingsFile(); err != nil {
+			logger.Error("Settings will not survive restarts: %v", err)
+		} else {
+			settingsFile = path
+		}
+	}
+	settings, err := loadSettings(settingsFile)
+	if err != nil {
+		logger.Error("Settings will not survive restarts: %v", err)
+	}
+	saved := settings.Get()
+
+	// Retention edited in the UI overrides the configured limits; the storage
+	// directory and on/off switch stay as configured
+	storageConfig := config.Storage
+	if saved.Storage != nil {
+		storageConfig.RetentionDays = saved.Storage.RetentionDays
+		storageConfig.MaxSizeMB = saved.Storage.MaxSizeMB
+		storageConfig.FullResolutionHours = saved.Storage.FullResolutionHours
+	}
+
+	// Persist recordings when enabled, falling back to memory-only storage
+	snapshotStore := NewSnapshotStore(20000) // ~20k snapshots for high-freq recording
+	if storageConfig.Enabled {
+		if store, err := NewPersistentSnapshotStore(20000, storageConfig); err != nil {
+			logger.Error("Session storage unavailable, recordings will not survive restarts: %v", err)
+		} else {
+			snapshotStore = store
+		}
+	}
+
+	resolverConfig := DefaultResolverConfig()
+	if saved.Resolver != nil {
+		resolverConfig = *saved.Resolver
+	}
+
+	geoIP := NewGeoIPResolver()
+	if saved.GeoIP != nil {
+		if err := geoIP.SetConfig(*saved.GeoIP); err != nil {
+			logger.Error("Failed to load saved GeoIP databases: %v", err)
+		}
+	}
+
+	serviceCatalog := NewServiceCatalog()
+	if err := serviceCatalog.SetEntries(saved.ServiceCatalog); err != nil {
+		logger.Error("Failed to load saved service catalog: %v", err)
+	}
+
 	// Create context for polling control
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -51,7 +92,16 @@ func NewService(config ServiceConfig) (*Service, error) {
 		filterEngine:      filterEngine,
 		apiLayer:          apiLayer,
 		llmService:        llm.NewGeminiService(),
-		snapshotStore:     NewSnapshotStore(20000), // ~20k snapshots for high-freq recording
+		snapshotStore:     snapshotStore,
+		settings:          settings,
+		resolver:          NewHostResolver(resolverConfig),
+		geoIP:             geoIP,
+		serviceCatalog:    serviceCatalog,
+		pmtuDetector:      NewPMTUDetector(),
+		windowAnalyzer:    NewWindowAnalyzer(),
+		bufferAnalyzer:    NewBufferAnalyzer(),
+		stallDetector:     NewStallDetector(),
+		throughput:        NewThroughputTester(statsCollector),
 		updateInterval:    config.UpdateInterval,
 		isAdmin:           isAdmin,
 		healthThresholds:  DefaultHealthThresholds(),
@@ -60,8 +110,38 @@ func NewService(config ServiceConfig) (*Service, error) {
 		logger:            logger,
 	}
 
-	// Start recording by default
-	service.snapshotStore.StartRecording()
+	// Active prober feeds its results into the snapshot store
+	proberConfig := DefaultProberConfig()
+	if saved.Prober != nil {
+		proberConfig = *saved.Prober
+	}
+	service.prober = NewProber(proberConfig, service.observedProbeTargets, service.snapshotStore.RecordProbes)
+
+	// Polls are sampled at the polling interval unless a session asks for its own
+	service.snapshotStore.SetSampleInterval(config.UpdateInterval)
+
+	// Record continuously by default, or only around triggers in flight-recorder mode
+	flightConfig := config.FlightRecorder
+	if saved.FlightRecorder != nil {
+		flightConfig = *saved.FlightRecorder
+	}
+	service.flightRecorder = newFlightRecorder(service.snapshotStore, flightConfig)
+	service.flightRecorder.RecordContinuously()
+
+	// Recording schedules survive restarts unless their file is unusable
+	scheduleFile := config.ScheduleFile
+	if scheduleFile == "" {
+		if path, err := defaultScheduleFile(); err != nil {
+			logger.Error("Recording schedules will not survive restarts: %v", err)
+		} else {
+			scheduleFile = path
+		}
+	}
+	sched, err := newScheduler(service.snapshotStore, scheduleFile)
+	if err != nil {
+		logger.Error("Recording schedules will not survive restarts: %v", err)
+	}
+	service.scheduler = sched
 
 	// Register AI tool handlers
 	service.registerAIHandlers()
@@ -72,6 +152,10 @@ func NewService(config ServiceConfig) (*Service, error) {
 func (s *Service) registerAIHandlers() {
 	s.llmService.RegisterTool("get_snapshots_by_time_range", s.handleGetSnapshotsByTimeRange)
 	s.llmService.RegisterTool("get_metric_history", s.handleGetMetricHistory)
+	s.llmService.RegisterTool("group_connections", s.handleGroupConnections)
+	s.llmService.RegisterTool("compare_sessions", s.handleCompareSessions)
+	s.llmService.RegisterTool("get_state_at", s.handleGetStateAt)
+	s.llmService.RegisterTool("query_history", s.handleQueryHistory)
 	s.llmService.RegisterTool("plot_graph", s.handlePlotGraph)
 }
 
@@ -124,6 +208,86 @@ func (s *Service) handleGetSnapshotsByTimeRange(ctx context.Context, args map[st
 	return s.GetSnapshotsByTimeRange(int64(sessionID), startTime, endTime, f)
 }
 
+func (s *Service) handleGroupConnections(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	sessionID, ok := args["sessionID"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("sessionID must be a number")
+	}
+	groupBy, _ := args["groupBy"].(string)
+
+	return s.GetSessionGroups(int64(sessionID), groupBy)
+}
+
+// maxToolComparisonGroups limits the groups a compare_sessions result returns
+const maxToolComparisonGroups = 20
+
+func (s *Service) handleCompareSessions(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	sessionA, okA := args["sessionA"].(float64)
+	sessionB, okB := args["sessionB"].(float64)
+	if !okA || !okB {
+		return nil, fmt.Errorf("sessionA and sessionB must be numbers")
+	}
+	groupBy, _ := args["groupBy"].(string)
+
+	comparison, err := s.CompareSessions(int64(sessionA), int64(sessionB), groupBy)
+	if err != nil {
+		return nil, err
+	}
+	if len(comparison.Groups) > maxToolComparisonGroups {
+		comparison.Groups = comparison.Groups[:maxToolComparisonGroups]
+	}
+	return comparison, nil
+}
+
+func (s *Service) handleGetStateAt(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	sessionID, ok := args["sessionID"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("sessionID must be a number")
+	}
+	timestampStr, _ := args["timestamp"].(string)
+	timestamp, err := time.Parse(time.RFC3339, timestampStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %v", err)
+	}
+
+	var filter FilterOptions
+	if name, ok := args["state"].(string); ok && name != "" {
+		state, ok := parseTCPState(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown state %q", name)
+		}
+		filter.State = &state
+	}
+	if port, ok := args["port"].(float64); ok && port > 0 {
+		p := uint16(port)
+		filter.Port = &p
+	}
+	if pid, ok := args["pid"].(float64); ok && pid > 0 {
+		p := uint32(pid)
+		filter.PID = &p
+	}
+	filter.SearchText, _ = args["search"].(string)
+	limit, _ := args["limit"].(float64)
+
+	state, err := s.GetStateAt(int64(sessionID), timestamp, filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.stateAtSummary(state, int(limit)), nil
+}
+
+func (s *Service) handleQueryHistory(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	query, _ := args["query"].(string)
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	result, err := s.QueryHistory(query)
+	if err != nil {
+		return nil, err
+	}
+	return queryAnswer(result), nil
+}
+
 // handlePlotGraph is a local handler for the plot_graph tool
 // It doesn't actually do anything on the backend side as the graphing logic
 // is handled within the AI loop in query_connections.go, but it needs to be registered
@@ -170,6 +334,9 @@ func (s *Service) handleGetMetricHistory(ctx context.Context, args map[string]in
 func (s *Service) Start() {
 	s.logger.Info("Starting TCP monitoring service with %v update interval", s.updateInterval)
 
+	s.resolver.Start()
+	s.prober.Start()
+
 	s.wg.Add(1)
 	go s.pollingLoop()
 }
@@ -184,6 +351,17 @@ func (s *Service) Stop() {
 	// Wait for polling loop to finish
 	s.wg.Wait()
 
+	// Stop background hostname lookups
+	s.resolver.Stop()
+	s.prober.Stop()
+	s.throughput.StopServer()
+	s.geoIP.Close()
+
+	// Close the active recording session so it is not treated as a crash
+	if err := s.snapshotStore.Close(); err != nil {
+		s.logger.Error("Failed to close session storage: %v", err)
+	}
+
 	s.logger.Info("TCP monitoring service stopped")
 }
 
@@ -191,7 +369,8 @@ func (s *Service) Stop() {
 func (s *Service) pollingLoop() {
 	defer s.wg.Done()
 
-	ticker := time.NewTicker(s.updateInterval)
+	interval := s.pollInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	// Perform initial update immediately
@@ -204,10 +383,29 @@ func (s *Service) pollingLoop() {
 			return
 		case <-ticker.C:
 			s.performUpdate()
+
+			// Follow interval changes and sessions sampling faster than the UI polls
+			if next := s.pollInterval(); next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
 		}
 	}
 }
 
+// pollInterval returns how often to poll: the update interval, or the
+// sample interval of a recording session if that is shorter
+func (s *Service) pollInterval() time.Duration {
+	s.mu.RLock()
+	interval := s.updateInterval
+	s.mu.RUnlock()
+
+	if sample := s.snapshotStore.MinSampleInterval(); sample > 0 && sample < interval {
+		interval = max(sample, minUpdateInterval)
+	}
+	return interval
+}
+
 // performUpdate executes a single update cycle
 func (s *Service) performUpdate() {
 	startTime := time.Now()
@@ -247,6 +445,7 @@ func (s *Service) performUpdate() {
 			if conn.State == StateEstablished {
 				if stats, err := s.statsCollector.GetExtendedStats(conn); err == nil {
 					conn.ExtendedStats = stats
+					s.extendedStats.Store(true)
 					// Also populate BasicStats from the data stats
 					conn.BasicStats = &BasicStats{
 						DataBytesOut: stats.ThruBytesAcked,
@@ -259,6 +458,11 @@ func (s *Service) performUpdate() {
 		}
 	}
 
+	// Attach cached hostnames; misses are resolved in the background
+	s.resolver.Enrich(allConnections)
+	s.geoIP.Enrich(allConnections)
+	s.serviceCatalog.Classify(allConnections)
+
 	// Calculate health indicators for all connections
 	s.mu.RLock()
 	thresholds := s.healthThresholds
@@ -267,12 +471,19 @@ func (s *Service) performUpdate() {
 	for i := range allConnections {
 		CalculateHealth(&allConnections[i], thresholds)
 	}
+	s.pmtuDetector.Analyze(allConnections)
+	s.windowAnalyzer.Analyze(allConnections)
+	s.bufferAnalyzer.Analyze(allConnections)
+	s.stallDetector.Analyze(allConnections)
 
 	// Update connection manager
 	events := s.connectionManager.Update(allConnections)
 
-	// Capture snapshot if recording is active
-	s.snapshotStore.Take(allConnections)
+	// Open and close scheduled windows, then capture snapshot if recording is active
+	s.scheduler.Tick(time.Now())
+	s.snapshotStore.NoteHealthThresholds(thresholds)
+	s.snapshotStore.Sample(allConnections)
+	s.flightRecorder.Observe(allConnections)
 
 	// Check if selected connection was closed
 	s.mu.Lock()
@@ -355,7 +566,7 @@ func (s *Service) IsAdministrator() bool {
 // SetUpdateInterval changes the polling interval
 func (s *Service) SetUpdateInterval(interval time.Duration) error {
 	// Validate interval
-	if interval < 100*time.Millisecond {
+	if interval < minUpdateInterval {
 		return ErrInvalidInterval
 	}
 	if interval > 10*time.Second {
@@ -366,11 +577,10 @@ func (s *Service) SetUpdateInterval(interval time.Duration) error {
 	defer s.mu.Unlock()
 
 	s.updateInterval = interval
+	s.snapshotStore.SetSampleInterval(interval)
 	s.logger.Info("Update interval changed to %v", interval)
 
-	// Note: The change will take effect on the next ticker cycle
-	// For immediate effect, we would need to restart the ticker
-	// which is more complex and not required by the spec
+	// The polling loop picks up the change on its next tick
 
 	return nil
 }
@@ -485,6 +695,13 @@ func (s *Service) getCSVHeader() string {
 		"LocalPort",
 		"RemoteAddr",
 		"RemotePort",
+		"RemoteHostname",
+		"RemoteCountry",
+		"RemoteCity",
+		"RemoteASN",
+		"RemoteASOrg",
+		"ServiceName",
+		"Role",
 		"State",
 		"PID",
 		"IsIPv6",
@@ -520,12 +737,19 @@ func (s *Service) getCSVHeader() string {
 		"CurrentSsthresh",
 		"SlowStartCount",
 		"CongAvoidCount",
+		"CongestionAlgorithm",
+		"CAState",
 
 		// Extended stats - Buffers
 		"CurRetxQueue",
 		"MaxRetxQueue",
 		"CurAppWQueue",
 		"MaxAppWQueue",
+		"RmemAlloc",
+		"RcvBuf",
+		"WmemQueued",
+		"SndBuf",
+		"SockDrops",
 
 		// Extended stats - Bandwidth
 		"OutboundBandwidth",
@@ -537,13 +761,20 @@ func (s *Service) getCSVHeader() string {
 
 // formatConnectionAsCSVRow formats a single connection as a CSV row
 func (s *Service) formatConnectionAsCSVRow(conn *ConnectionInfo) string {
-	fields := make([]string, 0, 42)
+	fields := make([]string, 0, 49)
 
 	// Basic connection info
 	fields = append(fields, s.escapeCSVField(conn.LocalAddr))
 	fields = append(fields, fmt.Sprintf("%d", conn.LocalPort))
 	fields = append(fields, s.escapeCSVField(conn.RemoteAddr))
 	fields = append(fields, fmt.Sprintf("%d", conn.RemotePort))
+	fields = append(fields, s.escapeCSVField(conn.RemoteHostname))
+	fields = append(fields, s.escapeCSVField(conn.RemoteCountry))
+	fields = append(fields, s.escapeCSVField(conn.RemoteCity))
+	fields = append(fields, fmt.Sprintf("%d", conn.RemoteASN))
+	fields = append(fields, s.escapeCSVField(conn.RemoteASOrg))
+	fields = append(fields, s.escapeCSVField(conn.ServiceName))
+	fields = append(fields, s.escapeCSVField(conn.Role))
 	fields = append(fields, s.escapeCSVField(conn.State.String()))
 	fields = append(fields, fmt.Sprintf("%d", conn.PID))
 	fields = append(fields, fmt.Sprintf("%t", conn.IsIPv6))
@@ -584,12 +815,19 @@ func (s *Service) formatConnectionAsCSVRow(conn *ConnectionInfo) string {
 		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.CurrentSsthresh))
 		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.SlowStartCount))
 		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.CongAvoidCount))
+		fields = append(fields, s.escapeCSVField(conn.ExtendedStats.CongestionAlgorithm))
+		fields = append(fields, s.escapeCSVField(conn.ExtendedStats.CAState))
 
 		// Extended stats - Buffers
 		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.CurRetxQueue))
 		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.MaxRetxQueue))
 		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.CurAppWQueue))
 		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.MaxAppWQueue))
+		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.RmemAlloc))
+		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.RcvBuf))
+		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.WmemQueued))
+		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.SndBuf))
+		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.SockDrops))
 
 		// Extended stats - Bandwidth
 		fields = append(fields, fmt.Sprintf("%d", conn.ExtendedStats.OutboundBandwidth))
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strings"
 	"time"
 
 	"tcpdoctor/internal/llm"
@@ -284,12 +285,17 @@ func (s *Service) rankConnectionsByMetric(conns []SessionConnectionSummary, metr
 		}
 
 		rankings = append(rankings, llm.ConnectionRanking{
-			LocalAddr:  conn.LocalAddr,
-			RemoteAddr: conn.RemoteAddr,
-			LocalPort:  conn.LocalPort,
-			RemotePort: conn.RemotePort,
-			Score:      score,
-			Severity:   s.classifySeverity(metric, score),
+			LocalAddr:      conn.LocalAddr,
+			RemoteAddr:     conn.RemoteAddr,
+			RemoteHostname: conn.RemoteHostname,
+			RemoteCountry:  conn.RemoteCountry,
+			RemoteASN:      conn.RemoteASN,
+			RemoteASOrg:    conn.RemoteASOrg,
+			ServiceName:    conn.ServiceName,
+			LocalPort:      conn.LocalPort,
+			RemotePort:     conn.RemotePort,
+			Score:          score,
+			Severity:       s.classifySeverity(metric, score),
 		})
 	}
 
@@ -332,6 +338,12 @@ func (s *Service) extractMajorEvents(conns []SessionConnectionSummary) []llm.Maj
 			eventType := "mass_degradation"
 			if evts[0].EventType == "burst" {
 				eventType = "retransmission_storm"
+			} else if evts[0].Metric == "mss" {
+				eventType = "path_mtu_change"
+			} else if evts[0].Metric == "buffer" {
+				eventType = "buffer_pressure"
+			} else if evts[0].Metric == "timer" {
+				eventType = "connection_stalls"
 			}
 
 			events = append(events, llm.MajorEvent{
@@ -374,6 +386,10 @@ type SessionConnectionSummary struct {
 	Events           []llm.TemporalEvent     `json:"events,omitempty"`
 	StateTransitions []llm.StateTransition   `json:"stateTransitions,omitempty"`
 	Periods          []llm.PerformancePeriod `json:"periods,omitempty"`
+	MSSFindings      []MSSFinding            `json:"mssFindings,omitempty"`
+	BufferFindings   []BufferFinding         `json:"bufferFindings,omitempty"`
+	Stalls           []StallFinding          `json:"stalls,omitempty"`
+	WindowAnalysis   *WindowAnalysis         `json:"windowAnalysis,omitempty"`
 
 	// Totals
 	TotalRetransmissions int64 `json:"totalRetransmissions"`
@@ -426,19 +442,15 @@ func (s *Service) GenerateSessionHighlights(sessionID int64) (*llm.SessionHighli
 	// Find worst/best performance times
 	highlights.TimeOfWorstPerformance, highlights.TimeOfBestPerformance = s.findPerformanceExtremes(aggregated)
 
+	// Compare metrics around user annotations
+	highlights.Annotations = s.annotationImpacts(sessionID)
+
 	return highlights, nil
 }
 
 // aggregateSessionConnections groups snapshots by connection and aggregates metrics
 func (s *Service) aggregateSessionConnections(timeline []TimelineConnection) []SessionConnectionSummary {
-	// Group by connection key
-	connMap := make(map[string][]TimelineConnection)
-	for _, tc := range timeline {
-		key := fmt.Sprintf("%s:%d->%s:%d",
-			tc.Connection.LocalAddr, tc.Connection.LocalPort,
-			tc.Connection.RemoteAddr, tc.Connection.RemotePort)
-		connMap[key] = append(connMap[key], tc)
-	}
+	connMap := connectionTimelines(timeline)
 
 	// Aggregate each connection
 	summaries := make([]SessionConnectionSummary, 0, len(connMap))
@@ -452,6 +464,18 @@ func (s *Service) aggregateSessionConnections(timeline []TimelineConnection) []S
 	return summaries
 }
 
+// connectionTimelines splits timeline rows by connection
+func connectionTimelines(timeline []TimelineConnection) map[string][]TimelineConnection {
+	connMap := make(map[string][]TimelineConnection)
+	for _, tc := range timeline {
+		key := fmt.Sprintf("%s:%d->%s:%d",
+			tc.Connection.LocalAddr, tc.Connection.LocalPort,
+			tc.Connection.RemoteAddr, tc.Connection.RemotePort)
+		connMap[key] = append(connMap[key], tc)
+	}
+	return connMap
+}
+
 // buildSessionConnectionSummary aggregates multiple snapshots for one connection
 func (s *Service) buildSessionConnectionSummary(snapshots []TimelineConnection) SessionConnectionSummary {
 	first := snapshots[0]
@@ -478,6 +502,14 @@ func (s *Service) buildSessionConnectionSummary(snapshots []TimelineConnection)
 			LocalPort:            uint16(last.Connection.LocalPort),
 			RemoteAddr:           last.Connection.RemoteAddr,
 			RemotePort:           uint16(last.Connection.RemotePort),
+			RemoteHostname:       last.Connection.RemoteHostname,
+			RemoteCountry:        last.Connection.RemoteCountry,
+			RemoteCity:           last.Connection.RemoteCity,
+			RemoteASN:            uint32(last.Connection.RemoteASN),
+			RemoteASOrg:          last.Connection.RemoteASOrg,
+			PID:                  uint32(last.Connection.PID),
+			ServiceName:          last.Connection.ServiceName,
+			Role:                 last.Connection.Role,
 			State:                TCPState(last.Connection.State).String(),
 			BytesIn:              uint64(last.Connection.BytesIn),
 			BytesOut:             uint64(last.Connection.BytesOut),
@@ -486,9 +518,23 @@ func (s *Service) buildSessionConnectionSummary(snapshots []TimelineConnection)
 			OutboundBandwidthBps: uint64(last.Connection.OutBandwidth),
 			CongestionWindow:     uint64(last.Connection.CongestionWin),
 			SlowStartThreshold:   uint64(last.Connection.CurrentSsthresh),
+			CongestionAlgorithm:  last.Connection.CongestionAlgorithm,
+			CongestionState:      last.Connection.CAState,
+			PacingRateBps:        uint64(last.Connection.PacingRate),
+			BBRBandwidthBps:      uint64(last.Connection.BBRBandwidth),
+			BBRMinRTTMs:          float64(last.Connection.BBRMinRTT) / 1000,
+			BBRPacingGain:        last.Connection.BBRPacingGain,
+			DCTCPAlpha:           float64(last.Connection.DCTCPAlpha) / 1024,
 			FastRetransmissions:  uint64(last.Connection.FastRetrans),
 			TimeoutEpisodes:      uint64(last.Connection.TimeoutEpisodes),
 			TotalSegmentsOut:     uint64(last.Connection.TotalSegsOut),
+			AppWriteQueue:        uint64(last.Connection.CurAppWQueue),
+			RetransmitQueue:      uint64(last.Connection.CurRetxQueue),
+			ReceiveBufferUsed:    uint64(last.Connection.RmemAlloc),
+			ReceiveBufferLimit:   uint64(last.Connection.RcvBuf),
+			SendBufferUsed:       uint64(last.Connection.WmemQueued),
+			SendBufferLimit:      uint64(last.Connection.SndBuf),
+			SocketDrops:          uint64(last.Connection.SockDrops),
 			CurrentMSS:           uint64(last.Connection.CurMss),
 			MinRTTMs:             minFloat64(rtts),
 			MaxRTTMs:             maxFloat64(rtts),
@@ -531,6 +577,64 @@ func (s *Service) buildSessionConnectionSummary(snapshots []TimelineConnection)
 		}
 	}
 
+	// Detect MSS/PMTU anomalies
+	summary.MSSFindings = DetectMSSAnomalies(snapshots)
+	for _, f := range summary.MSSFindings {
+		summary.Findings = append(summary.Findings, f.Description)
+		if f.Anomaly == MSSAnomalySmall {
+			// A standing condition rather than an event
+			continue
+		}
+		summary.Events = append(summary.Events, llm.TemporalEvent{
+			Timestamp: f.Timestamp,
+			Metric:    "mss",
+			EventType: f.Anomaly,
+			Value:     f.ToMSS,
+			Severity:  f.Severity,
+		})
+	}
+
+	// Detect socket buffer pressure
+	summary.BufferFindings = DetectBufferPressure(snapshots)
+	for _, f := range summary.BufferFindings {
+		summary.Findings = append(summary.Findings, f.Description)
+		summary.Events = append(summary.Events, llm.TemporalEvent{
+			Timestamp: f.Timestamp,
+			Metric:    "buffer",
+			EventType: f.Condition,
+			Value:     f.Used,
+			Severity:  f.Severity,
+		})
+	}
+
+	// Detect retransmission backoff and probe stalls
+	summary.Stalls = DetectStalls(snapshots)
+	for _, f := range summary.Stalls {
+		summary.Findings = append(summary.Findings, f.Description)
+		summary.Events = append(summary.Events, llm.TemporalEvent{
+			Timestamp: f.Timestamp,
+			Metric:    "timer",
+			EventType: f.Condition,
+			Value:     f.DurationMs,
+			Severity:  f.Severity,
+		})
+	}
+	if last.Connection.TimerKind != "" {
+		summary.Timer = describeTimer(timerFromCompact(&last.Connection))
+	}
+
+	// Classify what limited throughput over the session
+	summary.WindowAnalysis = AnalyzeSessionWindows(snapshots)
+	if summary.WindowAnalysis != nil {
+		summary.Limitation = summary.WindowAnalysis.Limitation
+		if summary.WindowAnalysis.Limitation != LimitIdle {
+			summary.Findings = append(summary.Findings, summary.WindowAnalysis.Explanation)
+		}
+		if summary.WindowAnalysis.WindowScaleIssue != "" {
+			summary.Findings = append(summary.Findings, summary.WindowAnalysis.WindowScaleIssue)
+		}
+	}
+
 	// Detect state transitions
 	for i := 1; i < len(snapshots); i++ {
 		if snapshots[i].Connection.State != snapshots[i-1].Connection.State {
@@ -613,6 +717,12 @@ func (s *Service) identifyPrimaryIssues(conns []SessionConnectionSummary) []stri
 	highRTTCount := 0
 	highRetransCount := 0
 	volatileCount := 0
+	mssCounts := make(map[string]int)
+	bufferCounts := make(map[string]int)
+	stallCounts := make(map[string]int)
+	limitCounts := make(map[string]int)
+	algorithmCounts := make(map[string]int)
+	windowScaleCount := 0
 
 	for _, conn := range conns {
 		if conn.AvgRTT > 100 {
@@ -627,6 +737,34 @@ func (s *Service) identifyPrimaryIssues(conns []SessionConnectionSummary) []stri
 		if conn.RTTVariability == "high" {
 			volatileCount++
 		}
+		if conn.CongestionAlgorithm != "" {
+			algorithmCounts[conn.CongestionAlgorithm]++
+		}
+		if conn.WindowAnalysis != nil {
+			limitCounts[conn.WindowAnalysis.Limitation]++
+			if conn.WindowAnalysis.WindowScaleIssue != "" {
+				windowScaleCount++
+			}
+		}
+		seen := make(map[string]bool)
+		for _, f := range conn.MSSFindings {
+			if !seen[f.Anomaly] {
+				seen[f.Anomaly] = true
+				mssCounts[f.Anomaly]++
+			}
+		}
+		for _, f := range conn.Stalls {
+			if !seen[f.Condition] {
+				seen[f.Condition] = true
+				stallCounts[f.Condition]++
+			}
+		}
+		for _, f := range conn.BufferFindings {
+			if !seen[f.Condition] {
+				seen[f.Condition] = true
+				bufferCounts[f.Condition]++
+			}
+		}
 	}
 
 	if highRTTCount > 0 {
@@ -638,6 +776,62 @@ func (s *Service) identifyPrimaryIssues(conns []SessionConnectionSummary) []stri
 	if volatileCount > 0 {
 		issues = append(issues, fmt.Sprintf("Volatile latency on %d connections", volatileCount))
 	}
+	if n := mssCounts[MSSAnomalyBlackHole]; n > 0 {
+		issues = append(issues, fmt.Sprintf("Suspected PMTU black hole on %d connections", n))
+	}
+	if n := mssCounts[MSSAnomalyDrop]; n > 0 {
+		issues = append(issues, fmt.Sprintf("MSS dropped mid-connection on %d connections (path MTU change)", n))
+	}
+	if n := mssCounts[MSSAnomalySmall]; n > 0 {
+		issues = append(issues, fmt.Sprintf("Small MSS on %d connections (tunnel/VPN overhead)", n))
+	}
+	if n := stallCounts[StallRetransmitBackoff]; n > 0 {
+		issues = append(issues, fmt.Sprintf("Connections stuck in retransmission backoff: %d", n))
+	}
+	if n := stallCounts[StallZeroWindow]; n > 0 {
+		issues = append(issues, fmt.Sprintf("Zero-window probing on %d connections (peer not reading)", n))
+	}
+	if n := stallCounts[StallKeepalive]; n > 0 {
+		issues = append(issues, fmt.Sprintf("Unanswered keepalive probes on %d connections", n))
+	}
+	if n := bufferCounts[BufferRecvFull]; n > 0 {
+		issues = append(issues, fmt.Sprintf("Receive buffer full on %d connections (application not reading)", n))
+	}
+	if n := bufferCounts[BufferSendPinned]; n > 0 {
+		issues = append(issues, fmt.Sprintf("Send buffer pinned at its limit on %d connections (autotuning capped)", n))
+	}
+	if n := bufferCounts[BufferDrops]; n > 0 {
+		issues = append(issues, fmt.Sprintf("Socket drops on %d connections", n))
+	}
+	if windowScaleCount > 0 {
+		issues = append(issues, fmt.Sprintf("Window scaling missing on high-BDP paths on %d connections (windows capped at 64 KB)", windowScaleCount))
+	}
+	if n := limitCounts[LimitReceiverWindow]; n > 0 {
+		issues = append(issues, fmt.Sprintf("Receiver-window-limited transfers on %d connections", n))
+	}
+	if n := limitCounts[LimitCongestionWindow]; n > 0 {
+		issues = append(issues, fmt.Sprintf("Congestion-window-limited transfers on %d connections", n))
+	}
+	if n := limitCounts[LimitNetwork]; n > 0 {
+		issues = append(issues, fmt.Sprintf("Network-limited transfers on %d connections", n))
+	}
+	if len(algorithmCounts) > 1 {
+		// Several algorithms side by side usually means a host or
+		// application overrides the system default
+		algorithms := make([]string, 0, len(algorithmCounts))
+		for name := range algorithmCounts {
+			algorithms = append(algorithms, name)
+		}
+		sort.Slice(algorithms, func(i, j int) bool {
+			return algorithmCounts[algorithms[i]] > algorithmCounts[algorithms[j]] ||
+				(algorithmCounts[algorithms[i]] == algorithmCounts[algorithms[j]] && algorithms[i] < algorithms[j])
+		})
+		parts := make([]string, len(algorithms))
+		for i, name := range algorithms {
+			parts[i] = fmt.Sprintf("%s on %d", name, algorithmCounts[name])
+		}
+		issues = append(issues, "Mixed congestion control algorithms: "+strings.Join(parts, ", "))
+	}
 
 	return issues
 }
@@ -665,6 +859,116 @@ func (s *Service) findPerformanceExtremes(conns []SessionConnectionSummary) (wor
 	return worst, best
 }
 
+// annotationWindow is how far before and after a point annotation metrics
+// are compared; range annotations are compared with an equally long window
+// before them
+const annotationWindow = 5 * time.Minute
+
+// annotationImpacts compares the metrics before each annotation of a session
+// with those after it
+func (s *Service) annotationImpacts(sessionID int64) []llm.AnnotationImpact {
+	annotations := s.snapshotStore.GetAnnotations(sessionID)
+	session := s.snapshotStore.GetSessionByID(sessionID)
+	if len(annotations) == 0 || session == nil {
+		return nil
+	}
+	sessionEnd := session.EndTime
+	if sessionEnd.IsZero() {
+		sessionEnd = time.Now()
+	}
+
+	impacts := make([]llm.AnnotationImpact, 0, len(annotations))
+	for _, a := range annotations {
+		start, end := a.Timestamp, a.Timestamp.Add(annotationWindow)
+		if a.IsRange() {
+			end = a.EndTime
+		}
+		before := start.Add(-end.Sub(start))
+		if before.Before(session.StartTime) {
+			before = session.StartTime
+		}
+		if end.After(sessionEnd) {
+			end = sessionEnd
+		}
+
+		rows := s.snapshotStore.GetSessionTimelineRange(sessionID, before, end)
+		impact := llm.AnnotationImpact{
+			Timestamp: a.Timestamp,
+			EndTime:   a.EndTime,
+			Text:      a.Text,
+			Category:  a.Category,
+			Author:    a.Author,
+			Before:    summarizeWindow(rows, before, start),
+			After:     summarizeWindow(rows, start, end),
+		}
+		impact.Summary = describeImpact(impact.Before, impact.After)
+		impacts = append(impacts, impact)
+	}
+	return impacts
+}
+
+// summarizeWindow aggregates the timeline rows recorded in [from, to)
+func summarizeWindow(rows []TimelineConnection, from, to time.Time) llm.AnnotationWindow {
+	window := llm.AnnotationWindow{Start: from, End: to}
+	type span struct{ first, last CompactConnection }
+	conns := make(map[string]*span)
+	samples := make(map[time.Time]bool)
+	var rttSum float64
+	var rttCount int
+
+	for _, row := range rows {
+		if row.Annotation != nil || row.Timestamp.Before(from) || !row.Timestamp.Before(to) {
+			continue
+		}
+		samples[row.Timestamp] = true
+		c := row.Connection
+		if c.RTT > 0 {
+			rttSum += float64(c.RTT)
+			rttCount++
+		}
+		key := fmt.Sprintf("%s:%d->%s:%d", c.LocalAddr, c.LocalPort, c.RemoteAddr, c.RemotePort)
+		if sp, ok := conns[key]; ok {
+			sp.last = c
+		} else {
+			conns[key] = &span{first: c, last: c}
+		}
+	}
+
+	window.Samples = len(samples)
+	window.Connections = len(conns)
+	if rttCount > 0 {
+		window.AvgRTTMs = rttSum / float64(rttCount)
+	}
+	var retrans, segsOut int64
+	for _, sp := range conns {
+		if d := sp.last.SegsRetrans - sp.first.SegsRetrans; d > 0 {
+			retrans += d
+		}
+		if d := sp.last.SegmentsOut - sp.first.SegmentsOut; d > 0 {
+			segsOut += d
+		}
+	}
+	if segsOut > 0 {
+		window.RetransRate = float64(retrans) / float64(segsOut) * 100
+	}
+	return window
+}
+
+// describeImpact summarizes how the metrics changed between two windows
+func describeImpact(before, after llm.AnnotationWindow) string {
+	if before.Samples == 0 || after.Samples == 0 {
+		return "not enough data on both sides to compare"
+	}
+	var parts []string
+	if before.AvgRTTMs > 0 && after.AvgRTTMs > 0 {
+		parts = append(parts, fmt.Sprintf("RTT %.1f → %.1f ms (%+.0f%%)",
+			before.AvgRTTMs, after.AvgRTTMs, (after.AvgRTTMs-before.AvgRTTMs)/before.AvgRTTMs*100))
+	}
+	parts = append(parts, fmt.Sprintf("retransmissions %.2f%% → %.2f%%", before.RetransRate, after.RetransRate))
+	parts = append(parts, fmt.Sprintf("connections %d → %d", before.Connections, after.Connections))
+	return strings.Join(parts, ", ")
+}
+
 // =====================================================
 // Session Query Methods
 // =====================================================
@@ -709,7 +1013,9 @@ WORST CONNECTIONS (by RTT):
 
 MAJOR EVENTS:
 %s
-
+ANNOTATIONS (noted by the user, with metrics before → after):
+%s
+%s
 USER QUESTION: %s`,
 		highlights.SessionID,
 		highlights.Duration/60,
@@ -720,6 +1026,8 @@ USER QUESTION: %s`,
 		formatIssues(highlights.PrimaryIssues),
 		formatRankings(highlights.WorstRTTConnections),
 		formatMajorEvents(highlights.MajorEvents),
+		formatAnnotations(highlights.Annotations),
+		formatGeoBreakdown(summaries),
 		query,
 	)
 
@@ -750,30 +1058,24 @@ func (s *Service) generateHealthReportForSession(sessionID int64) (*llm.HealthRe
 
 // GetSnapshotsByTimeRange returns aggregated data for a time range
 func (s *Service) GetSnapshotsByTimeRange(sessionID int64, startTime, endTime time.Time, filter *llm.ConnectionFilter) ([]SessionConnectionSummary, error) {
-	timeline := s.snapshotStore.GetSessionTimeline(sessionID)
+	timeline := s.snapshotStore.GetSessionTimelineRange(sessionID, startTime, endTime)
 
 	// DEBUG: Log the raw timeline size and requested range
 	s.logger.Debug("[GetSnapshotsByTimeRange] SessionID=%d, Start=%s, End=%s, TimelineLen=%d",
 		sessionID, startTime.Format(time.RFC3339), endTime.Format(time.RFC3339), len(timeline))
 
-	// Filter by time range
+	// Apply connection filter if provided
 	var filtered []TimelineConnection
 	for _, tc := range timeline {
-		if (tc.Timestamp.Equal(startTime) || tc.Timestamp.After(startTime)) &&
-			(tc.Timestamp.Equal(endTime) || tc.Timestamp.Before(endTime)) {
-
-			// Apply connection filter if provided
-			if filter != nil {
-				if filter.LocalAddr != nil && tc.Connection.LocalAddr != *filter.LocalAddr {
-					continue
-				}
-				if filter.RemoteAddr != nil && tc.Connection.RemoteAddr != *filter.RemoteAddr {
-					continue
-				}
+		if filter != nil {
+			if filter.LocalAddr != nil && tc.Connection.LocalAddr != *filter.LocalAddr {
+				continue
+			}
+			if filter.RemoteAddr != nil && tc.Connection.RemoteAddr != *filter.RemoteAddr {
+				continue
 			}
-
-			filtered = append(filtered, tc)
 		}
+		filtered = append(filtered, tc)
 	}
 
 	// DEBUG: Log how many matched the filter
@@ -782,6 +1084,94 @@ func (s *Service) GetSnapshotsByTimeRange(sessionID int64, startTime, endTime ti
 	return s.aggregateSessionConnections(filtered), nil
 }
 
+// =====================================================
+// Connection Grouping
+// =====================================================
+
+// GetConnectionGroups breaks down live connections by a dimension (country, asn, pid, ...)
+func (s *Service) GetConnectionGroups(filter FilterOptions, groupBy string) ([]llm.ConnectionGroup, error) {
+	connections, err := s.GetConnections(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]llm.ConnectionSummary, 0, len(connections))
+	for i := range connections {
+		summaries = append(summaries, s.buildConnectionSummary(&connections[i]))
+	}
+
+	return groupConnectionSummaries(summaries, groupBy)
+}
+
+// GetSessionGroups breaks down the connections of a recorded session by a dimension
+func (s *Service) GetSessionGroups(sessionID int64, groupBy string) ([]llm.ConnectionGroup, error) {
+	timeline := s.snapshotStore.GetSessionTimeline(sessionID)
+	if len(timeline) == 0 {
+		return nil, fmt.Errorf("session not found or empty")
+	}
+
+	aggregated := s.aggregateSessionConnections(timeline)
+	summaries := make([]llm.ConnectionSummary, len(aggregated))
+	for i, agg := range aggregated {
+		summaries[i] = agg.ConnectionSummary
+		summaries[i].RTTMs = agg.AvgRTT
+		if agg.TotalSegmentsOut > 0 {
+			summaries[i].RetransmissionRate = float64(agg.TotalRetransmissions) / float64(agg.TotalSegmentsOut) * 100
+		}
+	}
+
+	return groupConnectionSummaries(summaries, groupBy)
+}
+
+// =====================================================
+// Window / BDP Analysis
+// =====================================================
+
+// GetWindowAnalysis explains what limits a live connection's throughput
+func (s *Service) GetWindowAnalysis(localAddr string, localPort uint16, remoteAddr string, remotePort uint16) (*WindowAnalysis, error) {
+	if s.connectionManager == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+
+	key := ConnectionKey{
+		LocalAddr:  localAddr,
+		LocalPort:  localPort,
+		RemoteAddr: remoteAddr,
+		RemotePort: remotePort,
+		IsIPv6:     strings.Contains(localAddr, ":"),
+	}
+	conn, exists := s.connectionManager.Get(key)
+	if !exists {
+		return nil, fmt.Errorf("connection not found: %s", key.String())
+	}
+	if conn.WindowAnalysis == nil {
+		return nil, fmt.Errorf("window analysis not available yet (requires extended statistics and two samples)")
+	}
+	return conn.WindowAnalysis, nil
+}
+
+// GetSessionWindowAnalysis classifies what limited a connection's throughput over a recorded session
+func (s *Service) GetSessionWindowAnalysis(sessionID int64, localAddr string, localPort int, remoteAddr string, remotePort int) (*WindowAnalysis, error) {
+	timeline := s.snapshotStore.GetSessionTimeline(sessionID)
+
+	var snapshots []TimelineConnection
+	for _, tc := range timeline {
+		c := &tc.Connection
+		if c.LocalAddr == localAddr && c.LocalPort == localPort && c.RemoteAddr == remoteAddr && c.RemotePort == remotePort {
+			snapshots = append(snapshots, tc)
+		}
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("connection not found in session %d", sessionID)
+	}
+
+	analysis := AnalyzeSessionWindows(snapshots)
+	if analysis == nil {
+		return nil, fmt.Errorf("not enough data to analyze windows")
+	}
+	return analysis, nil
+}
+
 // Helper formatters
 func formatIssues(issues []string) string {
 	if len(issues) == 0 {
@@ -803,8 +1193,94 @@ func formatRankings(rankings []llm.ConnectionRanking) string {
 		if i >= 5 {
 			break
 		}
-		result += fmt.Sprintf("%d. %s:%d → %s:%d (%.1f, %s)\n",
-			i+1, r.LocalAddr, r.LocalPort, r.RemoteAddr, r.RemotePort, r.Score, r.Severity)
+		result += fmt.Sprintf("%d. %s:%d → %s:%d%s%s%s (%.1f, %s)\n",
+			i+1, r.LocalAddr, r.LocalPort, r.RemoteAddr, r.RemotePort,
+			formatService(r.ServiceName), formatHostname(r.RemoteHostname),
+			formatGeo(r.RemoteCountry, r.RemoteASN, r.RemoteASOrg), r.Score, r.Severity)
+	}
+	return result
+}
+
+// formatService renders a service label as a suffix, or nothing if unclassified
+func formatService(service string) string {
+	if service == "" {
+		return ""
+	}
+	return "/" + service
+}
+
+// formatHostname renders a resolved hostname as a suffix, or nothing if unresolved
+func formatHostname(hostname string) string {
+	if hostname == "" {
+		return ""
+	}
+	return " (" + hostname + ")"
+}
+
+// formatGeo renders country and AS information as a bracketed suffix
+func formatGeo(country string, asn uint32, asOrg string) string {
+	var parts []string
+	if country != "" {
+		parts = append(parts, country)
+	}
+	if asn != 0 {
+		parts = append(parts, fmt.Sprintf("AS%d %s", asn, asOrg))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(parts, ", ") + "]"
+}
+
+// formatGroups renders the largest groups of a breakdown for LLM context
+func formatGroups(groups []llm.ConnectionGroup, limit int) string {
+	if len(groups) == 0 {
+		return "No data"
+	}
+	result := ""
+	for i, g := range groups {
+		if i >= limit {
+			break
+		}
+		result += fmt.Sprintf("• %s: %d connections, avg RTT %.1fms, max RTT %.1fms, avg retrans %.2f%%\n",
+			g.Label, g.Connections, g.AvgRTTMs, g.MaxRTTMs, g.AvgRetrans)
+	}
+	return result
+}
+
+// formatGeoBreakdown renders per-country and per-AS breakdowns, or nothing
+// if no GeoIP databases were loaded while recording
+func formatGeoBreakdown(summaries []llm.ConnectionSummary) string {
+	result := ""
+	for _, dim := range []struct{ groupBy, title string }{
+		{GroupByCountry, "BY COUNTRY"},
+		{GroupByASN, "BY AUTONOMOUS SYSTEM"},
+	} {
+		groups, err := groupConnectionSummaries(summaries, dim.groupBy)
+		if err != nil || len(groups) == 0 || (len(groups) == 1 && groups[0].Key == "") {
+			continue
+		}
+		result += fmt.Sprintf("\n%s:\n%s", dim.title, formatGroups(groups, 5))
+	}
+	return result
+}
+
+// formatAnnotations renders user annotations and their impact for LLM context
+func formatAnnotations(impacts []llm.AnnotationImpact) string {
+	if len(impacts) == 0 {
+		return "None"
+	}
+	result := ""
+	for _, a := range impacts {
+		when := a.Timestamp.Format("15:04:05")
+		if a.EndTime.After(a.Timestamp) {
+			when += "–" + a.EndTime.Format("15:04:05")
+		}
+		author := ""
+		if a.Author != "" {
+			author = " (by " + a.Author + ")"
+		}
+		result += fmt.Sprintf("• %s [%s] %s%s: %s\n", when, a.Category, a.Text, author, a.Summary)
 	}
 	return result
 }
@@ -0,0 +1,97 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// === Session Archive Methods (Wails-exposed) ===
+
+// ExportSession writes a recorded session to a portable archive file
+func (s *Service) ExportSession(sessionID int64, path string) error {
+	if s.snapshotStore == nil {
+		return fmt.Errorf("snapshot store not available")
+	}
+	session, snapshots, probes := s.snapshotStore.GetSessionData(sessionID)
+	if session == nil {
+		return fmt.Errorf("session %d not found", sessionID)
+	}
+
+	archive := &SessionArchive{
+		Manifest: ArchiveManifest{
+			CreatedAt: time.Now(),
+			Tool:      currentArchiveTool(),
+			Host:      currentArchiveHost(s.extendedStats.Load()),
+			Session:   *session,
+		},
+		Snapshots:   snapshots,
+		Probes:      probes,
+		Annotations: s.snapshotStore.GetAnnotations(sessionID),
+	}
+	for _, tier := range rollupTiers {
+		rollups := s.snapshotStore.GetSessionRollups(sessionID, tier.resolution, time.Time{}, time.Time{})
+		archive.Rollups = append(archive.Rollups, rollups...)
+	}
+	// An imported session keeps describing the host it was recorded on
+	if session.ImportedHost != nil {
+		archive.Manifest.Host = *session.ImportedHost
+		archive.Manifest.Session.ImportedHost = nil
+	} else if session.ImportedFrom != "" {
+		archive.Manifest.Host = ArchiveHost{Hostname: session.ImportedFrom}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	if err := WriteSessionArchive(file, archive); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	s.logger.Info("Exported session %d (%d snapshots) to %s", sessionID, len(snapshots), path)
+	return nil
+}
+
+// ImportSession loads a session archive and returns the new local session
+func (s *Service) ImportSession(path string) (*RecordingSession, error) {
+	if s.snapshotStore == nil {
+		return nil, fmt.Errorf("snapshot store not available")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	archive, err := ReadSessionArchive(file)
+	if err != nil {
+		return nil, err
+	}
+
+	session := archive.Manifest.Session
+	host := archive.Manifest.Host
+	session.ImportedHost = &host
+	session.ImportedFrom = host.Hostname
+	if session.ImportedFrom == "" {
+		session.ImportedFrom = "unknown host"
+	}
+	id, err := s.snapshotStore.ImportSession(session, archive.Snapshots, archive.Probes, archive.Rollups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import session: %w", err)
+	}
+	if err := s.snapshotStore.importAnnotations(id, archive.Annotations); err != nil {
+		s.logger.Warn("Failed to import annotations of session %d: %v", id, err)
+	}
+
+	s.logger.Info("Imported session from %s (%s %s, tcpdoctor %s) as session %d with %d snapshots",
+		session.ImportedFrom, archive.Manifest.Host.OS, archive.Manifest.Host.Arch,
+		archive.Manifest.Tool.Version, id, len(archive.Snapshots))
+	return s.snapshotStore.GetSessionByID(id), nil
+}
@@ -0,0 +1,289 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Socket roles derived from local listeners
+const (
+	RoleListener = "listener" // LISTEN socket
+	RoleServer   = "server"   // Accepted connection on a local listening port
+	RoleClient   = "client"   // Outbound connection
+)
+
+// ServiceCatalogEntry maps a port, port range and/or remote CIDR to a service name.
+// Pattern forms: "5432", "8000-8099", "10.8.0.0/16:9092", "10.8.0.0/16",
+// "[fd00::/8]:443".
+type ServiceCatalogEntry struct {
+	Pattern string
+	Name    string
+}
+
+// serviceRule is a parsed ServiceCatalogEntry
+type serviceRule struct {
+	network  *net.IPNet // nil matches any remote address
+	portLow  uint16     // 0 with portHigh 0 matches any port
+	portHigh uint16
+	name     string
+}
+
+// matches checks a rule against a remote address and service port
+func (r *serviceRule) matches(remote net.IP, port uint16) bool {
+	if r.network != nil && (remote == nil || !r.network.Contains(remote)) {
+		return false
+	}
+	if r.portHigh != 0 && (port < r.portLow || port > r.portHigh) {
+		return false
+	}
+	return true
+}
+
+// wellKnownServices is the built-in subset of the IANA service name registry
+var wellKnownServices = map[uint16]string{
+	20:    "ftp-data",
+	21:    "ftp",
+	22:    "ssh",
+	23:    "telnet",
+	25:    "smtp",
+	53:    "dns",
+	80:    "http",
+	88:    "kerberos",
+	110:   "pop3",
+	111:   "rpcbind",
+	119:   "nntp",
+	123:   "ntp",
+	135:   "msrpc",
+	139:   "netbios-ssn",
+	143:   "imap",
+	179:   "bgp",
+	389:   "ldap",
+	443:   "https",
+	445:   "microsoft-ds",
+	465:   "smtps",
+	514:   "syslog",
+	515:   "printer",
+	587:   "submission",
+	631:   "ipp",
+	636:   "ldaps",
+	853:   "dns-over-tls",
+	873:   "rsync",
+	989:   "ftps-data",
+	990:   "ftps",
+	993:   "imaps",
+	995:   "pop3s",
+	1080:  "socks",
+	1194:  "openvpn",
+	1433:  "ms-sql-s",
+	1521:  "oracle",
+	1723:  "pptp",
+	1883:  "mqtt",
+	2049:  "nfs",
+	2181:  "zookeeper",
+	2375:  "docker",
+	2376:  "docker-s",
+	3268:  "globalcat-ldap",
+	3306:  "mysql",
+	3389:  "ms-wbt-server",
+	3478:  "stun",
+	4222:  "nats",
+	5060:  "sip",
+	5061:  "sips",
+	5222:  "xmpp-client",
+	5353:  "mdns",
+	5432:  "postgres",
+	5671:  "amqps",
+	5672:  "amqp",
+	5900:  "vnc",
+	5985:  "wsman",
+	5986:  "wsmans",
+	6379:  "redis",
+	6443:  "kubernetes-api",
+	6667:  "irc",
+	8080:  "http-alt",
+	8443:  "https-alt",
+	8883:  "secure-mqtt",
+	9000:  "cslistener",
+	9042:  "cassandra",
+	9092:  "kafka",
+	9200:  "elasticsearch",
+	9418:  "git",
+	11211: "memcache",
+	27017: "mongodb",
+}
+
+// ServiceCatalog classifies connections by service name and socket role
+type ServiceCatalog struct {
+	entries []ServiceCatalogEntry
+	rules   []serviceRule
+	mu      sync.RWMutex
+	logger  *Logger
+}
+
+// NewServiceCatalog creates a catalog containing only the built-in services
+func NewServiceCatalog() *ServiceCatalog {
+	return &ServiceCatalog{
+		logger: GetLogger(),
+	}
+}
+
+// SetEntries replaces the user-defined catalog entries.
+// User entries take precedence over the built-in catalog, first match wins.
+func (sc *ServiceCatalog) SetEntries(entries []ServiceCatalogEntry) error {
+	rules := make([]serviceRule, 0, len(entries))
+	for _, entry := range entries {
+		rule, err := parseServiceRule(entry)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.entries = append([]ServiceCatalogEntry(nil), entries...)
+	sc.rules = rules
+	return nil
+}
+
+// GetEntries returns the user-defined catalog entries
+func (sc *ServiceCatalog) GetEntries() []ServiceCatalogEntry {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	result := make([]ServiceCatalogEntry, len(sc.entries))
+	copy(result, sc.entries)
+	return result
+}
+
+// Lookup returns the service name for a remote address and service port
+func (sc *ServiceCatalog) Lookup(remoteAddr string, port uint16) string {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.lookupLocked(net.ParseIP(remoteAddr), port)
+}
+
+// lookupLocked matches user rules, then the built-in catalog. Caller must hold the lock.
+func (sc *ServiceCatalog) lookupLocked(remote net.IP, port uint16) string {
+	for i := range sc.rules {
+		if sc.rules[i].matches(remote, port) {
+			return sc.rules[i].name
+		}
+	}
+	return wellKnownServices[port]
+}
+
+// Classify sets Role and ServiceName on every connection. A connection whose
+// local port has a matching local listener is server-side and is named by its
+// local port; anything else is a client named by its remote port.
+func (sc *ServiceCatalog) Classify(connections []ConnectionInfo) {
+	// Collect listening endpoints by port
+	listeners := make(map[uint16][]string)
+	for i := range connections {
+		if connections[i].State == StateListen {
+			listeners[connections[i].LocalPort] = append(listeners[connections[i].LocalPort], connections[i].LocalAddr)
+		}
+	}
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	for i := range connections {
+		conn := &connections[i]
+		servicePort := conn.RemotePort
+
+		switch {
+		case conn.State == StateListen:
+			conn.Role = RoleListener
+			servicePort = conn.LocalPort
+		case hasListener(listeners[conn.LocalPort], conn.LocalAddr):
+			conn.Role = RoleServer
+			servicePort = conn.LocalPort
+		default:
+			conn.Role = RoleClient
+		}
+
+		conn.ServiceName = sc.lookupLocked(net.ParseIP(conn.RemoteAddr), servicePort)
+	}
+}
+
+// hasListener checks whether any listener address accepts connections on localAddr
+func hasListener(listenAddrs []string, localAddr string) bool {
+	for _, addr := range listenAddrs {
+		if addr == localAddr {
+			return true
+		}
+		if ip := net.ParseIP(addr); ip != nil && ip.IsUnspecified() {
+			return true
+		}
+	}
+	return false
+}
+
+// parseServiceRule parses a catalog entry pattern
+func parseServiceRule(entry ServiceCatalogEntry) (serviceRule, error) {
+	rule := serviceRule{name: strings.TrimSpace(entry.Name)}
+	pattern := strings.TrimSpace(entry.Pattern)
+
+	if rule.name == "" {
+		return rule, fmt.Errorf("service catalog entry %q has no name", entry.Pattern)
+	}
+	if pattern == "" {
+		return rule, fmt.Errorf("service catalog entry %q has an empty pattern", entry.Name)
+	}
+
+	cidr, ports := "", pattern
+	switch {
+	case strings.HasPrefix(pattern, "["):
+		// [v6cidr]:ports
+		end := strings.Index(pattern, "]")
+		if end < 0 {
+			return rule, fmt.Errorf("invalid service pattern %q: missing ]", pattern)
+		}
+		cidr = pattern[1:end]
+		ports = strings.TrimPrefix(pattern[end+1:], ":")
+	case strings.Contains(pattern, "/"):
+		// v4cidr[:ports]
+		cidr, ports = pattern, ""
+		if idx := strings.LastIndex(pattern, ":"); idx > strings.Index(pattern, "/") {
+			cidr, ports = pattern[:idx], pattern[idx+1:]
+		}
+	}
+
+	if cidr != "" {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return rule, fmt.Errorf("invalid service pattern %q: %w", pattern, err)
+		}
+		rule.network = network
+	}
+
+	if ports != "" {
+		low, high, err := parsePortRange(ports)
+		if err != nil {
+			return rule, fmt.Errorf("invalid service pattern %q: %w", pattern, err)
+		}
+		rule.portLow, rule.portHigh = low, high
+	}
+
+	return rule, nil
+}
+
+// parsePortRange parses "443" or "8000-8099"
+func parsePortRange(s string) (uint16, uint16, error) {
+	lowStr, highStr, isRange := strings.Cut(s, "-")
+	low, err := strconv.ParseUint(strings.TrimSpace(lowStr), 10, 16)
+	if err != nil || low == 0 {
+		return 0, 0, fmt.Errorf("invalid port %q", lowStr)
+	}
+	if !isRange {
+		return uint16(low), uint16(low), nil
+	}
+	high, err := strconv.ParseUint(strings.TrimSpace(highStr), 10, 16)
+	if err != nil || high < low {
+		return 0, 0, fmt.Errorf("invalid port range %q", s)
+	}
+	return uint16(low), uint16(high), nil
+}
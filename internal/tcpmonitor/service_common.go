@@ -3,6 +3,7 @@ package tcpmonitor
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"tcpdoctor/internal/llm"
@@ -22,8 +23,45 @@ type Service struct {
 	// Snapshot store for time-travel feature - Cross-platform
 	snapshotStore *SnapshotStore
 
+	// Reverse-DNS enrichment of remote addresses - Cross-platform
+	resolver *HostResolver
+
+	// Offline GeoIP/ASN enrichment - Cross-platform
+	geoIP *GeoIPResolver
+
+	// Port/service classification - Cross-platform
+	serviceCatalog *ServiceCatalog
+
+	// Active connect-latency prober - Cross-platform
+	prober *Prober
+
+	// Trigger-based recording - Cross-platform
+	flightRecorder *flightRecorder
+
+	// Scheduled recording windows - Cross-platform
+	scheduler *scheduler
+
+	// User-edited settings restored on startup - Cross-platform
+	settings *settingsStore
+
+	// MSS/PMTU anomaly detection - Cross-platform
+	pmtuDetector *PMTUDetector
+
+	// Window/BDP limitation analysis - Cross-platform
+	windowAnalyzer *WindowAnalyzer
+
+	// Socket buffer pressure analysis - Cross-platform
+	bufferAnalyzer *BufferAnalyzer
+
+	// Retransmission backoff / probe stall detection - Cross-platform
+	stallDetector *StallDetector
+
+	// Built-in throughput test server/client - Cross-platform
+	throughput *ThroughputTester
+
 	updateInterval time.Duration
 	isAdmin        bool
+	extendedStats  atomic.Bool // Set once the collector has returned extended stats
 
 	// Health thresholds
 	healthThresholds HealthThresholds
@@ -40,14 +78,24 @@ type Service struct {
 	logger *Logger
 }
 
+// minUpdateInterval is the shortest polling interval, and the shortest
+// sample interval a recording session may ask for
+const minUpdateInterval = 100 * time.Millisecond
+
 // ServiceConfig contains configuration options for the Service
 type ServiceConfig struct {
-	UpdateInterval time.Duration // How often to poll for connection updates
+	UpdateInterval time.Duration        // How often to poll for connection updates
+	Storage        StorageConfig        // On-disk persistence of recording sessions
+	FlightRecorder FlightRecorderConfig // Trigger-based instead of continuous recording
+	ScheduleFile   string               // Where recording schedules are kept (empty = per-user default)
+	SettingsFile   string               // Where user-edited settings are kept (empty = per-user default)
 }
 
 // DefaultServiceConfig returns the default service configuration
 func DefaultServiceConfig() ServiceConfig {
 	return ServiceConfig{
 		UpdateInterval: 1 * time.Second,
+		Storage:        DefaultStorageConfig(),
+		FlightRecorder: DefaultFlightRecorderConfig(),
 	}
 }
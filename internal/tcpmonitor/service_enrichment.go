@@ -0,0 +1,90 @@
+package tcpmonitor
+
+// === Hostname Resolution Methods (Wails-exposed) ===
+
+// GetResolverConfig returns the current reverse-DNS configuration
+func (s *Service) GetResolverConfig() ResolverConfig {
+	if s.resolver != nil {
+		return s.resolver.GetConfig()
+	}
+	return DefaultResolverConfig()
+}
+
+// SetResolverConfig updates the reverse-DNS configuration and reloads hosts files
+func (s *Service) SetResolverConfig(config ResolverConfig) error {
+	if s.resolver == nil {
+		return nil
+	}
+	if err := s.resolver.SetConfig(config); err != nil {
+		s.logger.Error("Failed to update resolver config: %v", err)
+		return err
+	}
+	s.logger.Info("Resolver config updated: enabled=%v, %d hosts files", config.Enabled, len(config.HostsFiles))
+	return s.saveSettings(func(st *appSettings) { st.Resolver = &config })
+}
+
+// ClearHostnameCache discards all cached reverse-DNS results
+func (s *Service) ClearHostnameCache() {
+	if s.resolver != nil {
+		s.resolver.ClearCache()
+		s.logger.Info("Hostname cache cleared")
+	}
+}
+
+// saveSettings records a settings change so it is restored on the next start
+func (s *Service) saveSettings(fn func(*appSettings)) error {
+	if s.settings == nil {
+		return nil
+	}
+	if err := s.settings.Update(fn); err != nil {
+		s.logger.Error("Failed to save settings: %v", err)
+		return err
+	}
+	return nil
+}
+
+// === GeoIP Methods (Wails-exposed) ===
+
+// GetGeoIPConfig returns the configured GeoIP database paths
+func (s *Service) GetGeoIPConfig() GeoIPConfig {
+	if s.geoIP != nil {
+		return s.geoIP.GetConfig()
+	}
+	return GeoIPConfig{}
+}
+
+// SetGeoIPConfig loads the given local .mmdb databases for country/city/ASN tagging
+func (s *Service) SetGeoIPConfig(config GeoIPConfig) error {
+	if s.geoIP == nil {
+		return nil
+	}
+	if err := s.geoIP.SetConfig(config); err != nil {
+		s.logger.Error("Failed to load GeoIP databases: %v", err)
+		return err
+	}
+	s.logger.Info("GeoIP databases loaded: city=%q, asn=%q", config.CityDBPath, config.ASNDBPath)
+	return s.saveSettings(func(st *appSettings) { st.GeoIP = &config })
+}
+
+// === Service Catalog Methods (Wails-exposed) ===
+
+// GetServiceCatalog returns the user-defined service catalog entries
+func (s *Service) GetServiceCatalog() []ServiceCatalogEntry {
+	if s.serviceCatalog != nil {
+		return s.serviceCatalog.GetEntries()
+	}
+	return nil
+}
+
+// SetServiceCatalog replaces the user-defined service catalog entries
+func (s *Service) SetServiceCatalog(entries []ServiceCatalogEntry) error {
+	if s.serviceCatalog == nil {
+		return nil
+	}
+	if err := s.serviceCatalog.SetEntries(entries); err != nil {
+		s.logger.Error("Failed to update service catalog: %v", err)
+		return err
+	}
+	s.logger.Info("Service catalog updated with %d entries", len(entries))
+	return s.saveSettings(func(st *appSettings) { st.ServiceCatalog = entries })
+}
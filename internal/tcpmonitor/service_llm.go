@@ -3,6 +3,7 @@ package tcpmonitor
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"tcpdoctor/internal/llm"
@@ -170,16 +171,21 @@ Session Details:
 - Start Time: %s
 - End Time: %s
 - Duration: %s
-- Snapshot Count: %d
+- Snapshot Count: %d%s
 
+Annotations (events noted by the user, with metrics before → after; ranges compare the time before with the time during):
+%s
 CRITICAL INSTRUCTIONS:
-1. Always use the provided tools (get_snapshots_by_time_range, get_metric_history, plot_graph) to fetch and analyze data. Do not guess or hallucinate connection details.
+1. Always use the provided tools (get_snapshots_by_time_range, get_metric_history, group_connections, compare_sessions, get_state_at, query_history, plot_graph) to fetch and analyze data. Do not guess or hallucinate connection details.
 2. For any data visualization (bar, line, or pie charts), you MUST use the "plot_graph" tool.
 3. NEVER describe a graph in text if it can be plotted. If you are showing distributions (e.g., states) or trends (e.g., RTT), call "plot_graph".
 4. Previous graphs in the chat history were rendered as interactive components. When you call "plot_graph", the user sees a rich chart, not just text.
-5. When calling get_snapshots_by_time_range or get_metric_history, use sessionID=%d and the ISO8601 timestamps above.
-6. Use markdown tables to present tabular data for better readability.`,
-		sessionID, sessionID, startISO, endISO, duration, session.SnapshotCount, sessionID)
+5. When calling get_snapshots_by_time_range, get_metric_history, group_connections or get_state_at, use sessionID=%d and the ISO8601 timestamps above; in query_history queries, write over session %d.
+6. Use markdown tables to present tabular data for better readability.
+7. When the user asks about an annotated event (e.g. "after the deploy"), use the annotation times above as the boundaries for your tool calls.
+8. To compare this session with another recording (e.g. before and after a change), call compare_sessions with the earlier session as sessionA; this session is %d.`,
+		sessionID, sessionID, startISO, endISO, duration, session.SnapshotCount,
+		formatSessionInfo(session), formatAnnotations(s.annotationImpacts(sessionID)), sessionID, sessionID, sessionID)
 
 	// We rely on the agent to use tools like get_snapshots_by_time_range or get_metric_history
 	// to fetch data as needed. We provide an empty summary list but a strong system prompt context.
@@ -192,6 +198,25 @@ CRITICAL INSTRUCTIONS:
 	return s.llmService.QueryConnectionsWithHistory(ctx, sessionContext+"\n\nUser Query: "+query, []llm.ConnectionSummary{}, history)
 }
 
+// formatSessionInfo renders the user-given name, description and tags of a
+// session as extra detail lines
+func formatSessionInfo(session *RecordingSession) string {
+	info := ""
+	if session.Name != "" {
+		info += "\n- Name: " + session.Name
+	}
+	if session.Description != "" {
+		info += "\n- Description: " + session.Description
+	}
+	if len(session.Tags) > 0 {
+		info += "\n- Tags: " + strings.Join(session.Tags, ", ")
+	}
+	if session.Scope != nil {
+		info += "\n- Recorded only: " + session.Scope.String()
+	}
+	return info
+}
+
 // Helper to convert TCP state int to string
 func tcpStateToString(state int) string {
 	states := []string{"CLOSED", "LISTEN", "SYN_SENT", "SYN_RCVD", "ESTABLISHED", "FIN_WAIT1", "FIN_WAIT2", "CLOSE_WAIT", "CLOSING", "LAST_ACK", "TIME_WAIT", "DELETE_TCB"}
@@ -224,7 +249,16 @@ func (s *Service) buildConnectionSummary(conn *ConnectionInfo) llm.ConnectionSum
 		RemoteAddr: conn.RemoteAddr,
 		RemotePort: conn.RemotePort,
 		State:      conn.State.String(),
-		HasWarning: conn.HighRetransmissionWarning || conn.HighRTTWarning,
+		HasWarning: HasHealthWarnings(conn),
+
+		RemoteHostname: conn.RemoteHostname,
+		RemoteCountry:  conn.RemoteCountry,
+		RemoteCity:     conn.RemoteCity,
+		RemoteASN:      conn.RemoteASN,
+		RemoteASOrg:    conn.RemoteASOrg,
+		PID:            conn.PID,
+		ServiceName:    conn.ServiceName,
+		Role:           conn.Role,
 	}
 
 	if conn.BasicStats != nil {
@@ -250,6 +284,17 @@ func (s *Service) buildConnectionSummary(conn *ConnectionInfo) llm.ConnectionSum
 		// Congestion Control
 		summary.CongestionWindow = uint64(conn.ExtendedStats.CurrentCwnd)
 		summary.SlowStartThreshold = uint64(conn.ExtendedStats.CurrentSsthresh)
+		summary.CongestionAlgorithm = conn.ExtendedStats.CongestionAlgorithm
+		summary.CongestionState = conn.ExtendedStats.CAState
+		summary.PacingRateBps = conn.ExtendedStats.PacingRate
+		if bbr := conn.ExtendedStats.BBR; bbr != nil {
+			summary.BBRBandwidthBps = bbr.BandwidthBps
+			summary.BBRMinRTTMs = float64(bbr.MinRTTUs) / 1000
+			summary.BBRPacingGain = bbr.PacingGain
+		}
+		if dctcp := conn.ExtendedStats.DCTCP; dctcp != nil {
+			summary.DCTCPAlpha = float64(dctcp.Alpha) / 1024
+		}
 
 		// Retransmission Details
 		summary.FastRetransmissions = uint64(conn.ExtendedStats.FastRetrans)
@@ -263,11 +308,42 @@ func (s *Service) buildConnectionSummary(conn *ConnectionInfo) llm.ConnectionSum
 		summary.WindowScaleSent = int(conn.ExtendedStats.WinScaleSent)
 		summary.WindowScaleReceived = int(conn.ExtendedStats.WinScaleRcvd)
 
+		// Socket buffers
+		summary.AppWriteQueue = uint64(conn.ExtendedStats.CurAppWQueue)
+		summary.RetransmitQueue = uint64(conn.ExtendedStats.CurRetxQueue)
+		summary.ReceiveBufferUsed = uint64(conn.ExtendedStats.RmemAlloc)
+		summary.ReceiveBufferLimit = uint64(conn.ExtendedStats.RcvBuf)
+		summary.SendBufferUsed = uint64(conn.ExtendedStats.WmemQueued)
+		summary.SendBufferLimit = uint64(conn.ExtendedStats.SndBuf)
+		summary.SocketDrops = uint64(conn.ExtendedStats.SockDrops)
+
 		// MSS
 		summary.CurrentMSS = uint64(conn.ExtendedStats.CurMss)
 		summary.MaxMSS = uint64(conn.ExtendedStats.MaxMss)
 		summary.MinMSS = uint64(conn.ExtendedStats.MinMss)
 	}
 
+	if conn.MSSAnomaly != nil {
+		summary.Findings = append(summary.Findings, conn.MSSAnomaly.Description)
+	}
+	if conn.Timer != nil {
+		summary.Timer = describeTimer(conn.Timer)
+	}
+	if conn.Stall != nil {
+		summary.Findings = append(summary.Findings, conn.Stall.Description)
+	}
+	if conn.BufferPressure != nil {
+		summary.Findings = append(summary.Findings, conn.BufferPressure.Description)
+	}
+	if conn.WindowAnalysis != nil {
+		summary.Limitation = conn.WindowAnalysis.Limitation
+		if conn.WindowAnalysis.Limitation != LimitIdle {
+			summary.Findings = append(summary.Findings, conn.WindowAnalysis.Explanation)
+		}
+		if conn.WindowAnalysis.WindowScaleIssue != "" {
+			summary.Findings = append(summary.Findings, conn.WindowAnalysis.WindowScaleIssue)
+		}
+	}
+
 	return summary
 }
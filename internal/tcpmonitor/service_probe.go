@@ -0,0 +1,80 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// === Active Probe Methods (Wails-exposed) ===
+
+// GetProberConfig returns the active prober configuration
+func (s *Service) GetProberConfig() ProberConfig {
+	if s.prober != nil {
+		return s.prober.GetConfig()
+	}
+	return DefaultProberConfig()
+}
+
+// SetProberConfig updates the active prober configuration
+func (s *Service) SetProberConfig(config ProberConfig) error {
+	if s.prober == nil {
+		return fmt.Errorf("prober not available")
+	}
+	if err := s.prober.SetConfig(config); err != nil {
+		s.logger.Error("Failed to update prober config: %v", err)
+		return err
+	}
+	s.logger.Info("Prober config updated: enabled=%v, interval=%ds, %d targets, observed=%v",
+		config.Enabled, config.IntervalSeconds, len(config.Targets), config.ProbeObserved)
+	return s.saveSettings(func(st *appSettings) { st.Prober = &config })
+}
+
+// ProbeTarget performs an immediate connect-latency probe of a "host:port" target
+func (s *Service) ProbeTarget(target string) (*ProbeResult, error) {
+	if s.prober == nil {
+		return nil, fmt.Errorf("prober not available")
+	}
+	result := s.prober.ProbeOnce(target)
+	return &result, nil
+}
+
+// GetLatestProbeResults returns the most recent probe result for every target
+func (s *Service) GetLatestProbeResults() []ProbeResult {
+	if s.prober != nil {
+		return s.prober.GetLatest()
+	}
+	return nil
+}
+
+// GetProbeHistory returns recorded probe results for a session, optionally for one target
+func (s *Service) GetProbeHistory(sessionID int64, target string) []ProbeResult {
+	if s.snapshotStore != nil {
+		return s.snapshotStore.GetProbeResults(sessionID, target)
+	}
+	return nil
+}
+
+// GetProbeComparison contrasts active connect time with passive RTT per probed endpoint
+func (s *Service) GetProbeComparison() []ProbeComparison {
+	if s.prober == nil || s.connectionManager == nil {
+		return nil
+	}
+	return s.prober.Compare(s.connectionManager.GetAll(), s.GetHealthThresholds())
+}
+
+// observedProbeTargets lists remote endpoints of established client connections
+func (s *Service) observedProbeTargets() []string {
+	if s.connectionManager == nil {
+		return nil
+	}
+
+	var targets []string
+	for _, conn := range s.connectionManager.GetAll() {
+		if conn.State != StateEstablished || conn.Role != RoleClient {
+			continue
+		}
+		targets = append(targets, net.JoinHostPort(conn.RemoteAddr, strconv.Itoa(int(conn.RemotePort))))
+	}
+	return targets
+}
@@ -1,13 +1,36 @@
 package tcpmonitor
 
+import (
+	"fmt"
+	"time"
+)
+
 // === Snapshot Methods (Wails-exposed) ===
 
-// StartRecording begins snapshot capture
-func (s *Service) StartRecording() {
-	if s.snapshotStore != nil {
-		s.snapshotStore.StartRecording()
-		s.logger.Info("Snapshot recording started")
+// StartRecording begins snapshot capture in a new session limited to the
+// given scope; an empty scope records everything at the polling interval
+func (s *Service) StartRecording(scope RecordingScope) error {
+	if s.snapshotStore == nil {
+		return fmt.Errorf("snapshot store not available")
+	}
+	if scope.IntervalMs > 0 && scope.Interval() < minUpdateInterval {
+		return fmt.Errorf("sample interval must be at least %v", minUpdateInterval)
+	}
+	id, err := s.snapshotStore.StartScopedRecording(scope)
+	if err != nil {
+		return err
 	}
+	if scope.IsEmpty() {
+		s.logger.Info("Snapshot recording started (session %d)", id)
+	} else {
+		s.logger.Info("Scoped snapshot recording started (session %d): %s", id, scope.String())
+	}
+	return nil
+}
+
+// GetRecordingFieldGroups returns the metric groups a recording scope can keep
+func (s *Service) GetRecordingFieldGroups() []string {
+	return RecordingFieldGroups()
 }
 
 // StopRecording stops snapshot capture
@@ -18,6 +41,18 @@ func (s *Service) StopRecording() {
 	}
 }
 
+// StopSession stops one recording session, leaving any others running
+func (s *Service) StopSession(sessionID int64) error {
+	if s.snapshotStore == nil {
+		return fmt.Errorf("snapshot store not available")
+	}
+	if err := s.snapshotStore.StopSession(sessionID); err != nil {
+		return err
+	}
+	s.logger.Info("Recording session %d stopped", sessionID)
+	return nil
+}
+
 // IsRecording returns current recording state
 func (s *Service) IsRecording() bool {
 	if s.snapshotStore != nil {
@@ -50,12 +85,28 @@ func (s *Service) GetSnapshot(id int64) *Snapshot {
 	return nil
 }
 
-// CompareSnapshots compares two snapshots
-func (s *Service) CompareSnapshots(id1, id2 int64) *ComparisonResult {
-	if s.snapshotStore != nil {
-		return s.snapshotStore.Compare(id1, id2)
+// CompareSnapshots diffs two snapshots
+func (s *Service) CompareSnapshots(id1, id2 int64, options DiffOptions) (*ComparisonResult, error) {
+	if s.snapshotStore == nil {
+		return nil, fmt.Errorf("snapshot store not available")
 	}
-	return nil
+	return s.snapshotStore.Compare(id1, id2, options)
+}
+
+// CompareRanges diffs the aggregate of one recorded time range against another
+func (s *Service) CompareRanges(a, b DiffRange, options DiffOptions) (*ComparisonResult, error) {
+	if s.snapshotStore == nil {
+		return nil, fmt.Errorf("snapshot store not available")
+	}
+	return s.snapshotStore.CompareRanges(a, b, options)
+}
+
+// QueryHistory runs a history query against the recorded sessions
+func (s *Service) QueryHistory(query string) (*HistoryQueryResult, error) {
+	if s.snapshotStore == nil {
+		return nil, fmt.Errorf("snapshot store not available")
+	}
+	return s.snapshotStore.Query(query)
 }
 
 // ClearSnapshots removes all stored snapshots
@@ -84,14 +135,56 @@ func (s *Service) GetConnectionHistory(localAddr string, localPort int, remoteAd
 
 // === Session Methods ===
 
-// GetSessions returns all recording sessions
-func (s *Service) GetSessions() []RecordingSession {
+// GetSessions returns the recording sessions matching a search query; an
+// empty query returns all of them
+func (s *Service) GetSessions(query string) []RecordingSession {
 	if s.snapshotStore != nil {
-		return s.snapshotStore.GetSessions()
+		return s.snapshotStore.SearchSessions(query)
 	}
 	return nil
 }
 
+// SetSessionInfo sets the name, description and tags of a session
+func (s *Service) SetSessionInfo(sessionID int64, name, description string, tags []string) (*RecordingSession, error) {
+	if s.snapshotStore == nil {
+		return nil, fmt.Errorf("snapshot store not available")
+	}
+	return s.snapshotStore.SetSessionInfo(sessionID, name, description, tags)
+}
+
+// DeleteSession removes a session and everything recorded in it
+func (s *Service) DeleteSession(sessionID int64) error {
+	if s.snapshotStore == nil {
+		return fmt.Errorf("snapshot store not available")
+	}
+	return s.snapshotStore.DeleteSession(sessionID)
+}
+
+// TrimSession keeps only the part of a session within a time range
+func (s *Service) TrimSession(sessionID int64, start, end time.Time) (*RecordingSession, error) {
+	if s.snapshotStore == nil {
+		return nil, fmt.Errorf("snapshot store not available")
+	}
+	return s.snapshotStore.TrimSession(sessionID, start, end)
+}
+
+// SplitSession splits a session in two at a timestamp and returns the new
+// second half
+func (s *Service) SplitSession(sessionID int64, at time.Time) (*RecordingSession, error) {
+	if s.snapshotStore == nil {
+		return nil, fmt.Errorf("snapshot store not available")
+	}
+	return s.snapshotStore.SplitSession(sessionID, at)
+}
+
+// MergeSessions joins two adjacent sessions into the earlier one
+func (s *Service) MergeSessions(firstID, secondID int64) (*RecordingSession, error) {
+	if s.snapshotStore == nil {
+		return nil, fmt.Errorf("snapshot store not available")
+	}
+	return s.snapshotStore.MergeSessions(firstID, secondID)
+}
+
 // GetSessionCount returns number of sessions
 func (s *Service) GetSessionCount() int {
 	if s.snapshotStore != nil {
@@ -100,10 +193,11 @@ func (s *Service) GetSessionCount() int {
 	return 0
 }
 
-// GetSessionTimeline returns all connection snapshots from a session as timeline rows
+// GetSessionTimeline returns all connection snapshots from a session as
+// timeline rows, with the session's annotations interleaved as marker rows
 func (s *Service) GetSessionTimeline(sessionID int64) []TimelineConnection {
 	if s.snapshotStore != nil {
-		return s.snapshotStore.GetSessionTimeline(sessionID)
+		return annotateTimeline(s.snapshotStore.GetSessionTimeline(sessionID), s.snapshotStore.GetAnnotations(sessionID))
 	}
 	return nil
 }
@@ -115,3 +209,195 @@ func (s *Service) GetConnectionHistoryForSession(sessionID int64, localAddr stri
 	}
 	return nil
 }
+
+// GetSessionTimelineRange returns the raw timeline rows of a session within
+// a time range, with the annotations that overlap the range interleaved as
+// marker rows
+func (s *Service) GetSessionTimelineRange(sessionID int64, start, end time.Time) []TimelineConnection {
+	if s.snapshotStore != nil {
+		annotations := annotationsBetween(s.snapshotStore.GetAnnotations(sessionID), start, end)
+		return annotateTimeline(s.snapshotStore.GetSessionTimelineRange(sessionID, start, end), annotations)
+	}
+	return nil
+}
+
+// GetSessionChartTimeline returns the timeline rows of a session within a
+// time range for charting, rolled up when the range is long, with the
+// overlapping annotations interleaved as marker rows
+func (s *Service) GetSessionChartTimeline(sessionID int64, start, end time.Time) []TimelineConnection {
+	if s.snapshotStore != nil {
+		annotations := annotationsBetween(s.snapshotStore.GetAnnotations(sessionID), start, end)
+		return annotateTimeline(s.snapshotStore.GetSessionChartTimeline(sessionID, start, end), annotations)
+	}
+	return nil
+}
+
+// GetConnectionHistoryRange returns raw connection history for a session
+// within a time range
+func (s *Service) GetConnectionHistoryRange(sessionID int64, start, end time.Time, localAddr string, localPort int, remoteAddr string, remotePort int) []ConnectionHistoryPoint {
+	if s.snapshotStore != nil {
+		return s.snapshotStore.GetConnectionHistoryRange(sessionID, start, end, localAddr, localPort, remoteAddr, remotePort)
+	}
+	return nil
+}
+
+// GetConnectionChartHistory returns connection history for a session within
+// a time range for charting, rolled up when the range is long
+func (s *Service) GetConnectionChartHistory(sessionID int64, start, end time.Time, localAddr string, localPort int, remoteAddr string, remotePort int) []ConnectionHistoryPoint {
+	if s.snapshotStore != nil {
+		return s.snapshotStore.GetConnectionChartHistory(sessionID, start, end, localAddr, localPort, remoteAddr, remotePort)
+	}
+	return nil
+}
+
+// === Annotation Methods ===
+
+// AddAnnotation marks a point in time, or a range when end is set, of a session
+func (s *Service) AddAnnotation(sessionID int64, start, end time.Time, text, category, author string) (*Annotation, error) {
+	if s.snapshotStore == nil {
+		return nil, fmt.Errorf("snapshot store not available")
+	}
+	return s.snapshotStore.AddAnnotation(Annotation{
+		SessionID: sessionID,
+		Timestamp: start,
+		EndTime:   end,
+		Text:      text,
+		Category:  category,
+		Author:    author,
+	})
+}
+
+// DeleteAnnotation removes an annotation from a session
+func (s *Service) DeleteAnnotation(sessionID, annotationID int64) error {
+	if s.snapshotStore == nil {
+		return fmt.Errorf("snapshot store not available")
+	}
+	return s.snapshotStore.DeleteAnnotation(sessionID, annotationID)
+}
+
+// GetAnnotations returns the annotations of a session in time order
+func (s *Service) GetAnnotations(sessionID int64) []Annotation {
+	if s.snapshotStore != nil {
+		return s.snapshotStore.GetAnnotations(sessionID)
+	}
+	return nil
+}
+
+// === Flight Recorder Methods ===
+
+// GetFlightRecorderConfig returns the flight-recorder settings in effect
+func (s *Service) GetFlightRecorderConfig() FlightRecorderConfig {
+	if s.flightRecorder != nil {
+		return s.flightRecorder.Config()
+	}
+	return FlightRecorderConfig{}
+}
+
+// SetFlightRecorderConfig switches between continuous and trigger-based
+// recording and updates the trigger settings
+func (s *Service) SetFlightRecorderConfig(config FlightRecorderConfig) error {
+	if s.flightRecorder == nil {
+		return fmt.Errorf("flight recorder not available")
+	}
+	if config.PreTriggerSeconds < 0 || config.PostTriggerSeconds < 0 || config.HealthWarnings < 0 || config.ConnectionSpike < 0 {
+		return fmt.Errorf("flight recorder settings must not be negative")
+	}
+	s.flightRecorder.Configure(config)
+	return s.saveSettings(func(st *appSettings) { st.FlightRecorder = &config })
+}
+
+// TriggerFlightRecorder fires a manual trigger, saving the buffered history
+// and the post-trigger window as a session
+func (s *Service) TriggerFlightRecorder(reason string) error {
+	if s.flightRecorder == nil {
+		return fmt.Errorf("flight recorder not available")
+	}
+	return s.flightRecorder.Trigger(reason)
+}
+
+// GetFlightRecorderStatus reports whether the flight recorder is armed or
+// recording a triggered session
+func (s *Service) GetFlightRecorderStatus() FlightRecorderStatus {
+	if s.flightRecorder != nil {
+		return s.flightRecorder.Status()
+	}
+	return FlightRecorderStatus{}
+}
+
+// === Schedule Methods ===
+
+// GetSchedules returns the recording schedules with their next window
+func (s *Service) GetSchedules() []RecordingSchedule {
+	if s.scheduler != nil {
+		return s.scheduler.Schedules()
+	}
+	return nil
+}
+
+// AddSchedule saves a recording schedule, which records a session in every
+// window from the next poll on
+func (s *Service) AddSchedule(schedule RecordingSchedule) (*RecordingSchedule, error) {
+	if s.scheduler == nil {
+		return nil, fmt.Errorf("scheduler not available")
+	}
+	return s.scheduler.Add(schedule)
+}
+
+// UpdateSchedule replaces a recording schedule's settings
+func (s *Service) UpdateSchedule(schedule RecordingSchedule) (*RecordingSchedule, error) {
+	if s.scheduler == nil {
+		return nil, fmt.Errorf("scheduler not available")
+	}
+	return s.scheduler.Update(schedule)
+}
+
+// DeleteSchedule removes a recording schedule. Sessions it recorded are kept.
+func (s *Service) DeleteSchedule(id int64) error {
+	if s.scheduler == nil {
+		return fmt.Errorf("scheduler not available")
+	}
+	return s.scheduler.Delete(id)
+}
+
+// === Storage Methods ===
+
+// GetStorageConfig returns the session persistence settings in effect
+func (s *Service) GetStorageConfig() StorageConfig {
+	if s.snapshotStore != nil {
+		return s.snapshotStore.StorageConfig()
+	}
+	return StorageConfig{}
+}
+
+// SetStorageConfig updates the retention limits of persisted sessions.
+// The storage directory and on/off switch are fixed at startup.
+func (s *Service) SetStorageConfig(config StorageConfig) error {
+	if s.snapshotStore == nil {
+		return nil
+	}
+	if config.RetentionDays < 0 || config.MaxSizeMB < 0 || config.FullResolutionHours < 0 {
+		return fmt.Errorf("retention limits must not be negative")
+	}
+	current := s.snapshotStore.StorageConfig()
+	if !current.Enabled {
+		return fmt.Errorf("session storage is not enabled")
+	}
+	s.snapshotStore.SetRetention(config.RetentionDays, config.MaxSizeMB, config.FullResolutionHours)
+	s.logger.Info("Storage retention updated: %d days, %d MB, full resolution for %d hours",
+		config.RetentionDays, config.MaxSizeMB, config.FullResolutionHours)
+	return s.saveSettings(func(st *appSettings) {
+		st.Storage = &StorageConfig{
+			RetentionDays:       config.RetentionDays,
+			MaxSizeMB:           config.MaxSizeMB,
+			FullResolutionHours: config.FullResolutionHours,
+		}
+	})
+}
+
+// GetStorageSize returns the bytes used by persisted sessions
+func (s *Service) GetStorageSize() int64 {
+	if s.snapshotStore != nil {
+		return s.snapshotStore.StorageSize()
+	}
+	return 0
+}
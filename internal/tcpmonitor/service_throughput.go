@@ -0,0 +1,58 @@
+package tcpmonitor
+
+import "fmt"
+
+// === Throughput Test Methods (Wails-exposed) ===
+
+// StartThroughputServer listens for throughput tests from another tcpdoctor instance
+func (s *Service) StartThroughputServer(listenAddr string) error {
+	if s.throughput == nil {
+		return fmt.Errorf("throughput tester not available")
+	}
+	if listenAddr == "" {
+		listenAddr = ":5201"
+	}
+	return s.throughput.StartServer(listenAddr)
+}
+
+// StopThroughputServer stops accepting throughput tests
+func (s *Service) StopThroughputServer() {
+	if s.throughput != nil {
+		s.throughput.StopServer()
+	}
+}
+
+// GetThroughputServerStatus returns the server state and reports of recently served streams
+func (s *Service) GetThroughputServerStatus() ThroughputServerStatus {
+	if s.throughput != nil {
+		return s.throughput.ServerStatus()
+	}
+	return ThroughputServerStatus{}
+}
+
+// RunThroughputTest runs a client test against a remote server and returns its report.
+// Blocks for the test duration.
+func (s *Service) RunThroughputTest(config ThroughputTestConfig) (*ThroughputTestReport, error) {
+	if s.throughput == nil {
+		return nil, fmt.Errorf("throughput tester not available")
+	}
+	report, err := s.throughput.RunTest(config)
+	if err != nil {
+		s.logger.Error("Throughput test failed: %v", err)
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetThroughputTestReports returns recent client-side test reports
+func (s *Service) GetThroughputTestReports() []ThroughputTestReport {
+	if s.throughput != nil {
+		return s.throughput.ClientReports()
+	}
+	return nil
+}
+
+// GetDefaultThroughputTestConfig returns the default client test configuration
+func (s *Service) GetDefaultThroughputTestConfig() ThroughputTestConfig {
+	return DefaultThroughputTestConfig()
+}
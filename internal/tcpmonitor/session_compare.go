@@ -0,0 +1,429 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"tcpdoctor/internal/llm"
+)
+
+const (
+	// Significance level of the RTT test
+	comparisonAlpha = 0.05
+
+	// Groups with fewer RTT samples on either side are not tested
+	minSignificanceSamples = 8
+
+	// RTT samples per side are thinned to this many before testing.
+	// Successive samples of a connection are strongly correlated, so
+	// thinning also keeps large sessions from overstating significance.
+	maxSignificanceSamples = 5000
+
+	// Relative changes below this count as unchanged
+	comparisonTolerance = 0.05
+)
+
+// Metrics compared between sessions. Direction is -1 when lower is better,
+// 0 when neither is; changes smaller than minDelta count as unchanged.
+var comparedMetrics = []struct {
+	name      string
+	direction int
+	minDelta  float64
+	value     func(st *llm.SessionGroupStats) float64
+}{
+	{"rtt_p50_ms", -1, 1, func(st *llm.SessionGroupStats) float64 { return st.RTTP50Ms }},
+	{"rtt_p90_ms", -1, 1, func(st *llm.SessionGroupStats) float64 { return st.RTTP90Ms }},
+	{"rtt_p99_ms", -1, 1, func(st *llm.SessionGroupStats) float64 { return st.RTTP99Ms }},
+	{"rtt_mean_ms", -1, 1, func(st *llm.SessionGroupStats) float64 { return st.RTTMeanMs }},
+	{"loss_rate_pct", -1, 0.1, func(st *llm.SessionGroupStats) float64 { return st.LossRate }},
+	{"throughput_in_bps", 0, 0, func(st *llm.SessionGroupStats) float64 { return st.ThroughputIn }},
+	{"throughput_out_bps", 0, 0, func(st *llm.SessionGroupStats) float64 { return st.ThroughputOut }},
+	{"churn_per_minute", -1, 1, func(st *llm.SessionGroupStats) float64 { return st.ChurnPerMinute }},
+	{"connections", 0, 0, func(st *llm.SessionGroupStats) float64 { return float64(st.Connections) }},
+}
+
+// comparisonGroup collects the samples of one group of connections
+type comparisonGroup struct {
+	label    string
+	conns    int
+	rtts     []float64
+	retrans  int64
+	segsOut  int64
+	bytesIn  int64
+	bytesOut int64
+	opened   int
+	closed   int
+}
+
+// comparisonSide holds one session's groups
+type comparisonSide struct {
+	duration time.Duration
+	overall  *comparisonGroup
+	groups   map[string]*comparisonGroup
+}
+
+// CompareSessions compares session b against session a, overall and per
+// group of connections (empty groupBy for overall only), reporting RTT
+// percentiles, loss, throughput and churn with a Mann-Whitney U test on
+// the RTT samples
+func (s *Service) CompareSessions(a, b int64, groupBy string) (*llm.SessionComparison, error) {
+	if s.snapshotStore == nil {
+		return nil, fmt.Errorf("snapshot store not available")
+	}
+	if groupBy != "" {
+		if _, _, err := summaryGroupKey(&llm.ConnectionSummary{}, groupBy); err != nil {
+			return nil, err
+		}
+	}
+
+	sideA, err := s.collectComparisonSide(a, groupBy)
+	if err != nil {
+		return nil, err
+	}
+	sideB, err := s.collectComparisonSide(b, groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &llm.SessionComparison{
+		SessionA: a,
+		SessionB: b,
+		GroupBy:  groupBy,
+		Overall:  compareGroups("", "All connections", sideA.overall, sideA.duration, sideB.overall, sideB.duration),
+	}
+	if groupBy != "" {
+		keys := make(map[string]bool)
+		for key := range sideA.groups {
+			keys[key] = true
+		}
+		for key := range sideB.groups {
+			keys[key] = true
+		}
+		for key := range keys {
+			ga, gb := sideA.groups[key], sideB.groups[key]
+			label := ""
+			if ga != nil {
+				label = ga.label
+			} else {
+				label = gb.label
+			}
+			result.Groups = append(result.Groups, compareGroups(key, label, ga, sideA.duration, gb, sideB.duration))
+		}
+		sort.Slice(result.Groups, func(i, j int) bool {
+			ci := result.Groups[i].A.Connections + result.Groups[i].B.Connections
+			cj := result.Groups[j].A.Connections + result.Groups[j].B.Connections
+			if ci != cj {
+				return ci > cj
+			}
+			return result.Groups[i].Key < result.Groups[j].Key
+		})
+	}
+	result.Summary = describeComparison(result)
+	return result, nil
+}
+
+// collectComparisonSide gathers a session's samples overall and by group
+func (s *Service) collectComparisonSide(sessionID int64, groupBy string) (*comparisonSide, error) {
+	timeline := s.snapshotStore.GetSessionTimeline(sessionID)
+	if len(timeline) == 0 {
+		return nil, fmt.Errorf("session %d not found or empty", sessionID)
+	}
+
+	first, last := timeline[0].Timestamp, timeline[0].Timestamp
+	for i := range timeline {
+		if timeline[i].Timestamp.Before(first) {
+			first = timeline[i].Timestamp
+		}
+		if timeline[i].Timestamp.After(last) {
+			last = timeline[i].Timestamp
+		}
+	}
+
+	side := &comparisonSide{
+		duration: last.Sub(first),
+		overall:  &comparisonGroup{},
+		groups:   make(map[string]*comparisonGroup),
+	}
+	for _, rows := range connectionTimelines(timeline) {
+		side.overall.add(rows, first, last)
+		if groupBy == "" {
+			continue
+		}
+
+		summary := s.buildSessionConnectionSummary(rows)
+		key, label, err := summaryGroupKey(&summary.ConnectionSummary, groupBy)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			label = "unknown"
+		}
+		g, ok := side.groups[key]
+		if !ok {
+			g = &comparisonGroup{label: label}
+			side.groups[key] = g
+		}
+		g.add(rows, first, last)
+	}
+	return side, nil
+}
+
+// add accumulates the rows of one connection, recorded in a session
+// running from first to last
+func (g *comparisonGroup) add(rows []TimelineConnection, first, last time.Time) {
+	g.conns++
+	for i := range rows {
+		if rtt := rows[i].Connection.RTT; rtt > 0 {
+			g.rtts = append(g.rtts, float64(rtt))
+		}
+	}
+
+	start, end := rows[0], rows[len(rows)-1]
+	g.retrans += max(end.Connection.SegsRetrans-start.Connection.SegsRetrans, 0)
+	g.segsOut += max(end.Connection.SegmentsOut-start.Connection.SegmentsOut, 0)
+	g.bytesIn += max(end.Connection.BytesIn-start.Connection.BytesIn, 0)
+	g.bytesOut += max(end.Connection.BytesOut-start.Connection.BytesOut, 0)
+	if start.Timestamp.After(first) {
+		g.opened++
+	}
+	if end.Timestamp.Before(last) {
+		g.closed++
+	}
+}
+
+// stats summarizes a group over a session of the given duration
+func (g *comparisonGroup) stats(duration time.Duration) llm.SessionGroupStats {
+	if g == nil {
+		return llm.SessionGroupStats{}
+	}
+	st := llm.SessionGroupStats{
+		Connections: g.conns,
+		RTTSamples:  len(g.rtts),
+		RTTMeanMs:   avgFloat64(g.rtts),
+		RTTP50Ms:    percentileFloat64(g.rtts, 50),
+		RTTP90Ms:    percentileFloat64(g.rtts, 90),
+		RTTP99Ms:    percentileFloat64(g.rtts, 99),
+		Opened:      g.opened,
+		Closed:      g.closed,
+	}
+	if g.segsOut > 0 {
+		st.LossRate = float64(g.retrans) / float64(g.segsOut) * 100
+	}
+	if seconds := duration.Seconds(); seconds > 0 {
+		st.ThroughputIn = float64(g.bytesIn) / seconds
+		st.ThroughputOut = float64(g.bytesOut) / seconds
+		st.ChurnPerMinute = float64(g.opened+g.closed) / (seconds / 60)
+	}
+	return st
+}
+
+// compareGroups compares one group across the two sessions; either side
+// may be nil when the group only appears in one of them
+func compareGroups(key, label string, a *comparisonGroup, durationA time.Duration, b *comparisonGroup, durationB time.Duration) llm.GroupComparison {
+	cmp := llm.GroupComparison{
+		Key:   key,
+		Label: label,
+		A:     a.stats(durationA),
+		B:     b.stats(durationB),
+	}
+	for _, m := range comparedMetrics {
+		cmp.Deltas = append(cmp.Deltas, metricDelta(m.name, m.value(&cmp.A), m.value(&cmp.B), m.direction, m.minDelta))
+	}
+
+	switch {
+	case a == nil:
+		cmp.Verdict = "only_b"
+		return cmp
+	case b == nil:
+		cmp.Verdict = "only_a"
+		return cmp
+	}
+	cmp.RTTTest = mannWhitneyU(a.rtts, b.rtts)
+
+	// RTT counts when the shift is significant and large enough to show in
+	// the median, the 90th percentile or the mean
+	var shifted string
+	if cmp.RTTTest != nil && cmp.RTTTest.Significant {
+		shifted = "worse"
+		if cmp.RTTTest.ProbabilityLower > 0.5 {
+			shifted = "better"
+		}
+	}
+	var rtt, loss string
+	for _, d := range cmp.Deltas {
+		switch d.Metric {
+		case "rtt_p50_ms", "rtt_p90_ms", "rtt_mean_ms":
+			if shifted != "" && d.Change == shifted {
+				rtt = shifted
+			}
+		case "loss_rate_pct":
+			if d.Change != "unchanged" {
+				loss = d.Change
+			}
+		}
+	}
+	switch {
+	case rtt == "" && loss == "":
+		cmp.Verdict = "unchanged"
+	case rtt != "" && loss != "" && rtt != loss:
+		cmp.Verdict = "mixed"
+	case rtt != "":
+		cmp.Verdict = rtt
+	default:
+		cmp.Verdict = loss
+	}
+	return cmp
+}
+
+// metricDelta describes the change of a metric from a to b
+func metricDelta(name string, a, b float64, direction int, minDelta float64) llm.MetricDelta {
+	d := llm.MetricDelta{Metric: name, A: a, B: b, Delta: b - a}
+	if a != 0 {
+		d.PercentChange = (b - a) / math.Abs(a) * 100
+	}
+	if direction == 0 {
+		return d
+	}
+
+	largest := math.Max(math.Abs(a), math.Abs(b))
+	switch {
+	case math.Abs(d.Delta) < minDelta || largest == 0 || math.Abs(d.Delta)/largest < comparisonTolerance:
+		d.Change = "unchanged"
+	case (d.Delta < 0) == (direction < 0):
+		d.Change = "better"
+	default:
+		d.Change = "worse"
+	}
+	return d
+}
+
+// mannWhitneyU tests whether the b samples tend to differ from the a
+// samples, using the normal approximation with tie and continuity
+// corrections. It returns nil when either side has too few samples.
+func mannWhitneyU(a, b []float64) *llm.SignificanceResult {
+	a = thinSamples(a, maxSignificanceSamples)
+	b = thinSamples(b, maxSignificanceSamples)
+	if len(a) < minSignificanceSamples || len(b) < minSignificanceSamples {
+		return nil
+	}
+
+	type sample struct {
+		value float64
+		fromA bool
+	}
+	all := make([]sample, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range b {
+		all = append(all, sample{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	// Tied values share the average of their ranks
+	var rankSumA, ties float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	n1, n2 := float64(len(a)), float64(len(b))
+	n := n1 + n2
+	uA := rankSumA - n1*(n1+1)/2 // Pairs with the A sample higher, ties counting half
+	result := &llm.SignificanceResult{
+		Test:             "mann-whitney-u",
+		SamplesA:         len(a),
+		SamplesB:         len(b),
+		U:                math.Min(uA, n1*n2-uA),
+		PValue:           1,
+		ProbabilityLower: uA / (n1 * n2),
+	}
+
+	variance := n1 * n2 / 12 * (n + 1 - ties/(n*(n-1)))
+	if variance > 0 {
+		shift := math.Max(math.Abs(uA-n1*n2/2)-0.5, 0)
+		result.Z = shift / math.Sqrt(variance)
+		if uA < n1*n2/2 {
+			result.Z = -result.Z
+		}
+		result.PValue = math.Erfc(math.Abs(result.Z) / math.Sqrt2)
+	}
+	result.Significant = result.PValue < comparisonAlpha
+	return result
+}
+
+// thinSamples returns at most limit evenly spaced values
+func thinSamples(values []float64, limit int) []float64 {
+	if len(values) <= limit {
+		return values
+	}
+	thinned := make([]float64, limit)
+	for i := range thinned {
+		thinned[i] = values[i*len(values)/limit]
+	}
+	return thinned
+}
+
+// describeComparison summarizes a comparison in one line
+func describeComparison(c *llm.SessionComparison) string {
+	o := c.Overall
+	var parts []string
+	if o.A.RTTSamples > 0 && o.B.RTTSamples > 0 {
+		part := fmt.Sprintf("RTT p50 %.1f → %.1f ms", o.A.RTTP50Ms, o.B.RTTP50Ms)
+		if o.A.RTTP50Ms > 0 {
+			part += fmt.Sprintf(" (%+.0f%%", (o.B.RTTP50Ms-o.A.RTTP50Ms)/o.A.RTTP50Ms*100)
+			if o.RTTTest != nil {
+				part += ", " + describeSignificance(o.RTTTest)
+			}
+			part += ")"
+		}
+		parts = append(parts, part)
+	}
+	parts = append(parts, fmt.Sprintf("loss %.2f%% → %.2f%%", o.A.LossRate, o.B.LossRate))
+	parts = append(parts, fmt.Sprintf("connections %d → %d", o.A.Connections, o.B.Connections))
+	summary := fmt.Sprintf("Session %d → %d %s: %s", c.SessionA, c.SessionB, o.Verdict, strings.Join(parts, ", "))
+
+	if len(c.Groups) > 0 {
+		counts := make(map[string]int)
+		for _, g := range c.Groups {
+			counts[g.Verdict]++
+		}
+		var verdicts []string
+		for _, v := range []struct{ key, text string }{
+			{"better", "better"}, {"worse", "worse"}, {"mixed", "mixed"},
+			{"unchanged", "unchanged"}, {"only_a", "only in A"}, {"only_b", "only in B"},
+		} {
+			if counts[v.key] > 0 {
+				verdicts = append(verdicts, fmt.Sprintf("%d %s", counts[v.key], v.text))
+			}
+		}
+		summary += fmt.Sprintf(". By %s: %s", c.GroupBy, strings.Join(verdicts, ", "))
+	}
+	return summary
+}
+
+// describeSignificance phrases a test result, e.g. "significant, p<0.001"
+func describeSignificance(t *llm.SignificanceResult) string {
+	p := fmt.Sprintf("p=%.3f", t.PValue)
+	if t.PValue < 0.001 {
+		p = "p<0.001"
+	}
+	if t.Significant {
+		return "significant, " + p
+	}
+	return "not significant, " + p
+}
@@ -0,0 +1,93 @@
+package tcpmonitor
+
+import (
+	"math"
+	"testing"
+)
+
+// seq returns the integers from lo to hi as samples
+func seq(lo, hi int) []float64 {
+	var values []float64
+	for v := lo; v <= hi; v++ {
+		values = append(values, float64(v))
+	}
+	return values
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	// Reference values from the normal approximation with tie and
+	// continuity corrections (scipy.stats.mannwhitneyu, method="asymptotic")
+	tests := []struct {
+		name             string
+		a, b             []float64
+		u, z, p          float64
+		probabilityLower float64
+		significant      bool
+	}{
+		{
+			name: "b higher",
+			a:    seq(1, 10), b: seq(11, 20),
+			u: 0, z: -3.7418482827913495, p: 0.0001826717911095504,
+			probabilityLower: 0, significant: true,
+		},
+		{
+			name: "b lower",
+			a:    seq(11, 20), b: seq(1, 10),
+			u: 0, z: 3.7418482827913495, p: 0.0001826717911095504,
+			probabilityLower: 1, significant: true,
+		},
+		{
+			name: "ties",
+			a:    []float64{1, 2, 2, 3, 3, 3, 4, 5},
+			b:    []float64{3, 4, 4, 5, 5, 6, 6, 7},
+			u:    7.5, z: -2.5584085962673253, p: 0.010515245935858918,
+			probabilityLower: 0.1171875, significant: true,
+		},
+		{
+			name: "interleaved",
+			a:    []float64{3.1, 2.2, 4.5, 3.3, 2.8, 3.9, 4.1, 3.0, 2.5, 3.6},
+			b:    []float64{3.4, 2.9, 4.4, 3.2, 2.7, 4.0, 3.8, 3.05, 2.6, 3.5},
+			u:    48, z: -0.11338934190276817, p: 0.9097218891455553,
+			probabilityLower: 0.48, significant: false,
+		},
+		{
+			name: "all tied",
+			a:    []float64{5, 5, 5, 5, 5, 5, 5, 5},
+			b:    []float64{5, 5, 5, 5, 5, 5, 5, 5},
+			u:    32, z: 0, p: 1,
+			probabilityLower: 0.5, significant: false,
+		},
+	}
+
+	const epsilon = 1e-9
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mannWhitneyU(tt.a, tt.b)
+			if r == nil {
+				t.Fatal("no result")
+			}
+			if r.SamplesA != len(tt.a) || r.SamplesB != len(tt.b) {
+				t.Errorf("samples = %d, %d, want %d, %d", r.SamplesA, r.SamplesB, len(tt.a), len(tt.b))
+			}
+			if math.Abs(r.U-tt.u) > epsilon || math.Abs(r.Z-tt.z) > epsilon || math.Abs(r.PValue-tt.p) > epsilon {
+				t.Errorf("U = %v, Z = %v, p = %v; want %v, %v, %v", r.U, r.Z, r.PValue, tt.u, tt.z, tt.p)
+			}
+			if math.Abs(r.ProbabilityLower-tt.probabilityLower) > epsilon {
+				t.Errorf("ProbabilityLower = %v, want %v", r.ProbabilityLower, tt.probabilityLower)
+			}
+			if r.Significant != tt.significant {
+				t.Errorf("Significant = %v, want %v", r.Significant, tt.significant)
+			}
+		})
+	}
+
+	// Swapping the sides mirrors the direction but not U or p
+	ab, ba := mannWhitneyU(tests[2].a, tests[2].b), mannWhitneyU(tests[2].b, tests[2].a)
+	if ab.U != ba.U || ab.PValue != ba.PValue || ab.Z != -ba.Z || math.Abs(ab.ProbabilityLower+ba.ProbabilityLower-1) > epsilon {
+		t.Errorf("swapped sides: %+v vs %+v", ab, ba)
+	}
+
+	if r := mannWhitneyU(seq(1, minSignificanceSamples-1), seq(1, 20)); r != nil {
+		t.Errorf("got %+v from %d samples, want nil", r, minSignificanceSamples-1)
+	}
+}
@@ -0,0 +1,474 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// === Session Management ===
+
+// SearchSessions returns the sessions matching a query. Every
+// whitespace-separated term must match: "tag:x" matches a tag exactly, any
+// other term is a case-insensitive substring of the name, description, tags,
+// source host or ID. An empty query returns every session.
+func (s *SnapshotStore) SearchSessions(query string) []RecordingSession {
+	terms := strings.Fields(strings.ToLower(query))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]RecordingSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		if sessionMatches(&session, terms) {
+			result = append(result, session)
+		}
+	}
+	return result
+}
+
+// sessionMatches reports whether a session matches every search term
+func sessionMatches(session *RecordingSession, terms []string) bool {
+	for _, term := range terms {
+		if tag, ok := strings.CutPrefix(term, "tag:"); ok {
+			found := false
+			for _, t := range session.Tags {
+				if strings.ToLower(t) == tag {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+			continue
+		}
+
+		fields := []string{
+			session.Name,
+			session.Description,
+			session.ImportedFrom,
+			strconv.FormatInt(session.ID, 10),
+			strings.Join(session.Tags, " "),
+		}
+		found := false
+		for _, field := range fields {
+			if strings.Contains(strings.ToLower(field), term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// SetSessionInfo names, describes and tags a session. Tags are trimmed and
+// deduplicated.
+func (s *SnapshotStore) SetSessionInfo(sessionID int64, name, description string, tags []string) (*RecordingSession, error) {
+	var cleaned []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		cleaned = append(cleaned, tag)
+	}
+	name = strings.TrimSpace(name)
+	description = strings.TrimSpace(description)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.sessions {
+		if s.sessions[i].ID != sessionID {
+			continue
+		}
+		if s.disk != nil {
+			if err := s.disk.SetSessionInfo(sessionID, name, description, cleaned); err != nil {
+				return nil, err
+			}
+		}
+		s.sessions[i].Name = name
+		s.sessions[i].Description = description
+		s.sessions[i].Tags = cleaned
+		session := s.sessions[i]
+		return &session, nil
+	}
+	return nil, fmt.Errorf("session %d not found", sessionID)
+}
+
+// DeleteSession removes one session with its snapshots, probe results and
+// rollups. The session being recorded cannot be deleted.
+func (s *SnapshotStore) DeleteSession(sessionID int64) error {
+	return s.applyEdit(&sessionEdit{
+		affected: []int64{sessionID},
+		route:    func(int64, time.Time) int64 { return 0 },
+	})
+}
+
+// TrimSession keeps only what a session recorded between start and end
+// inclusive; a zero time leaves that end of the range open
+func (s *SnapshotStore) TrimSession(sessionID int64, start, end time.Time) (*RecordingSession, error) {
+	session := s.GetSessionByID(sessionID)
+	if session == nil {
+		return nil, fmt.Errorf("session %d not found", sessionID)
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return nil, fmt.Errorf("trim range ends before it starts")
+	}
+
+	trimmed := *session
+	if !start.IsZero() && start.After(trimmed.StartTime) {
+		trimmed.StartTime = start
+	}
+	if !end.IsZero() && end.Before(trimmed.EndTime) {
+		trimmed.EndTime = end
+	}
+	err := s.applyEdit(&sessionEdit{
+		affected: []int64{sessionID},
+		result:   []RecordingSession{trimmed},
+		route: func(_ int64, t time.Time) int64 {
+			if (!start.IsZero() && t.Before(start)) || (!end.IsZero() && t.After(end)) {
+				return 0
+			}
+			return sessionID
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetSessionByID(sessionID), nil
+}
+
+// SplitSession splits a session at a timestamp. The session keeps what it
+// recorded before at; the rest moves to a new session, which is returned.
+func (s *SnapshotStore) SplitSession(sessionID int64, at time.Time) (*RecordingSession, error) {
+	session := s.GetSessionByID(sessionID)
+	if session == nil {
+		return nil, fmt.Errorf("session %d not found", sessionID)
+	}
+	if !at.After(session.StartTime) || !at.Before(session.EndTime) {
+		return nil, fmt.Errorf("split time must fall within the session")
+	}
+
+	first, second := *session, *session
+	first.EndTime = at
+	second.ID = s.reserveSessionID()
+	second.StartTime = at
+	if second.Name != "" {
+		second.Name += " (2)"
+	}
+	err := s.applyEdit(&sessionEdit{
+		affected: []int64{sessionID},
+		result:   []RecordingSession{first, second},
+		route: func(_ int64, t time.Time) int64 {
+			if t.Before(at) {
+				return first.ID
+			}
+			return second.ID
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetSessionByID(second.ID), nil
+}
+
+// MergeSessions joins two sessions that follow each other, with no other
+// session starting between them, into the earlier one. The sessions must not
+// overlap in time and must have been recorded with the same scope.
+func (s *SnapshotStore) MergeSessions(firstID, secondID int64) (*RecordingSession, error) {
+	s.mu.RLock()
+	ordered := make([]RecordingSession, len(s.sessions))
+	copy(ordered, s.sessions)
+	s.mu.RUnlock()
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].StartTime.Before(ordered[j].StartTime) })
+
+	i := -1
+	for k := range ordered {
+		if ordered[k].ID == firstID || ordered[k].ID == secondID {
+			i = k
+			break
+		}
+	}
+	if i < 0 || i+1 >= len(ordered) || firstID == secondID ||
+		(ordered[i+1].ID != firstID && ordered[i+1].ID != secondID) {
+		return nil, fmt.Errorf("sessions %d and %d are not adjacent", firstID, secondID)
+	}
+	first, second := ordered[i], ordered[i+1]
+	if !first.EndTime.IsZero() && second.StartTime.Before(first.EndTime) {
+		return nil, fmt.Errorf("sessions %d and %d overlap", first.ID, second.ID)
+	}
+	if !sameScope(first.Scope, second.Scope) {
+		return nil, fmt.Errorf("sessions %d and %d were recorded with different scopes", first.ID, second.ID)
+	}
+
+	merged := first
+	merged.EndTime = second.EndTime
+	if merged.Name == "" {
+		merged.Name = second.Name
+	}
+	if merged.Description == "" {
+		merged.Description = second.Description
+	}
+	merged.Tags = append(append([]string(nil), first.Tags...), second.Tags...)
+	merged.HealthThresholds = append(append([]ThresholdChange(nil), first.HealthThresholds...), second.HealthThresholds...)
+	merged.Recovered = first.Recovered || second.Recovered
+	if second.DownsampledUntil.After(merged.DownsampledUntil) {
+		merged.DownsampledUntil = second.DownsampledUntil
+	}
+
+	err := s.applyEdit(&sessionEdit{
+		affected: []int64{first.ID, second.ID},
+		result:   []RecordingSession{merged},
+		route:    func(int64, time.Time) int64 { return merged.ID },
+	})
+	if err != nil {
+		return nil, err
+	}
+	info := s.GetSessionByID(merged.ID)
+	// Deduplicate the combined tags
+	return s.SetSessionInfo(merged.ID, info.Name, info.Description, info.Tags)
+}
+
+// reserveSessionID allocates a session ID for a session created by an edit
+func (s *SnapshotStore) reserveSessionID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextSessionID
+	s.nextSessionID++
+	return id
+}
+
+// sessionEdit is a structural change to closed sessions. route maps every
+// snapshot, probe result, rollup and annotation of the affected sessions to
+// the session it ends up in, or to 0 to drop it; result holds the metadata
+// of those sessions. Snapshot counts are filled in by applyEdit.
+type sessionEdit struct {
+	affected []int64
+	result   []RecordingSession
+	route    func(sessionID int64, timestamp time.Time) int64
+}
+
+// sessionContent is everything recorded in one session
+type sessionContent struct {
+	session     RecordingSession
+	snapshots   []Snapshot
+	probes      []ProbeResult
+	rollups     []ConnectionRollup
+	annotations []Annotation
+}
+
+// applyEdit carries out a session edit. Content is read, rewritten on disk
+// and re-encoded in memory without holding the store lock; only the final
+// swap takes it, so recording continues meanwhile.
+func (s *SnapshotStore) applyEdit(edit *sessionEdit) error {
+	s.editMu.Lock()
+	defer s.editMu.Unlock()
+
+	affected := make(map[int64]bool)
+	s.mu.RLock()
+	for _, id := range edit.affected {
+		found := false
+		for i := range s.sessions {
+			if s.sessions[i].ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.mu.RUnlock()
+			return fmt.Errorf("session %d not found", id)
+		}
+		if s.recordingLocked(id) != nil {
+			s.mu.RUnlock()
+			return fmt.Errorf("session %d is being recorded; stop recording first", id)
+		}
+		affected[id] = true
+	}
+	s.mu.RUnlock()
+
+	// Route the content of the affected sessions
+	contents := make(map[int64]*sessionContent)
+	for i := range edit.result {
+		session := edit.result[i]
+		session.SnapshotCount = 0
+		contents[session.ID] = &sessionContent{session: session}
+	}
+	for _, id := range edit.affected {
+		_, snapshots, probes := s.GetSessionData(id)
+		for _, snap := range snapshots {
+			if to := edit.route(id, snap.Timestamp); to != 0 {
+				snap.SessionID = to
+				contents[to].snapshots = append(contents[to].snapshots, snap)
+			}
+		}
+		for _, r := range probes {
+			if to := edit.route(id, r.Timestamp); to != 0 {
+				r.SessionID = to
+				contents[to].probes = append(contents[to].probes, r)
+			}
+		}
+		for _, tier := range rollupTiers {
+			for _, r := range s.GetSessionRollups(id, tier.resolution, time.Time{}, time.Time{}) {
+				if to := edit.route(id, r.Start); to != 0 {
+					r.SessionID = to
+					contents[to].rollups = append(contents[to].rollups, r)
+				}
+			}
+		}
+		annotations, err := s.loadAnnotations(id)
+		if err != nil {
+			return err
+		}
+		for _, a := range annotations {
+			if to := edit.route(id, a.Timestamp); to != 0 {
+				a.SessionID = to
+				contents[to].annotations = append(contents[to].annotations, a)
+			}
+		}
+	}
+	for _, content := range contents {
+		content.session.SnapshotCount = len(content.snapshots)
+		// Keep the stored rollups even when empty rather than recomputing
+		// them from snapshots that may have been downsampled
+		if content.rollups == nil {
+			content.rollups = []ConnectionRollup{}
+		}
+		// Merged sessions may bring the same annotation IDs
+		sortAnnotations(content.annotations)
+		for i := range content.annotations {
+			content.annotations[i].ID = int64(i + 1)
+		}
+	}
+
+	if s.disk != nil {
+		for _, session := range edit.result {
+			c := contents[session.ID]
+			if err := s.disk.ReplaceSession(c.session, c.snapshots, c.probes, c.rollups); err != nil {
+				return fmt.Errorf("failed to rewrite session %d: %w", session.ID, err)
+			}
+			if err := s.disk.SaveAnnotations(session.ID, c.annotations); err != nil {
+				return fmt.Errorf("failed to rewrite annotations of session %d: %w", session.ID, err)
+			}
+		}
+		for _, id := range edit.affected {
+			if _, kept := contents[id]; !kept {
+				if err := s.disk.DeleteSession(id); err != nil {
+					return fmt.Errorf("failed to delete session %d: %w", id, err)
+				}
+			}
+		}
+	}
+
+	// Re-encode the in-memory snapshots of the affected sessions from views
+	s.mu.RLock()
+	var views []*logView
+	for id := range affected {
+		if l := s.snapshots.logs[id]; l != nil {
+			views = append(views, l.view(l.allRuns()))
+		}
+	}
+	s.mu.RUnlock()
+
+	rebuilt := make(map[int64]*snapshotLog)
+	eachSnapshot(views, func(snap *Snapshot) {
+		if snap.SessionID = edit.route(snap.SessionID, snap.Timestamp); snap.SessionID == 0 {
+			return
+		}
+		l := rebuilt[snap.SessionID]
+		if l == nil {
+			l = newSnapshotLog()
+			rebuilt[snap.SessionID] = l
+		}
+		l.Append(snap)
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(views) > 0 {
+		// Snapshots dropped from memory while rebuilding were the oldest
+		// held, so whatever is older than the oldest one left went too
+		oldest := int64(-1)
+		for _, l := range s.snapshots.logs {
+			if oldest < 0 || l.frames[0].id < oldest {
+				oldest = l.frames[0].id
+			}
+		}
+		for _, l := range rebuilt {
+			for l.Len() > 0 && (oldest < 0 || l.frames[0].id < oldest) {
+				l.DropOldest()
+			}
+		}
+		for id := range affected {
+			if _, ok := rebuilt[id]; !ok {
+				rebuilt[id] = nil
+			}
+		}
+		s.snapshots.Replace(rebuilt)
+	}
+
+	probes := s.probes[:0]
+	for _, r := range s.probes {
+		if affected[r.SessionID] {
+			if r.SessionID = edit.route(r.SessionID, r.Timestamp); r.SessionID == 0 {
+				continue
+			}
+		}
+		probes = append(probes, r)
+	}
+	s.probes = probes
+
+	for tier := range s.rollups {
+		rollups := s.rollups[tier][:0]
+		for _, r := range s.rollups[tier] {
+			if affected[r.SessionID] {
+				if r.SessionID = edit.route(r.SessionID, r.Start); r.SessionID == 0 {
+					continue
+				}
+			}
+			rollups = append(rollups, r)
+		}
+		s.rollups[tier] = rollups
+	}
+
+	if s.disk == nil {
+		for id := range affected {
+			delete(s.annotations, id)
+		}
+		for _, result := range edit.result {
+			if annotations := contents[result.ID].annotations; len(annotations) > 0 {
+				s.annotations[result.ID] = annotations
+			}
+		}
+	}
+
+	// The resulting sessions take the place of the first affected one
+	var sessions []RecordingSession
+	inserted := false
+	for _, session := range s.sessions {
+		if !affected[session.ID] {
+			sessions = append(sessions, session)
+			continue
+		}
+		if !inserted {
+			for _, result := range edit.result {
+				sessions = append(sessions, contents[result.ID].session)
+			}
+			inserted = true
+		}
+	}
+	s.sessions = sessions
+	if s.disk != nil {
+		s.syncSessions()
+	}
+	return nil
+}
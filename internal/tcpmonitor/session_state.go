@@ -0,0 +1,96 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"tcpdoctor/internal/llm"
+)
+
+// SessionState is the connection table of a recorded session at an instant
+type SessionState struct {
+	SessionID   int64            `json:"sessionId"`
+	Requested   time.Time        `json:"requested"`
+	SnapshotID  int64            `json:"snapshotId"`
+	Timestamp   time.Time        `json:"timestamp"`  // When the snapshot the table is built from was taken
+	Thresholds  HealthThresholds `json:"thresholds"` // Health thresholds in effect at Timestamp
+	Total       int              `json:"total"`      // Connections in the snapshot before filtering
+	Connections []ConnectionInfo `json:"connections"`
+}
+
+// GetStateAt rebuilds the connection table of a session as it was at t
+// from the nearest snapshot. Health flags are recomputed with the
+// thresholds in effect at the time and the filter is applied as by
+// GetConnections. Findings that need a connection's history (MSS, buffers,
+// stalls, window limitation) are not reconstructed.
+func (s *Service) GetStateAt(sessionID int64, t time.Time, filter FilterOptions) (*SessionState, error) {
+	if s.snapshotStore == nil {
+		return nil, fmt.Errorf("snapshot store not available")
+	}
+	session := s.snapshotStore.GetSessionByID(sessionID)
+	if session == nil {
+		return nil, fmt.Errorf("session %d not found", sessionID)
+	}
+	snap := s.snapshotStore.SnapshotAt(sessionID, t)
+	if snap == nil {
+		return nil, fmt.Errorf("session %d has no snapshots", sessionID)
+	}
+
+	state := &SessionState{
+		SessionID:  sessionID,
+		Requested:  t,
+		SnapshotID: snap.ID,
+		Timestamp:  snap.Timestamp,
+		Thresholds: session.ThresholdsAt(snap.Timestamp, s.GetHealthThresholds()),
+		Total:      len(snap.Connections),
+	}
+
+	connections := make([]ConnectionInfo, len(snap.Connections))
+	for i := range snap.Connections {
+		connections[i] = expandConnection(&snap.Connections[i])
+		connections[i].LastSeen = snap.Timestamp
+		CalculateHealth(&connections[i], state.Thresholds)
+	}
+	state.Connections = s.filterEngine.Apply(connections, filter)
+	return state, nil
+}
+
+// maxToolStateConnections limits the connections a get_state_at result lists
+const maxToolStateConnections = 30
+
+// stateAtSummary condenses a session state for the AI: connections with
+// warnings first, then by bytes transferred, up to limit
+func (s *Service) stateAtSummary(state *SessionState, limit int) *llm.SessionStateAt {
+	result := &llm.SessionStateAt{
+		SessionID:    state.SessionID,
+		Requested:    state.Requested.Format(time.RFC3339),
+		SnapshotTime: state.Timestamp.Format(time.RFC3339),
+		OffsetSec:    state.Timestamp.Sub(state.Requested).Seconds(),
+		Total:        state.Total,
+		Matched:      len(state.Connections),
+		Connections:  make([]llm.ConnectionSummary, 0, len(state.Connections)),
+	}
+	for i := range state.Connections {
+		summary := s.buildConnectionSummary(&state.Connections[i])
+		if summary.HasWarning {
+			result.Warnings++
+		}
+		result.Connections = append(result.Connections, summary)
+	}
+
+	sort.SliceStable(result.Connections, func(i, j int) bool {
+		a, b := &result.Connections[i], &result.Connections[j]
+		if a.HasWarning != b.HasWarning {
+			return a.HasWarning
+		}
+		return a.BytesIn+a.BytesOut > b.BytesIn+b.BytesOut
+	})
+	if limit <= 0 || limit > maxToolStateConnections {
+		limit = maxToolStateConnections
+	}
+	if len(result.Connections) > limit {
+		result.Connections = result.Connections[:limit]
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package tcpmonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// appSettings are the user-edited settings restored on startup. Nil fields
+// were never changed and keep their defaults.
+type appSettings struct {
+	Resolver       *ResolverConfig       `json:"resolver,omitempty"`
+	GeoIP          *GeoIPConfig          `json:"geoip,omitempty"`
+	ServiceCatalog []ServiceCatalogEntry `json:"serviceCatalog,omitempty"`
+	Storage        *StorageConfig        `json:"storage,omitempty"`
+	FlightRecorder *FlightRecorderConfig `json:"flightRecorder,omitempty"`
+	Prober         *ProberConfig         `json:"prober,omitempty"`
+}
+
+// defaultSettingsFile returns the per-user file holding app settings
+func defaultSettingsFile() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "tcpdoctor", "settings.json"), nil
+}
+
+// settingsStore keeps app settings in memory and mirrors them to disk
+type settingsStore struct {
+	mu       sync.Mutex
+	path     string // Where settings are saved, empty to keep them in memory
+	settings appSettings
+}
+
+// loadSettings reads the settings saved at path. On error the store still
+// works but keeps settings in memory only.
+func loadSettings(path string) (*settingsStore, error) {
+	st := &settingsStore{}
+	if path == "" {
+		return st, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		st.path = path
+		return st, nil
+	}
+	if err != nil {
+		return st, fmt.Errorf("failed to read settings: %w", err)
+	}
+	if err := json.Unmarshal(data, &st.settings); err != nil {
+		return st, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	st.path = path
+	return st, nil
+}
+
+// Get returns a copy of the current settings
+func (st *settingsStore) Get() appSettings {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.settings
+}
+
+// Update applies fn to the settings and saves them to disk
+func (st *settingsStore) Update(fn func(*appSettings)) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	fn(&st.settings)
+	return st.saveLocked()
+}
+
+// saveLocked writes the settings to disk.
+// Must be called with st.mu held.
+func (st *settingsStore) saveLocked() error {
+	if st.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+	data, err := json.MarshalIndent(st.settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := st.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write settings: %w", err)
+	}
+	return os.Rename(tmp, st.path)
+}
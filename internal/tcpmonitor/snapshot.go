@@ -1,11 +1,15 @@
 package tcpmonitor
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
-// CompactConnection stores data for snapshots - expanded for full history
+// CompactConnection stores data for snapshots - expanded for full history.
+// New fields also need a column in snapshot_log.go (packRow/unpackRow).
 type CompactConnection struct {
 	LocalAddr  string `json:"localAddr"`
 	LocalPort  int    `json:"localPort"`
@@ -13,11 +17,26 @@ type CompactConnection struct {
 	RemotePort int    `json:"remotePort"`
 	State      int    `json:"state"`
 	PID        int    `json:"pid"`
+	// Enrichment
+	RemoteHostname string `json:"remoteHostname,omitempty"`
+	RemoteCountry  string `json:"remoteCountry,omitempty"`
+	RemoteCity     string `json:"remoteCity,omitempty"`
+	RemoteASN      int64  `json:"remoteAsn,omitempty"`
+	RemoteASOrg    string `json:"remoteAsOrg,omitempty"`
+	ServiceName    string `json:"serviceName,omitempty"`
+	Role           string `json:"role,omitempty"`
 	// Basic Stats
 	BytesIn     int64 `json:"bytesIn"`
 	BytesOut    int64 `json:"bytesOut"`
 	SegmentsIn  int64 `json:"segmentsIn"`
 	SegmentsOut int64 `json:"segmentsOut"`
+	// Pending timer (Linux)
+	TimerKind        string `json:"timerKind,omitempty"`
+	TimerExpiresMs   int64  `json:"timerExpiresMs,omitempty"`
+	TimerRetransmits int64  `json:"timerRetransmits,omitempty"`
+	TimerProbes      int64  `json:"timerProbes,omitempty"`
+	TimerBackoff     int64  `json:"timerBackoff,omitempty"`
+	RTOMs            int64  `json:"rtoMs,omitempty"`
 	// Extended Stats
 	SampleRTT         int64 `json:"sampleRTT"`
 	FastRetrans       int64 `json:"fastRetrans"`
@@ -43,6 +62,15 @@ type CompactConnection struct {
 	CurAppWQueue      int64 `json:"curAppWQueue"`
 	MaxAppWQueue      int64 `json:"maxAppWQueue"`
 
+	// Socket memory (Linux)
+	RmemAlloc  int64 `json:"rmemAlloc,omitempty"`
+	RcvBuf     int64 `json:"rcvBuf,omitempty"`
+	WmemAlloc  int64 `json:"wmemAlloc,omitempty"`
+	WmemQueued int64 `json:"wmemQueued,omitempty"`
+	SndBuf     int64 `json:"sndBuf,omitempty"`
+	FwdAlloc   int64 `json:"fwdAlloc,omitempty"`
+	SockDrops  int64 `json:"sockDrops,omitempty"`
+
 	// New Stats
 	WinScaleRcvd   int   `json:"winScaleRcvd"`
 	WinScaleSent   int   `json:"winScaleSent"`
@@ -58,6 +86,23 @@ type CompactConnection struct {
 	SacksRcvd      int64 `json:"sacksRcvd"`
 	SackBlocksRcvd int64 `json:"sackBlocksRcvd"`
 	DsackDups      int64 `json:"dsackDups"`
+
+	// Sender limitation times (ms)
+	SndLimTimeRwin int64 `json:"sndLimTimeRwin,omitempty"`
+	SndLimTimeCwnd int64 `json:"sndLimTimeCwnd,omitempty"`
+	SndLimTimeSnd  int64 `json:"sndLimTimeSnd,omitempty"`
+
+	// Congestion control algorithm and its state (Linux)
+	CongestionAlgorithm string  `json:"congestionAlgorithm,omitempty"`
+	CAState             string  `json:"caState,omitempty"`
+	PacingRate          int64   `json:"pacingRate,omitempty"`
+	BBRBandwidth        int64   `json:"bbrBandwidth,omitempty"`
+	BBRMinRTT           int64   `json:"bbrMinRtt,omitempty"` // Microseconds
+	BBRPacingGain       float64 `json:"bbrPacingGain,omitempty"`
+	BBRCwndGain         float64 `json:"bbrCwndGain,omitempty"`
+	DCTCPAlpha          int64   `json:"dctcpAlpha,omitempty"`
+	VegasRTT            int64   `json:"vegasRtt,omitempty"`    // Microseconds
+	VegasMinRTT         int64   `json:"vegasMinRtt,omitempty"` // Microseconds
 }
 
 // Snapshot represents a point-in-time capture
@@ -74,95 +119,615 @@ type RecordingSession struct {
 	StartTime     time.Time `json:"startTime"`
 	EndTime       time.Time `json:"endTime"`
 	SnapshotCount int       `json:"snapshotCount"`
+	Recovered     bool      `json:"recovered,omitempty"`    // Closed after an unclean shutdown
+	ImportedFrom  string    `json:"importedFrom,omitempty"` // Host an imported session was recorded on
+	Name          string    `json:"name,omitempty"`
+	Description   string    `json:"description,omitempty"`
+	Tags          []string  `json:"tags,omitempty"`
+
+	// The host an imported session was recorded on, as its archive described it
+	ImportedHost *ArchiveHost `json:"importedHost,omitempty"`
+
+	// What the session records; nil for every connection and metric
+	Scope *RecordingScope `json:"scope,omitempty"`
+
+	// Snapshots up to this time were dropped, leaving only rollups
+	DownsampledUntil time.Time `json:"downsampledUntil,omitempty"`
+
+	// Health thresholds in effect while recording, oldest first
+	HealthThresholds []ThresholdChange `json:"healthThresholds,omitempty"`
+}
+
+// ThresholdChange records the health thresholds in effect from a point in time
+type ThresholdChange struct {
+	From       time.Time        `json:"from"`
+	Thresholds HealthThresholds `json:"thresholds"`
+}
+
+// ThresholdsAt returns the health thresholds in effect at t, or fallback for
+// sessions recorded before thresholds were kept
+func (r *RecordingSession) ThresholdsAt(t time.Time, fallback HealthThresholds) HealthThresholds {
+	if len(r.HealthThresholds) == 0 {
+		return fallback
+	}
+	thresholds := r.HealthThresholds[0].Thresholds
+	for _, change := range r.HealthThresholds[1:] {
+		if change.From.After(t) {
+			break
+		}
+		thresholds = change.Thresholds
+	}
+	return thresholds
+}
+
+// activeRecording is a session being recorded. Several sessions can record
+// at once, each with its own scope and sample interval.
+type activeRecording struct {
+	sessionID  int64
+	filter     *recordingFilter // nil records every connection and metric
+	interval   time.Duration    // 0 samples at the store's default interval
+	lastSample time.Time
+	rollup     *rollupBuilder // Open rollup buckets
 }
 
 // SnapshotStore manages snapshot recording with sessions
 type SnapshotStore struct {
-	mu               sync.RWMutex
-	snapshots        []Snapshot
-	sessions         []RecordingSession
-	maxSize          int
-	nextSnapshotID   int64
-	nextSessionID    int64
-	isRecording      bool
-	currentSessionID int64 // Active session during recording
+	mu             sync.RWMutex
+	snapshots      *sessionLogs // Delta-encoded per session, decoded on read
+	sessions       []RecordingSession
+	maxSize        int
+	nextSnapshotID int64
+	nextSessionID  int64
+
+	// Sessions being recorded, in the order they started
+	recordings      []*activeRecording
+	defaultInterval time.Duration // Sample interval of sessions without their own
+
+	// Health thresholds as of the last poll, recorded with sessions
+	healthThresholds HealthThresholds
+
+	// Serializes structural session edits, which run mostly outside mu
+	editMu sync.Mutex
+
+	// Active probe results recorded alongside snapshots
+	probes        []ProbeResult
+	maxProbeCount int
+
+	// Rollups of every session per tier when there is no disk to store them
+	rollups        [][]ConnectionRollup
+	maxRollupCount int
+	downsampling   bool // A background downsampling pass is running
+	downsampleMore bool // Another pass was requested while one was running
+
+	// Annotations per session when there is no disk to store them
+	annotations map[int64][]Annotation
+
+	// Flight-recorder history, promoted into the next session started with
+	// StartRecordingWithBuffer; the buffer rests while that session records
+	pending       *snapshotLog
+	preTrigger    time.Duration
+	pendingSample time.Time
+	bufferSession int64
+
+	// Optional on-disk persistence; memory holds the most recent snapshots
+	disk   *DiskStore
+	logger *Logger
 }
 
 // NewSnapshotStore creates a store with fixed capacity
 func NewSnapshotStore(maxSnapshots int) *SnapshotStore {
 	return &SnapshotStore{
-		snapshots:      make([]Snapshot, 0, maxSnapshots),
+		snapshots:      newSessionLogs(),
 		sessions:       make([]RecordingSession, 0),
 		maxSize:        maxSnapshots,
 		nextSnapshotID: 1,
 		nextSessionID:  1,
+		maxProbeCount:  maxSnapshots * 5,
+		rollups:        make([][]ConnectionRollup, len(rollupTiers)),
+		maxRollupCount: maxSnapshots * 10,
+		annotations:    make(map[int64][]Annotation),
+
+		healthThresholds: DefaultHealthThresholds(),
+		logger:           GetLogger(),
 	}
 }
 
-// StartRecording enables snapshot capture and creates a new session
+// NewPersistentSnapshotStore creates a store that writes every session
+// through to disk and lists sessions recorded by previous runs
+func NewPersistentSnapshotStore(maxSnapshots int, config StorageConfig) (*SnapshotStore, error) {
+	disk, err := OpenDiskStore(config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewSnapshotStore(maxSnapshots)
+	s.disk = disk
+	for _, meta := range disk.Sessions() {
+		s.sessions = append(s.sessions, meta.RecordingSession)
+		if meta.ID >= s.nextSessionID {
+			s.nextSessionID = meta.ID + 1
+		}
+		if meta.LastSnapshotID >= s.nextSnapshotID {
+			s.nextSnapshotID = meta.LastSnapshotID + 1
+		}
+	}
+	return s, nil
+}
+
+// Close ends the active session and flushes it to disk
+func (s *SnapshotStore) Close() error {
+	s.StopRecording()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.disk == nil {
+		return nil
+	}
+	return s.disk.Close()
+}
+
+// StorageConfig returns the persistence settings in effect; Enabled is
+// false when sessions are kept in memory only
+func (s *SnapshotStore) StorageConfig() StorageConfig {
+	if s.disk == nil {
+		return StorageConfig{}
+	}
+	return s.disk.Config()
+}
+
+// StorageSize returns the bytes used by persisted sessions
+func (s *SnapshotStore) StorageSize() int64 {
+	if s.disk == nil {
+		return 0
+	}
+	return s.disk.SizeBytes()
+}
+
+// SetRetention updates the age, size and full-resolution limits of
+// persisted sessions
+func (s *SnapshotStore) SetRetention(retentionDays, maxSizeMB, fullResolutionHours int) {
+	if s.disk == nil {
+		return
+	}
+	s.disk.SetRetention(retentionDays, maxSizeMB, fullResolutionHours)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncSessions()
+}
+
+// syncSessions forgets sessions retention removed from disk and picks up
+// the counts of sessions it downsampled. Must be called with s.mu held.
+func (s *SnapshotStore) syncSessions() {
+	stored := make(map[int64]storedSession)
+	for _, meta := range s.disk.Sessions() {
+		stored[meta.ID] = meta
+	}
+	kept := s.sessions[:0]
+	for _, session := range s.sessions {
+		if meta, ok := stored[session.ID]; ok {
+			session.SnapshotCount = meta.SnapshotCount
+			session.DownsampledUntil = meta.DownsampledUntil
+			kept = append(kept, session)
+		} else if s.recordingLocked(session.ID) != nil {
+			kept = append(kept, session)
+		}
+	}
+	s.sessions = kept
+}
+
+// downsampleLocked starts a background retention pass, or asks the running
+// one to go again. Retention reads and removes files, so it never runs
+// under s.mu. Must be called with s.mu held.
+func (s *SnapshotStore) downsampleLocked() {
+	if s.disk == nil {
+		return
+	}
+	if s.downsampling {
+		s.downsampleMore = true
+		return
+	}
+	s.downsampling = true
+	go s.downsample()
+}
+
+// downsample applies retention in the background: during long recordings,
+// so that full-resolution snapshots are dropped without waiting for the
+// recording to stop, and after sessions are closed or imported
+func (s *SnapshotStore) downsample() {
+	for {
+		s.disk.applyRetention()
+
+		s.mu.Lock()
+		s.syncSessions()
+		if !s.downsampleMore {
+			s.downsampling = false
+			s.mu.Unlock()
+			return
+		}
+		s.downsampleMore = false
+		s.mu.Unlock()
+	}
+}
+
+// storeRollups keeps closed rollup buckets of a session being recorded.
+// Must be called with s.mu held.
+func (s *SnapshotStore) storeRollups(sessionID int64, rollups []ConnectionRollup) {
+	if len(rollups) == 0 {
+		return
+	}
+	for i := range rollups {
+		rollups[i].SessionID = sessionID
+	}
+
+	if s.disk != nil {
+		if err := s.disk.AppendRollups(sessionID, rollups); err != nil {
+			s.logger.Error("Failed to persist rollups: %v", err)
+		}
+		return
+	}
+	for _, r := range rollups {
+		tier := rollupTierIndex(time.Duration(r.Resolution) * time.Second)
+		s.rollups[tier] = append(s.rollups[tier], r)
+	}
+	// Ring buffer per tier, so coarse tiers reach further back
+	for tier := range s.rollups {
+		if over := len(s.rollups[tier]) - s.maxRollupCount; over > 0 {
+			s.rollups[tier] = append(s.rollups[tier][:0], s.rollups[tier][over:]...)
+		}
+	}
+}
+
+// StartRecording starts a new session recording every connection. Sessions
+// already being recorded carry on.
 func (s *SnapshotStore) StartRecording() int64 {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.startRecordingLocked(time.Now(), nil, nil)
+}
+
+// StartScopedRecording starts a session recording only the connections and
+// metrics in scope, at the scope's sample interval
+func (s *SnapshotStore) StartScopedRecording(scope RecordingScope) (int64, error) {
+	filter, err := compileRecordingScope(scope)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if scope.IsEmpty() {
+		return s.startRecordingLocked(time.Now(), nil, nil), nil
+	}
+	return s.startRecordingLocked(time.Now(), &scope, filter), nil
+}
+
+// SetSampleInterval sets how often polls are sampled by sessions without a
+// sample interval of their own, normally the polling interval
+func (s *SnapshotStore) SetSampleInterval(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultInterval = interval
+}
+
+// NoteHealthThresholds records the health thresholds in effect from now on
+// with every session being recorded, so that health flags can later be
+// recomputed as they were at the time
+func (s *SnapshotStore) NoteHealthThresholds(thresholds HealthThresholds) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if thresholds == s.healthThresholds {
+		return
+	}
+	s.healthThresholds = thresholds
+
+	change := ThresholdChange{From: time.Now(), Thresholds: thresholds}
+	for _, r := range s.recordings {
+		for i := range s.sessions {
+			if s.sessions[i].ID != r.sessionID {
+				continue
+			}
+			s.sessions[i].HealthThresholds = append(s.sessions[i].HealthThresholds, change)
+			if s.disk != nil {
+				if err := s.disk.SetSessionThresholds(r.sessionID, s.sessions[i].HealthThresholds); err != nil {
+					s.logger.Error("Failed to persist thresholds of session %d: %v", r.sessionID, err)
+				}
+			}
+			break
+		}
+	}
+}
+
+// MinSampleInterval returns the shortest sample interval a session being
+// recorded asks for, or 0 if they all sample at the default interval
+func (s *SnapshotStore) MinSampleInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var shortest time.Duration
+	for _, r := range s.recordings {
+		if r.interval > 0 && (shortest == 0 || r.interval < shortest) {
+			shortest = r.interval
+		}
+	}
+	return shortest
+}
+
+// StartRecordingWithBuffer starts a session that begins with the snapshots
+// held in the pre-trigger buffer, so it covers the time before the call.
+// The buffer rests while that session is recorded.
+func (s *SnapshotStore) StartRecordingWithBuffer() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buffered []Snapshot
+	if s.pending != nil {
+		s.pending.Each(func(snap *Snapshot) bool {
+			buffered = append(buffered, *snap)
+			return true
+		})
+		s.pending.Reset()
+	}
+
+	start := time.Now()
+	if len(buffered) > 0 {
+		start = buffered[0].Timestamp
+	}
+	id := s.startRecordingLocked(start, nil, nil)
+	r := s.recordingLocked(id)
+	for i := range buffered {
+		s.recordLocked(r, &buffered[i])
+	}
+	s.bufferSession = id
+	return id
+}
+
+// SetPreTriggerBuffer keeps the snapshots of the last window, for
+// StartRecordingWithBuffer. A zero window disables it.
+func (s *SnapshotStore) SetPreTriggerBuffer(window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	s.preTrigger = window
+	if window <= 0 {
+		s.pending = nil
+	} else if s.pending == nil {
+		s.pending = newSnapshotLog()
+	}
+}
+
+// PreTriggerBuffer reports how many snapshots the pre-trigger buffer holds
+// and when the oldest was taken
+func (s *SnapshotStore) PreTriggerBuffer() (int, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.pending == nil {
+		return 0, time.Time{}
+	}
+	oldest, _ := s.pending.sessionStart(0)
+	return s.pending.Len(), oldest
+}
+
+// startRecordingLocked creates a session starting at start, recording the
+// given scope, and adds it to the sessions being recorded.
+// Must be called with s.mu held.
+func (s *SnapshotStore) startRecordingLocked(start time.Time, scope *RecordingScope, filter *recordingFilter) int64 {
 	// Create new session
 	session := RecordingSession{
 		ID:        s.nextSessionID,
-		StartTime: time.Now(),
+		StartTime: start,
+		Scope:     scope,
+
+		HealthThresholds: []ThresholdChange{{From: start, Thresholds: s.healthThresholds}},
 	}
 	s.sessions = append(s.sessions, session)
-	s.currentSessionID = s.nextSessionID
 	s.nextSessionID++
-	s.isRecording = true
 
-	return s.currentSessionID
+	r := &activeRecording{
+		sessionID: session.ID,
+		filter:    filter,
+		rollup:    newTieredRollupBuilder(),
+	}
+	if scope != nil {
+		r.interval = scope.Interval()
+	}
+	s.recordings = append(s.recordings, r)
+
+	if s.disk != nil {
+		if err := s.disk.BeginSession(session); err != nil {
+			s.logger.Error("Failed to persist session %d: %v", session.ID, err)
+		}
+	}
+
+	return session.ID
 }
 
-// StopRecording disables snapshot capture and closes current session
+// StopRecording stops every session being recorded
 func (s *SnapshotStore) StopRecording() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if !s.isRecording {
+	if len(s.recordings) == 0 {
 		return
 	}
+	for len(s.recordings) > 0 {
+		s.stopLocked(s.recordings[0])
+	}
+	if s.disk != nil {
+		s.syncSessions()
+	}
+}
+
+// StopSession stops recording one session, leaving the others running
+func (s *SnapshotStore) StopSession(sessionID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.recordingLocked(sessionID)
+	if r == nil {
+		return fmt.Errorf("session %d is not being recorded", sessionID)
+	}
+	s.stopLocked(r)
+	if s.disk != nil {
+		s.syncSessions()
+	}
+	return nil
+}
+
+// stopLocked closes a session being recorded. Must be called with s.mu held.
+func (s *SnapshotStore) stopLocked(r *activeRecording) {
+	s.storeRollups(r.sessionID, r.rollup.flush())
 
 	// Update session with end time and snapshot count
 	for i := range s.sessions {
-		if s.sessions[i].ID == s.currentSessionID {
+		if s.sessions[i].ID == r.sessionID {
 			s.sessions[i].EndTime = time.Now()
-			// Count snapshots in this session
-			count := 0
-			for _, snap := range s.snapshots {
-				if snap.SessionID == s.currentSessionID {
-					count++
+			if s.disk != nil {
+				// Take kept the count; older snapshots may only be on disk
+				if err := s.disk.EndSession(s.sessions[i]); err != nil {
+					s.logger.Error("Failed to close persisted session %d: %v", r.sessionID, err)
 				}
+				s.downsampleLocked()
+				break
 			}
-			s.sessions[i].SnapshotCount = count
+			s.sessions[i].SnapshotCount = s.snapshots.sessionLen(r.sessionID)
 			break
 		}
 	}
 
-	s.isRecording = false
-	s.currentSessionID = 0
+	for i := range s.recordings {
+		if s.recordings[i] == r {
+			s.recordings = append(s.recordings[:i], s.recordings[i+1:]...)
+			break
+		}
+	}
+	if s.bufferSession == r.sessionID {
+		s.bufferSession = 0
+	}
 }
 
-// IsRecording returns current recording state
+// IsRecording reports whether any session is being recorded
 func (s *SnapshotStore) IsRecording() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.isRecording
+	return len(s.recordings) > 0
+}
+
+// IsSessionRecording reports whether a session is being recorded
+func (s *SnapshotStore) IsSessionRecording(sessionID int64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.recordingLocked(sessionID) != nil
+}
+
+// RecordingSessionIDs returns the sessions being recorded, oldest first
+func (s *SnapshotStore) RecordingSessionIDs() []int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]int64, len(s.recordings))
+	for i, r := range s.recordings {
+		ids[i] = r.sessionID
+	}
+	return ids
+}
+
+// recordingLocked returns the recording of a session, or nil if it is not
+// being recorded. Must be called with s.mu held.
+func (s *SnapshotStore) recordingLocked(sessionID int64) *activeRecording {
+	for _, r := range s.recordings {
+		if r.sessionID == sessionID {
+			return r
+		}
+	}
+	return nil
 }
 
-// Take captures a snapshot if recording is enabled
-func (s *SnapshotStore) Take(connections []ConnectionInfo) *Snapshot {
+// Take captures the connections in every session being recorded, each
+// limited to its scope, and in the pre-trigger buffer if enabled
+func (s *SnapshotStore) Take(connections []ConnectionInfo) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if !s.isRecording {
-		return nil
+	now := time.Now()
+	var all []CompactConnection
+	for _, r := range s.recordings {
+		s.captureLocked(r, connections, &all, now)
+	}
+	s.bufferLocked(connections, &all, now)
+}
+
+// Sample is Take for the polling loop: each session, and the pre-trigger
+// buffer, only captures a poll once its sample interval has passed since
+// its previous capture
+func (s *SnapshotStore) Sample(connections []ConnectionInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var all []CompactConnection
+	for _, r := range s.recordings {
+		interval := r.interval
+		if interval == 0 {
+			interval = s.defaultInterval
+		}
+		if sampleDue(r.lastSample, now, interval) {
+			s.captureLocked(r, connections, &all, now)
+		}
+	}
+	if sampleDue(s.pendingSample, now, s.defaultInterval) {
+		s.bufferLocked(connections, &all, now)
+	}
+}
+
+// sampleDue reports whether a sample interval has passed since last. Polls
+// drift by a few milliseconds; that must not skip a whole interval.
+func sampleDue(last, now time.Time, interval time.Duration) bool {
+	return now.Sub(last) >= interval-interval/10
+}
+
+// captureLocked records the connections in a session's scope. all caches
+// the unfiltered recorded form for the sessions that keep everything.
+// Must be called with s.mu held.
+func (s *SnapshotStore) captureLocked(r *activeRecording, connections []ConnectionInfo, all *[]CompactConnection, now time.Time) {
+	r.lastSample = now
+	snapshot := Snapshot{Timestamp: now}
+	if r.filter != nil {
+		snapshot.Connections = r.filter.apply(connections)
+	} else {
+		if *all == nil {
+			*all = compactConnections(connections)
+		}
+		snapshot.Connections = *all
+	}
+	s.recordLocked(r, &snapshot)
+}
+
+// bufferLocked adds the connections to the pre-trigger buffer, if enabled
+// and its session is not being recorded, and drops those older than the
+// window. Must be called with s.mu held.
+func (s *SnapshotStore) bufferLocked(connections []ConnectionInfo, all *[]CompactConnection, now time.Time) {
+	if s.pending == nil || s.bufferSession != 0 {
+		return
+	}
+	s.pendingSample = now
+	if *all == nil {
+		*all = compactConnections(connections)
+	}
+	s.pending.Append(&Snapshot{Timestamp: now, Connections: *all})
+	for s.pending.Len() > 0 {
+		oldest, _ := s.pending.sessionStart(0)
+		if !oldest.Before(now.Add(-s.preTrigger)) && s.pending.Len() <= s.maxSize {
+			break
+		}
+		s.pending.DropOldest()
 	}
+}
 
-	// Convert to compact format
+// compactConnections converts live connections to their recorded form
+func compactConnections(connections []ConnectionInfo) []CompactConnection {
 	compact := make([]CompactConnection, len(connections))
 	for i, c := range connections {
 		compact[i] = CompactConnection{
@@ -172,6 +737,22 @@ func (s *SnapshotStore) Take(connections []ConnectionInfo) *Snapshot {
 			RemotePort: int(c.RemotePort),
 			State:      int(c.State),
 			PID:        int(c.PID),
+
+			RemoteHostname: c.RemoteHostname,
+			RemoteCountry:  c.RemoteCountry,
+			RemoteCity:     c.RemoteCity,
+			RemoteASN:      int64(c.RemoteASN),
+			RemoteASOrg:    c.RemoteASOrg,
+			ServiceName:    c.ServiceName,
+			Role:           c.Role,
+		}
+		if c.Timer != nil {
+			compact[i].TimerKind = c.Timer.Kind
+			compact[i].TimerExpiresMs = int64(c.Timer.ExpiresMs)
+			compact[i].TimerRetransmits = int64(c.Timer.Retransmits)
+			compact[i].TimerProbes = int64(c.Timer.Probes)
+			compact[i].TimerBackoff = int64(c.Timer.Backoff)
+			compact[i].RTOMs = int64(c.Timer.RTOMs)
 		}
 		if c.BasicStats != nil {
 			compact[i].BytesIn = int64(c.BasicStats.DataBytesIn)
@@ -203,6 +784,13 @@ func (s *SnapshotStore) Take(connections []ConnectionInfo) *Snapshot {
 			compact[i].MaxRetxQueue = int64(c.ExtendedStats.MaxRetxQueue)
 			compact[i].CurAppWQueue = int64(c.ExtendedStats.CurAppWQueue)
 			compact[i].MaxAppWQueue = int64(c.ExtendedStats.MaxAppWQueue)
+			compact[i].RmemAlloc = int64(c.ExtendedStats.RmemAlloc)
+			compact[i].RcvBuf = int64(c.ExtendedStats.RcvBuf)
+			compact[i].WmemAlloc = int64(c.ExtendedStats.WmemAlloc)
+			compact[i].WmemQueued = int64(c.ExtendedStats.WmemQueued)
+			compact[i].SndBuf = int64(c.ExtendedStats.SndBuf)
+			compact[i].FwdAlloc = int64(c.ExtendedStats.FwdAlloc)
+			compact[i].SockDrops = int64(c.ExtendedStats.SockDrops)
 
 			// New Stats
 			compact[i].WinScaleRcvd = int(c.ExtendedStats.WinScaleRcvd)
@@ -219,75 +807,249 @@ func (s *SnapshotStore) Take(connections []ConnectionInfo) *Snapshot {
 			compact[i].SacksRcvd = int64(c.ExtendedStats.SacksRcvd)
 			compact[i].SackBlocksRcvd = int64(c.ExtendedStats.SackBlocksRcvd)
 			compact[i].DsackDups = int64(c.ExtendedStats.DsackDups)
+			compact[i].SndLimTimeRwin = int64(c.ExtendedStats.SndLimTimeRwin)
+			compact[i].SndLimTimeCwnd = int64(c.ExtendedStats.SndLimTimeCwnd)
+			compact[i].SndLimTimeSnd = int64(c.ExtendedStats.SndLimTimeSnd)
+
+			compact[i].CongestionAlgorithm = c.ExtendedStats.CongestionAlgorithm
+			compact[i].CAState = c.ExtendedStats.CAState
+			compact[i].PacingRate = int64(c.ExtendedStats.PacingRate)
+			if bbr := c.ExtendedStats.BBR; bbr != nil {
+				compact[i].BBRBandwidth = int64(bbr.BandwidthBps)
+				compact[i].BBRMinRTT = int64(bbr.MinRTTUs)
+				compact[i].BBRPacingGain = bbr.PacingGain
+				compact[i].BBRCwndGain = bbr.CwndGain
+			}
+			if dctcp := c.ExtendedStats.DCTCP; dctcp != nil {
+				compact[i].DCTCPAlpha = int64(dctcp.Alpha)
+			}
+			if vegas := c.ExtendedStats.Vegas; vegas != nil {
+				compact[i].VegasRTT = int64(vegas.RTTUs)
+				compact[i].VegasMinRTT = int64(vegas.MinRTTUs)
+			}
 		}
 	}
 
-	// Find active session and increment count
+	return compact
+}
+
+// expandConnection is the inverse of compactConnections. Raw API values,
+// health flags and the window analysis are not recorded and stay empty.
+func expandConnection(c *CompactConnection) ConnectionInfo {
+	conn := ConnectionInfo{
+		LocalAddr:  c.LocalAddr,
+		LocalPort:  uint16(c.LocalPort),
+		RemoteAddr: c.RemoteAddr,
+		RemotePort: uint16(c.RemotePort),
+		State:      TCPState(c.State),
+		PID:        uint32(c.PID),
+		IsIPv6:     strings.Contains(c.LocalAddr, ":"),
+
+		RemoteHostname: c.RemoteHostname,
+		RemoteCountry:  c.RemoteCountry,
+		RemoteCity:     c.RemoteCity,
+		RemoteASN:      uint32(c.RemoteASN),
+		RemoteASOrg:    c.RemoteASOrg,
+		ServiceName:    c.ServiceName,
+		Role:           c.Role,
+
+		BasicStats: &BasicStats{
+			DataBytesIn:  uint64(c.BytesIn),
+			DataBytesOut: uint64(c.BytesOut),
+			DataSegsIn:   uint64(c.SegmentsIn),
+			DataSegsOut:  uint64(c.SegmentsOut),
+		},
+		ExtendedStats: &ExtendedStats{
+			SampleRTT:         uint32(c.SampleRTT),
+			FastRetrans:       uint32(c.FastRetrans),
+			TimeoutEpisodes:   uint32(c.TimeoutEpisodes),
+			SmoothedRTT:       uint32(c.RTT),
+			RTTVariance:       uint32(c.RTTVariance),
+			MinRTT:            uint32(c.MinRTT),
+			MaxRTT:            uint32(c.MaxRTT),
+			BytesRetrans:      uint32(c.Retrans),
+			SegsRetrans:       uint32(c.SegsRetrans),
+			TotalSegsOut:      uint64(c.TotalSegsOut),
+			TotalSegsIn:       uint64(c.TotalSegsIn),
+			CurrentCwnd:       uint32(c.CongestionWin),
+			InboundBandwidth:  uint64(c.InBandwidth),
+			OutboundBandwidth: uint64(c.OutBandwidth),
+			ThruBytesAcked:    uint64(c.ThruBytesAcked),
+			ThruBytesReceived: uint64(c.ThruBytesReceived),
+			CurrentSsthresh:   uint32(c.CurrentSsthresh),
+			SlowStartCount:    uint32(c.SlowStartCount),
+			CongAvoidCount:    uint32(c.CongAvoidCount),
+			CurRetxQueue:      uint32(c.CurRetxQueue),
+			MaxRetxQueue:      uint32(c.MaxRetxQueue),
+			CurAppWQueue:      uint32(c.CurAppWQueue),
+			MaxAppWQueue:      uint32(c.MaxAppWQueue),
+			RmemAlloc:         uint32(c.RmemAlloc),
+			RcvBuf:            uint32(c.RcvBuf),
+			WmemAlloc:         uint32(c.WmemAlloc),
+			WmemQueued:        uint32(c.WmemQueued),
+			SndBuf:            uint32(c.SndBuf),
+			FwdAlloc:          uint32(c.FwdAlloc),
+			SockDrops:         uint32(c.SockDrops),
+
+			WinScaleRcvd:   uint32(c.WinScaleRcvd),
+			WinScaleSent:   uint32(c.WinScaleSent),
+			CurRwinRcvd:    uint32(c.CurRwinRcvd),
+			MaxRwinRcvd:    uint32(c.MaxRwinRcvd),
+			CurRwinSent:    uint32(c.CurRwinSent),
+			MaxRwinSent:    uint32(c.MaxRwinSent),
+			CurMss:         uint32(c.CurMss),
+			MaxMss:         uint32(c.MaxMss),
+			MinMss:         uint32(c.MinMss),
+			DupAcksIn:      uint32(c.DupAcksIn),
+			DupAcksOut:     uint32(c.DupAcksOut),
+			SacksRcvd:      uint32(c.SacksRcvd),
+			SackBlocksRcvd: uint32(c.SackBlocksRcvd),
+			DsackDups:      uint32(c.DsackDups),
+			SndLimTimeRwin: uint32(c.SndLimTimeRwin),
+			SndLimTimeCwnd: uint32(c.SndLimTimeCwnd),
+			SndLimTimeSnd:  uint32(c.SndLimTimeSnd),
+
+			CongestionAlgorithm: c.CongestionAlgorithm,
+			CAState:             c.CAState,
+			PacingRate:          uint64(c.PacingRate),
+		},
+	}
+	if c.TimerKind != "" {
+		conn.Timer = &TimerInfo{
+			Kind:        c.TimerKind,
+			ExpiresMs:   uint32(c.TimerExpiresMs),
+			Retransmits: uint32(c.TimerRetransmits),
+			Probes:      uint32(c.TimerProbes),
+			Backoff:     uint32(c.TimerBackoff),
+			RTOMs:       uint32(c.RTOMs),
+		}
+	}
+	if strings.HasPrefix(c.CongestionAlgorithm, "bbr") {
+		conn.ExtendedStats.BBR = &BBRInfo{
+			BandwidthBps: uint64(c.BBRBandwidth),
+			MinRTTUs:     uint32(c.BBRMinRTT),
+			PacingGain:   c.BBRPacingGain,
+			CwndGain:     c.BBRCwndGain,
+		}
+	}
+	if c.CongestionAlgorithm == "dctcp" {
+		conn.ExtendedStats.DCTCP = &DCTCPInfo{Enabled: true, Alpha: uint32(c.DCTCPAlpha)}
+	}
+	if c.VegasRTT != 0 || c.VegasMinRTT != 0 {
+		conn.ExtendedStats.Vegas = &VegasInfo{Enabled: true, RTTUs: uint32(c.VegasRTT), MinRTTUs: uint32(c.VegasMinRTT)}
+	}
+	return conn
+}
+
+// recordLocked adds a snapshot to a session being recorded, assigning its
+// ID. Must be called with s.mu held.
+func (s *SnapshotStore) recordLocked(r *activeRecording, snapshot *Snapshot) {
+	// Find the session and increment its count
 	for i := range s.sessions {
-		if s.sessions[i].ID == s.currentSessionID {
+		if s.sessions[i].ID == r.sessionID {
 			s.sessions[i].SnapshotCount++
 			break
 		}
 	}
 
-	snapshot := Snapshot{
-		ID:          s.nextSnapshotID,
-		SessionID:   s.currentSessionID,
-		Timestamp:   time.Now(),
-		Connections: compact,
-	}
+	snapshot.ID = s.nextSnapshotID
+	snapshot.SessionID = r.sessionID
 	s.nextSnapshotID++
 
 	// Ring buffer: remove oldest if at capacity
-	if len(s.snapshots) >= s.maxSize {
-		s.snapshots = s.snapshots[1:]
+	if s.snapshots.Len() >= s.maxSize {
+		s.snapshots.DropOldest()
+	}
+	s.snapshots.Append(snapshot)
+
+	if s.disk != nil {
+		if err := s.disk.AppendSnapshot(snapshot); err != nil {
+			s.logger.Error("Failed to persist snapshot %d: %v", snapshot.ID, err)
+		}
 	}
-	s.snapshots = append(s.snapshots, snapshot)
 
-	return &snapshot
+	closed := r.rollup.add(snapshot)
+	s.storeRollups(r.sessionID, closed)
+
+	// Each closed coarsest bucket is a chance to drop old snapshots of a
+	// recording that runs for days
+	coarsest := int64(rollupTiers[len(rollupTiers)-1].resolution / time.Second)
+	if s.disk != nil && !s.downsampling && len(closed) > 0 && closed[len(closed)-1].Resolution == coarsest {
+		s.downsampleLocked()
+	}
 }
 
 // Count returns number of stored snapshots
 func (s *SnapshotStore) Count() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return len(s.snapshots)
+	return s.snapshots.Len()
 }
 
 // GetRange returns snapshots within time range
 func (s *SnapshotStore) GetRange(start, end time.Time) []Snapshot {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
+	views := s.snapshots.views(func(l *snapshotLog) *logView {
+		return l.view(l.clipTime(l.allSessionRuns(), start, end))
+	})
+	s.mu.RUnlock()
 
-	var result []Snapshot
-	for _, snap := range s.snapshots {
-		if (snap.Timestamp.Equal(start) || snap.Timestamp.After(start)) &&
-			(snap.Timestamp.Equal(end) || snap.Timestamp.Before(end)) {
-			result = append(result, snap)
-		}
-	}
+	result := make([]Snapshot, 0)
+	eachSnapshot(views, func(snap *Snapshot) {
+		result = append(result, *snap)
+	})
 	return result
 }
 
 // GetByID returns a specific snapshot
 func (s *SnapshotStore) GetByID(id int64) *Snapshot {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
+	var view *logView
+	for _, l := range s.snapshots.logs {
+		if pos, ok := l.position(id); ok {
+			view = l.view([]frameRun{{start: pos, end: pos + 1}})
+			break
+		}
+	}
+	s.mu.RUnlock()
 
-	for _, snap := range s.snapshots {
-		if snap.ID == id {
-			return &snap
+	// Older snapshots are only on disk
+	if view == nil && s.disk != nil {
+		for _, sessionID := range s.disk.FindSnapshotSessions(id) {
+			stored, err := s.disk.LoadSnapshotSegment(sessionID, id)
+			if err != nil {
+				continue
+			}
+			if pos, ok := stored.position(id); ok {
+				view = stored.view([]frameRun{{start: pos, end: pos + 1}})
+				break
+			}
 		}
 	}
-	return nil
+	if view == nil {
+		return nil
+	}
+
+	var result *Snapshot
+	view.Each(func(snap *Snapshot) bool {
+		result = snap
+		return false
+	})
+	return result
 }
 
 // GetAll returns all snapshots (for timeline view)
 func (s *SnapshotStore) GetAll() []Snapshot {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
-	result := make([]Snapshot, len(s.snapshots))
-	copy(result, s.snapshots)
+	views := s.snapshots.views(func(l *snapshotLog) *logView {
+		return l.view(l.allRuns())
+	})
+	s.mu.RUnlock()
+
+	result := make([]Snapshot, 0)
+	eachSnapshot(views, func(snap *Snapshot) {
+		result = append(result, *snap)
+	})
 	return result
 }
 
@@ -296,14 +1058,14 @@ func (s *SnapshotStore) GetMeta() []SnapshotMeta {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	meta := make([]SnapshotMeta, len(s.snapshots))
-	for i, snap := range s.snapshots {
-		meta[i] = SnapshotMeta{
-			ID:              snap.ID,
-			Timestamp:       snap.Timestamp,
-			ConnectionCount: len(snap.Connections),
-		}
-	}
+	meta := make([]SnapshotMeta, 0, s.snapshots.Len())
+	s.snapshots.EachMeta(func(id, _ int64, timestamp time.Time, connections int) {
+		meta = append(meta, SnapshotMeta{
+			ID:              id,
+			Timestamp:       timestamp,
+			ConnectionCount: connections,
+		})
+	})
 	return meta
 }
 
@@ -314,12 +1076,62 @@ type SnapshotMeta struct {
 	ConnectionCount int       `json:"connectionCount"`
 }
 
-// Clear removes all snapshots and sessions
+// Clear removes all snapshots and sessions, including persisted ones.
+// With persistence enabled, the sessions being recorded are kept.
 func (s *SnapshotStore) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.snapshots = s.snapshots[:0]
-	s.sessions = s.sessions[:0]
+
+	for tier := range s.rollups {
+		kept := s.rollups[tier][:0]
+		for _, r := range s.rollups[tier] {
+			if s.recordingLocked(r.SessionID) != nil {
+				kept = append(kept, r)
+			}
+		}
+		s.rollups[tier] = kept
+	}
+
+	if s.disk == nil || len(s.recordings) == 0 {
+		s.snapshots.Reset()
+		s.sessions = s.sessions[:0]
+		s.probes = s.probes[:0]
+	} else {
+		s.snapshots.Keep(func(sessionID int64) bool {
+			return s.recordingLocked(sessionID) != nil
+		})
+
+		sessions := s.sessions[:0]
+		for _, session := range s.sessions {
+			if s.recordingLocked(session.ID) != nil {
+				sessions = append(sessions, session)
+			}
+		}
+		s.sessions = sessions
+
+		probes := s.probes[:0]
+		for _, r := range s.probes {
+			if s.recordingLocked(r.SessionID) != nil {
+				probes = append(probes, r)
+			}
+		}
+		s.probes = probes
+	}
+
+	if s.pending != nil {
+		s.pending.Reset()
+	}
+
+	// Only used without a disk, where every session was just removed
+	for id := range s.annotations {
+		delete(s.annotations, id)
+	}
+
+	if s.disk != nil {
+		if err := s.disk.DeleteAll(); err != nil {
+			s.logger.Error("Failed to delete persisted sessions: %v", err)
+		}
+	}
 }
 
 // GetSessions returns all recording sessions
@@ -345,40 +1157,283 @@ func (s *SnapshotStore) GetSessionByID(sessionID int64) *RecordingSession {
 
 	for i := range s.sessions {
 		if s.sessions[i].ID == sessionID {
-			return &s.sessions[i]
+			session := s.sessions[i]
+			return &session
 		}
 	}
 	return nil
 }
 
+// RecordProbes stores probe results in every session being recorded
+func (s *SnapshotStore) RecordProbes(results []ProbeResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rec := range s.recordings {
+		start := len(s.probes)
+		for _, r := range results {
+			r.SessionID = rec.sessionID
+			s.probes = append(s.probes, r)
+		}
+		if s.disk != nil {
+			if err := s.disk.AppendProbes(rec.sessionID, s.probes[start:]); err != nil {
+				s.logger.Error("Failed to persist probe results: %v", err)
+			}
+		}
+	}
+
+	// Ring buffer: drop oldest results beyond capacity
+	if over := len(s.probes) - s.maxProbeCount; over > 0 {
+		s.probes = append(s.probes[:0], s.probes[over:]...)
+	}
+}
+
+// GetProbeResults returns recorded probe results for a session, optionally for one target
+func (s *SnapshotStore) GetProbeResults(sessionID int64, target string) []ProbeResult {
+	_, probes := s.sessionView(sessionID, time.Time{}, time.Time{}, func(log *snapshotLog) *logView { return nil })
+
+	var result []ProbeResult
+	for _, r := range probes {
+		if target == "" || r.Target == target {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// GetSessionData returns a session with all of its snapshots and probe results
+func (s *SnapshotStore) GetSessionData(sessionID int64) (*RecordingSession, []Snapshot, []ProbeResult) {
+	session := s.GetSessionByID(sessionID)
+	if session == nil {
+		return nil, nil, nil
+	}
+	view, probes := s.sessionView(sessionID, time.Time{}, time.Time{}, func(log *snapshotLog) *logView {
+		return log.view(log.sessionRuns(sessionID))
+	})
+
+	snapshots := make([]Snapshot, 0, view.Len())
+	view.Each(func(snap *Snapshot) bool {
+		snapshots = append(snapshots, *snap)
+		return true
+	})
+	return session, snapshots, probes
+}
+
+// ImportSession adds a complete session recorded elsewhere. Sessions and
+// snapshots are renumbered to fit this store; the new session ID is returned.
+// Rollups are computed from the snapshots when the archive has none.
+func (s *SnapshotStore) ImportSession(session RecordingSession, snapshots []Snapshot, probes []ProbeResult, rollups []ConnectionRollup) (int64, error) {
+	s.mu.Lock()
+	if s.disk == nil && len(snapshots) > s.maxSize {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("session has %d snapshots, more than the %d kept in memory", len(snapshots), s.maxSize)
+	}
+	// IDs are reserved up front so recording carries on while the session
+	// is written; a failed import leaves a gap
+	session.ID = s.nextSessionID
+	firstID := s.nextSnapshotID
+	s.nextSessionID++
+	s.nextSnapshotID += int64(len(snapshots))
+	s.mu.Unlock()
+
+	session.SnapshotCount = len(snapshots)
+	imported := make([]Snapshot, len(snapshots))
+	for i, snap := range snapshots {
+		snap.ID = firstID + int64(i)
+		snap.SessionID = session.ID
+		imported[i] = snap
+	}
+	importedProbes := make([]ProbeResult, len(probes))
+	for i, r := range probes {
+		r.SessionID = session.ID
+		importedProbes[i] = r
+	}
+	var importedRollups []ConnectionRollup
+	if len(rollups) == 0 {
+		b := newTieredRollupBuilder()
+		for i := range imported {
+			importedRollups = append(importedRollups, b.add(&imported[i])...)
+		}
+		importedRollups = append(importedRollups, b.flush()...)
+	}
+	for _, r := range rollups {
+		if rollupTierIndex(time.Duration(r.Resolution)*time.Second) >= 0 {
+			importedRollups = append(importedRollups, r)
+		}
+	}
+	for i := range importedRollups {
+		importedRollups[i].SessionID = session.ID
+	}
+
+	// Served from disk on demand, leaving live snapshots in memory
+	if s.disk != nil {
+		if err := s.disk.WriteSession(session, imported, importedProbes, importedRollups); err != nil {
+			return 0, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.disk != nil {
+		s.downsampleLocked()
+	} else {
+		for over := s.snapshots.Len() + len(imported) - s.maxSize; over > 0; over-- {
+			s.snapshots.DropOldest()
+		}
+		for i := range imported {
+			s.snapshots.Append(&imported[i])
+		}
+		s.probes = append(s.probes, importedProbes...)
+		for _, r := range importedRollups {
+			tier := rollupTierIndex(time.Duration(r.Resolution) * time.Second)
+			s.rollups[tier] = append(s.rollups[tier], r)
+		}
+	}
+
+	// Sessions started meanwhile have later IDs
+	i := sort.Search(len(s.sessions), func(i int) bool { return s.sessions[i].ID > session.ID })
+	s.sessions = append(s.sessions, RecordingSession{})
+	copy(s.sessions[i+1:], s.sessions[i:])
+	s.sessions[i] = session
+	return session.ID, nil
+}
+
 // TimelineConnection represents a connection snapshot with its timestamp for timeline view
 type TimelineConnection struct {
 	Timestamp  time.Time         `json:"timestamp"`
+	Resolution int64             `json:"resolution,omitempty"` // Seconds per row when rolled up, 0 for snapshots
 	Connection CompactConnection `json:"connection"`
+	Annotation *Annotation       `json:"annotation,omitempty"` // Set on annotation markers, which carry no connection
 }
 
 // GetSessionTimeline returns all connection snapshots from a session as timeline rows
 func (s *SnapshotStore) GetSessionTimeline(sessionID int64) []TimelineConnection {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	return s.GetSessionTimelineRange(sessionID, time.Time{}, time.Time{})
+}
+
+// GetSessionTimelineRange returns the timeline rows of a session between
+// start and end inclusive; a zero time leaves that end of the range open.
+// Rows are always raw snapshots, so analyses see every sample.
+func (s *SnapshotStore) GetSessionTimelineRange(sessionID int64, start, end time.Time) []TimelineConnection {
+	view, _ := s.sessionView(sessionID, start, end, func(log *snapshotLog) *logView {
+		return log.view(log.clipTime(log.sessionRuns(sessionID), start, end))
+	})
 
 	var timeline []TimelineConnection
-	for _, snap := range s.snapshots {
-		if snap.SessionID == sessionID {
-			for _, conn := range snap.Connections {
-				timeline = append(timeline, TimelineConnection{
-					Timestamp:  snap.Timestamp,
-					Connection: conn,
-				})
-			}
+	view.Each(func(snap *Snapshot) bool {
+		for _, conn := range snap.Connections {
+			timeline = append(timeline, TimelineConnection{
+				Timestamp:  snap.Timestamp,
+				Connection: conn,
+			})
+		}
+		return true
+	})
+	return timeline
+}
+
+// GetSessionChartTimeline returns the timeline rows of a session between
+// start and end for charting. Long ranges, and ranges reaching back before
+// dropped snapshots, are served from rollups, marked by their Resolution.
+func (s *SnapshotStore) GetSessionChartTimeline(sessionID int64, start, end time.Time) []TimelineConnection {
+	rollups, ok := s.rollupsFor(sessionID, start, end)
+	if !ok {
+		return s.GetSessionTimelineRange(sessionID, start, end)
+	}
+	timeline := make([]TimelineConnection, len(rollups))
+	for i := range rollups {
+		timeline[i] = TimelineConnection{
+			Timestamp:  rollups[i].Start,
+			Resolution: rollups[i].Resolution,
+			Connection: rollups[i].connection(),
 		}
 	}
 	return timeline
 }
 
+// SnapshotAt returns the snapshot of a session taken closest to t, or nil if
+// none is left
+func (s *SnapshotStore) SnapshotAt(sessionID int64, t time.Time) *Snapshot {
+	view, _ := s.sessionView(sessionID, t, t, func(log *snapshotLog) *logView {
+		pos, ok := log.nearest(log.sessionRuns(sessionID), t)
+		if !ok {
+			return log.view(nil)
+		}
+		return log.view([]frameRun{{start: pos, end: pos + 1}})
+	})
+
+	var result *Snapshot
+	view.Each(func(snap *Snapshot) bool {
+		result = snap
+		return false
+	})
+	return result
+}
+
+// sessionView builds a read view of a session's snapshots between start and
+// end and returns it with the session's probe results; a zero time leaves
+// that end open. Ranges still held in memory are served from there, older
+// ones are read from disk, opening only the segments the range needs. build
+// runs under the store lock for in-memory sessions, so it must only build the
+// view; decoding happens after the lock is released so that Take is not held
+// up by large reads. build must still clip to the range, since a disk read
+// may hold snapshots around it.
+func (s *SnapshotStore) sessionView(sessionID int64, start, end time.Time, build func(log *snapshotLog) *logView) (*logView, []ProbeResult) {
+	s.mu.RLock()
+	var probes []ProbeResult
+	for _, r := range s.probes {
+		if r.SessionID == sessionID {
+			probes = append(probes, r)
+		}
+	}
+
+	inMemory := s.disk == nil
+	if !inMemory {
+		held := s.snapshots.sessionLen(sessionID)
+		expected := 0
+		for i := range s.sessions {
+			if s.sessions[i].ID == sessionID {
+				expected = s.sessions[i].SnapshotCount
+				break
+			}
+		}
+		inMemory = held >= expected && (held > 0 || s.recordingLocked(sessionID) != nil)
+
+		// Memory holds the newest snapshots, so a range starting at or
+		// after the oldest of them needs no disk read
+		if first, ok := s.snapshots.sessionStart(sessionID); ok && !start.IsZero() && !start.Before(first) {
+			inMemory = true
+		}
+	}
+	if inMemory {
+		view := build(s.snapshots.log(sessionID))
+		s.mu.RUnlock()
+		return view, probes
+	}
+	s.mu.RUnlock()
+
+	var stored *snapshotLog
+	var err error
+	if start.IsZero() && end.IsZero() {
+		var storedProbes []ProbeResult
+		stored, storedProbes, err = s.disk.LoadSession(sessionID)
+		probes = storedProbes
+	} else {
+		stored, err = s.disk.LoadSessionRange(sessionID, start, end)
+	}
+	if err != nil {
+		s.logger.Warn("Failed to load session %d from disk: %v", sessionID, err)
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return build(s.snapshots.log(sessionID)), probes
+	}
+	return build(stored), probes
+}
+
 // ConnectionHistoryPoint is a single data point for charting - all metrics
 type ConnectionHistoryPoint struct {
 	Timestamp     time.Time `json:"timestamp"`
+	Resolution    int64     `json:"resolution,omitempty"` // Seconds per point when rolled up, 0 for snapshots
 	State         int       `json:"state"`
 	BytesIn       int64     `json:"bytesIn"`
 	BytesOut      int64     `json:"bytesOut"`
@@ -398,150 +1453,207 @@ type ConnectionHistoryPoint struct {
 // GetConnectionHistory returns historical data for a specific connection
 func (s *SnapshotStore) GetConnectionHistory(localAddr string, localPort int, remoteAddr string, remotePort int) []ConnectionHistoryPoint {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
+	views := s.snapshots.views(func(l *snapshotLog) *logView {
+		id, ok := l.findIdentity(localAddr, localPort, remoteAddr, remotePort)
+		if !ok {
+			return nil
+		}
+		return l.connView(id, l.allRuns())
+	})
+	s.mu.RUnlock()
 
+	// Sessions recorded at the same time interleave
 	var history []ConnectionHistoryPoint
-	for _, snap := range s.snapshots {
-		for _, conn := range snap.Connections {
-			if conn.LocalAddr == localAddr && conn.LocalPort == localPort &&
-				conn.RemoteAddr == remoteAddr && conn.RemotePort == remotePort {
-				history = append(history, ConnectionHistoryPoint{
-					Timestamp:     snap.Timestamp,
-					State:         conn.State,
-					BytesIn:       conn.BytesIn,
-					BytesOut:      conn.BytesOut,
-					SegmentsIn:    conn.SegmentsIn,
-					SegmentsOut:   conn.SegmentsOut,
-					RTT:           conn.RTT,
-					RTTVariance:   conn.RTTVariance,
-					MinRTT:        conn.MinRTT,
-					MaxRTT:        conn.MaxRTT,
-					Retrans:       conn.Retrans,
-					SegsRetrans:   conn.SegsRetrans,
-					CongestionWin: conn.CongestionWin,
-					InBandwidth:   conn.InBandwidth,
-					OutBandwidth:  conn.OutBandwidth,
-				})
-				break
-			}
-		}
+	for _, view := range views {
+		history = append(history, connectionHistory(view)...)
 	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].Timestamp.Before(history[j].Timestamp) })
 	return history
 }
 
 // GetConnectionHistoryForSession returns historical data for a connection within a specific session
 func (s *SnapshotStore) GetConnectionHistoryForSession(sessionID int64, localAddr string, localPort int, remoteAddr string, remotePort int) []ConnectionHistoryPoint {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	return s.GetConnectionHistoryRange(sessionID, time.Time{}, time.Time{}, localAddr, localPort, remoteAddr, remotePort)
+}
 
-	var history []ConnectionHistoryPoint
-	for _, snap := range s.snapshots {
-		if snap.SessionID != sessionID {
-			continue
+// GetConnectionHistoryRange returns historical data for a connection within
+// a session between start and end inclusive; a zero time leaves that end of
+// the range open. Only the connection's own rows are decoded, always from
+// raw snapshots.
+func (s *SnapshotStore) GetConnectionHistoryRange(sessionID int64, start, end time.Time, localAddr string, localPort int, remoteAddr string, remotePort int) []ConnectionHistoryPoint {
+	view, _ := s.sessionView(sessionID, start, end, func(log *snapshotLog) *logView {
+		id, ok := log.findIdentity(localAddr, localPort, remoteAddr, remotePort)
+		if !ok {
+			return nil
 		}
-		for _, conn := range snap.Connections {
-			if conn.LocalAddr == localAddr && conn.LocalPort == localPort &&
-				conn.RemoteAddr == remoteAddr && conn.RemotePort == remotePort {
-				history = append(history, ConnectionHistoryPoint{
-					Timestamp:     snap.Timestamp,
-					State:         conn.State,
-					BytesIn:       conn.BytesIn,
-					BytesOut:      conn.BytesOut,
-					SegmentsIn:    conn.SegmentsIn,
-					SegmentsOut:   conn.SegmentsOut,
-					RTT:           conn.RTT,
-					RTTVariance:   conn.RTTVariance,
-					MinRTT:        conn.MinRTT,
-					MaxRTT:        conn.MaxRTT,
-					Retrans:       conn.Retrans,
-					SegsRetrans:   conn.SegsRetrans,
-					CongestionWin: conn.CongestionWin,
-					InBandwidth:   conn.InBandwidth,
-					OutBandwidth:  conn.OutBandwidth,
-				})
-				break
-			}
+		return log.connView(id, log.clipTime(log.sessionRuns(sessionID), start, end))
+	})
+	return connectionHistory(view)
+}
+
+// GetConnectionChartHistory returns historical data for a connection within
+// a session between start and end for charting. Like the chart timeline,
+// long ranges are served from rollups.
+func (s *SnapshotStore) GetConnectionChartHistory(sessionID int64, start, end time.Time, localAddr string, localPort int, remoteAddr string, remotePort int) []ConnectionHistoryPoint {
+	rollups, ok := s.rollupsFor(sessionID, start, end)
+	if !ok {
+		return s.GetConnectionHistoryRange(sessionID, start, end, localAddr, localPort, remoteAddr, remotePort)
+	}
+	var history []ConnectionHistoryPoint
+	for i := range rollups {
+		if rollups[i].matches(localAddr, localPort, remoteAddr, remotePort) {
+			history = append(history, rollups[i].historyPoint())
 		}
 	}
 	return history
 }
 
-// Compare compares two snapshots and returns differences
-func (s *SnapshotStore) Compare(id1, id2 int64) *ComparisonResult {
-	snap1 := s.GetByID(id1)
-	snap2 := s.GetByID(id2)
-
-	if snap1 == nil || snap2 == nil {
-		return nil
+// rollupsFor returns the rollups serving a chart over [start, end] of a
+// session, or false when raw snapshots should be read instead. A tier that
+// retention removed falls back to the next coarser one.
+func (s *SnapshotStore) rollupsFor(sessionID int64, start, end time.Time) ([]ConnectionRollup, bool) {
+	s.mu.RLock()
+	var session *RecordingSession
+	for i := range s.sessions {
+		if s.sessions[i].ID == sessionID {
+			session = &s.sessions[i]
+			break
+		}
 	}
-
-	// Build connection maps
-	map1 := make(map[string]CompactConnection)
-	map2 := make(map[string]CompactConnection)
-
-	for _, c := range snap1.Connections {
-		key := connKey(c)
-		map1[key] = c
+	if session == nil {
+		s.mu.RUnlock()
+		return nil, false
 	}
-	for _, c := range snap2.Connections {
-		key := connKey(c)
-		map2[key] = c
+	from, to := start, end
+	if from.IsZero() {
+		from = session.StartTime
 	}
-
-	result := &ComparisonResult{
-		Snapshot1: snap1.ID,
-		Snapshot2: snap2.ID,
+	if to.IsZero() {
+		if to = session.EndTime; to.IsZero() {
+			to = time.Now()
+		}
 	}
-
-	// Find added (in snap2 but not snap1)
-	for key, c := range map2 {
-		if _, exists := map1[key]; !exists {
-			result.Added = append(result.Added, c)
+	droppedUntil := session.DownsampledUntil
+	if s.disk == nil && s.snapshots.sessionLen(sessionID) < session.SnapshotCount {
+		// Evicted from the ring buffer; only rollups reach further back
+		if first, ok := s.snapshots.sessionStart(sessionID); ok {
+			droppedUntil = first.Add(-time.Nanosecond)
+		} else {
+			droppedUntil = to
 		}
 	}
+	s.mu.RUnlock()
 
-	// Find removed (in snap1 but not snap2)
-	for key, c := range map1 {
-		if _, exists := map2[key]; !exists {
-			result.Removed = append(result.Removed, c)
+	resolution := historyResolution(from, to, droppedUntil)
+	if resolution == 0 {
+		return nil, false
+	}
+	for tier := rollupTierIndex(resolution); tier < len(rollupTiers); tier++ {
+		if rollups, ok := s.sessionRollups(sessionID, rollupTiers[tier].resolution, start, end); ok {
+			return rollups, true
 		}
 	}
+	return nil, false
+}
 
-	// Find changed (in both, but stats differ)
-	for key, c1 := range map1 {
-		if c2, exists := map2[key]; exists {
-			if c1.BytesIn != c2.BytesIn || c1.BytesOut != c2.BytesOut ||
-				c1.RTT != c2.RTT || c1.Retrans != c2.Retrans || c1.State != c2.State {
-				result.Changed = append(result.Changed, ConnectionDiff{
-					Connection: c2,
-					DeltaIn:    c2.BytesIn - c1.BytesIn,
-					DeltaOut:   c2.BytesOut - c1.BytesOut,
-					DeltaRTT:   c2.RTT - c1.RTT,
-				})
+// GetSessionRollups returns the rollups of one resolution of a session that
+// overlap [start, end]; a zero time leaves that end of the range open
+func (s *SnapshotStore) GetSessionRollups(sessionID int64, resolution time.Duration, start, end time.Time) []ConnectionRollup {
+	rollups, _ := s.sessionRollups(sessionID, resolution, start, end)
+	return rollups
+}
+
+// sessionRollups returns the rollups of one resolution of a session that
+// overlap [start, end], including the open bucket of the active session. It
+// reports false when the tier is not available. Sessions stored without
+// rollups have them computed from their snapshots.
+func (s *SnapshotStore) sessionRollups(sessionID int64, resolution time.Duration, start, end time.Time) ([]ConnectionRollup, bool) {
+	overlaps := func(r *ConnectionRollup) bool {
+		bucketEnd := r.Start.Add(time.Duration(r.Resolution) * time.Second)
+		return (start.IsZero() || bucketEnd.After(start)) && (end.IsZero() || !r.Start.After(end))
+	}
+	tier := rollupTierIndex(resolution)
+	if tier < 0 {
+		return nil, false
+	}
+
+	s.mu.RLock()
+	var open []ConnectionRollup
+	if rec := s.recordingLocked(sessionID); rec != nil {
+		for _, r := range rec.rollup.open(resolution) {
+			if overlaps(&r) {
+				r.SessionID = sessionID
+				open = append(open, r)
 			}
 		}
 	}
+	if s.disk == nil {
+		var result []ConnectionRollup
+		for i := range s.rollups[tier] {
+			if r := &s.rollups[tier][i]; r.SessionID == sessionID && overlaps(r) {
+				result = append(result, *r)
+			}
+		}
+		s.mu.RUnlock()
+		result = append(result, open...)
+		return result, len(result) > 0
+	}
+	s.mu.RUnlock()
 
-	return result
-}
-
-func connKey(c CompactConnection) string {
-	return c.LocalAddr + ":" + string(rune(c.LocalPort)) + "-" + c.RemoteAddr + ":" + string(rune(c.RemotePort))
-}
+	stored, ok, err := s.disk.LoadRollups(sessionID, resolution, start, end)
+	if err != nil {
+		s.logger.Warn("Failed to load rollups of session %d: %v", sessionID, err)
+	}
+	if ok {
+		return append(stored, open...), true
+	}
+	if err != nil || s.GetSessionByID(sessionID) == nil {
+		return nil, false
+	}
 
-// ComparisonResult holds diff between two snapshots
-type ComparisonResult struct {
-	Snapshot1 int64               `json:"snapshot1"`
-	Snapshot2 int64               `json:"snapshot2"`
-	Added     []CompactConnection `json:"added"`
-	Removed   []CompactConnection `json:"removed"`
-	Changed   []ConnectionDiff    `json:"changed"`
+	// Recorded before rollups existed and never downsampled, so every
+	// snapshot is still there
+	from, to := start, end
+	if !from.IsZero() {
+		from = from.Truncate(resolution)
+	}
+	if !to.IsZero() {
+		to = to.Truncate(resolution).Add(resolution - time.Nanosecond)
+	}
+	view, _ := s.sessionView(sessionID, from, to, func(log *snapshotLog) *logView {
+		return log.view(log.clipTime(log.sessionRuns(sessionID), from, to))
+	})
+	rollups := rollupView(view, resolution)
+	for i := range rollups {
+		rollups[i].SessionID = sessionID
+	}
+	return rollups, len(rollups) > 0
 }
 
-// ConnectionDiff shows what changed for a connection
-type ConnectionDiff struct {
-	Connection CompactConnection `json:"connection"`
-	DeltaIn    int64             `json:"deltaIn"`
-	DeltaOut   int64             `json:"deltaOut"`
-	DeltaRTT   int64             `json:"deltaRtt"`
+// connectionHistory decodes the history points of a connection view
+func connectionHistory(view *logView) []ConnectionHistoryPoint {
+	if view == nil {
+		return nil
+	}
+	var history []ConnectionHistoryPoint
+	view.EachRow(func(timestamp time.Time, conn *CompactConnection) {
+		history = append(history, ConnectionHistoryPoint{
+			Timestamp:     timestamp,
+			State:         conn.State,
+			BytesIn:       conn.BytesIn,
+			BytesOut:      conn.BytesOut,
+			SegmentsIn:    conn.SegmentsIn,
+			SegmentsOut:   conn.SegmentsOut,
+			RTT:           conn.RTT,
+			RTTVariance:   conn.RTTVariance,
+			MinRTT:        conn.MinRTT,
+			MaxRTT:        conn.MaxRTT,
+			Retrans:       conn.Retrans,
+			SegsRetrans:   conn.SegsRetrans,
+			CongestionWin: conn.CongestionWin,
+			InBandwidth:   conn.InBandwidth,
+			OutBandwidth:  conn.OutBandwidth,
+		})
+	})
+	return history
 }
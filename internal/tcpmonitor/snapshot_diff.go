@@ -0,0 +1,646 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Change classes of a connection diff
+const (
+	DiffAdded              = "added"
+	DiffRemoved            = "removed"
+	DiffStateTransition    = "state_transition"
+	DiffNewRetransmissions = "new_retransmissions"
+	DiffTimeouts           = "timeouts"
+	DiffRTTShift           = "rtt_shift"
+	DiffProcessChanged     = "process_changed"
+	DiffCongestionState    = "congestion_state"
+	DiffTraffic            = "traffic"
+	DiffOther              = "other"
+)
+
+var diffChangeClasses = []string{
+	DiffAdded, DiffRemoved, DiffStateTransition, DiffNewRetransmissions, DiffTimeouts,
+	DiffRTTShift, DiffProcessChanged, DiffCongestionState, DiffTraffic, DiffOther,
+}
+
+// Default RTT shift thresholds; a change must exceed both to count
+const (
+	defaultRTTShiftMs      = 20
+	defaultRTTShiftPercent = 25
+)
+
+// DiffOptions controls what a snapshot or range diff reports and in which order
+type DiffOptions struct {
+	Filter          FilterOptions // Only diff connections matching these criteria on either side
+	Changes         []string      // Keep only connections with one of these change classes (empty keeps all)
+	SortBy          string        // Metric to order by, e.g. "rtt" or "segsRetrans" (empty = bytes transferred)
+	Ascending       bool          // Smallest change first instead of largest
+	Limit           int           // Maximum connections per list (0 = no limit)
+	RTTShiftMs      int64         // Minimum RTT change reported as a shift (0 = 20ms)
+	RTTShiftPercent float64       // Minimum relative RTT change reported as a shift (0 = 25%)
+}
+
+// DiffRange is one side of a range diff
+type DiffRange struct {
+	SessionID int64     `json:"sessionId"`
+	Start     time.Time `json:"start"`     // Zero = start of the session
+	End       time.Time `json:"end"`       // Zero = end of the session
+	Snapshots int       `json:"snapshots"` // Snapshots found in the range, filled in by the diff
+}
+
+// ComparisonResult holds the diff between two snapshots or time ranges
+type ComparisonResult struct {
+	Snapshot1    int64               `json:"snapshot1"`
+	Snapshot2    int64               `json:"snapshot2"`
+	RangeA       *DiffRange          `json:"rangeA,omitempty"` // Set for range diffs
+	RangeB       *DiffRange          `json:"rangeB,omitempty"`
+	Added        []CompactConnection `json:"added"`
+	Removed      []CompactConnection `json:"removed"`
+	Changed      []ConnectionDiff    `json:"changed"`
+	Unchanged    int                 `json:"unchanged"`    // Connections on both sides with nothing changed
+	ChangeCounts map[string]int      `json:"changeCounts"` // Connections per change class, before filtering by class and limiting
+}
+
+// ConnectionDiff shows what changed for a connection
+type ConnectionDiff struct {
+	Connection CompactConnection `json:"connection"` // Second side
+	Previous   CompactConnection `json:"previous"`   // First side
+	DeltaIn    int64             `json:"deltaIn"`
+	DeltaOut   int64             `json:"deltaOut"`
+	DeltaRTT   int64             `json:"deltaRtt"`
+	Changes    []string          `json:"changes"`          // Change classes, e.g. state_transition
+	Metrics    []MetricChange    `json:"metrics"`          // Every counter and gauge that moved
+	Fields     []FieldChange     `json:"fields,omitempty"` // Descriptive fields that changed
+}
+
+// MetricChange is the change of one counter or gauge
+type MetricChange struct {
+	Metric        string  `json:"metric"` // JSON name of the CompactConnection field
+	Kind          string  `json:"kind"`   // counter or gauge
+	From          float64 `json:"from"`
+	To            float64 `json:"to"`
+	Delta         float64 `json:"delta"`
+	PercentChange float64 `json:"percentChange"` // 0 when From is 0
+}
+
+// FieldChange is the change of a descriptive field such as the state or PID
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// diffColumn is a counter or gauge column of a packed row
+type diffColumn struct {
+	name    string // JSON name in CompactConnection
+	col     int
+	counter bool
+	float   bool // Stored as float64 bits
+}
+
+// diffColumns lists every counter and gauge of CompactConnection
+var diffColumns = []diffColumn{
+	{name: "bytesIn", col: colBytesIn, counter: true},
+	{name: "bytesOut", col: colBytesOut, counter: true},
+	{name: "segmentsIn", col: colSegmentsIn, counter: true},
+	{name: "segmentsOut", col: colSegmentsOut, counter: true},
+	{name: "timerExpiresMs", col: colTimerExpiresMs},
+	{name: "timerRetransmits", col: colTimerRetransmits},
+	{name: "timerProbes", col: colTimerProbes},
+	{name: "timerBackoff", col: colTimerBackoff},
+	{name: "rtoMs", col: colRTOMs},
+	{name: "sampleRTT", col: colSampleRTT},
+	{name: "fastRetrans", col: colFastRetrans, counter: true},
+	{name: "timeoutEpisodes", col: colTimeoutEpisodes, counter: true},
+	{name: "rtt", col: colRTT},
+	{name: "rttVariance", col: colRTTVariance},
+	{name: "minRtt", col: colMinRTT},
+	{name: "maxRtt", col: colMaxRTT},
+	{name: "retrans", col: colRetrans, counter: true},
+	{name: "segsRetrans", col: colSegsRetrans, counter: true},
+	{name: "totalSegsOut", col: colTotalSegsOut, counter: true},
+	{name: "totalSegsIn", col: colTotalSegsIn, counter: true},
+	{name: "congestionWin", col: colCongestionWin},
+	{name: "inBandwidth", col: colInBandwidth},
+	{name: "outBandwidth", col: colOutBandwidth},
+	{name: "thruBytesAcked", col: colThruBytesAcked, counter: true},
+	{name: "thruBytesReceived", col: colThruBytesReceived, counter: true},
+	{name: "currentSsthresh", col: colCurrentSsthresh},
+	{name: "slowStartCount", col: colSlowStartCount, counter: true},
+	{name: "congAvoidCount", col: colCongAvoidCount, counter: true},
+	{name: "curRetxQueue", col: colCurRetxQueue},
+	{name: "maxRetxQueue", col: colMaxRetxQueue},
+	{name: "curAppWQueue", col: colCurAppWQueue},
+	{name: "maxAppWQueue", col: colMaxAppWQueue},
+	{name: "rmemAlloc", col: colRmemAlloc},
+	{name: "rcvBuf", col: colRcvBuf},
+	{name: "wmemAlloc", col: colWmemAlloc},
+	{name: "wmemQueued", col: colWmemQueued},
+	{name: "sndBuf", col: colSndBuf},
+	{name: "fwdAlloc", col: colFwdAlloc},
+	{name: "sockDrops", col: colSockDrops, counter: true},
+	{name: "winScaleRcvd", col: colWinScaleRcvd},
+	{name: "winScaleSent", col: colWinScaleSent},
+	{name: "curRwinRcvd", col: colCurRwinRcvd},
+	{name: "maxRwinRcvd", col: colMaxRwinRcvd},
+	{name: "curRwinSent", col: colCurRwinSent},
+	{name: "maxRwinSent", col: colMaxRwinSent},
+	{name: "curMss", col: colCurMss},
+	{name: "maxMss", col: colMaxMss},
+	{name: "minMss", col: colMinMss},
+	{name: "dupAcksIn", col: colDupAcksIn, counter: true},
+	{name: "dupAcksOut", col: colDupAcksOut, counter: true},
+	{name: "sacksRcvd", col: colSacksRcvd, counter: true},
+	{name: "sackBlocksRcvd", col: colSackBlocksRcvd, counter: true},
+	{name: "dsackDups", col: colDsackDups, counter: true},
+	{name: "sndLimTimeRwin", col: colSndLimTimeRwin, counter: true},
+	{name: "sndLimTimeCwnd", col: colSndLimTimeCwnd, counter: true},
+	{name: "sndLimTimeSnd", col: colSndLimTimeSnd, counter: true},
+	{name: "pacingRate", col: colPacingRate},
+	{name: "bbrBandwidth", col: colBBRBandwidth},
+	{name: "bbrMinRtt", col: colBBRMinRTT},
+	{name: "bbrPacingGain", col: colBBRPacingGain, float: true},
+	{name: "bbrCwndGain", col: colBBRCwndGain, float: true},
+	{name: "dctcpAlpha", col: colDCTCPAlpha},
+	{name: "vegasRtt", col: colVegasRTT},
+	{name: "vegasMinRtt", col: colVegasMinRTT},
+}
+
+// lookupDiffColumn finds a counter or gauge by its JSON name, ignoring case
+func lookupDiffColumn(name string) (diffColumn, bool) {
+	for _, c := range diffColumns {
+		if strings.EqualFold(c.name, name) {
+			return c, true
+		}
+	}
+	return diffColumn{}, false
+}
+
+// value reads the column from a packed row
+func (d diffColumn) value(r *packedRow) float64 {
+	if d.float {
+		return math.Float64frombits(uint64(r[d.col]))
+	}
+	return float64(r[d.col])
+}
+
+// set writes v to the column of a packed row
+func (d diffColumn) set(r *packedRow, v float64) {
+	if d.float {
+		r[d.col] = int64(math.Float64bits(v))
+		return
+	}
+	r[d.col] = int64(math.Round(v))
+}
+
+// kind returns "counter" or "gauge"
+func (d diffColumn) kind() string {
+	if d.counter {
+		return "counter"
+	}
+	return "gauge"
+}
+
+// diffFields lists the descriptive fields of CompactConnection
+var diffFields = []struct {
+	name  string
+	value func(c *CompactConnection) string
+}{
+	{"state", func(c *CompactConnection) string { return TCPState(c.State).String() }},
+	{"pid", func(c *CompactConnection) string { return strconv.Itoa(c.PID) }},
+	{"remoteHostname", func(c *CompactConnection) string { return c.RemoteHostname }},
+	{"remoteCountry", func(c *CompactConnection) string { return c.RemoteCountry }},
+	{"remoteCity", func(c *CompactConnection) string { return c.RemoteCity }},
+	{"remoteAsn", func(c *CompactConnection) string { return strconv.FormatInt(c.RemoteASN, 10) }},
+	{"remoteAsOrg", func(c *CompactConnection) string { return c.RemoteASOrg }},
+	{"serviceName", func(c *CompactConnection) string { return c.ServiceName }},
+	{"role", func(c *CompactConnection) string { return c.Role }},
+	{"timerKind", func(c *CompactConnection) string { return c.TimerKind }},
+	{"congestionAlgorithm", func(c *CompactConnection) string { return c.CongestionAlgorithm }},
+	{"caState", func(c *CompactConnection) string { return c.CAState }},
+}
+
+// connTuple is the 4-tuple of a connection
+type connTuple struct {
+	localAddr  string
+	localPort  int
+	remoteAddr string
+	remotePort int
+}
+
+func tupleOf(c *CompactConnection) connTuple {
+	return connTuple{c.LocalAddr, c.LocalPort, c.RemoteAddr, c.RemotePort}
+}
+
+// Compare diffs two snapshots
+func (s *SnapshotStore) Compare(id1, id2 int64, opts DiffOptions) (*ComparisonResult, error) {
+	snap1 := s.GetByID(id1)
+	if snap1 == nil {
+		return nil, fmt.Errorf("snapshot %d not found", id1)
+	}
+	snap2 := s.GetByID(id2)
+	if snap2 == nil {
+		return nil, fmt.Errorf("snapshot %d not found", id2)
+	}
+
+	d, err := newDiffer(opts)
+	if err != nil {
+		return nil, err
+	}
+	result := d.diff(snap1.Connections, snap2.Connections)
+	result.Snapshot1 = snap1.ID
+	result.Snapshot2 = snap2.ID
+	return result, nil
+}
+
+// CompareRanges diffs two time ranges of recorded sessions. Each range is
+// reduced to one row per connection: counters hold their increase within
+// the range and gauges their mean, so counter deltas compare how much
+// happened in each range. Descriptive fields are taken from the last row.
+func (s *SnapshotStore) CompareRanges(a, b DiffRange, opts DiffOptions) (*ComparisonResult, error) {
+	d, err := newDiffer(opts)
+	if err != nil {
+		return nil, err
+	}
+	connsA, err := d.aggregateRange(s, &a)
+	if err != nil {
+		return nil, err
+	}
+	connsB, err := d.aggregateRange(s, &b)
+	if err != nil {
+		return nil, err
+	}
+
+	result := d.diff(connsA, connsB)
+	result.RangeA = &a
+	result.RangeB = &b
+	return result, nil
+}
+
+// differ holds the options of one diff and the string table its rows are
+// packed with
+type differ struct {
+	opts    DiffOptions
+	sortBy  *diffColumn
+	classes map[string]bool
+	filters *FilterEngine
+	table   *stringTable
+}
+
+func newDiffer(opts DiffOptions) (*differ, error) {
+	d := &differ{opts: opts, filters: NewFilterEngine(), table: newStringTable()}
+	if d.opts.RTTShiftMs <= 0 {
+		d.opts.RTTShiftMs = defaultRTTShiftMs
+	}
+	if d.opts.RTTShiftPercent <= 0 {
+		d.opts.RTTShiftPercent = defaultRTTShiftPercent
+	}
+	if opts.SortBy != "" {
+		col, ok := lookupDiffColumn(opts.SortBy)
+		if !ok {
+			return nil, fmt.Errorf("unknown sort metric %q", opts.SortBy)
+		}
+		d.sortBy = &col
+	}
+	if len(opts.Changes) > 0 {
+		d.classes = make(map[string]bool)
+		for _, class := range opts.Changes {
+			known := false
+			for _, c := range diffChangeClasses {
+				known = known || c == class
+			}
+			if !known {
+				return nil, fmt.Errorf("unknown change class %q (use %s)", class, strings.Join(diffChangeClasses, ", "))
+			}
+			d.classes[class] = true
+		}
+	}
+	return d, nil
+}
+
+// diff compares two connection lists
+func (d *differ) diff(a, b []CompactConnection) *ComparisonResult {
+	result := &ComparisonResult{
+		Added:        []CompactConnection{},
+		Removed:      []CompactConnection{},
+		Changed:      []ConnectionDiff{},
+		ChangeCounts: make(map[string]int),
+	}
+
+	pairs, removed, added := matchConnections(a, b)
+	for _, i := range removed {
+		if d.matches(&a[i]) {
+			result.ChangeCounts[DiffRemoved]++
+			if d.wants(DiffRemoved) {
+				result.Removed = append(result.Removed, a[i])
+			}
+		}
+	}
+	for _, j := range added {
+		if d.matches(&b[j]) {
+			result.ChangeCounts[DiffAdded]++
+			if d.wants(DiffAdded) {
+				result.Added = append(result.Added, b[j])
+			}
+		}
+	}
+	for _, p := range pairs {
+		prev, cur := &a[p[0]], &b[p[1]]
+		if !d.matches(prev) && !d.matches(cur) {
+			continue
+		}
+		diff := d.diffPair(prev, cur)
+		if len(diff.Changes) == 0 {
+			result.Unchanged++
+			continue
+		}
+		wanted := false
+		for _, class := range diff.Changes {
+			result.ChangeCounts[class]++
+			wanted = wanted || d.wants(class)
+		}
+		if wanted {
+			result.Changed = append(result.Changed, diff)
+		}
+	}
+
+	d.sortConnections(result.Added)
+	d.sortConnections(result.Removed)
+	d.sortDiffs(result.Changed)
+	if limit := d.opts.Limit; limit > 0 {
+		if len(result.Added) > limit {
+			result.Added = result.Added[:limit]
+		}
+		if len(result.Removed) > limit {
+			result.Removed = result.Removed[:limit]
+		}
+		if len(result.Changed) > limit {
+			result.Changed = result.Changed[:limit]
+		}
+	}
+	return result
+}
+
+// matches applies the filter of the diff
+func (d *differ) matches(c *CompactConnection) bool {
+	if !d.filters.hasActiveFilters(d.opts.Filter) {
+		return true
+	}
+	return d.filters.matchesFilter(expandConnection(c), d.opts.Filter)
+}
+
+// wants reports whether connections of a change class are listed
+func (d *differ) wants(class string) bool {
+	return d.classes == nil || d.classes[class]
+}
+
+// diffPair computes the metric and field changes of a connection and
+// classifies them. No changes means the connection is unchanged.
+func (d *differ) diffPair(prev, cur *CompactConnection) ConnectionDiff {
+	diff := ConnectionDiff{
+		Connection: *cur,
+		Previous:   *prev,
+		DeltaIn:    cur.BytesIn - prev.BytesIn,
+		DeltaOut:   cur.BytesOut - prev.BytesOut,
+		DeltaRTT:   cur.RTT - prev.RTT,
+	}
+
+	var rowA, rowB packedRow
+	d.table.packRow(prev, &rowA)
+	d.table.packRow(cur, &rowB)
+	for _, col := range diffColumns {
+		from, to := col.value(&rowA), col.value(&rowB)
+		if from == to {
+			continue
+		}
+		change := MetricChange{Metric: col.name, Kind: col.kind(), From: from, To: to, Delta: to - from}
+		if from != 0 {
+			change.PercentChange = (to - from) / math.Abs(from) * 100
+		}
+		diff.Metrics = append(diff.Metrics, change)
+	}
+	for _, f := range diffFields {
+		if from, to := f.value(prev), f.value(cur); from != to {
+			diff.Fields = append(diff.Fields, FieldChange{Field: f.name, From: from, To: to})
+		}
+	}
+	if len(diff.Metrics) == 0 && len(diff.Fields) == 0 {
+		return diff
+	}
+
+	if prev.State != cur.State {
+		diff.Changes = append(diff.Changes, DiffStateTransition)
+	}
+	if prev.PID != cur.PID {
+		diff.Changes = append(diff.Changes, DiffProcessChanged)
+	}
+	if cur.SegsRetrans > prev.SegsRetrans || cur.Retrans > prev.Retrans || cur.FastRetrans > prev.FastRetrans {
+		diff.Changes = append(diff.Changes, DiffNewRetransmissions)
+	}
+	if cur.TimeoutEpisodes > prev.TimeoutEpisodes {
+		diff.Changes = append(diff.Changes, DiffTimeouts)
+	}
+	if d.rttShifted(prev.RTT, cur.RTT) {
+		diff.Changes = append(diff.Changes, DiffRTTShift)
+	}
+	if prev.CongestionAlgorithm != cur.CongestionAlgorithm || prev.CAState != cur.CAState {
+		diff.Changes = append(diff.Changes, DiffCongestionState)
+	}
+	if diff.DeltaIn != 0 || diff.DeltaOut != 0 {
+		diff.Changes = append(diff.Changes, DiffTraffic)
+	}
+	if len(diff.Changes) == 0 {
+		diff.Changes = append(diff.Changes, DiffOther)
+	}
+	return diff
+}
+
+// rttShifted reports whether an RTT change exceeds both shift thresholds
+func (d *differ) rttShifted(from, to int64) bool {
+	change := math.Abs(float64(to - from))
+	if change < float64(d.opts.RTTShiftMs) {
+		return false
+	}
+	return from == 0 || change/float64(from)*100 >= d.opts.RTTShiftPercent
+}
+
+// sortDiffs orders changed connections by the size of their change in the
+// sort metric, largest first unless ascending
+func (d *differ) sortDiffs(diffs []ConnectionDiff) {
+	key := func(diff *ConnectionDiff) float64 {
+		if d.sortBy == nil {
+			return math.Abs(float64(diff.DeltaIn)) + math.Abs(float64(diff.DeltaOut))
+		}
+		for _, m := range diff.Metrics {
+			if m.Metric == d.sortBy.name {
+				return math.Abs(m.Delta)
+			}
+		}
+		return 0
+	}
+	sort.SliceStable(diffs, func(i, j int) bool {
+		if d.opts.Ascending {
+			return key(&diffs[i]) < key(&diffs[j])
+		}
+		return key(&diffs[i]) > key(&diffs[j])
+	})
+}
+
+// sortConnections orders added or removed connections by the sort metric
+func (d *differ) sortConnections(conns []CompactConnection) {
+	keys := make([]float64, len(conns))
+	for i := range conns {
+		if d.sortBy == nil {
+			keys[i] = float64(conns[i].BytesIn + conns[i].BytesOut)
+			continue
+		}
+		var row packedRow
+		d.table.packRow(&conns[i], &row)
+		keys[i] = d.sortBy.value(&row)
+	}
+	order := make([]int, len(conns))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if d.opts.Ascending {
+			return keys[order[i]] < keys[order[j]]
+		}
+		return keys[order[i]] > keys[order[j]]
+	})
+	sorted := make([]CompactConnection, len(conns))
+	for i, k := range order {
+		sorted[i] = conns[k]
+	}
+	copy(conns, sorted)
+}
+
+// matchConnections pairs the connections of two snapshots by 4-tuple. Rows
+// sharing a 4-tuple, such as listeners bound with SO_REUSEPORT, are paired
+// by PID first and then in order. It returns the index pairs and the
+// indexes left over on either side.
+func matchConnections(a, b []CompactConnection) (pairs [][2]int, removed, added []int) {
+	byTuple := make(map[connTuple][]int)
+	for j := range b {
+		t := tupleOf(&b[j])
+		byTuple[t] = append(byTuple[t], j)
+	}
+
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+	matched := make([]bool, len(b))
+	for pass := 0; pass < 2; pass++ {
+		for i := range a {
+			if match[i] >= 0 {
+				continue
+			}
+			for _, j := range byTuple[tupleOf(&a[i])] {
+				if !matched[j] && (pass == 1 || a[i].PID == b[j].PID) {
+					match[i], matched[j] = j, true
+					break
+				}
+			}
+		}
+	}
+
+	for i, j := range match {
+		if j >= 0 {
+			pairs = append(pairs, [2]int{i, j})
+		} else {
+			removed = append(removed, i)
+		}
+	}
+	for j := range b {
+		if !matched[j] {
+			added = append(added, j)
+		}
+	}
+	return pairs, removed, added
+}
+
+// rangeConnection accumulates the rows of one connection within a range
+type rangeConnection struct {
+	conn  CompactConnection // Last row
+	last  packedRow
+	sums  [numColumns]float64
+	count int
+}
+
+// aggregateRange reduces the rows of a range to one row per connection and
+// PID and records the number of snapshots in r
+func (d *differ) aggregateRange(s *SnapshotStore, r *DiffRange) ([]CompactConnection, error) {
+	if !r.Start.IsZero() && !r.End.IsZero() && r.End.Before(r.Start) {
+		return nil, fmt.Errorf("range of session %d ends before it starts", r.SessionID)
+	}
+
+	type rangeKey struct {
+		tuple connTuple
+		pid   int
+	}
+	var order []*rangeConnection
+	conns := make(map[rangeKey]*rangeConnection)
+	timestamps := make(map[time.Time]bool)
+
+	for _, row := range s.GetSessionTimelineRange(r.SessionID, r.Start, r.End) {
+		if row.Annotation != nil {
+			continue
+		}
+		timestamps[row.Timestamp] = true
+
+		c := &row.Connection
+		key := rangeKey{tupleOf(c), c.PID}
+		rc := conns[key]
+		var packed packedRow
+		d.table.packRow(c, &packed)
+		if rc == nil {
+			rc = &rangeConnection{}
+			conns[key] = rc
+			order = append(order, rc)
+		}
+		for _, col := range diffColumns {
+			v := col.value(&packed)
+			if !col.counter {
+				rc.sums[col.col] += v
+				continue
+			}
+			// Counters add their increase; a drop means the counter was reset
+			if rc.count > 0 {
+				prev := col.value(&rc.last)
+				if v >= prev {
+					rc.sums[col.col] += v - prev
+				} else {
+					rc.sums[col.col] += v
+				}
+			}
+		}
+		rc.conn = *c
+		rc.last = packed
+		rc.count++
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("session %d has no snapshots in the range", r.SessionID)
+	}
+	r.Snapshots = len(timestamps)
+
+	result := make([]CompactConnection, len(order))
+	for i, rc := range order {
+		agg := rc.last
+		for _, col := range diffColumns {
+			if col.counter {
+				col.set(&agg, rc.sums[col.col])
+			} else {
+				col.set(&agg, rc.sums[col.col]/float64(rc.count))
+			}
+		}
+		result[i] = rc.conn
+		d.table.strings.unpackRow(&agg, &result[i])
+	}
+	return result, nil
+}
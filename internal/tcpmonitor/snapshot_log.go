@@ -0,0 +1,1200 @@
+package tcpmonitor
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"time"
+)
+
+// Snapshots are held in memory in an encoded form. Connection identities and
+// strings are interned, each connection row is a fixed vector of int64
+// columns, and every frame stores only the rows that changed since the
+// previous frame as varint deltas. Keyframes, which hold every row against a
+// zero baseline, start each session and recur every snapshotKeyframeInterval
+// frames so that a single snapshot can be rebuilt without replaying the
+// whole log. Snapshot and TimelineConnection values are rebuilt on read.
+//
+// The log is indexed by session (runs of frame positions), by time (binary
+// search within a run) and by connection identity (the frames holding the
+// connection and those where its row changed). A single connection's history
+// is rebuilt from its own changes only, starting at the nearest one encoded
+// against zero. Queries copy what they need into a logView under the owner's
+// lock and decode it after releasing the lock.
+
+// A keyframe is stored at least this often
+const snapshotKeyframeInterval = 64
+
+// Columns of a packed connection row: every CompactConnection field except
+// the identity (addresses and ports). Keep in sync with packRow/unpackRow
+// when fields are added to CompactConnection.
+const (
+	colState = iota
+	colPID
+	colRemoteHostname
+	colRemoteCountry
+	colRemoteCity
+	colRemoteASN
+	colRemoteASOrg
+	colServiceName
+	colRole
+	colBytesIn
+	colBytesOut
+	colSegmentsIn
+	colSegmentsOut
+	colTimerKind
+	colTimerExpiresMs
+	colTimerRetransmits
+	colTimerProbes
+	colTimerBackoff
+	colRTOMs
+	colSampleRTT
+	colFastRetrans
+	colTimeoutEpisodes
+	colRTT
+	colRTTVariance
+	colMinRTT
+	colMaxRTT
+	colRetrans
+	colSegsRetrans
+	colTotalSegsOut
+	colTotalSegsIn
+	colCongestionWin
+	colInBandwidth
+	colOutBandwidth
+	colThruBytesAcked
+	colThruBytesReceived
+	colCurrentSsthresh
+	colSlowStartCount
+	colCongAvoidCount
+	colCurRetxQueue
+	colMaxRetxQueue
+	colCurAppWQueue
+	colMaxAppWQueue
+	colRmemAlloc
+	colRcvBuf
+	colWmemAlloc
+	colWmemQueued
+	colSndBuf
+	colFwdAlloc
+	colSockDrops
+	colWinScaleRcvd
+	colWinScaleSent
+	colCurRwinRcvd
+	colMaxRwinRcvd
+	colCurRwinSent
+	colMaxRwinSent
+	colCurMss
+	colMaxMss
+	colMinMss
+	colDupAcksIn
+	colDupAcksOut
+	colSacksRcvd
+	colSackBlocksRcvd
+	colDsackDups
+	colSndLimTimeRwin
+	colSndLimTimeCwnd
+	colSndLimTimeSnd
+	colCongestionAlgorithm
+	colCAState
+	colPacingRate
+	colBBRBandwidth
+	colBBRMinRTT
+	colBBRPacingGain
+	colBBRCwndGain
+	colDCTCPAlpha
+	colVegasRTT
+	colVegasMinRTT
+	numColumns
+)
+
+// packedRow is a connection row with strings replaced by interned IDs and
+// floats by their bit patterns
+type packedRow [numColumns]int64
+
+// connIdentity identifies a connection row within a frame. Occurrence tells
+// apart rows that share a 4-tuple in the same snapshot.
+type connIdentity struct {
+	LocalAddr  uint32
+	LocalPort  uint16
+	RemoteAddr uint32
+	RemotePort uint16
+	Occurrence uint16
+}
+
+// stringTable interns strings; ID 0 is the empty string
+type stringTable struct {
+	ids     map[string]uint32
+	strings stringList
+}
+
+// stringList resolves interned IDs. Strings are only ever appended, so a
+// copy of the slice taken under the store lock stays valid without it.
+type stringList []string
+
+func newStringTable() *stringTable {
+	return &stringTable{
+		ids:     make(map[string]uint32),
+		strings: []string{""},
+	}
+}
+
+// intern returns the ID of s, adding it if needed
+func (t *stringTable) intern(s string) uint32 {
+	if s == "" {
+		return 0
+	}
+	if id, ok := t.ids[s]; ok {
+		return id
+	}
+	id := uint32(len(t.strings))
+	t.ids[s] = id
+	t.strings = append(t.strings, s)
+	return id
+}
+
+// lookup returns the string with the given ID
+func (l stringList) lookup(id int64) string {
+	if id <= 0 || id >= int64(len(l)) {
+		return ""
+	}
+	return l[id]
+}
+
+// packRow converts a connection to its column vector
+func (t *stringTable) packRow(c *CompactConnection, r *packedRow) {
+	r[colState] = int64(c.State)
+	r[colPID] = int64(c.PID)
+	r[colRemoteHostname] = int64(t.intern(c.RemoteHostname))
+	r[colRemoteCountry] = int64(t.intern(c.RemoteCountry))
+	r[colRemoteCity] = int64(t.intern(c.RemoteCity))
+	r[colRemoteASN] = c.RemoteASN
+	r[colRemoteASOrg] = int64(t.intern(c.RemoteASOrg))
+	r[colServiceName] = int64(t.intern(c.ServiceName))
+	r[colRole] = int64(t.intern(c.Role))
+	r[colBytesIn] = c.BytesIn
+	r[colBytesOut] = c.BytesOut
+	r[colSegmentsIn] = c.SegmentsIn
+	r[colSegmentsOut] = c.SegmentsOut
+	r[colTimerKind] = int64(t.intern(c.TimerKind))
+	r[colTimerExpiresMs] = c.TimerExpiresMs
+	r[colTimerRetransmits] = c.TimerRetransmits
+	r[colTimerProbes] = c.TimerProbes
+	r[colTimerBackoff] = c.TimerBackoff
+	r[colRTOMs] = c.RTOMs
+	r[colSampleRTT] = c.SampleRTT
+	r[colFastRetrans] = c.FastRetrans
+	r[colTimeoutEpisodes] = c.TimeoutEpisodes
+	r[colRTT] = c.RTT
+	r[colRTTVariance] = c.RTTVariance
+	r[colMinRTT] = c.MinRTT
+	r[colMaxRTT] = c.MaxRTT
+	r[colRetrans] = c.Retrans
+	r[colSegsRetrans] = c.SegsRetrans
+	r[colTotalSegsOut] = c.TotalSegsOut
+	r[colTotalSegsIn] = c.TotalSegsIn
+	r[colCongestionWin] = c.CongestionWin
+	r[colInBandwidth] = c.InBandwidth
+	r[colOutBandwidth] = c.OutBandwidth
+	r[colThruBytesAcked] = c.ThruBytesAcked
+	r[colThruBytesReceived] = c.ThruBytesReceived
+	r[colCurrentSsthresh] = c.CurrentSsthresh
+	r[colSlowStartCount] = c.SlowStartCount
+	r[colCongAvoidCount] = c.CongAvoidCount
+	r[colCurRetxQueue] = c.CurRetxQueue
+	r[colMaxRetxQueue] = c.MaxRetxQueue
+	r[colCurAppWQueue] = c.CurAppWQueue
+	r[colMaxAppWQueue] = c.MaxAppWQueue
+	r[colRmemAlloc] = c.RmemAlloc
+	r[colRcvBuf] = c.RcvBuf
+	r[colWmemAlloc] = c.WmemAlloc
+	r[colWmemQueued] = c.WmemQueued
+	r[colSndBuf] = c.SndBuf
+	r[colFwdAlloc] = c.FwdAlloc
+	r[colSockDrops] = c.SockDrops
+	r[colWinScaleRcvd] = int64(c.WinScaleRcvd)
+	r[colWinScaleSent] = int64(c.WinScaleSent)
+	r[colCurRwinRcvd] = c.CurRwinRcvd
+	r[colMaxRwinRcvd] = c.MaxRwinRcvd
+	r[colCurRwinSent] = c.CurRwinSent
+	r[colMaxRwinSent] = c.MaxRwinSent
+	r[colCurMss] = c.CurMss
+	r[colMaxMss] = c.MaxMss
+	r[colMinMss] = c.MinMss
+	r[colDupAcksIn] = c.DupAcksIn
+	r[colDupAcksOut] = c.DupAcksOut
+	r[colSacksRcvd] = c.SacksRcvd
+	r[colSackBlocksRcvd] = c.SackBlocksRcvd
+	r[colDsackDups] = c.DsackDups
+	r[colSndLimTimeRwin] = c.SndLimTimeRwin
+	r[colSndLimTimeCwnd] = c.SndLimTimeCwnd
+	r[colSndLimTimeSnd] = c.SndLimTimeSnd
+	r[colCongestionAlgorithm] = int64(t.intern(c.CongestionAlgorithm))
+	r[colCAState] = int64(t.intern(c.CAState))
+	r[colPacingRate] = c.PacingRate
+	r[colBBRBandwidth] = c.BBRBandwidth
+	r[colBBRMinRTT] = c.BBRMinRTT
+	r[colBBRPacingGain] = int64(math.Float64bits(c.BBRPacingGain))
+	r[colBBRCwndGain] = int64(math.Float64bits(c.BBRCwndGain))
+	r[colDCTCPAlpha] = c.DCTCPAlpha
+	r[colVegasRTT] = c.VegasRTT
+	r[colVegasMinRTT] = c.VegasMinRTT
+}
+
+// unpackRow converts a column vector back to a connection
+func (t stringList) unpackRow(r *packedRow, c *CompactConnection) {
+	c.State = int(r[colState])
+	c.PID = int(r[colPID])
+	c.RemoteHostname = t.lookup(r[colRemoteHostname])
+	c.RemoteCountry = t.lookup(r[colRemoteCountry])
+	c.RemoteCity = t.lookup(r[colRemoteCity])
+	c.RemoteASN = r[colRemoteASN]
+	c.RemoteASOrg = t.lookup(r[colRemoteASOrg])
+	c.ServiceName = t.lookup(r[colServiceName])
+	c.Role = t.lookup(r[colRole])
+	c.BytesIn = r[colBytesIn]
+	c.BytesOut = r[colBytesOut]
+	c.SegmentsIn = r[colSegmentsIn]
+	c.SegmentsOut = r[colSegmentsOut]
+	c.TimerKind = t.lookup(r[colTimerKind])
+	c.TimerExpiresMs = r[colTimerExpiresMs]
+	c.TimerRetransmits = r[colTimerRetransmits]
+	c.TimerProbes = r[colTimerProbes]
+	c.TimerBackoff = r[colTimerBackoff]
+	c.RTOMs = r[colRTOMs]
+	c.SampleRTT = r[colSampleRTT]
+	c.FastRetrans = r[colFastRetrans]
+	c.TimeoutEpisodes = r[colTimeoutEpisodes]
+	c.RTT = r[colRTT]
+	c.RTTVariance = r[colRTTVariance]
+	c.MinRTT = r[colMinRTT]
+	c.MaxRTT = r[colMaxRTT]
+	c.Retrans = r[colRetrans]
+	c.SegsRetrans = r[colSegsRetrans]
+	c.TotalSegsOut = r[colTotalSegsOut]
+	c.TotalSegsIn = r[colTotalSegsIn]
+	c.CongestionWin = r[colCongestionWin]
+	c.InBandwidth = r[colInBandwidth]
+	c.OutBandwidth = r[colOutBandwidth]
+	c.ThruBytesAcked = r[colThruBytesAcked]
+	c.ThruBytesReceived = r[colThruBytesReceived]
+	c.CurrentSsthresh = r[colCurrentSsthresh]
+	c.SlowStartCount = r[colSlowStartCount]
+	c.CongAvoidCount = r[colCongAvoidCount]
+	c.CurRetxQueue = r[colCurRetxQueue]
+	c.MaxRetxQueue = r[colMaxRetxQueue]
+	c.CurAppWQueue = r[colCurAppWQueue]
+	c.MaxAppWQueue = r[colMaxAppWQueue]
+	c.RmemAlloc = r[colRmemAlloc]
+	c.RcvBuf = r[colRcvBuf]
+	c.WmemAlloc = r[colWmemAlloc]
+	c.WmemQueued = r[colWmemQueued]
+	c.SndBuf = r[colSndBuf]
+	c.FwdAlloc = r[colFwdAlloc]
+	c.SockDrops = r[colSockDrops]
+	c.WinScaleRcvd = int(r[colWinScaleRcvd])
+	c.WinScaleSent = int(r[colWinScaleSent])
+	c.CurRwinRcvd = r[colCurRwinRcvd]
+	c.MaxRwinRcvd = r[colMaxRwinRcvd]
+	c.CurRwinSent = r[colCurRwinSent]
+	c.MaxRwinSent = r[colMaxRwinSent]
+	c.CurMss = r[colCurMss]
+	c.MaxMss = r[colMaxMss]
+	c.MinMss = r[colMinMss]
+	c.DupAcksIn = r[colDupAcksIn]
+	c.DupAcksOut = r[colDupAcksOut]
+	c.SacksRcvd = r[colSacksRcvd]
+	c.SackBlocksRcvd = r[colSackBlocksRcvd]
+	c.DsackDups = r[colDsackDups]
+	c.SndLimTimeRwin = r[colSndLimTimeRwin]
+	c.SndLimTimeCwnd = r[colSndLimTimeCwnd]
+	c.SndLimTimeSnd = r[colSndLimTimeSnd]
+	c.CongestionAlgorithm = t.lookup(r[colCongestionAlgorithm])
+	c.CAState = t.lookup(r[colCAState])
+	c.PacingRate = r[colPacingRate]
+	c.BBRBandwidth = r[colBBRBandwidth]
+	c.BBRMinRTT = r[colBBRMinRTT]
+	c.BBRPacingGain = math.Float64frombits(uint64(r[colBBRPacingGain]))
+	c.BBRCwndGain = math.Float64frombits(uint64(r[colBBRCwndGain]))
+	c.DCTCPAlpha = r[colDCTCPAlpha]
+	c.VegasRTT = r[colVegasRTT]
+	c.VegasMinRTT = r[colVegasMinRTT]
+}
+
+// appendRowDelta encodes the columns of cur that differ from prev as
+// (column, zigzag varint difference) pairs. An empty result means unchanged.
+func appendRowDelta(dst []byte, prev, cur *packedRow) []byte {
+	for i := range cur {
+		if cur[i] != prev[i] {
+			dst = append(dst, byte(i))
+			dst = binary.AppendVarint(dst, cur[i]-prev[i])
+		}
+	}
+	return dst
+}
+
+// applyRowDelta applies an encoded delta to a row in place
+func applyRowDelta(r *packedRow, delta []byte) {
+	for len(delta) > 0 {
+		col := delta[0]
+		diff, n := binary.Varint(delta[1:])
+		if n <= 0 || int(col) >= numColumns {
+			return
+		}
+		r[col] += diff
+		delta = delta[1+n:]
+	}
+}
+
+// rowChange is the encoded delta of one connection row
+type rowChange struct {
+	conn  uint32
+	delta []byte
+}
+
+// snapshotFrame is the encoded form of a Snapshot. Frames are immutable once
+// appended, so read views can share them.
+type snapshotFrame struct {
+	id        int64
+	sessionID int64
+	timestamp time.Time
+	keyframe  bool        // Changes are against an empty frame
+	conns     []uint32    // Identities present, in capture order; shared with the previous frame when unchanged
+	changes   []rowChange // Rows that differ from the previous frame; new rows always have one
+}
+
+// frameRun is a range of frame positions, end exclusive. Positions count
+// every frame ever appended, so they stay stable when old frames are dropped.
+type frameRun struct {
+	start int64
+	end   int64
+}
+
+// posting locates the change of one connection in a frame
+type posting struct {
+	pos   int64
+	index int32 // Index into the frame's changes
+}
+
+// connIndex lists where one connection identity is present and changed
+type connIndex struct {
+	spans    []frameRun // Runs of consecutive frames holding the connection
+	postings []posting  // Frames with a change for the connection, in order
+}
+
+// snapshotLog is an append-only, delta-encoded sequence of snapshots that
+// can drop its oldest frames. It is indexed by session, by time within a
+// session and by connection identity. The log itself is guarded by the
+// owner's lock; reads go through logViews that can be decoded without it.
+type snapshotLog struct {
+	frames     []*snapshotFrame
+	base       int64 // Position of frames[0]
+	strings    *stringTable
+	identities []connIdentity
+	identityID map[connIdentity]uint32
+
+	sessions map[int64][]frameRun
+	conns    map[uint32]*connIndex
+
+	// Encoder state: the rows of the last appended frame, plus a second set
+	// reused for the next one
+	last     rowSet
+	next     rowSet
+	changes  changeBuilder
+	sinceKey int
+
+	// Decoder reused by DropOldest
+	dropDecoder frameDecoder
+}
+
+// newSnapshotLog creates an empty log
+func newSnapshotLog() *snapshotLog {
+	return &snapshotLog{
+		strings:    newStringTable(),
+		identityID: make(map[connIdentity]uint32),
+		sessions:   make(map[int64][]frameRun),
+		conns:      make(map[uint32]*connIndex),
+	}
+}
+
+// changeBuilder collects a frame's row deltas in a reusable scratch buffer
+type changeBuilder struct {
+	scratch []byte
+	conns   []uint32
+	ends    []int // End offset of each delta in scratch
+}
+
+// add records the delta of one row if it changed, or unconditionally when
+// forced, and returns the index of the change or -1
+func (b *changeBuilder) add(id uint32, prev, cur *packedRow, force bool) int32 {
+	start := len(b.scratch)
+	b.scratch = appendRowDelta(b.scratch, prev, cur)
+	if len(b.scratch) == start && !force {
+		return -1
+	}
+	b.conns = append(b.conns, id)
+	b.ends = append(b.ends, len(b.scratch))
+	return int32(len(b.conns) - 1)
+}
+
+// build returns the collected changes, backed by one exactly-sized buffer,
+// and resets the builder
+func (b *changeBuilder) build() []rowChange {
+	var changes []rowChange
+	if len(b.conns) > 0 {
+		deltas := append([]byte(nil), b.scratch...)
+		changes = make([]rowChange, len(b.conns))
+		start := 0
+		for i, id := range b.conns {
+			changes[i] = rowChange{conn: id, delta: deltas[start:b.ends[i]:b.ends[i]]}
+			start = b.ends[i]
+		}
+	}
+	b.scratch = b.scratch[:0]
+	b.conns = b.conns[:0]
+	b.ends = b.ends[:0]
+	return changes
+}
+
+// rowSet holds the packed rows of one frame by identity
+type rowSet struct {
+	index map[uint32]int
+	rows  []packedRow
+	conns []uint32
+}
+
+// reset empties the set, keeping its storage
+func (r *rowSet) reset(size int) {
+	if r.index == nil {
+		r.index = make(map[uint32]int, size)
+	} else {
+		clear(r.index)
+	}
+	if cap(r.rows) < size {
+		r.rows = make([]packedRow, 0, size)
+	}
+	r.rows = r.rows[:0]
+	r.conns = nil
+}
+
+// get returns the row of an identity, or nil
+func (r *rowSet) get(id uint32) *packedRow {
+	if i, ok := r.index[id]; ok {
+		return &r.rows[i]
+	}
+	return nil
+}
+
+// Len returns the number of snapshots in the log
+func (l *snapshotLog) Len() int {
+	return len(l.frames)
+}
+
+// end returns the position after the last frame
+func (l *snapshotLog) end() int64 {
+	return l.base + int64(len(l.frames))
+}
+
+// frame returns the frame at a position
+func (l *snapshotLog) frame(pos int64) *snapshotFrame {
+	return l.frames[pos-l.base]
+}
+
+// identity interns the identity of a connection
+func (l *snapshotLog) identity(c *CompactConnection, occurrence uint16) uint32 {
+	key := connIdentity{
+		LocalAddr:  l.strings.intern(c.LocalAddr),
+		LocalPort:  uint16(c.LocalPort),
+		RemoteAddr: l.strings.intern(c.RemoteAddr),
+		RemotePort: uint16(c.RemotePort),
+		Occurrence: occurrence,
+	}
+	if id, ok := l.identityID[key]; ok {
+		return id
+	}
+	id := uint32(len(l.identities))
+	l.identities = append(l.identities, key)
+	l.identityID[key] = id
+	return id
+}
+
+// findIdentity looks up the first occurrence of a 4-tuple without interning it
+func (l *snapshotLog) findIdentity(localAddr string, localPort int, remoteAddr string, remotePort int) (uint32, bool) {
+	local, ok := l.strings.ids[localAddr]
+	if !ok && localAddr != "" {
+		return 0, false
+	}
+	remote, ok := l.strings.ids[remoteAddr]
+	if !ok && remoteAddr != "" {
+		return 0, false
+	}
+	id, ok := l.identityID[connIdentity{
+		LocalAddr:  local,
+		LocalPort:  uint16(localPort),
+		RemoteAddr: remote,
+		RemotePort: uint16(remotePort),
+	}]
+	return id, ok
+}
+
+// Append encodes a snapshot at the end of the log
+func (l *snapshotLog) Append(snap *Snapshot) {
+	pos := l.end()
+	keyframe := len(l.frames) == 0 || l.sinceKey >= snapshotKeyframeInterval-1 ||
+		l.frames[len(l.frames)-1].sessionID != snap.SessionID
+
+	frame := &snapshotFrame{
+		id:        snap.ID,
+		sessionID: snap.SessionID,
+		timestamp: snap.Timestamp,
+		keyframe:  keyframe,
+	}
+
+	conns := make([]uint32, len(snap.Connections))
+	rows := &l.next
+	rows.reset(len(snap.Connections))
+	var seen map[uint32]uint16
+	var zero packedRow
+	for i := range snap.Connections {
+		c := &snap.Connections[i]
+		id := l.identity(c, 0)
+		if _, dup := rows.index[id]; dup {
+			if seen == nil {
+				seen = make(map[uint32]uint16)
+			}
+			seen[id]++
+			id = l.identity(c, seen[id])
+		}
+		conns[i] = id
+
+		rows.index[id] = len(rows.rows)
+		rows.rows = append(rows.rows, packedRow{})
+		row := &rows.rows[len(rows.rows)-1]
+		l.strings.packRow(c, row)
+
+		// Rows new to this frame, and every row of a keyframe, are encoded
+		// against zero and always get a change so history reads can start there
+		last := l.last.get(id)
+		prev := &zero
+		if last != nil && !keyframe {
+			prev = last
+		}
+		index := l.changes.add(id, prev, row, last == nil || keyframe)
+
+		ci := l.conns[id]
+		if ci == nil {
+			ci = &connIndex{}
+			l.conns[id] = ci
+		}
+		if index >= 0 {
+			ci.postings = append(ci.postings, posting{pos: pos, index: index})
+		}
+		if last == nil {
+			ci.spans = append(ci.spans, frameRun{start: pos, end: pos + 1})
+		} else {
+			ci.spans[len(ci.spans)-1].end = pos + 1
+		}
+	}
+	frame.changes = l.changes.build()
+
+	if equalConns(conns, l.last.conns) {
+		conns = l.last.conns
+	}
+	frame.conns = conns
+	rows.conns = conns
+
+	runs := l.sessions[snap.SessionID]
+	if n := len(runs); n > 0 && runs[n-1].end == pos {
+		runs[n-1].end = pos + 1
+	} else {
+		l.sessions[snap.SessionID] = append(runs, frameRun{start: pos, end: pos + 1})
+	}
+
+	l.frames = append(l.frames, frame)
+	l.last, l.next = l.next, l.last
+	if keyframe {
+		l.sinceKey = 0
+	} else {
+		l.sinceKey++
+	}
+}
+
+// DropOldest removes the first snapshot, turning the next one into a keyframe
+func (l *snapshotLog) DropOldest() {
+	if len(l.frames) == 0 {
+		return
+	}
+	first := l.frames[0]
+	pos := l.base
+
+	if len(l.frames) > 1 && !l.frames[1].keyframe {
+		d := &l.dropDecoder
+		d.apply(first)
+		d.apply(l.frames[1])
+		l.frames[1] = l.rekey(l.frames[1], d)
+
+		// Every row of the new keyframe now has a change at pos+1
+		for i, id := range l.frames[1].conns {
+			ci := l.conns[id]
+			switch {
+			case ci.postings[0].pos == pos+1:
+				ci.postings[0].index = int32(i)
+			case len(ci.postings) > 1 && ci.postings[1].pos == pos+1:
+				ci.postings = ci.postings[1:]
+				ci.postings[0].index = int32(i)
+			default:
+				ci.postings[0] = posting{pos: pos + 1, index: int32(i)}
+			}
+		}
+	}
+
+	for _, id := range first.conns {
+		ci := l.conns[id]
+		if len(ci.postings) > 0 && ci.postings[0].pos == pos {
+			ci.postings = ci.postings[1:]
+		}
+		if ci.spans[0].end <= pos+1 {
+			ci.spans = ci.spans[1:]
+		} else {
+			ci.spans[0].start = pos + 1
+		}
+		if len(ci.spans) == 0 {
+			delete(l.conns, id)
+		}
+	}
+
+	runs := l.sessions[first.sessionID]
+	if runs[0].start++; runs[0].start == runs[0].end {
+		runs = runs[1:]
+	}
+	if len(runs) == 0 {
+		delete(l.sessions, first.sessionID)
+	} else {
+		l.sessions[first.sessionID] = runs
+	}
+
+	l.frames[0] = nil
+	l.frames = l.frames[1:]
+	l.base++
+
+	if len(l.frames) == 0 {
+		l.Reset()
+	}
+}
+
+// rekey re-encodes a decoded frame as a keyframe
+func (l *snapshotLog) rekey(f *snapshotFrame, d *frameDecoder) *snapshotFrame {
+	key := *f
+	key.keyframe = true
+	var zero packedRow
+	for _, id := range f.conns {
+		l.changes.add(id, &zero, d.cur.get(id), true)
+	}
+	key.changes = l.changes.build()
+	return &key
+}
+
+// Reset empties the log and its indexes and intern tables
+func (l *snapshotLog) Reset() {
+	*l = *newSnapshotLog()
+}
+
+// EachMeta visits the undecoded metadata of every snapshot
+func (l *snapshotLog) EachMeta(fn func(id, sessionID int64, timestamp time.Time, connections int)) {
+	for _, f := range l.frames {
+		fn(f.id, f.sessionID, f.timestamp, len(f.conns))
+	}
+}
+
+// allRuns returns a run covering the whole log
+func (l *snapshotLog) allRuns() []frameRun {
+	if len(l.frames) == 0 {
+		return nil
+	}
+	return []frameRun{{start: l.base, end: l.end()}}
+}
+
+// sessionRuns returns the runs of frames recorded in a session
+func (l *snapshotLog) sessionRuns(sessionID int64) []frameRun {
+	return append([]frameRun(nil), l.sessions[sessionID]...)
+}
+
+// allSessionRuns returns the runs of every session. Timestamps only
+// increase within a run, so these are the runs to clip by time.
+func (l *snapshotLog) allSessionRuns() []frameRun {
+	var runs []frameRun
+	for _, sessionRuns := range l.sessions {
+		runs = append(runs, sessionRuns...)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].start < runs[j].start })
+	return runs
+}
+
+// sessionStart returns the timestamp of the first frame of a session
+func (l *snapshotLog) sessionStart(sessionID int64) (time.Time, bool) {
+	runs := l.sessions[sessionID]
+	if len(runs) == 0 {
+		return time.Time{}, false
+	}
+	return l.frame(runs[0].start).timestamp, true
+}
+
+// sessionLen returns the number of frames of a session
+func (l *snapshotLog) sessionLen(sessionID int64) int {
+	n := 0
+	for _, run := range l.sessions[sessionID] {
+		n += int(run.end - run.start)
+	}
+	return n
+}
+
+// position returns the position of the snapshot with the given ID.
+// Snapshot IDs increase along the log.
+func (l *snapshotLog) position(id int64) (int64, bool) {
+	i := sort.Search(len(l.frames), func(i int) bool { return l.frames[i].id >= id })
+	if i == len(l.frames) || l.frames[i].id != id {
+		return 0, false
+	}
+	return l.base + int64(i), true
+}
+
+// clipTime narrows runs to frames within [start, end]; a zero bound is open.
+// Timestamps increase within a run, so each run is binary searched.
+func (l *snapshotLog) clipTime(runs []frameRun, start, end time.Time) []frameRun {
+	var clipped []frameRun
+	for _, run := range runs {
+		n := int(run.end - run.start)
+		from, to := 0, n
+		if !start.IsZero() {
+			from = sort.Search(n, func(i int) bool { return !l.frame(run.start + int64(i)).timestamp.Before(start) })
+		}
+		if !end.IsZero() {
+			to = sort.Search(n, func(i int) bool { return l.frame(run.start + int64(i)).timestamp.After(end) })
+		}
+		if from < to {
+			clipped = append(clipped, frameRun{start: run.start + int64(from), end: run.start + int64(to)})
+		}
+	}
+	sort.Slice(clipped, func(i, j int) bool { return clipped[i].start < clipped[j].start })
+	return clipped
+}
+
+// nearest returns the position of the frame in runs taken closest to t
+func (l *snapshotLog) nearest(runs []frameRun, t time.Time) (int64, bool) {
+	var best int64
+	var bestDist time.Duration
+	found := false
+	for _, run := range runs {
+		n := int(run.end - run.start)
+		i := sort.Search(n, func(i int) bool { return !l.frame(run.start + int64(i)).timestamp.Before(t) })
+		for _, k := range []int{i - 1, i} {
+			if k < 0 || k >= n {
+				continue
+			}
+			pos := run.start + int64(k)
+			dist := l.frame(pos).timestamp.Sub(t)
+			if dist < 0 {
+				dist = -dist
+			}
+			if !found || dist < bestDist {
+				best, bestDist, found = pos, dist, true
+			}
+		}
+	}
+	return best, found
+}
+
+// view returns a read view covering the given runs, extended back to the
+// keyframe each run needs. Must be called under the owner's lock; the view
+// can then be decoded after the lock is released.
+func (l *snapshotLog) view(runs []frameRun) *logView {
+	v := &logView{
+		strings:    l.strings.strings,
+		identities: l.identities,
+		runs:       runs,
+	}
+	if len(runs) == 0 {
+		return v
+	}
+	from, to := runs[0].start, runs[0].end
+	for _, run := range runs[1:] {
+		from = min(from, run.start)
+		to = max(to, run.end)
+	}
+	for from > l.base && !l.frame(from).keyframe {
+		from--
+	}
+	v.start = from
+	v.frames = append([]*snapshotFrame(nil), l.frames[from-l.base:to-l.base]...)
+	return v
+}
+
+// connView returns a read view for one connection's history within runs,
+// copying only the index entries that fall inside the view
+func (l *snapshotLog) connView(id uint32, runs []frameRun) *logView {
+	v := l.view(runs)
+	ci := l.conns[id]
+	if ci == nil || len(v.frames) == 0 {
+		v.runs = nil
+		return v
+	}
+	from, to := v.start, v.start+int64(len(v.frames))
+
+	i := sort.Search(len(ci.postings), func(i int) bool { return ci.postings[i].pos >= from })
+	j := sort.Search(len(ci.postings), func(i int) bool { return ci.postings[i].pos >= to })
+	v.postings = append([]posting(nil), ci.postings[i:j]...)
+
+	i = sort.Search(len(ci.spans), func(i int) bool { return ci.spans[i].end > from })
+	j = sort.Search(len(ci.spans), func(i int) bool { return ci.spans[i].start >= to })
+	v.spans = append([]frameRun(nil), ci.spans[i:j]...)
+	return v
+}
+
+// logView is a consistent read-only view of part of a log. It shares the
+// immutable frames and the append-only intern tables, so it can be decoded
+// without the owner's lock while the log keeps changing.
+type logView struct {
+	frames     []*snapshotFrame // frames[0] is at position start
+	start      int64
+	strings    stringList
+	identities []connIdentity
+	runs       []frameRun // Frames the view was created for
+
+	// Connection views only
+	spans    []frameRun
+	postings []posting
+}
+
+// frame returns the frame at a position
+func (v *logView) frame(pos int64) *snapshotFrame {
+	return v.frames[pos-v.start]
+}
+
+// Each decodes the snapshots of the view's runs in order until fn returns false
+func (v *logView) Each(fn func(snap *Snapshot) bool) {
+	var d frameDecoder
+	for _, run := range v.runs {
+		from := run.start
+		for from > v.start && !v.frame(from).keyframe {
+			from--
+		}
+		for pos := from; pos < run.end; pos++ {
+			f := v.frame(pos)
+			d.apply(f)
+			if pos < run.start {
+				continue
+			}
+			snap := v.snapshot(f, &d)
+			if !fn(&snap) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of snapshots in the view's runs
+func (v *logView) Len() int {
+	n := 0
+	for _, run := range v.runs {
+		n += int(run.end - run.start)
+	}
+	return n
+}
+
+// EachRow decodes one connection's row at every snapshot of the view's runs
+// where it is present. Only that connection's changes are decoded, starting
+// from the nearest change encoded against zero, so the cost follows the
+// number of rows returned rather than the size of the snapshots.
+func (v *logView) EachRow(fn func(timestamp time.Time, conn *CompactConnection)) {
+	for _, run := range v.runs {
+		for _, span := range v.spans {
+			from, to := max(run.start, span.start), min(run.end, span.end)
+			if from >= to {
+				continue
+			}
+
+			// Last change at or before the first frame that is absolute
+			j := sort.Search(len(v.postings), func(i int) bool { return v.postings[i].pos > from }) - 1
+			for j > 0 && !v.absolute(v.postings[j], span) {
+				j--
+			}
+			if j < 0 {
+				continue
+			}
+
+			var row packedRow
+			var conn CompactConnection
+			for ; j < len(v.postings) && v.postings[j].pos <= from; j++ {
+				v.applyPosting(&row, v.postings[j], span)
+			}
+			for pos := from; pos < to; pos++ {
+				if j < len(v.postings) && v.postings[j].pos == pos {
+					v.applyPosting(&row, v.postings[j], span)
+					j++
+				}
+				v.strings.unpackRow(&row, &conn)
+				fn(v.frame(pos).timestamp, &conn)
+			}
+		}
+	}
+}
+
+// absolute reports whether a change is encoded against zero: the first
+// frame of a span or any keyframe
+func (v *logView) absolute(p posting, span frameRun) bool {
+	return p.pos == span.start || v.frame(p.pos).keyframe
+}
+
+// applyPosting applies one change of a connection to its row
+func (v *logView) applyPosting(row *packedRow, p posting, span frameRun) {
+	if v.absolute(p, span) {
+		*row = packedRow{}
+	}
+	applyRowDelta(row, v.frame(p.pos).changes[p.index].delta)
+}
+
+// snapshot rebuilds a Snapshot from a decoded frame
+func (v *logView) snapshot(f *snapshotFrame, d *frameDecoder) Snapshot {
+	snap := Snapshot{
+		ID:          f.id,
+		SessionID:   f.sessionID,
+		Timestamp:   f.timestamp,
+		Connections: make([]CompactConnection, len(f.conns)),
+	}
+	for i, id := range f.conns {
+		c := &snap.Connections[i]
+		v.strings.unpackRow(d.cur.get(id), c)
+		key := v.identities[id]
+		c.LocalAddr = v.strings.lookup(int64(key.LocalAddr))
+		c.LocalPort = int(key.LocalPort)
+		c.RemoteAddr = v.strings.lookup(int64(key.RemoteAddr))
+		c.RemotePort = int(key.RemotePort)
+	}
+	return snap
+}
+
+// frameDecoder holds the rows of the last decoded frame
+type frameDecoder struct {
+	cur   rowSet
+	spare rowSet
+}
+
+// apply advances the decoder by one frame
+func (d *frameDecoder) apply(f *snapshotFrame) {
+	if f.keyframe || d.cur.index == nil || !sameConns(f.conns, d.cur.conns) {
+		next := &d.spare
+		next.reset(len(f.conns))
+		for _, id := range f.conns {
+			next.index[id] = len(next.rows)
+			var row packedRow
+			if !f.keyframe {
+				if prev := d.cur.get(id); prev != nil {
+					row = *prev
+				}
+			}
+			next.rows = append(next.rows, row)
+		}
+		next.conns = f.conns
+		d.cur, d.spare = d.spare, d.cur
+	}
+
+	for _, ch := range f.changes {
+		applyRowDelta(d.cur.get(ch.conn), ch.delta)
+	}
+}
+
+// equalConns reports whether two identity lists have the same content
+func equalConns(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameConns reports whether two identity lists share storage, which the
+// encoder arranges whenever consecutive frames hold the same connections
+func sameConns(a, b []uint32) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// Each decodes every snapshot in order until fn returns false
+func (l *snapshotLog) Each(fn func(snap *Snapshot) bool) {
+	l.view(l.allRuns()).Each(fn)
+}
+
+// EachInSession decodes the snapshots of one session in order until fn
+// returns false
+func (l *snapshotLog) EachInSession(sessionID int64, fn func(snap *Snapshot) bool) {
+	l.view(l.sessionRuns(sessionID)).Each(fn)
+}
+
+// Get decodes the snapshot with the given ID
+func (l *snapshotLog) Get(id int64) (Snapshot, bool) {
+	pos, ok := l.position(id)
+	if !ok {
+		return Snapshot{}, false
+	}
+	var snap Snapshot
+	l.view([]frameRun{{start: pos, end: pos + 1}}).Each(func(s *Snapshot) bool {
+		snap = *s
+		return false
+	})
+	return snap, true
+}
+
+// sessionLogs holds the snapshots kept in memory, one log per session, so
+// that sessions recorded at the same time keep their own delta encoding and
+// contiguous runs. The logs share one capacity; when it is reached the
+// oldest snapshot of any session is dropped first.
+type sessionLogs struct {
+	logs map[int64]*snapshotLog
+	size int
+}
+
+// newSessionLogs creates an empty set of session logs
+func newSessionLogs() *sessionLogs {
+	return &sessionLogs{logs: make(map[int64]*snapshotLog)}
+}
+
+// Len returns the number of snapshots held across all sessions
+func (m *sessionLogs) Len() int {
+	return m.size
+}
+
+// log returns the log of a session, empty if none of its snapshots are held
+func (m *sessionLogs) log(sessionID int64) *snapshotLog {
+	if l := m.logs[sessionID]; l != nil {
+		return l
+	}
+	return newSnapshotLog()
+}
+
+// Append encodes a snapshot at the end of its session's log
+func (m *sessionLogs) Append(snap *Snapshot) {
+	l := m.logs[snap.SessionID]
+	if l == nil {
+		l = newSnapshotLog()
+		m.logs[snap.SessionID] = l
+	}
+	l.Append(snap)
+	m.size++
+}
+
+// DropOldest removes the snapshot with the lowest ID. Snapshot IDs increase
+// over time, so this is the oldest snapshot held.
+func (m *sessionLogs) DropOldest() {
+	var oldest *snapshotLog
+	var oldestID int64
+	for id, l := range m.logs {
+		if oldest == nil || l.frames[0].id < oldest.frames[0].id {
+			oldest, oldestID = l, id
+		}
+	}
+	if oldest == nil {
+		return
+	}
+	oldest.DropOldest()
+	m.size--
+	if oldest.Len() == 0 {
+		delete(m.logs, oldestID)
+	}
+}
+
+// Reset drops every session's log
+func (m *sessionLogs) Reset() {
+	*m = *newSessionLogs()
+}
+
+// Keep drops the logs of the sessions keep rejects
+func (m *sessionLogs) Keep(keep func(sessionID int64) bool) {
+	for id, l := range m.logs {
+		if !keep(id) {
+			m.size -= l.Len()
+			delete(m.logs, id)
+		}
+	}
+}
+
+// Replace swaps in new logs for a set of sessions; a nil log removes one
+func (m *sessionLogs) Replace(logs map[int64]*snapshotLog) {
+	for id, l := range logs {
+		if old := m.logs[id]; old != nil {
+			m.size -= old.Len()
+			delete(m.logs, id)
+		}
+		if l != nil && l.Len() > 0 {
+			m.logs[id] = l
+			m.size += l.Len()
+		}
+	}
+}
+
+// ordered returns the logs in the order their sessions' oldest held
+// snapshots were taken
+func (m *sessionLogs) ordered() []*snapshotLog {
+	logs := make([]*snapshotLog, 0, len(m.logs))
+	for _, l := range m.logs {
+		logs = append(logs, l)
+	}
+	sort.Slice(logs, func(i, j int) bool { return logs[i].frames[0].id < logs[j].frames[0].id })
+	return logs
+}
+
+// EachMeta visits the undecoded metadata of every snapshot in ID order
+func (m *sessionLogs) EachMeta(fn func(id, sessionID int64, timestamp time.Time, connections int)) {
+	var frames []*snapshotFrame
+	for _, l := range m.logs {
+		frames = append(frames, l.frames...)
+	}
+	sort.Slice(frames, func(i, j int) bool { return frames[i].id < frames[j].id })
+	for _, f := range frames {
+		fn(f.id, f.sessionID, f.timestamp, len(f.conns))
+	}
+}
+
+// sessionLen returns the number of snapshots held of a session
+func (m *sessionLogs) sessionLen(sessionID int64) int {
+	if l := m.logs[sessionID]; l != nil {
+		return l.Len()
+	}
+	return 0
+}
+
+// sessionStart returns the timestamp of the oldest held snapshot of a session
+func (m *sessionLogs) sessionStart(sessionID int64) (time.Time, bool) {
+	if l := m.logs[sessionID]; l != nil {
+		return l.sessionStart(sessionID)
+	}
+	return time.Time{}, false
+}
+
+// views builds a view of each session's log; build returns nil to skip one
+func (m *sessionLogs) views(build func(l *snapshotLog) *logView) []*logView {
+	var views []*logView
+	for _, l := range m.ordered() {
+		if v := build(l); v != nil {
+			views = append(views, v)
+		}
+	}
+	return views
+}
+
+// eachSnapshot decodes the snapshots of several views in ID order
+func eachSnapshot(views []*logView, fn func(snap *Snapshot)) {
+	var snapshots []Snapshot
+	for _, v := range views {
+		v.Each(func(snap *Snapshot) bool {
+			snapshots = append(snapshots, *snap)
+			return true
+		})
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].ID < snapshots[j].ID })
+	for i := range snapshots {
+		fn(&snapshots[i])
+	}
+}
@@ -0,0 +1,531 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Benchmarks for the delta-encoded snapshot log against the plain []Snapshot
+// it replaced. Run with:
+//
+//	go test -run '^$' -bench SnapshotLog -benchmem ./internal/tcpmonitor
+const (
+	benchConnections = 300
+	benchSnapshots   = 2000
+)
+
+// benchRecording simulates a recording: a mostly stable set of connections
+// where a fifth of them move traffic every sample and a few come and go
+func benchRecording(snapshots, connections int) []Snapshot {
+	rng := rand.New(rand.NewSource(1))
+
+	conns := make([]CompactConnection, connections)
+	for i := range conns {
+		conns[i] = CompactConnection{
+			LocalAddr:      "192.168.1.20",
+			LocalPort:      40000 + i,
+			RemoteAddr:     fmt.Sprintf("203.0.113.%d", i%250),
+			RemotePort:     443,
+			State:          int(StateEstablished),
+			PID:            1000 + i%20,
+			RemoteHostname: fmt.Sprintf("host-%d.example.com", i%250),
+			RemoteCountry:  "US",
+			ServiceName:    "https",
+			Role:           "client",
+			RTT:            20 + int64(i%50),
+			MinRTT:         18,
+			MaxRTT:         90,
+			CongestionWin:  14480,
+			CurMss:         1448,
+			MaxMss:         1448,
+			MinMss:         1448,
+			CurRwinRcvd:    65535,
+			CurRwinSent:    65535,
+			RcvBuf:         131072,
+			SndBuf:         87040,
+			WinScaleRcvd:   7,
+			WinScaleSent:   7,
+
+			CongestionAlgorithm: "cubic",
+			CAState:             "open",
+		}
+	}
+
+	result := make([]Snapshot, snapshots)
+	start := time.Unix(1700000000, 0)
+	for n := range result {
+		for k := 0; k < connections/5; k++ {
+			c := &conns[rng.Intn(len(conns))]
+			c.BytesIn += int64(rng.Intn(64 << 10))
+			c.BytesOut += int64(rng.Intn(8 << 10))
+			c.SegmentsIn += int64(rng.Intn(50))
+			c.SegmentsOut += int64(rng.Intn(20))
+			c.TotalSegsIn = c.SegmentsIn
+			c.TotalSegsOut = c.SegmentsOut
+			c.RTT = 18 + int64(rng.Intn(40))
+			c.SampleRTT = c.RTT
+			c.RTTVariance = int64(rng.Intn(10))
+		}
+		// Connection churn
+		if n%10 == 0 {
+			i := rng.Intn(len(conns))
+			conns[i].LocalPort = 40000 + connections + n
+			conns[i].BytesIn, conns[i].BytesOut = 0, 0
+		}
+
+		result[n] = Snapshot{
+			ID:          int64(n + 1),
+			SessionID:   1,
+			Timestamp:   start.Add(time.Duration(n) * time.Second),
+			Connections: append([]CompactConnection(nil), conns...),
+		}
+	}
+	return result
+}
+
+// heapInUse returns live heap bytes after a full collection
+func heapInUse() uint64 {
+	runtime.GC()
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// BenchmarkSnapshotLogMemory reports the retained bytes per snapshot of both
+// representations for a full recording
+func BenchmarkSnapshotLogMemory(b *testing.B) {
+	recording := benchRecording(benchSnapshots, benchConnections)
+
+	for i := 0; i < b.N; i++ {
+		before := heapInUse()
+		plain := make([]Snapshot, 0, len(recording))
+		for _, snap := range recording {
+			snap.Connections = append([]CompactConnection(nil), snap.Connections...)
+			plain = append(plain, snap)
+		}
+		plainBytes := heapInUse() - before
+		runtime.KeepAlive(plain)
+		plain = nil
+
+		before = heapInUse()
+		log := newSnapshotLog()
+		for j := range recording {
+			log.Append(&recording[j])
+		}
+		encodedBytes := heapInUse() - before
+		runtime.KeepAlive(log)
+
+		b.ReportMetric(float64(plainBytes)/float64(len(recording)), "plain-B/snapshot")
+		b.ReportMetric(float64(encodedBytes)/float64(len(recording)), "encoded-B/snapshot")
+		b.ReportMetric(float64(plainBytes)/float64(encodedBytes), "ratio")
+	}
+}
+
+// BenchmarkSnapshotLogAppend measures the cost of recording one snapshot
+func BenchmarkSnapshotLogAppend(b *testing.B) {
+	recording := benchRecording(benchSnapshots, benchConnections)
+	log := newSnapshotLog()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if log.Len() >= benchSnapshots {
+			log.DropOldest()
+		}
+		snap := recording[i%len(recording)]
+		snap.ID = int64(i + 1)
+		log.Append(&snap)
+	}
+}
+
+// BenchmarkSnapshotPlainAppend is the baseline: copying rows into a slice
+func BenchmarkSnapshotPlainAppend(b *testing.B) {
+	recording := benchRecording(benchSnapshots, benchConnections)
+	plain := make([]Snapshot, 0, benchSnapshots)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(plain) >= benchSnapshots {
+			plain = plain[1:]
+		}
+		snap := recording[i%len(recording)]
+		snap.Connections = append([]CompactConnection(nil), snap.Connections...)
+		plain = append(plain, snap)
+	}
+}
+
+// BenchmarkSnapshotLogReadSession measures rebuilding a whole session
+func BenchmarkSnapshotLogReadSession(b *testing.B) {
+	recording := benchRecording(benchSnapshots, benchConnections)
+	log := newSnapshotLog()
+	for i := range recording {
+		log.Append(&recording[i])
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows := 0
+		log.EachInSession(1, func(snap *Snapshot) bool {
+			rows += len(snap.Connections)
+			return true
+		})
+		if rows != benchSnapshots*benchConnections {
+			b.Fatalf("decoded %d rows", rows)
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*benchSnapshots), "ns/snapshot")
+}
+
+// BenchmarkSnapshotLogGet measures random access to a single snapshot
+func BenchmarkSnapshotLogGet(b *testing.B) {
+	recording := benchRecording(benchSnapshots, benchConnections)
+	log := newSnapshotLog()
+	for i := range recording {
+		log.Append(&recording[i])
+	}
+	rng := rand.New(rand.NewSource(2))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id := int64(rng.Intn(benchSnapshots) + 1)
+		if _, ok := log.Get(id); !ok {
+			b.Fatalf("snapshot %d not found", id)
+		}
+	}
+}
+
+// BenchmarkSnapshotLogConnectionHistory measures reading one connection's
+// rows over a minute of a session through the identity index
+func BenchmarkSnapshotLogConnectionHistory(b *testing.B) {
+	recording := benchRecording(benchSnapshots, benchConnections)
+	log := newSnapshotLog()
+	for i := range recording {
+		log.Append(&recording[i])
+	}
+	conn := recording[0].Connections[benchConnections-1]
+	start := recording[benchSnapshots/2].Timestamp
+	end := start.Add(time.Minute)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id, ok := log.findIdentity(conn.LocalAddr, conn.LocalPort, conn.RemoteAddr, conn.RemotePort)
+		if !ok {
+			b.Fatal("connection not indexed")
+		}
+		rows := 0
+		log.connView(id, log.clipTime(log.sessionRuns(1), start, end)).EachRow(func(time.Time, *CompactConnection) {
+			rows++
+		})
+		if rows == 0 {
+			b.Fatal("no history")
+		}
+	}
+}
+
+// appendAll builds a log holding the given snapshots
+func appendAll(snapshots []Snapshot) *snapshotLog {
+	log := newSnapshotLog()
+	for i := range snapshots {
+		log.Append(&snapshots[i])
+	}
+	return log
+}
+
+// checkSnapshots verifies that a log decodes exactly the wanted snapshots,
+// both one at a time and as a session
+func checkSnapshots(t *testing.T, log *snapshotLog, want []Snapshot) {
+	t.Helper()
+	if log.Len() != len(want) {
+		t.Fatalf("log holds %d snapshots, want %d", log.Len(), len(want))
+	}
+	for i := range want {
+		got, ok := log.Get(want[i].ID)
+		if !ok {
+			t.Fatalf("snapshot %d not found", want[i].ID)
+		}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Fatalf("snapshot %d decoded differently:\ngot  %+v\nwant %+v", want[i].ID, got, want[i])
+		}
+	}
+
+	sessions := make(map[int64][]Snapshot)
+	for _, snap := range want {
+		sessions[snap.SessionID] = append(sessions[snap.SessionID], snap)
+	}
+	for sessionID, snaps := range sessions {
+		n := 0
+		log.EachInSession(sessionID, func(snap *Snapshot) bool {
+			if n >= len(snaps) || !reflect.DeepEqual(*snap, snaps[n]) {
+				t.Fatalf("session %d: snapshot %d out of order or decoded differently", sessionID, n)
+			}
+			n++
+			return true
+		})
+		if n != len(snaps) {
+			t.Fatalf("session %d: decoded %d snapshots, want %d", sessionID, n, len(snaps))
+		}
+	}
+}
+
+// checkRows verifies that one connection's history matches its rows in the
+// wanted snapshots
+func checkRows(t *testing.T, log *snapshotLog, want []Snapshot, conn CompactConnection) {
+	t.Helper()
+	var rows []CompactConnection
+	for _, snap := range want {
+		for _, c := range snap.Connections {
+			if c.LocalAddr == conn.LocalAddr && c.LocalPort == conn.LocalPort &&
+				c.RemoteAddr == conn.RemoteAddr && c.RemotePort == conn.RemotePort {
+				rows = append(rows, c)
+				break
+			}
+		}
+	}
+
+	id, ok := log.findIdentity(conn.LocalAddr, conn.LocalPort, conn.RemoteAddr, conn.RemotePort)
+	if !ok {
+		t.Fatalf("connection %s:%d not indexed", conn.LocalAddr, conn.LocalPort)
+	}
+	n := 0
+	log.connView(id, log.allSessionRuns()).EachRow(func(_ time.Time, c *CompactConnection) {
+		// Rows come without their 4-tuple, which the caller already knows
+		c.LocalAddr, c.LocalPort, c.RemoteAddr, c.RemotePort = conn.LocalAddr, conn.LocalPort, conn.RemoteAddr, conn.RemotePort
+		if n < len(rows) && !reflect.DeepEqual(*c, rows[n]) {
+			t.Fatalf("row %d decoded differently:\ngot  %+v\nwant %+v", n, *c, rows[n])
+		}
+		n++
+	})
+	if n != len(rows) {
+		t.Fatalf("decoded %d rows, want %d", n, len(rows))
+	}
+}
+
+func TestSnapshotLogRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		snapshots int
+		sessions  int // Snapshots are split evenly between sessions
+	}{
+		{"single snapshot", 1, 1},
+		{"last frame before a keyframe", snapshotKeyframeInterval, 1},
+		{"first frame after a keyframe", snapshotKeyframeInterval + 1, 1},
+		{"several keyframes", 3*snapshotKeyframeInterval + 7, 1},
+		{"session boundary inside a keyframe interval", 2*snapshotKeyframeInterval + 10, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recording := benchRecording(tt.snapshots, 20)
+			per := (tt.snapshots + tt.sessions - 1) / tt.sessions
+			for i := range recording {
+				recording[i].SessionID = int64(i/per + 1)
+			}
+
+			log := appendAll(recording)
+			checkSnapshots(t, log, recording)
+			checkRows(t, log, recording, recording[0].Connections[19])
+		})
+	}
+}
+
+func TestSnapshotLogDropOldest(t *testing.T) {
+	tests := []struct {
+		name    string
+		dropped int
+	}{
+		{"first keyframe", 1},
+		{"up to the frame before the second keyframe", snapshotKeyframeInterval - 1},
+		{"through the second keyframe", snapshotKeyframeInterval + 1},
+		{"through several keyframes", 2*snapshotKeyframeInterval + 5},
+		{"everything", 3 * snapshotKeyframeInterval},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recording := benchRecording(3*snapshotKeyframeInterval, 20)
+			log := appendAll(recording)
+			for i := 0; i < tt.dropped; i++ {
+				log.DropOldest()
+			}
+
+			kept := recording[tt.dropped:]
+			checkSnapshots(t, log, kept)
+			for _, snap := range recording[:tt.dropped] {
+				if _, ok := log.Get(snap.ID); ok {
+					t.Fatalf("dropped snapshot %d still found", snap.ID)
+				}
+			}
+			if len(kept) > 0 {
+				checkRows(t, log, kept, kept[0].Connections[19])
+			}
+
+			// Appending after the drops continues the log
+			next := benchRecording(snapshotKeyframeInterval+1, 20)
+			for i := range next {
+				next[i].ID = int64(len(recording) + i + 1)
+				log.Append(&next[i])
+			}
+			checkSnapshots(t, log, append(append([]Snapshot(nil), kept...), next...))
+		})
+	}
+}
+
+func TestSnapshotLogDuplicateTuples(t *testing.T) {
+	conn := func(pid int, bytesIn int64) CompactConnection {
+		return CompactConnection{
+			LocalAddr: "0.0.0.0", LocalPort: 8080, State: int(StateListen),
+			PID: pid, BytesIn: bytesIn,
+		}
+	}
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		name  string
+		conns [][]CompactConnection // Rows of each snapshot
+	}{
+		{
+			name:  "two processes sharing a listener",
+			conns: [][]CompactConnection{{conn(100, 1), conn(200, 2)}, {conn(100, 3), conn(200, 4)}},
+		},
+		{
+			name:  "order of the duplicates changes",
+			conns: [][]CompactConnection{{conn(100, 1), conn(200, 2)}, {conn(200, 2), conn(100, 5)}},
+		},
+		{
+			name:  "one duplicate goes away",
+			conns: [][]CompactConnection{{conn(100, 1), conn(200, 2), conn(300, 3)}, {conn(300, 3)}, {conn(100, 1), conn(300, 4)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Repeat past a keyframe so duplicates are also encoded as deltas
+			var recording []Snapshot
+			for n := 0; n < snapshotKeyframeInterval+len(tt.conns); n++ {
+				recording = append(recording, Snapshot{
+					ID:          int64(n + 1),
+					SessionID:   1,
+					Timestamp:   start.Add(time.Duration(n) * time.Second),
+					Connections: append([]CompactConnection(nil), tt.conns[n%len(tt.conns)]...),
+				})
+			}
+
+			log := appendAll(recording)
+			checkSnapshots(t, log, recording)
+
+			log.DropOldest()
+			checkSnapshots(t, log, recording[1:])
+		})
+	}
+}
+
+func TestSnapshotLogStringReuse(t *testing.T) {
+	recording := benchRecording(2*snapshotKeyframeInterval, 20)
+	log := appendAll(recording)
+
+	distinct := make(map[string]bool)
+	for _, snap := range recording {
+		for _, c := range snap.Connections {
+			for _, s := range []string{c.LocalAddr, c.RemoteAddr, c.RemoteHostname, c.RemoteCountry,
+				c.ServiceName, c.Role, c.CongestionAlgorithm, c.CAState} {
+				if s != "" {
+					distinct[s] = true
+				}
+			}
+		}
+	}
+	if got := len(log.strings.strings) - 1; got != len(distinct) {
+		t.Errorf("string table holds %d strings, want %d distinct", got, len(distinct))
+	}
+
+	// Recording the same strings again adds nothing
+	before := len(log.strings.strings)
+	for i := range recording {
+		snap := recording[i]
+		snap.ID += int64(len(recording))
+		log.Append(&snap)
+	}
+	if got := len(log.strings.strings); got != before {
+		t.Errorf("string table grew from %d to %d on repeated strings", before, got)
+	}
+
+	table := newStringTable()
+	tests := []struct {
+		in   string
+		want uint32
+	}{
+		{"", 0},
+		{"cubic", 1},
+		{"bbr", 2},
+		{"cubic", 1},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := table.intern(tt.in); got != tt.want {
+			t.Errorf("intern(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+	if got := table.strings.lookup(2); got != "bbr" {
+		t.Errorf("lookup(2) = %q, want bbr", got)
+	}
+}
+
+func TestSessionLogsInterleaved(t *testing.T) {
+	const per = 200
+	a := benchRecording(per, 20)
+	b := benchRecording(per, 20)
+	var recording []Snapshot
+	for i := 0; i < per; i++ {
+		a[i].SessionID, b[i].SessionID = 1, 2
+		a[i].ID, b[i].ID = int64(2*i+1), int64(2*i+2)
+		recording = append(recording, a[i], b[i])
+	}
+
+	logs := newSessionLogs()
+	for i := range recording {
+		logs.Append(&recording[i])
+	}
+	if logs.Len() != 2*per {
+		t.Fatalf("holds %d snapshots, want %d", logs.Len(), 2*per)
+	}
+
+	wantKeyframes := (per + snapshotKeyframeInterval - 1) / snapshotKeyframeInterval
+	for sessionID, want := range map[int64][]Snapshot{1: a, 2: b} {
+		log := logs.log(sessionID)
+		keyframes := 0
+		for _, f := range log.frames {
+			if f.keyframe {
+				keyframes++
+			}
+		}
+		if keyframes != wantKeyframes {
+			t.Fatalf("session %d has %d keyframes, want %d", sessionID, keyframes, wantKeyframes)
+		}
+		if runs := log.sessionRuns(sessionID); len(runs) != 1 {
+			t.Fatalf("session %d spans %d runs, want 1", sessionID, len(runs))
+		}
+		checkSnapshots(t, log, want)
+		checkRows(t, log, want, want[0].Connections[19])
+	}
+
+	// The oldest snapshot of any session goes first
+	for i := 0; i < 3; i++ {
+		logs.DropOldest()
+	}
+	checkSnapshots(t, logs.log(1), a[2:])
+	checkSnapshots(t, logs.log(2), b[1:])
+
+	n := 0
+	logs.EachMeta(func(id, _ int64, _ time.Time, _ int) {
+		if want := recording[n+3].ID; id != want {
+			t.Fatalf("snapshot %d visited as %d, want %d", n, id, want)
+		}
+		n++
+	})
+	if n != 2*per-3 {
+		t.Fatalf("visited %d snapshots, want %d", n, 2*per-3)
+	}
+}
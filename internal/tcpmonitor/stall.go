@@ -0,0 +1,267 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TCP timer kinds
+const (
+	TimerRetransmit      = "retransmit"
+	TimerKeepalive       = "keepalive"
+	TimerTimeWait        = "time_wait"
+	TimerZeroWindowProbe = "zero_window_probe"
+)
+
+// Stall conditions
+const (
+	StallRetransmitBackoff = "retransmit_backoff" // RTO retransmissions without an ACK
+	StallZeroWindow        = "zero_window_probe"  // Peer's receive window stays closed
+	StallKeepalive         = "keepalive_probe"    // Keepalive probes go unanswered
+)
+
+const (
+	// Consecutive unacknowledged retransmissions before a connection counts as stalled
+	stallMinRetransmits = 2
+
+	// Unanswered probes before a connection counts as stalled
+	stallMinZeroWindowProbes = 1
+	stallMinKeepaliveProbes  = 2
+
+	// Backoff level at which a stall is reported with high severity
+	stallHighBackoff = 4
+)
+
+// StallFinding describes a connection stuck in retransmission backoff or probing
+type StallFinding struct {
+	Condition   string    `json:"condition"`
+	Severity    string    `json:"severity"` // "high", "medium", "low"
+	Description string    `json:"description"`
+	Backoff     uint32    `json:"backoff,omitempty"`
+	Retransmits uint32    `json:"retransmits,omitempty"`
+	Probes      uint32    `json:"probes,omitempty"`
+	DurationMs  int64     `json:"durationMs"`          // Time spent in the condition so far
+	ExpiresMs   uint32    `json:"expiresMs,omitempty"` // Time until the next retry or probe
+	Timestamp   time.Time `json:"timestamp,omitempty"` // When replaying recorded sessions
+}
+
+// stallCondition returns the stall condition a timer indicates, or "" if none
+func stallCondition(t *TimerInfo) string {
+	if t == nil {
+		return ""
+	}
+	switch t.Kind {
+	case TimerRetransmit:
+		if t.Retransmits >= stallMinRetransmits {
+			return StallRetransmitBackoff
+		}
+	case TimerZeroWindowProbe:
+		if t.Probes >= stallMinZeroWindowProbes {
+			return StallZeroWindow
+		}
+	case TimerKeepalive:
+		if t.Probes >= stallMinKeepaliveProbes {
+			return StallKeepalive
+		}
+	}
+	return ""
+}
+
+// estimatedBackoffDuration estimates how long a connection has been backing
+// off from its timer alone: each retry doubled the RTO, so the completed
+// intervals add up to RTO - RTO>>backoff, plus the part of the current one
+// that has already elapsed
+func estimatedBackoffDuration(t *TimerInfo) time.Duration {
+	if t.RTOMs == 0 || t.Backoff == 0 || t.Backoff >= 32 {
+		return 0
+	}
+	ms := t.RTOMs - t.RTOMs>>t.Backoff
+	if t.ExpiresMs < t.RTOMs {
+		ms += t.RTOMs - t.ExpiresMs
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// stallFinding describes a stall condition that has lasted for the given duration
+func stallFinding(condition string, t *TimerInfo, duration time.Duration) StallFinding {
+	f := StallFinding{
+		Condition:   condition,
+		Severity:    "medium",
+		Backoff:     t.Backoff,
+		Retransmits: t.Retransmits,
+		Probes:      t.Probes,
+		DurationMs:  duration.Milliseconds(),
+		ExpiresMs:   t.ExpiresMs,
+	}
+	seconds := duration.Seconds()
+
+	switch condition {
+	case StallRetransmitBackoff:
+		if t.Backoff >= stallHighBackoff {
+			f.Severity = "high"
+		}
+		f.Description = fmt.Sprintf("Retransmitting with backoff %d for %.0fs (%d retransmissions without an ACK, next retry in %s)",
+			t.Backoff, seconds, t.Retransmits, formatTimerMs(t.ExpiresMs))
+	case StallZeroWindow:
+		f.Description = fmt.Sprintf("Zero-window probing for %.0fs (%d probes): the peer is not reading and its receive window stays closed",
+			seconds, t.Probes)
+	case StallKeepalive:
+		f.Severity = "high"
+		f.Description = fmt.Sprintf("%d keepalive probes unanswered for %.0fs: the peer is unreachable",
+			t.Probes, seconds)
+	}
+	return f
+}
+
+// formatTimerMs formats a timer expiry for descriptions
+func formatTimerMs(ms uint32) string {
+	if ms >= 1000 {
+		return fmt.Sprintf("%.1fs", float64(ms)/1000)
+	}
+	return fmt.Sprintf("%dms", ms)
+}
+
+// describeTimer summarizes a pending timer for the AI context
+func describeTimer(t *TimerInfo) string {
+	s := fmt.Sprintf("%s in %s", t.Kind, formatTimerMs(t.ExpiresMs))
+	switch {
+	case t.Backoff > 0:
+		s += fmt.Sprintf(" (backoff %d, %d retransmissions)", t.Backoff, t.Retransmits)
+	case t.Probes > 0:
+		s += fmt.Sprintf(" (%d probes)", t.Probes)
+	}
+	return s
+}
+
+// stallTracker follows one connection's timer across samples.
+// It is used both live (StallDetector) and when replaying recorded sessions.
+type stallTracker struct {
+	condition string
+	since     time.Time // Estimated start of the current condition
+}
+
+// observe advances the tracker and returns the standing stall, if any.
+// started is true when the condition began at this sample.
+func (t *stallTracker) observe(timer *TimerInfo, at time.Time) (f *StallFinding, started bool) {
+	condition := stallCondition(timer)
+	if condition == "" {
+		t.condition = ""
+		return nil, false
+	}
+
+	if condition != t.condition {
+		t.condition = condition
+		t.since = at
+		if condition == StallRetransmitBackoff {
+			t.since = at.Add(-estimatedBackoffDuration(timer))
+		}
+		started = true
+	}
+
+	finding := stallFinding(condition, timer, at.Sub(t.since))
+	return &finding, started
+}
+
+// DetectStalls replays a recorded connection timeline and returns every
+// stall, stamped with the time of the sample where it started. Durations are
+// those reached by the end of each stall.
+func DetectStalls(snapshots []TimelineConnection) []StallFinding {
+	var tracker stallTracker
+	var findings []StallFinding
+
+	for i := range snapshots {
+		f, started := tracker.observe(timerFromCompact(&snapshots[i].Connection), snapshots[i].Timestamp)
+		if f == nil {
+			continue
+		}
+		if started {
+			f.Timestamp = snapshots[i].Timestamp
+			findings = append(findings, *f)
+		} else {
+			// Keep the start time, report the latest duration and backoff
+			f.Timestamp = findings[len(findings)-1].Timestamp
+			findings[len(findings)-1] = *f
+		}
+	}
+	return findings
+}
+
+// timerFromCompact rebuilds timer state from a recorded snapshot
+func timerFromCompact(c *CompactConnection) *TimerInfo {
+	if c.TimerKind == "" {
+		return nil
+	}
+	return &TimerInfo{
+		Kind:        c.TimerKind,
+		ExpiresMs:   uint32(c.TimerExpiresMs),
+		Retransmits: uint32(c.TimerRetransmits),
+		Probes:      uint32(c.TimerProbes),
+		Backoff:     uint32(c.TimerBackoff),
+		RTOMs:       uint32(c.RTOMs),
+	}
+}
+
+// StallDetector flags live connections stuck in retransmission backoff or probing
+type StallDetector struct {
+	trackers map[ConnectionKey]*stallTracker
+	mu       sync.Mutex
+	logger   *Logger
+}
+
+// NewStallDetector creates a new detector
+func NewStallDetector() *StallDetector {
+	return &StallDetector{
+		trackers: make(map[ConnectionKey]*stallTracker),
+		logger:   GetLogger(),
+	}
+}
+
+// Analyze sets StallWarning and Stall on every connection with timer
+// information and forgets connections that are gone
+func (d *StallDetector) Analyze(connections []ConnectionInfo) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	seen := make(map[ConnectionKey]bool, len(connections))
+	for i := range connections {
+		conn := &connections[i]
+		conn.StallWarning = false
+		conn.Stall = nil
+
+		if conn.Timer == nil {
+			continue
+		}
+
+		key := ConnectionKey{
+			LocalAddr:  conn.LocalAddr,
+			LocalPort:  conn.LocalPort,
+			RemoteAddr: conn.RemoteAddr,
+			RemotePort: conn.RemotePort,
+			IsIPv6:     conn.IsIPv6,
+		}
+		seen[key] = true
+
+		tracker := d.trackers[key]
+		if tracker == nil {
+			tracker = &stallTracker{}
+			d.trackers[key] = tracker
+		}
+
+		f, started := tracker.observe(conn.Timer, now)
+		if started {
+			d.logger.Info("%s on %s", f.Description, key.String())
+		}
+		if f != nil {
+			conn.StallWarning = true
+			conn.Stall = f
+		}
+	}
+
+	for key := range d.trackers {
+		if !seen[key] {
+			delete(d.trackers, key)
+		}
+	}
+}
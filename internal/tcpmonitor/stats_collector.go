@@ -169,6 +169,20 @@ func (sc *StatsCollector) EnableExtendedStats(conn *ConnectionInfo) error {
 	return nil
 }
 
+// PrepareConnection fills the raw Windows API fields of a connection built
+// from address strings, e.g. a socket opened by tcpdoctor itself
+func (sc *StatsCollector) PrepareConnection(conn *ConnectionInfo) {
+	conn.RawLocalPort = sc.portToNetworkOrder(conn.LocalPort)
+	conn.RawRemotePort = sc.portToNetworkOrder(conn.RemotePort)
+	if conn.IsIPv6 {
+		conn.RawLocalAddr6 = sc.ipv6StringToBytes(conn.LocalAddr)
+		conn.RawRemoteAddr6 = sc.ipv6StringToBytes(conn.RemoteAddr)
+	} else {
+		conn.RawLocalAddr = sc.ipv4StringToUint32(conn.LocalAddr)
+		conn.RawRemoteAddr = sc.ipv4StringToUint32(conn.RemoteAddr)
+	}
+}
+
 // GetExtendedStats retrieves extended statistics for a connection
 func (sc *StatsCollector) GetExtendedStats(conn *ConnectionInfo) (*ExtendedStats, error) {
 	sc.logger.Debug("Getting extended stats for %s:%d -> %s:%d",
@@ -224,6 +238,9 @@ func (sc *StatsCollector) GetExtendedStats(conn *ConnectionInfo) (*ExtendedStats
 		stats.CurrentSsthresh = congStats.CurSsthresh
 		stats.SlowStartCount = congStats.SlowStart
 		stats.CongAvoidCount = congStats.CongAvoid
+		stats.SndLimTimeRwin = congStats.SndLimTimeRwin
+		stats.SndLimTimeCwnd = congStats.SndLimTimeCwnd
+		stats.SndLimTimeSnd = congStats.SndLimTimeSnd
 	} else {
 		sc.logger.Debug("Failed to get congestion stats: %v", err)
 	}
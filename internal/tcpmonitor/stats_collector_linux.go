@@ -0,0 +1,459 @@
+//go:build linux
+
+package tcpmonitor
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Dumped stats younger than this are served from the cache
+	statsCacheMaxAge = 500 * time.Millisecond
+
+	// Minimum interval between /proc scans for socket owners
+	pidScanInterval = 2 * time.Second
+
+	// tcp_info reports an "infinite" ssthresh as TCP_INFINITE_SSTHRESH
+	linuxInfiniteSsthresh = 0x7FFFFFFF
+)
+
+// linuxTCPStates maps kernel TCP states (include/net/tcp_states.h)
+var linuxTCPStates = map[uint8]TCPState{
+	1:  StateEstablished,
+	2:  StateSynSent,
+	3:  StateSynRcvd,
+	4:  StateFinWait1,
+	5:  StateFinWait2,
+	6:  StateTimeWait,
+	7:  StateClosed,
+	8:  StateCloseWait,
+	9:  StateLastAck,
+	10: StateListen,
+	11: StateClosing,
+	12: StateSynRcvd, // TCP_NEW_SYN_RECV
+}
+
+// linuxTimers maps idiag_timer values to timer kinds
+var linuxTimers = map[uint8]string{
+	1: TimerRetransmit, // Also the tail loss probe and reordering timers
+	2: TimerKeepalive,
+	3: TimerTimeWait,
+	4: TimerZeroWindowProbe,
+}
+
+// linuxCAStates names tcp_info.tcpi_ca_state values
+var linuxCAStates = []string{"open", "disorder", "cwr", "recovery", "loss"}
+
+// linuxStatsEntry is the latest tcp_info of one connection plus tracked maxima
+type linuxStatsEntry struct {
+	stats     *ExtendedStats
+	updatedAt time.Time
+}
+
+// StatsCollector reads TCP connections and statistics through inet_diag
+type StatsCollector struct {
+	entries  map[ConnectionKey]*linuxStatsEntry
+	pids     map[uint32]uint32 // Socket inode -> owning PID
+	lastScan time.Time
+	mu       sync.Mutex
+	logger   *Logger
+}
+
+// NewStatsCollector creates a new statistics collector
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{
+		entries: make(map[ConnectionKey]*linuxStatsEntry),
+		pids:    make(map[uint32]uint32),
+		logger:  GetLogger(),
+	}
+}
+
+// CollectIPv4Connections retrieves all IPv4 TCP connections
+func (sc *StatsCollector) CollectIPv4Connections() ([]ConnectionInfo, error) {
+	sc.logger.Debug("Collecting IPv4 connections")
+	return sc.collect(unix.AF_INET)
+}
+
+// CollectIPv6Connections retrieves all IPv6 TCP connections
+func (sc *StatsCollector) CollectIPv6Connections() ([]ConnectionInfo, error) {
+	sc.logger.Debug("Collecting IPv6 connections")
+	return sc.collect(unix.AF_INET6)
+}
+
+// collect dumps one address family and caches the statistics of every socket
+func (sc *StatsCollector) collect(family uint8) ([]ConnectionInfo, error) {
+	sockets, err := inetDiagDump(family)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.refreshPIDs(sockets)
+
+	isIPv6 := family == unix.AF_INET6
+	now := time.Now()
+	seen := make(map[ConnectionKey]bool, len(sockets))
+	connections := make([]ConnectionInfo, 0, len(sockets))
+
+	for i := range sockets {
+		sock := &sockets[i]
+		state, ok := linuxTCPStates[sock.State]
+		if !ok {
+			continue
+		}
+
+		conn := ConnectionInfo{
+			LocalAddr:  sock.localAddr().String(),
+			LocalPort:  sock.ID.SrcPort,
+			RemoteAddr: sock.remoteAddr().String(),
+			RemotePort: sock.ID.DstPort,
+			State:      state,
+			PID:        sc.pids[sock.Inode],
+			IsIPv6:     isIPv6,
+			LastSeen:   now,
+			Timer:      timerFromDiag(sock),
+		}
+		connections = append(connections, conn)
+
+		if sock.Info == nil {
+			continue
+		}
+		key := connectionKeyOf(&conn)
+		seen[key] = true
+		entry := sc.entries[key]
+		if entry == nil {
+			entry = &linuxStatsEntry{}
+			sc.entries[key] = entry
+		}
+		entry.stats = extendedStatsFromDiag(sock, entry.stats)
+		entry.updatedAt = now
+	}
+
+	sc.logger.Debug("Found %d connections (family %d)", len(connections), family)
+
+	// Forget connections of this family that are gone
+	for key := range sc.entries {
+		if key.IsIPv6 == isIPv6 && !seen[key] {
+			delete(sc.entries, key)
+		}
+	}
+
+	return connections, nil
+}
+
+// EnableExtendedStats is a no-op: the kernel always maintains tcp_info
+func (sc *StatsCollector) EnableExtendedStats(conn *ConnectionInfo) error {
+	return nil
+}
+
+// PrepareConnection is a no-op on Linux; sockets are looked up by address
+func (sc *StatsCollector) PrepareConnection(conn *ConnectionInfo) {}
+
+// GetExtendedStats retrieves extended statistics for a connection
+func (sc *StatsCollector) GetExtendedStats(conn *ConnectionInfo) (*ExtendedStats, error) {
+	sc.logger.Debug("Getting extended stats for %s:%d -> %s:%d",
+		conn.LocalAddr, conn.LocalPort, conn.RemoteAddr, conn.RemotePort)
+
+	key := connectionKeyOf(conn)
+
+	sc.mu.Lock()
+	if entry := sc.entries[key]; entry != nil && time.Since(entry.updatedAt) < statsCacheMaxAge {
+		stats := *entry.stats
+		sc.mu.Unlock()
+		return &stats, nil
+	}
+	sc.mu.Unlock()
+
+	family := uint8(unix.AF_INET)
+	if conn.IsIPv6 {
+		family = unix.AF_INET6
+	}
+	id, err := diagSockIDOf(conn)
+	if err != nil {
+		return nil, err
+	}
+	sock, err := inetDiagQuery(family, id)
+	if err != nil {
+		return nil, err
+	}
+	if sock.Info == nil {
+		return nil, ErrConnectionNotFound
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	entry := sc.entries[key]
+	if entry == nil {
+		entry = &linuxStatsEntry{}
+		sc.entries[key] = entry
+	}
+	entry.stats = extendedStatsFromDiag(sock, entry.stats)
+	entry.updatedAt = time.Now()
+
+	stats := *entry.stats
+	return &stats, nil
+}
+
+// connectionKeyOf returns the connection manager key of a connection
+func connectionKeyOf(conn *ConnectionInfo) ConnectionKey {
+	return ConnectionKey{
+		LocalAddr:  conn.LocalAddr,
+		LocalPort:  conn.LocalPort,
+		RemoteAddr: conn.RemoteAddr,
+		RemotePort: conn.RemotePort,
+		IsIPv6:     conn.IsIPv6,
+	}
+}
+
+// diagSockIDOf builds the inet_diag socket id of a connection
+func diagSockIDOf(conn *ConnectionInfo) (inetDiagSockID, error) {
+	id := inetDiagSockID{
+		SrcPort: conn.LocalPort,
+		DstPort: conn.RemotePort,
+		Cookie:  inetDiagNoCookie,
+	}
+	local, remote := net.ParseIP(conn.LocalAddr), net.ParseIP(conn.RemoteAddr)
+	if local == nil || remote == nil {
+		return id, ErrInvalidParameter
+	}
+	if conn.IsIPv6 {
+		copy(id.Src[:], local.To16())
+		copy(id.Dst[:], remote.To16())
+	} else {
+		copy(id.Src[:4], local.To4())
+		copy(id.Dst[:4], remote.To4())
+	}
+	return id, nil
+}
+
+// timerFromDiag returns the pending timer of a socket, nil if none is armed
+func timerFromDiag(sock *diagSocket) *TimerInfo {
+	kind, ok := linuxTimers[sock.Timer]
+	if !ok {
+		return nil
+	}
+
+	timer := &TimerInfo{
+		Kind:      kind,
+		ExpiresMs: sock.Expires,
+	}
+	// idiag_retrans counts retransmissions for the retransmit timer and
+	// unanswered probes for the probe and keepalive timers
+	switch kind {
+	case TimerRetransmit:
+		timer.Retransmits = uint32(sock.Retrans)
+	case TimerKeepalive, TimerZeroWindowProbe:
+		timer.Probes = uint32(sock.Retrans)
+	}
+	if info := sock.Info; info != nil {
+		timer.Retransmits = uint32(info.Retransmits)
+		timer.Probes = uint32(info.Probes)
+		timer.Backoff = uint32(info.Backoff)
+		timer.RTOMs = usToMs(info.Rto)
+	}
+	return timer
+}
+
+// extendedStatsFromDiag converts tcp_info and congestion control attributes
+// to ExtendedStats, carrying tracked maxima and minima over from prev
+func extendedStatsFromDiag(sock *diagSocket, prev *ExtendedStats) *ExtendedStats {
+	info := sock.Info
+	mss := info.Snd_mss
+
+	stats := &ExtendedStats{
+		TotalSegsOut:      uint64(info.Segs_out),
+		TotalSegsIn:       uint64(info.Segs_in),
+		ThruBytesAcked:    info.Bytes_acked,
+		ThruBytesReceived: info.Bytes_received,
+
+		SegsRetrans:     info.Total_retrans,
+		BytesRetrans:    uint32(info.Bytes_retrans),
+		TimeoutEpisodes: uint32(info.Total_rto),
+
+		SampleRTT:   usToMs(info.Rtt),
+		SmoothedRTT: usToMs(info.Rtt),
+		RTTVariance: usToMs(info.Rttvar),
+
+		CurrentCwnd:     info.Snd_cwnd * mss,
+		CurrentSsthresh: 0xFFFFFFFF,
+
+		SndLimTimeRwin: uint32(info.Rwnd_limited / 1000),
+
+		CurRetxQueue: info.Unacked * mss,
+		CurAppWQueue: info.Notsent_bytes,
+
+		OutboundBandwidth: info.Delivery_rate * 8,
+
+		WinScaleRcvd: uint32(sock.SndWscale),
+		WinScaleSent: uint32(sock.RcvWscale),
+		CurRwinRcvd:  info.Snd_wnd,
+		CurRwinSent:  info.Rcv_wnd,
+
+		CurMss: mss,
+
+		DsackDups: info.Dsack_dups,
+	}
+
+	// rcv_wnd was added in 4.19; fall back to the receive space estimate
+	if stats.CurRwinSent == 0 {
+		stats.CurRwinSent = info.Rcv_space
+	}
+	if info.Snd_ssthresh < linuxInfiniteSsthresh && uint64(info.Snd_ssthresh)*uint64(mss) < 0xFFFFFFFF {
+		stats.CurrentSsthresh = info.Snd_ssthresh * mss
+	}
+	// min_rtt is ~0U until the first RTT sample
+	if info.Min_rtt != 0 && info.Min_rtt != 0xFFFFFFFF {
+		stats.MinRTT = usToMs(info.Min_rtt)
+	}
+	if info.Busy_time > info.Rwnd_limited+info.Sndbuf_limited {
+		stats.SndLimTimeCwnd = uint32((info.Busy_time - info.Rwnd_limited - info.Sndbuf_limited) / 1000)
+	}
+
+	// Socket memory
+	if m := sock.MemInfo; m != nil {
+		stats.RmemAlloc = m[skMemInfoRmemAlloc]
+		stats.RcvBuf = m[skMemInfoRcvBuf]
+		stats.WmemAlloc = m[skMemInfoWmemAlloc]
+		stats.WmemQueued = m[skMemInfoWmemQueued]
+		stats.SndBuf = m[skMemInfoSndBuf]
+		stats.FwdAlloc = m[skMemInfoFwdAlloc]
+		stats.SockDrops = m[skMemInfoDrops]
+	}
+
+	// Congestion control algorithm and state
+	stats.CongestionAlgorithm = sock.Congestion
+	if int(info.Ca_state) < len(linuxCAStates) {
+		stats.CAState = linuxCAStates[info.Ca_state]
+	}
+	if info.Pacing_rate != 0 && info.Pacing_rate != ^uint64(0) {
+		stats.PacingRate = info.Pacing_rate * 8
+	}
+	if sock.BBR != nil {
+		stats.BBR = &BBRInfo{
+			BandwidthBps: (uint64(sock.BBR.Bw_hi)<<32 | uint64(sock.BBR.Bw_lo)) * 8,
+			MinRTTUs:     sock.BBR.Min_rtt,
+			PacingGain:   float64(sock.BBR.Pacing_gain) / 256,
+			CwndGain:     float64(sock.BBR.Cwnd_gain) / 256,
+		}
+	}
+	if sock.DCTCP != nil && sock.DCTCP.Enabled != 0 {
+		stats.DCTCP = &DCTCPInfo{
+			Enabled: true,
+			CEState: sock.DCTCP.Ce_state,
+			Alpha:   sock.DCTCP.Alpha,
+			ABECN:   sock.DCTCP.Ab_ecn,
+			ABTotal: sock.DCTCP.Ab_tot,
+		}
+	}
+	if sock.Vegas != nil && sock.Vegas.Enabled != 0 {
+		stats.Vegas = &VegasInfo{
+			Enabled:  true,
+			RTTCount: sock.Vegas.Rttcnt,
+			RTTUs:    sock.Vegas.Rtt,
+			MinRTTUs: sock.Vegas.Minrtt,
+		}
+	}
+
+	// Windows ESTATS keeps running maxima; track them across samples
+	stats.MaxRTT = stats.SmoothedRTT
+	stats.MaxMss, stats.MinMss = mss, mss
+	stats.MaxRwinRcvd = stats.CurRwinRcvd
+	stats.MaxRwinSent = stats.CurRwinSent
+	stats.MaxRetxQueue = stats.CurRetxQueue
+	stats.MaxAppWQueue = stats.CurAppWQueue
+	if prev != nil {
+		stats.MaxRTT = maxUint32(stats.MaxRTT, prev.MaxRTT)
+		stats.MaxMss = maxUint32(stats.MaxMss, prev.MaxMss)
+		if prev.MinMss != 0 && prev.MinMss < stats.MinMss {
+			stats.MinMss = prev.MinMss
+		}
+		stats.MaxRwinRcvd = maxUint32(stats.MaxRwinRcvd, prev.MaxRwinRcvd)
+		stats.MaxRwinSent = maxUint32(stats.MaxRwinSent, prev.MaxRwinSent)
+		stats.MaxRetxQueue = maxUint32(stats.MaxRetxQueue, prev.MaxRetxQueue)
+		stats.MaxAppWQueue = maxUint32(stats.MaxAppWQueue, prev.MaxAppWQueue)
+	}
+
+	return stats
+}
+
+// refreshPIDs rescans /proc for socket owners when unknown sockets appear.
+// Must be called with sc.mu held.
+func (sc *StatsCollector) refreshPIDs(sockets []diagSocket) {
+	unknown := false
+	for i := range sockets {
+		if inode := sockets[i].Inode; inode != 0 {
+			if _, ok := sc.pids[inode]; !ok {
+				unknown = true
+				break
+			}
+		}
+	}
+	if !unknown || time.Since(sc.lastScan) < pidScanInterval {
+		return
+	}
+	sc.lastScan = time.Now()
+	sc.pids = scanSocketOwners()
+	// Remember sockets without a visible owner so they do not force rescans
+	for i := range sockets {
+		if inode := sockets[i].Inode; inode != 0 {
+			if _, ok := sc.pids[inode]; !ok {
+				sc.pids[inode] = 0
+			}
+		}
+	}
+}
+
+// scanSocketOwners maps socket inodes to PIDs from /proc/<pid>/fd. Processes
+// of other users are only visible when running as root.
+func scanSocketOwners() map[uint32]uint32 {
+	owners := make(map[uint32]uint32)
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return owners
+	}
+	for _, proc := range procs {
+		pid, err := strconv.ParseUint(proc.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join("/proc", proc.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(link[len("socket:["):], "]"), 10, 32)
+			if err == nil {
+				owners[uint32(inode)] = uint32(pid)
+			}
+		}
+	}
+	return owners
+}
+
+// usToMs converts microseconds to milliseconds, rounding up non-zero values
+func usToMs(us uint32) uint32 {
+	return (us + 999) / 1000
+}
+
+// maxUint32 returns the larger of two values
+func maxUint32(a, b uint32) uint32 {
+	if a > b {
+		return a
+	}
+	return b
+}
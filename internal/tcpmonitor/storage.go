@@ -0,0 +1,1616 @@
+package tcpmonitor
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StorageConfig controls on-disk persistence of recording sessions
+type StorageConfig struct {
+	Enabled       bool   // Persist sessions to disk; disabled keeps recordings in memory only
+	Dir           string // Directory holding session data (empty = per-user default)
+	RetentionDays int    // Sessions older than this are deleted (0 = keep forever)
+	MaxSizeMB     int    // Total size cap; oldest sessions are deleted first (0 = unlimited)
+
+	// Snapshots older than this are dropped, leaving their rollups (0 = keep
+	// full resolution as long as the session)
+	FullResolutionHours int
+}
+
+// DefaultStorageConfig returns the default storage configuration
+func DefaultStorageConfig() StorageConfig {
+	return StorageConfig{
+		Enabled:       true,
+		RetentionDays: 30,
+		MaxSizeMB:     2048,
+
+		FullResolutionHours: 24,
+	}
+}
+
+// defaultStorageDir returns the per-user directory for recordings
+func defaultStorageDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "tcpdoctor", "recordings"), nil
+}
+
+// Record types in segment files
+const (
+	recordSnapshot byte = 1
+	recordProbe    byte = 2
+	recordRollup   byte = 3
+)
+
+const (
+	// Record header: payload length, CRC32 of type+payload, type
+	recordHeaderSize = 9
+
+	// Largest record accepted when reading; anything bigger is corruption
+	maxRecordSize = 64 << 20
+
+	// A new segment file is started beyond this size
+	segmentMaxBytes = 16 << 20
+
+	// Appended records are synced at least this often; a crash loses at most
+	// the records written since the last sync
+	segmentSyncInterval = time.Second
+
+	// Number of session reads kept decoded
+	loadCacheEntries = 4
+
+	sessionDirPrefix  = "session-"
+	sessionMetaFile   = "session.json"
+	segmentFilePrefix = "seg-"
+	segmentFileSuffix = ".log"
+
+	// Probe results rescued from segments dropped by downsampling
+	keptProbesFile = "probes.log"
+
+	// User annotations, rewritten whole on every change
+	annotationsFile = "annotations.json"
+)
+
+// storedSession is the on-disk metadata of a session
+type storedSession struct {
+	RecordingSession
+	FirstSnapshotID int64 `json:"firstSnapshotId"`
+	LastSnapshotID  int64 `json:"lastSnapshotId"`
+	ProbeCount      int   `json:"probeCount"`
+	Rollups         bool  `json:"rollups,omitempty"` // Rollup files cover the whole session
+
+	// Where each segment's snapshots lie, so reads open only the segments
+	// they need. Indexed is false for sessions recorded before the index.
+	Segments []segmentInfo `json:"segments,omitempty"`
+	Indexed  bool          `json:"indexed,omitempty"`
+}
+
+// segmentInfo is the snapshot ID and time range of one segment file
+type segmentInfo struct {
+	Index   int       `json:"index"`
+	FirstID int64     `json:"firstId"`
+	LastID  int64     `json:"lastId"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+}
+
+// indexSnapshot adds a snapshot written to a segment to the session's
+// counts, ID range and segment index
+func (m *storedSession) indexSnapshot(segment int, snap *Snapshot) {
+	if m.FirstSnapshotID == 0 {
+		m.FirstSnapshotID = snap.ID
+	}
+	m.LastSnapshotID = max(m.LastSnapshotID, snap.ID)
+	m.SnapshotCount++
+
+	n := len(m.Segments)
+	if n == 0 || m.Segments[n-1].Index != segment {
+		m.Segments = append(m.Segments, segmentInfo{Index: segment, FirstID: snap.ID, Start: snap.Timestamp})
+		n++
+	}
+	seg := &m.Segments[n-1]
+	seg.LastID, seg.End = snap.ID, snap.Timestamp
+}
+
+// segmentsInRange returns the indexed segments holding snapshots between
+// start and end inclusive; a zero time leaves that end open. When the range
+// falls between segments, the segments on either side are returned so that
+// the nearest snapshots are still found.
+func segmentsInRange(segments []segmentInfo, start, end time.Time) []segmentInfo {
+	from, to := -1, -1
+	for i := range segments {
+		if (!start.IsZero() && segments[i].End.Before(start)) || (!end.IsZero() && segments[i].Start.After(end)) {
+			continue
+		}
+		if from < 0 {
+			from = i
+		}
+		to = i
+	}
+	if from < 0 {
+		if len(segments) == 0 {
+			return nil
+		}
+		i := sort.Search(len(segments), func(i int) bool { return !segments[i].End.Before(start) })
+		from, to = max(i-1, 0), min(i, len(segments)-1)
+	}
+	return segments[from : to+1]
+}
+
+// segmentHolding returns the indexed segment holding a snapshot ID
+func segmentHolding(segments []segmentInfo, snapshotID int64) []segmentInfo {
+	for i := range segments {
+		if snapshotID >= segments[i].FirstID && snapshotID <= segments[i].LastID {
+			return segments[i : i+1]
+		}
+	}
+	return nil
+}
+
+// sessionWriter appends records to the session being recorded
+type sessionWriter struct {
+	meta     storedSession
+	dir      string
+	segment  *os.File
+	segIndex int
+	segSize  int64
+	dirty    bool // Records written since the segment was last synced
+}
+
+// loadedSession caches the records read from sealed segments of a session:
+// all of them with its probe results, or the range first..last of indexes
+type loadedSession struct {
+	id          int64
+	whole       bool
+	first, last int
+	snapshots   *snapshotLog
+	probes      []ProbeResult
+}
+
+// DiskStore persists recording sessions as append-only segment files.
+// Every record carries a CRC, so a crash loses at most the records written
+// since the last periodic sync; a torn record at the end of the active
+// segment is truncated on reopen.
+type DiskStore struct {
+	config   StorageConfig
+	dir      string
+	sessions map[int64]*storedSession
+	active   map[int64]*sessionWriter // Sessions being recorded
+	cached   []*loadedSession         // Most recently used first
+	mu       sync.Mutex
+	logger   *Logger
+
+	// Serializes retention passes and rewrites, which read and remove files
+	// outside mu. Taken before mu.
+	maintenance sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// OpenDiskStore opens (or creates) the storage directory, recovers sessions
+// interrupted by a crash and applies retention
+func OpenDiskStore(config StorageConfig) (*DiskStore, error) {
+	dir := config.Dir
+	if dir == "" {
+		var err error
+		if dir, err = defaultStorageDir(); err != nil {
+			return nil, fmt.Errorf("failed to locate storage directory: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	d := &DiskStore{
+		config:   config,
+		dir:      dir,
+		sessions: make(map[int64]*storedSession),
+		active:   make(map[int64]*sessionWriter),
+		logger:   GetLogger(),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage directory: %w", err)
+	}
+	for _, entry := range entries {
+		id, ok := parseSessionDir(entry.Name())
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		meta, err := d.recoverSession(id)
+		if err != nil {
+			d.logger.Warn("Skipping unreadable session %d: %v", id, err)
+			continue
+		}
+		d.sessions[id] = meta
+	}
+
+	d.applyRetention()
+	d.logger.Info("Opened session storage at %s (%d sessions)", dir, len(d.sessions))
+
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	d.wg.Add(1)
+	go d.syncLoop()
+	return d, nil
+}
+
+// syncLoop periodically syncs the segments written to since the last pass,
+// so that appends never wait for the disk
+func (d *DiskStore) syncLoop() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(segmentSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.syncDirty()
+		}
+	}
+}
+
+// syncDirty syncs segments with unsynced records. The syncs run outside the
+// lock; a segment closed meanwhile was synced when it was closed.
+func (d *DiskStore) syncDirty() {
+	d.mu.Lock()
+	var files []*os.File
+	for _, w := range d.active {
+		if w.dirty {
+			files = append(files, w.segment)
+			w.dirty = false
+		}
+	}
+	d.mu.Unlock()
+
+	for _, f := range files {
+		if err := f.Sync(); err != nil && !errors.Is(err, os.ErrClosed) {
+			d.logger.Warn("Failed to sync %s: %v", f.Name(), err)
+		}
+	}
+}
+
+// Config returns the storage configuration with the resolved directory
+func (d *DiskStore) Config() StorageConfig {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	config := d.config
+	config.Dir = d.dir
+	return config
+}
+
+// Sessions returns the metadata of all stored sessions, oldest first
+func (d *DiskStore) Sessions() []storedSession {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := make([]storedSession, 0, len(d.sessions))
+	for _, meta := range d.sessions {
+		result = append(result, *meta)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// BeginSession creates the directory and first segment of a new session.
+// Several sessions can be recorded at once.
+func (d *DiskStore) BeginSession(session RecordingSession) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if w := d.active[session.ID]; w != nil {
+		d.closeWriterLocked(w)
+	}
+
+	w, err := newSessionWriter(d.sessionDir(session.ID), session)
+	if err != nil {
+		return err
+	}
+
+	d.active[session.ID] = w
+	meta := w.meta
+	d.sessions[session.ID] = &meta
+	return nil
+}
+
+// WriteSession stores a complete session, such as an imported one,
+// alongside the session being recorded. Rollups are computed from the
+// snapshots when none are given.
+func (d *DiskStore) WriteSession(session RecordingSession, snapshots []Snapshot, probes []ProbeResult, rollups []ConnectionRollup) error {
+	d.mu.Lock()
+	_, exists := d.sessions[session.ID]
+	d.mu.Unlock()
+	if exists {
+		return fmt.Errorf("session %d already exists", session.ID)
+	}
+
+	// Not listed until complete, so retention leaves it alone meanwhile
+	meta, err := writeSessionFiles(d.sessionDir(session.ID), session, snapshots, probes, rollups)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sessions[session.ID] = meta
+	return nil
+}
+
+// ReplaceSession rewrites a closed session with new content, or creates it.
+// The new files are written next to the old ones and swapped in, so the
+// session is never partially rewritten. Segments keep the age of the
+// session's end so that downsampling is not postponed by the rewrite.
+func (d *DiskStore) ReplaceSession(session RecordingSession, snapshots []Snapshot, probes []ProbeResult, rollups []ConnectionRollup) error {
+	d.maintenance.Lock()
+	defer d.maintenance.Unlock()
+
+	dir := d.sessionDir(session.ID)
+	tmp := dir + ".tmp"
+	os.RemoveAll(tmp)
+	meta, err := writeSessionFiles(tmp, session, snapshots, probes, rollups)
+	if err != nil {
+		return err
+	}
+	if !session.EndTime.IsZero() && session.EndTime.Before(time.Now()) {
+		if segments, err := listSegments(tmp); err == nil {
+			for _, name := range segments {
+				os.Chtimes(filepath.Join(tmp, name), session.EndTime, session.EndTime)
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.active[session.ID] != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("session %d is being recorded", session.ID)
+	}
+	old := dir + ".old"
+	if _, exists := d.sessions[session.ID]; exists {
+		if err := os.Rename(dir, old); err != nil {
+			os.RemoveAll(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		os.Rename(old, dir)
+		os.RemoveAll(tmp)
+		return err
+	}
+	os.RemoveAll(old)
+
+	d.uncacheLocked(session.ID)
+	d.sessions[session.ID] = meta
+	return nil
+}
+
+// writeSessionFiles writes a complete session into a new directory.
+// Rollups are computed from the snapshots when none are given.
+func writeSessionFiles(dir string, session RecordingSession, snapshots []Snapshot, probes []ProbeResult, rollups []ConnectionRollup) (*storedSession, error) {
+	// Until the metadata is finalized, a crash leaves a recoverable open session
+	endTime := session.EndTime
+	session.EndTime = time.Time{}
+	w, err := newSessionWriter(dir, session)
+	if err != nil {
+		return nil, err
+	}
+	w.meta.SnapshotCount = 0
+	if rollups == nil {
+		b := newTieredRollupBuilder()
+		for i := range snapshots {
+			rollups = append(rollups, b.add(&snapshots[i])...)
+		}
+		rollups = append(rollups, b.flush()...)
+	}
+
+	err = func() error {
+		for i := range snapshots {
+			payload, err := json.Marshal(&snapshots[i])
+			if err != nil {
+				return err
+			}
+			if err := w.appendSnapshot(&snapshots[i], payload); err != nil {
+				return err
+			}
+		}
+		for i := range probes {
+			payload, err := json.Marshal(&probes[i])
+			if err != nil {
+				return err
+			}
+			if err := w.append(recordProbe, payload); err != nil {
+				return err
+			}
+			w.meta.ProbeCount++
+		}
+		if err := writeRollups(w.dir, rollups); err != nil {
+			return err
+		}
+		if err := w.segment.Sync(); err != nil {
+			return err
+		}
+		if err := w.segment.Close(); err != nil {
+			return err
+		}
+		w.meta.EndTime = endTime
+		return writeSessionMeta(w.dir, &w.meta)
+	}()
+	if err != nil {
+		w.segment.Close()
+		os.RemoveAll(w.dir)
+		return nil, err
+	}
+	meta := w.meta
+	return &meta, nil
+}
+
+// SetSessionInfo updates the descriptive metadata of a session
+func (d *DiskStore) SetSessionInfo(sessionID int64, name, description string, tags []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	meta, ok := d.sessions[sessionID]
+	if !ok {
+		return fmt.Errorf("session %d not found", sessionID)
+	}
+	if w := d.active[sessionID]; w != nil {
+		meta = &w.meta
+	}
+	meta.Name = name
+	meta.Description = description
+	meta.Tags = tags
+	*d.sessions[sessionID] = *meta
+	return writeSessionMeta(d.sessionDir(sessionID), meta)
+}
+
+// SetSessionThresholds updates the health thresholds recorded with a session
+func (d *DiskStore) SetSessionThresholds(sessionID int64, thresholds []ThresholdChange) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	meta, ok := d.sessions[sessionID]
+	if !ok {
+		return fmt.Errorf("session %d not found", sessionID)
+	}
+	if w := d.active[sessionID]; w != nil {
+		meta = &w.meta
+	}
+	meta.HealthThresholds = append([]ThresholdChange(nil), thresholds...)
+	*d.sessions[sessionID] = *meta
+	return writeSessionMeta(d.sessionDir(sessionID), meta)
+}
+
+// AppendSnapshot journals a snapshot of a session being recorded
+func (d *DiskStore) AppendSnapshot(snap *Snapshot) error {
+	payload, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w := d.active[snap.SessionID]
+	if w == nil {
+		return fmt.Errorf("session %d is not being recorded", snap.SessionID)
+	}
+	if err := w.appendSnapshot(snap, payload); err != nil {
+		return err
+	}
+	w.dirty = true
+	*d.sessions[w.meta.ID] = w.meta
+	return nil
+}
+
+// AppendProbes journals probe results of a session being recorded
+func (d *DiskStore) AppendProbes(sessionID int64, results []ProbeResult) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w := d.active[sessionID]
+	if w == nil {
+		return fmt.Errorf("session %d is not being recorded", sessionID)
+	}
+	for i := range results {
+		payload, err := json.Marshal(&results[i])
+		if err != nil {
+			return err
+		}
+		if err := w.append(recordProbe, payload); err != nil {
+			return err
+		}
+		w.meta.ProbeCount++
+	}
+	w.dirty = true
+	*d.sessions[w.meta.ID] = w.meta
+	return nil
+}
+
+// AppendRollups stores closed rollup buckets of a session
+func (d *DiskStore) AppendRollups(sessionID int64, rollups []ConnectionRollup) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.sessions[sessionID]; !ok {
+		return fmt.Errorf("session %d not found", sessionID)
+	}
+	return writeRollups(d.sessionDir(sessionID), rollups)
+}
+
+// EndSession finalizes a recorded session's metadata and applies retention
+func (d *DiskStore) EndSession(session RecordingSession) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w := d.active[session.ID]
+	if w == nil {
+		return fmt.Errorf("session %d is not being recorded", session.ID)
+	}
+	w.meta.EndTime = session.EndTime
+	return d.closeWriterLocked(w)
+}
+
+// closeWriterLocked syncs and closes a session being recorded.
+// Must be called with d.mu held.
+func (d *DiskStore) closeWriterLocked(w *sessionWriter) error {
+	delete(d.active, w.meta.ID)
+
+	var firstErr error
+	if w.segment != nil {
+		if err := w.segment.Sync(); err != nil {
+			firstErr = err
+		}
+		if err := w.segment.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if w.meta.EndTime.IsZero() {
+		w.meta.EndTime = time.Now()
+	}
+	if err := writeSessionMeta(w.dir, &w.meta); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	*d.sessions[w.meta.ID] = w.meta
+	return firstErr
+}
+
+// LoadSession reads all snapshots and probe results of a session
+func (d *DiskStore) LoadSession(sessionID int64) (*snapshotLog, []ProbeResult, error) {
+	loaded, err := d.load(sessionID, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return loaded.snapshots, loaded.probes, nil
+}
+
+// LoadSessionRange reads the snapshots of a session between start and end
+// inclusive, opening only the segments that hold them; a zero time leaves
+// that end open. The log may also hold snapshots outside the range, and
+// holds the nearest ones when none lie inside it.
+func (d *DiskStore) LoadSessionRange(sessionID int64, start, end time.Time) (*snapshotLog, error) {
+	loaded, err := d.load(sessionID, func(segments []segmentInfo) []segmentInfo {
+		return segmentsInRange(segments, start, end)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loaded.snapshots, nil
+}
+
+// LoadSnapshotSegment reads the segment of a session holding a snapshot
+func (d *DiskStore) LoadSnapshotSegment(sessionID, snapshotID int64) (*snapshotLog, error) {
+	loaded, err := d.load(sessionID, func(segments []segmentInfo) []segmentInfo {
+		return segmentHolding(segments, snapshotID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loaded.snapshots, nil
+}
+
+// load reads the segments of a session that pick chooses from its index, or
+// the whole session with its probe results when pick is nil. Sessions
+// recorded before the index are read whole and indexed on the way.
+//
+// Files are read outside the lock, so appends are not held up: sealed
+// segments no longer change, and of the segment being recorded only the
+// records already written are read. Reads of sealed segments are cached.
+func (d *DiskStore) load(sessionID int64, pick func([]segmentInfo) []segmentInfo) (*loadedSession, error) {
+	d.mu.Lock()
+	meta, ok := d.sessions[sessionID]
+	if !ok {
+		d.mu.Unlock()
+		return nil, fmt.Errorf("session %d not found", sessionID)
+	}
+	if !meta.Indexed {
+		pick = nil
+	}
+
+	loaded := &loadedSession{id: sessionID, whole: pick == nil, snapshots: newSnapshotLog()}
+	var indexes []int
+	if pick != nil {
+		for _, seg := range pick(meta.Segments) {
+			indexes = append(indexes, seg.Index)
+		}
+		if len(indexes) == 0 {
+			d.mu.Unlock()
+			return loaded, nil
+		}
+		loaded.first, loaded.last = indexes[0], indexes[len(indexes)-1]
+	}
+	if cached := d.cachedLocked(loaded); cached != nil {
+		d.mu.Unlock()
+		return cached, nil
+	}
+
+	live, liveSize := -1, int64(0)
+	if w := d.active[sessionID]; w != nil {
+		live, liveSize = w.segIndex, w.segSize
+	}
+	indexed, count, firstID := meta.Indexed, meta.SnapshotCount, meta.FirstSnapshotID
+	d.mu.Unlock()
+
+	dir := d.sessionDir(sessionID)
+	var names []string
+	if pick != nil {
+		for _, index := range indexes {
+			names = append(names, segmentFileName(index))
+		}
+	} else {
+		segments, err := listSegments(dir)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(filepath.Join(dir, keptProbesFile)); err == nil {
+			names = append(names, keptProbesFile)
+		}
+		names = append(names, segments...)
+	}
+
+	var index storedSession
+	sealed := true
+	for _, name := range names {
+		segment := segmentIndexOf(name)
+		if live >= 0 && segment > live {
+			break // Started after the lock was released
+		}
+		fn := func(recordType byte, payload []byte) error {
+			switch recordType {
+			case recordSnapshot:
+				var snap Snapshot
+				if err := json.Unmarshal(payload, &snap); err != nil {
+					return err
+				}
+				loaded.snapshots.Append(&snap)
+				index.indexSnapshot(segment, &snap)
+			case recordProbe:
+				if !loaded.whole {
+					return nil
+				}
+				var probe ProbeResult
+				if err := json.Unmarshal(payload, &probe); err != nil {
+					return err
+				}
+				loaded.probes = append(loaded.probes, probe)
+			}
+			return nil
+		}
+
+		path := filepath.Join(dir, name)
+		var err error
+		if live >= 0 && segment == live {
+			sealed = false
+			err = readLiveSegment(path, liveSize, fn)
+		} else {
+			err = readSegment(path, fn, d.logger)
+		}
+		if os.IsNotExist(err) {
+			continue // Dropped by downsampling while being read
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	meta, ok = d.sessions[sessionID]
+	if !ok {
+		return loaded, nil
+	}
+	if !indexed && !meta.Indexed && meta.SnapshotCount == count && meta.FirstSnapshotID == firstID {
+		meta.Segments, meta.Indexed = index.Segments, true
+		if err := writeSessionMeta(dir, meta); err != nil {
+			d.logger.Warn("Failed to save the segment index of session %d: %v", sessionID, err)
+		}
+	}
+	if sealed {
+		d.cacheLocked(loaded)
+	}
+	return loaded, nil
+}
+
+// cachedLocked returns the cached read matching key's session and segments,
+// marking it most recently used. Must be called with d.mu held.
+func (d *DiskStore) cachedLocked(key *loadedSession) *loadedSession {
+	for i, c := range d.cached {
+		if c.id == key.id && c.whole == key.whole && c.first == key.first && c.last == key.last {
+			copy(d.cached[1:i+1], d.cached[:i])
+			d.cached[0] = c
+			return c
+		}
+	}
+	return nil
+}
+
+// cacheLocked keeps a read, forgetting the least recently used beyond
+// loadCacheEntries. Must be called with d.mu held.
+func (d *DiskStore) cacheLocked(loaded *loadedSession) {
+	d.cached = append([]*loadedSession{loaded}, d.cached...)
+	if len(d.cached) > loadCacheEntries {
+		d.cached = d.cached[:loadCacheEntries]
+	}
+}
+
+// uncacheLocked forgets the cached reads of a session that changed.
+// Must be called with d.mu held.
+func (d *DiskStore) uncacheLocked(id int64) {
+	kept := d.cached[:0]
+	for _, c := range d.cached {
+		if c.id != id {
+			kept = append(kept, c)
+		}
+	}
+	clear(d.cached[len(kept):])
+	d.cached = kept
+}
+
+// FindSnapshotSessions returns the sessions whose snapshot ID range holds a
+// snapshot ID. Sessions recorded at the same time share a range, so more
+// than one may be returned.
+func (d *DiskStore) FindSnapshotSessions(snapshotID int64) []int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var ids []int64
+	for id, meta := range d.sessions {
+		if meta.FirstSnapshotID != 0 && snapshotID >= meta.FirstSnapshotID && snapshotID <= meta.LastSnapshotID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// LoadRollups reads the rollups of one tier of a session that overlap
+// [start, end]; a zero time leaves that end of the range open. It reports
+// false when the tier is not stored, either because the session predates
+// rollups or because retention removed the tier.
+func (d *DiskStore) LoadRollups(sessionID int64, resolution time.Duration, start, end time.Time) ([]ConnectionRollup, bool, error) {
+	d.mu.Lock()
+	meta, ok := d.sessions[sessionID]
+	if !ok {
+		d.mu.Unlock()
+		return nil, false, fmt.Errorf("session %d not found", sessionID)
+	}
+	path := filepath.Join(d.sessionDir(sessionID), rollupFileName(resolution))
+	if _, err := os.Stat(path); err != nil || !meta.Rollups {
+		d.mu.Unlock()
+		return nil, false, nil
+	}
+	// The active session's files are still being written
+	if d.active[sessionID] != nil {
+		defer d.mu.Unlock()
+	} else {
+		d.mu.Unlock()
+	}
+
+	var rollups []ConnectionRollup
+	err := readSegment(path, func(recordType byte, payload []byte) error {
+		if recordType != recordRollup {
+			return nil
+		}
+		var r ConnectionRollup
+		if err := json.Unmarshal(payload, &r); err != nil {
+			return err
+		}
+		bucketEnd := r.Start.Add(time.Duration(r.Resolution) * time.Second)
+		if (start.IsZero() || bucketEnd.After(start)) && (end.IsZero() || !r.Start.After(end)) {
+			rollups = append(rollups, r)
+		}
+		return nil
+	}, d.logger)
+	if err != nil {
+		return nil, false, err
+	}
+	return rollups, true, nil
+}
+
+// LoadAnnotations returns the annotations of a session
+func (d *DiskStore) LoadAnnotations(sessionID int64) ([]Annotation, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.sessions[sessionID]; !ok {
+		return nil, fmt.Errorf("session %d not found", sessionID)
+	}
+	data, err := os.ReadFile(filepath.Join(d.sessionDir(sessionID), annotationsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var annotations []Annotation
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return nil, fmt.Errorf("corrupt annotations of session %d: %w", sessionID, err)
+	}
+	return annotations, nil
+}
+
+// SaveAnnotations replaces the annotations of a session
+func (d *DiskStore) SaveAnnotations(sessionID int64, annotations []Annotation) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.sessions[sessionID]; !ok {
+		return fmt.Errorf("session %d not found", sessionID)
+	}
+	dir := d.sessionDir(sessionID)
+	if len(annotations) == 0 {
+		err := os.Remove(filepath.Join(dir, annotationsFile))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	data, err := json.MarshalIndent(annotations, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, annotationsFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write annotations: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(dir, annotationsFile))
+}
+
+// DeleteSession removes a session from disk. The active session cannot be deleted.
+func (d *DiskStore) DeleteSession(sessionID int64) error {
+	d.maintenance.Lock()
+	defer d.maintenance.Unlock()
+
+	d.mu.Lock()
+	dir, err := d.forgetSessionLocked(sessionID)
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// forgetSessionLocked unlists a session and returns its directory for the
+// caller to remove once the lock is released. Must be called with d.mu held.
+func (d *DiskStore) forgetSessionLocked(sessionID int64) (string, error) {
+	if d.active[sessionID] != nil {
+		return "", fmt.Errorf("session %d is being recorded", sessionID)
+	}
+	d.uncacheLocked(sessionID)
+	delete(d.sessions, sessionID)
+	return d.sessionDir(sessionID), nil
+}
+
+// DeleteAll removes every stored session except those being recorded
+func (d *DiskStore) DeleteAll() error {
+	d.maintenance.Lock()
+	defer d.maintenance.Unlock()
+
+	d.mu.Lock()
+	var dirs []string
+	for id := range d.sessions {
+		if d.active[id] != nil {
+			continue
+		}
+		if dir, err := d.forgetSessionLocked(id); err == nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	d.mu.Unlock()
+
+	var firstErr error
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// SetRetention updates the retention limits and applies them immediately
+func (d *DiskStore) SetRetention(retentionDays, maxSizeMB, fullResolutionHours int) {
+	d.mu.Lock()
+	d.config.RetentionDays = retentionDays
+	d.config.MaxSizeMB = maxSizeMB
+	d.config.FullResolutionHours = fullResolutionHours
+	d.mu.Unlock()
+	d.applyRetention()
+}
+
+// SizeBytes returns the total size of all stored sessions
+func (d *DiskStore) SizeBytes() int64 {
+	var total int64
+	for _, dir := range d.sessionDirs() {
+		total += dirSize(dir)
+	}
+	return total
+}
+
+// sessionDirs returns the directory of every stored session by ID
+func (d *DiskStore) sessionDirs() map[int64]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dirs := make(map[int64]string, len(d.sessions))
+	for id := range d.sessions {
+		dirs[id] = d.sessionDir(id)
+	}
+	return dirs
+}
+
+// Close finalizes the sessions being recorded
+func (d *DiskStore) Close() error {
+	d.cancel()
+	d.wg.Wait()
+
+	// Let a running retention pass finish
+	d.maintenance.Lock()
+	defer d.maintenance.Unlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var firstErr error
+	for _, w := range d.active {
+		if err := d.closeWriterLocked(w); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// applyRetention deletes the oldest closed sessions until the store is
+// within its age and size limits, then downsamples. Sessions are chosen
+// under the lock but their files are measured, read and removed outside
+// it, so appends to the sessions being recorded are never held up.
+func (d *DiskStore) applyRetention() {
+	d.maintenance.Lock()
+	defer d.maintenance.Unlock()
+	if d.ctx != nil && d.ctx.Err() != nil {
+		return // Closed
+	}
+
+	d.mu.Lock()
+	config := d.config
+	ids := make([]int64, 0, len(d.sessions))
+	for id := range d.sessions {
+		if d.active[id] == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// Sessions may set their own retention in their scope
+	now := time.Now()
+	expired := make(map[int64]string)
+	kept := ids[:0]
+	for _, id := range ids {
+		meta := d.sessions[id]
+		days := meta.retentionDays(config.RetentionDays)
+		if days > 0 && meta.EndTime.Before(now.AddDate(0, 0, -days)) {
+			if dir, err := d.forgetSessionLocked(id); err == nil {
+				expired[id] = dir
+				d.logger.Info("Deleting session %d (older than %d days)", id, days)
+			}
+			continue
+		}
+		kept = append(kept, id)
+	}
+	ids = kept
+	d.mu.Unlock()
+	d.removeSessionDirs(expired)
+
+	if config.MaxSizeMB > 0 {
+		limit := int64(config.MaxSizeMB) << 20
+		sizes := make(map[int64]int64)
+		var total int64
+		for id, dir := range d.sessionDirs() {
+			sizes[id] = dirSize(dir)
+			total += sizes[id]
+		}
+
+		over := make(map[int64]string)
+		d.mu.Lock()
+		for _, id := range ids {
+			if total <= limit {
+				break
+			}
+			if _, ok := d.sessions[id]; !ok {
+				continue
+			}
+			dir, err := d.forgetSessionLocked(id)
+			if err != nil {
+				continue
+			}
+			over[id] = dir
+			total -= sizes[id]
+			d.logger.Info("Deleting session %d (storage over %d MB)", id, config.MaxSizeMB)
+		}
+		d.mu.Unlock()
+		d.removeSessionDirs(over)
+	}
+
+	d.downsample(config)
+}
+
+// removeSessionDirs removes the directories of unlisted sessions
+func (d *DiskStore) removeSessionDirs(dirs map[int64]string) {
+	for id, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			d.logger.Warn("Failed to delete session %d: %v", id, err)
+		}
+	}
+}
+
+// downsample drops snapshots older than the full-resolution window, whole
+// segments at a time, and rollup tiers older than they are kept.
+// Must be called with d.maintenance held.
+func (d *DiskStore) downsample(config StorageConfig) {
+	type plan struct {
+		id     int64
+		cutoff time.Time // Zero when the session keeps full resolution
+		live   int       // Segment being written, -1 for closed sessions
+		end    time.Time
+	}
+
+	now := time.Now()
+	d.mu.Lock()
+	plans := make([]plan, 0, len(d.sessions))
+	for id, meta := range d.sessions {
+		p := plan{id: id, live: -1, end: meta.EndTime}
+		if hours := meta.fullResolutionHours(config.FullResolutionHours); hours > 0 {
+			p.cutoff = now.Add(-time.Duration(hours) * time.Hour)
+		}
+		if w := d.active[id]; w != nil {
+			p.live = w.segIndex
+		}
+		plans = append(plans, p)
+	}
+	d.mu.Unlock()
+
+	for _, p := range plans {
+		if !p.cutoff.IsZero() {
+			if err := d.dropSegments(p.id, p.cutoff, p.live); err != nil {
+				d.logger.Warn("Failed to downsample session %d: %v", p.id, err)
+			}
+		}
+
+		if p.live >= 0 {
+			continue
+		}
+		for _, tier := range rollupTiers {
+			if tier.keep == 0 || !p.end.Before(now.Add(-tier.keep)) {
+				continue
+			}
+			err := os.Remove(filepath.Join(d.sessionDir(p.id), rollupFileName(tier.resolution)))
+			if err != nil && !os.IsNotExist(err) {
+				d.logger.Warn("Failed to delete %s rollups of session %d: %v", tier.resolution, p.id, err)
+			}
+		}
+	}
+}
+
+// dropSegments deletes the segments of a session last written before
+// cutoff, stopping at the segment being written (live, -1 for none). A
+// segment is never written after its snapshots were taken, so its
+// modification time bounds their age; imported sessions are therefore kept
+// at full resolution for the window after their import. Probe results in
+// the dropped segments are kept. Segments are read and removed outside the
+// lock; the metadata is updated under it.
+// Must be called with d.maintenance held.
+func (d *DiskStore) dropSegments(id int64, cutoff time.Time, live int) error {
+	dir := d.sessionDir(id)
+	segments, err := listSegments(dir)
+	if err != nil {
+		return err
+	}
+
+	var dropped []string
+	for _, name := range segments {
+		if live >= 0 && segmentIndexOf(name) >= live {
+			break // Being written
+		}
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil || !info.ModTime().Before(cutoff) {
+			break
+		}
+		dropped = append(dropped, name)
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	meta, ok := d.sessions[id]
+	hasRollups := ok && meta.Rollups
+	d.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if !hasRollups {
+		// Sessions recorded before rollups existed are never being recorded
+		if err := d.rollupSession(id); err != nil {
+			return err
+		}
+	}
+
+	var probes []byte
+	var count int
+	var lastID int64
+	var lastTime time.Time
+	for _, name := range dropped {
+		err := readSegment(filepath.Join(dir, name), func(recordType byte, payload []byte) error {
+			switch recordType {
+			case recordSnapshot:
+				var snap Snapshot
+				if err := json.Unmarshal(payload, &snap); err != nil {
+					return err
+				}
+				count++
+				lastID = snap.ID
+				lastTime = snap.Timestamp
+			case recordProbe:
+				probes = encodeRecord(probes, recordProbe, payload)
+			}
+			return nil
+		}, d.logger)
+		if err != nil {
+			return err
+		}
+	}
+	if len(probes) > 0 {
+		if err := appendRecordFile(filepath.Join(dir, keptProbesFile), probes); err != nil {
+			return err
+		}
+	}
+	for _, name := range dropped {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.uncacheLocked(id)
+	if meta, ok = d.sessions[id]; !ok {
+		return nil
+	}
+	w := d.active[id]
+	if w != nil {
+		meta = &w.meta
+	}
+	meta.Rollups = true
+
+	// LastSnapshotID stays as a high-water mark so snapshot IDs are not
+	// reused after a restart, even once every snapshot was dropped
+	meta.SnapshotCount -= count
+	if meta.SnapshotCount > 0 {
+		meta.FirstSnapshotID = lastID + 1
+	} else {
+		meta.FirstSnapshotID = 0
+	}
+	if lastTime.After(meta.DownsampledUntil) {
+		meta.DownsampledUntil = lastTime
+	}
+	lastDropped := segmentIndexOf(dropped[len(dropped)-1])
+	kept := meta.Segments[:0]
+	for _, seg := range meta.Segments {
+		if seg.Index > lastDropped {
+			kept = append(kept, seg)
+		}
+	}
+	meta.Segments = kept
+	if w != nil {
+		*d.sessions[id] = *meta
+	}
+	d.logger.Info("Downsampled session %d: dropped %d snapshots up to %s", id, count, lastTime.Format(time.RFC3339))
+	return writeSessionMeta(dir, meta)
+}
+
+// rollupSession writes the rollup tiers of a session recorded before
+// rollups existed. Must be called with d.maintenance held.
+func (d *DiskStore) rollupSession(id int64) error {
+	dir := d.sessionDir(id)
+	for _, tier := range rollupTiers {
+		os.Remove(filepath.Join(dir, rollupFileName(tier.resolution)))
+	}
+
+	b := newTieredRollupBuilder()
+	var rollups []ConnectionRollup
+	err := d.readSession(id, func(_ int, recordType byte, payload []byte) error {
+		if recordType != recordSnapshot {
+			return nil
+		}
+		var snap Snapshot
+		if err := json.Unmarshal(payload, &snap); err != nil {
+			return err
+		}
+		rollups = append(rollups, b.add(&snap)...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writeRollups(dir, append(rollups, b.flush()...))
+}
+
+// recoverSession loads a session's metadata and repairs it after a crash:
+// torn records at the end of a segment are truncated and counts, snapshot
+// ID range and end time are rebuilt from the journal
+func (d *DiskStore) recoverSession(id int64) (*storedSession, error) {
+	dir := d.sessionDir(id)
+	meta := &storedSession{}
+	data, err := os.ReadFile(filepath.Join(dir, sessionMetaFile))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, err
+	}
+	if meta.ID != id {
+		return nil, fmt.Errorf("metadata belongs to session %d", meta.ID)
+	}
+	if !meta.EndTime.IsZero() {
+		return meta, nil
+	}
+
+	// The session was still recording when the app stopped. The saved
+	// LastSnapshotID is kept if retention already dropped the snapshots.
+	meta.SnapshotCount, meta.ProbeCount = 0, 0
+	meta.FirstSnapshotID = 0
+	meta.Segments, meta.Indexed = nil, true
+	lastTimestamp := meta.StartTime
+	err = d.readSession(id, func(segment int, recordType byte, payload []byte) error {
+		switch recordType {
+		case recordSnapshot:
+			var snap Snapshot
+			if err := json.Unmarshal(payload, &snap); err != nil {
+				return err
+			}
+			meta.indexSnapshot(segment, &snap)
+			lastTimestamp = snap.Timestamp
+		case recordProbe:
+			meta.ProbeCount++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	meta.EndTime = lastTimestamp
+	meta.Recovered = true
+	if err := writeSessionMeta(dir, meta); err != nil {
+		return nil, err
+	}
+	d.logger.Info("Recovered session %d after an unclean shutdown (%d snapshots)", id, meta.SnapshotCount)
+	return meta, nil
+}
+
+// readSession decodes every record of a session in order, starting with
+// probe results kept from dropped segments, which have segment index -1.
+// A torn or corrupt record ends its segment; the rest of that segment is
+// truncated.
+func (d *DiskStore) readSession(id int64, fn func(segment int, recordType byte, payload []byte) error) error {
+	dir := d.sessionDir(id)
+	segments, err := listSegments(dir)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, keptProbesFile)); err == nil {
+		segments = append([]string{keptProbesFile}, segments...)
+	}
+	for _, name := range segments {
+		segment := segmentIndexOf(name)
+		err := readSegment(filepath.Join(dir, name), func(recordType byte, payload []byte) error {
+			return fn(segment, recordType, payload)
+		}, d.logger)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errTornRecord marks a record cut short or failing its checksum
+var errTornRecord = errors.New("torn record")
+
+// readSegment decodes the records of one segment file
+func readSegment(path string, fn func(recordType byte, payload []byte) error, logger *Logger) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	offset, err := readRecords(f, fn)
+	if err == errTornRecord {
+		return truncateSegment(f, path, offset, logger)
+	}
+	return err
+}
+
+// readLiveSegment decodes the first size bytes of the segment being
+// recorded. Records past size are still being written and are left alone.
+func readLiveSegment(path string, size int64, fn func(recordType byte, payload []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = readRecords(io.LimitReader(f, size), fn)
+	return err
+}
+
+// readRecords decodes records until the end of r. On errTornRecord, offset
+// is where the torn record starts.
+func readRecords(r io.Reader, fn func(recordType byte, payload []byte) error) (offset int64, err error) {
+	header := make([]byte, recordHeaderSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err != io.EOF {
+				return offset, errTornRecord
+			}
+			return offset, nil
+		}
+		size := binary.LittleEndian.Uint32(header[0:4])
+		sum := binary.LittleEndian.Uint32(header[4:8])
+		if size > maxRecordSize {
+			return offset, errTornRecord
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return offset, errTornRecord
+		}
+		crc := crc32.NewIEEE()
+		crc.Write(header[8:9])
+		crc.Write(payload)
+		if crc.Sum32() != sum {
+			return offset, errTornRecord
+		}
+		if err := fn(header[8], payload); err != nil {
+			return offset, err
+		}
+		offset += recordHeaderSize + int64(size)
+	}
+}
+
+// truncateSegment drops a torn record and everything after it
+func truncateSegment(f *os.File, path string, offset int64, logger *Logger) error {
+	logger.Warn("Truncating torn record at offset %d of %s", offset, path)
+	return f.Truncate(offset)
+}
+
+// newSessionWriter creates a session directory with its metadata and first segment
+func newSessionWriter(dir string, session RecordingSession) (*sessionWriter, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	w := &sessionWriter{
+		meta: storedSession{RecordingSession: session, Rollups: true, Indexed: true},
+		dir:  dir,
+	}
+	if err := writeSessionMeta(dir, &w.meta); err != nil {
+		return nil, err
+	}
+	if err := w.openSegment(0); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// openSegment creates the segment file with the given index
+func (w *sessionWriter) openSegment(index int) error {
+	path := filepath.Join(w.dir, segmentFileName(index))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open segment: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.segment = f
+	w.segIndex = index
+	w.segSize = info.Size()
+	return nil
+}
+
+// append writes one record, rotating segments as needed. Callers sync the
+// segment once their records are written.
+func (w *sessionWriter) append(recordType byte, payload []byte) error {
+	if w.segSize >= segmentMaxBytes {
+		if err := w.segment.Sync(); err != nil {
+			return err
+		}
+		if err := w.segment.Close(); err != nil {
+			return err
+		}
+		if err := w.openSegment(w.segIndex + 1); err != nil {
+			return err
+		}
+	}
+
+	record := encodeRecord(nil, recordType, payload)
+	if _, err := w.segment.Write(record); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	w.segSize += int64(len(record))
+	return nil
+}
+
+// appendSnapshot writes a snapshot record and adds it to the segment index
+func (w *sessionWriter) appendSnapshot(snap *Snapshot, payload []byte) error {
+	if err := w.append(recordSnapshot, payload); err != nil {
+		return err
+	}
+	w.meta.indexSnapshot(w.segIndex, snap)
+	return nil
+}
+
+// encodeRecord appends a framed record to dst
+func encodeRecord(dst []byte, recordType byte, payload []byte) []byte {
+	start := len(dst)
+	dst = append(dst, make([]byte, recordHeaderSize)...)
+	binary.LittleEndian.PutUint32(dst[start:start+4], uint32(len(payload)))
+	dst[start+8] = recordType
+	dst = append(dst, payload...)
+	binary.LittleEndian.PutUint32(dst[start+4:start+8], crc32.ChecksumIEEE(dst[start+8:]))
+	return dst
+}
+
+// appendRecordFile appends framed records to a file outside the segment
+// sequence and syncs it
+func appendRecordFile(path string, records []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(records); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rollupFileName returns the file holding one rollup tier of a session
+func rollupFileName(resolution time.Duration) string {
+	return fmt.Sprintf("rollup-%ds.log", int64(resolution/time.Second))
+}
+
+// writeRollups appends rollups to the tier files of a session directory
+func writeRollups(dir string, rollups []ConnectionRollup) error {
+	records := make(map[int64][]byte)
+	for i := range rollups {
+		payload, err := json.Marshal(&rollups[i])
+		if err != nil {
+			return err
+		}
+		records[rollups[i].Resolution] = encodeRecord(records[rollups[i].Resolution], recordRollup, payload)
+	}
+	for resolution, data := range records {
+		path := filepath.Join(dir, rollupFileName(time.Duration(resolution)*time.Second))
+		if err := appendRecordFile(path, data); err != nil {
+			return fmt.Errorf("failed to write rollups: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeSessionMeta atomically replaces a session's metadata file
+func writeSessionMeta(dir string, meta *storedSession) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, sessionMetaFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write session metadata: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(dir, sessionMetaFile))
+}
+
+// sessionDir returns the directory of a session
+func (d *DiskStore) sessionDir(id int64) string {
+	return filepath.Join(d.dir, sessionDirPrefix+strconv.FormatInt(id, 10))
+}
+
+// parseSessionDir extracts the session ID from a directory name
+func parseSessionDir(name string) (int64, bool) {
+	if !strings.HasPrefix(name, sessionDirPrefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(name, sessionDirPrefix), 10, 64)
+	return id, err == nil && id > 0
+}
+
+// segmentFileName returns the name of the segment file with the given index
+func segmentFileName(index int) string {
+	return fmt.Sprintf("%s%06d%s", segmentFilePrefix, index, segmentFileSuffix)
+}
+
+// segmentIndexOf returns the index of a segment file, or -1 for other files
+func segmentIndexOf(name string) int {
+	if !strings.HasPrefix(name, segmentFilePrefix) || !strings.HasSuffix(name, segmentFileSuffix) {
+		return -1
+	}
+	index, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, segmentFilePrefix), segmentFileSuffix))
+	if err != nil {
+		return -1
+	}
+	return index
+}
+
+// listSegments returns a session's segment files in order
+func listSegments(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, segmentFilePrefix) && strings.HasSuffix(name, segmentFileSuffix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names) // Zero-padded indexes sort numerically
+	return names, nil
+}
+
+// dirSize returns the total size of the files in a directory
+func dirSize(dir string) int64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && !info.IsDir() {
+			total += info.Size()
+		}
+	}
+	return total
+}
@@ -0,0 +1,243 @@
+package tcpmonitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// storedRecording returns n snapshots of a session starting at start
+func storedRecording(sessionID int64, firstID int64, start time.Time, n, connections int) []Snapshot {
+	snapshots := benchRecording(n, connections)
+	for i := range snapshots {
+		snapshots[i].ID = firstID + int64(i)
+		snapshots[i].SessionID = sessionID
+		snapshots[i].Timestamp = start.Add(time.Duration(i) * time.Second)
+	}
+	return snapshots
+}
+
+// openTestStore opens a disk store in dir that keeps everything by default
+func openTestStore(t *testing.T, dir string, config StorageConfig) *DiskStore {
+	t.Helper()
+	config.Enabled = true
+	config.Dir = dir
+	d, err := OpenDiskStore(config)
+	if err != nil {
+		t.Fatalf("OpenDiskStore: %v", err)
+	}
+	return d
+}
+
+// storedMeta returns the metadata of a stored session
+func storedMeta(d *DiskStore, id int64) (storedSession, bool) {
+	for _, meta := range d.Sessions() {
+		if meta.ID == id {
+			return meta, true
+		}
+	}
+	return storedSession{}, false
+}
+
+func TestDiskStoreRecoversDamagedTail(t *testing.T) {
+	damage := []struct {
+		name string
+		fn   func(data []byte) []byte
+	}{
+		{"truncated", func(data []byte) []byte { return data[:len(data)-5] }},
+		{"corrupt", func(data []byte) []byte {
+			data[len(data)-3] ^= 0xff
+			return data
+		}},
+		{"garbage", func(data []byte) []byte { return append(data, 0x01, 0x02, 0x03) }},
+	}
+
+	for _, tc := range damage {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			d := openTestStore(t, dir, StorageConfig{})
+			start := time.Now().UTC().Add(-time.Minute)
+			want := storedRecording(1, 1, start, 20, 5)
+
+			if err := d.BeginSession(RecordingSession{ID: 1, StartTime: start}); err != nil {
+				t.Fatalf("BeginSession: %v", err)
+			}
+			for i := range want {
+				if err := d.AppendSnapshot(&want[i]); err != nil {
+					t.Fatalf("AppendSnapshot: %v", err)
+				}
+			}
+
+			// The app stops while recording, without finalizing the session
+			d.cancel()
+			d.wg.Wait()
+			d.active[1].segment.Close()
+
+			path := filepath.Join(d.sessionDir(1), segmentFileName(0))
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, tc.fn(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			d = openTestStore(t, dir, StorageConfig{})
+			defer d.Close()
+
+			// Appended garbage leaves every snapshot; a damaged last record loses it
+			if tc.name != "garbage" {
+				want = want[:len(want)-1]
+			}
+			meta, ok := storedMeta(d, 1)
+			if !ok {
+				t.Fatalf("session not reopened")
+			}
+			if !meta.Recovered || meta.SnapshotCount != len(want) || meta.LastSnapshotID != want[len(want)-1].ID {
+				t.Errorf("recovered meta = %+v, want %d snapshots up to %d", meta, len(want), want[len(want)-1].ID)
+			}
+			if !meta.EndTime.Equal(want[len(want)-1].Timestamp) {
+				t.Errorf("end time = %v, want %v", meta.EndTime, want[len(want)-1].Timestamp)
+			}
+
+			log, _, err := d.LoadSession(1)
+			if err != nil {
+				t.Fatalf("LoadSession: %v", err)
+			}
+			checkSnapshots(t, log, want)
+
+			// The torn tail was cut off, so the segment reads cleanly again
+			if _, err := readRecords(mustOpen(t, path), func(byte, []byte) error { return nil }); err != nil {
+				t.Errorf("segment still damaged after reopen: %v", err)
+			}
+		})
+	}
+}
+
+// mustOpen opens a file that is closed when the test ends
+func mustOpen(t *testing.T, path string) *os.File {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestDiskStoreAgeRetention(t *testing.T) {
+	d := openTestStore(t, t.TempDir(), StorageConfig{})
+	defer d.Close()
+
+	now := time.Now()
+	ends := map[int64]time.Time{
+		1: now.AddDate(0, 0, -40),
+		2: now.AddDate(0, 0, -10),
+		3: now.Add(-time.Hour),
+	}
+	for id, end := range ends {
+		start := end.Add(-time.Minute)
+		session := RecordingSession{ID: id, StartTime: start, EndTime: end}
+		if err := d.WriteSession(session, storedRecording(id, id*100, start, 5, 3), nil, nil); err != nil {
+			t.Fatalf("WriteSession %d: %v", id, err)
+		}
+	}
+
+	d.SetRetention(30, 0, 0)
+	if _, ok := storedMeta(d, 1); ok {
+		t.Errorf("session older than 30 days kept")
+	}
+	if _, err := os.Stat(d.sessionDir(1)); !os.IsNotExist(err) {
+		t.Errorf("expired session directory left behind: %v", err)
+	}
+
+	d.SetRetention(7, 0, 0)
+	if _, ok := storedMeta(d, 2); ok {
+		t.Errorf("session older than 7 days kept")
+	}
+	if _, ok := storedMeta(d, 3); !ok {
+		t.Errorf("recent session deleted")
+	}
+}
+
+func TestDiskStoreSizeRetention(t *testing.T) {
+	d := openTestStore(t, t.TempDir(), StorageConfig{})
+	defer d.Close()
+
+	start := time.Now().Add(-time.Hour)
+	const sessions = 6
+	for id := int64(1); id <= sessions; id++ {
+		session := RecordingSession{ID: id, StartTime: start, EndTime: start.Add(time.Minute)}
+		if err := d.WriteSession(session, storedRecording(id, id*100, start, 10, 40), nil, nil); err != nil {
+			t.Fatalf("WriteSession %d: %v", id, err)
+		}
+	}
+	if d.SizeBytes() <= 1<<20 {
+		t.Fatalf("test sessions total %d bytes, need more than 1 MB", d.SizeBytes())
+	}
+
+	d.SetRetention(0, 1, 0)
+	if size := d.SizeBytes(); size > 1<<20 {
+		t.Errorf("storage is %d bytes after retention, want at most 1 MB", size)
+	}
+
+	// Oldest sessions go first, so the survivors are the newest ones
+	remaining := d.Sessions()
+	if len(remaining) == 0 || len(remaining) == sessions {
+		t.Fatalf("%d sessions left, want some but not all", len(remaining))
+	}
+	for i, meta := range remaining {
+		if want := int64(sessions - len(remaining) + 1 + i); meta.ID != want {
+			t.Errorf("remaining session %d = %d, want %d", i, meta.ID, want)
+		}
+	}
+}
+
+func TestDiskStoreDropSegmentsKeepsHighWaterMark(t *testing.T) {
+	dir := t.TempDir()
+	d := openTestStore(t, dir, StorageConfig{})
+
+	end := time.Now().Add(-48 * time.Hour)
+	start := end.Add(-time.Minute)
+	snapshots := storedRecording(1, 1, start, 10, 3)
+	probes := []ProbeResult{{SessionID: 1, Timestamp: start, Target: "192.0.2.1:443", ConnectMs: 12, Outcome: "ok"}}
+	session := RecordingSession{ID: 1, StartTime: start, EndTime: end}
+	if err := d.ReplaceSession(session, snapshots, probes, nil); err != nil {
+		t.Fatalf("ReplaceSession: %v", err)
+	}
+
+	// Keep full resolution for a day: the two-day-old segment is dropped
+	d.SetRetention(0, 0, 24)
+	meta, ok := storedMeta(d, 1)
+	if !ok {
+		t.Fatalf("downsampled session deleted")
+	}
+	if meta.SnapshotCount != 0 || meta.FirstSnapshotID != 0 || len(meta.Segments) != 0 {
+		t.Errorf("meta after dropping every segment = %+v", meta)
+	}
+	if meta.LastSnapshotID != snapshots[len(snapshots)-1].ID {
+		t.Errorf("LastSnapshotID = %d, want high-water mark %d", meta.LastSnapshotID, snapshots[len(snapshots)-1].ID)
+	}
+	if !meta.DownsampledUntil.Equal(snapshots[len(snapshots)-1].Timestamp) {
+		t.Errorf("DownsampledUntil = %v, want %v", meta.DownsampledUntil, snapshots[len(snapshots)-1].Timestamp)
+	}
+
+	log, kept, err := d.LoadSession(1)
+	if err != nil {
+		t.Fatalf("LoadSession: %v", err)
+	}
+	if log.Len() != 0 || len(kept) != len(probes) {
+		t.Errorf("after downsampling: %d snapshots and %d probes, want 0 and %d", log.Len(), len(kept), len(probes))
+	}
+
+	// The high-water mark survives a restart
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	d = openTestStore(t, dir, StorageConfig{})
+	defer d.Close()
+	if meta, _ := storedMeta(d, 1); meta.LastSnapshotID != snapshots[len(snapshots)-1].ID {
+		t.Errorf("LastSnapshotID after reopen = %d, want %d", meta.LastSnapshotID, snapshots[len(snapshots)-1].ID)
+	}
+}
@@ -1,5 +1,5 @@
-//go:build !windows
-// +build !windows
+//go:build !windows && !linux
+// +build !windows,!linux
 
 package tcpmonitor
 
@@ -8,7 +8,7 @@ import (
 	"time"
 )
 
-// Stub types for non-Windows platforms (for development/testing purposes)
+// Stub types for unsupported platforms (for development/testing purposes)
 
 type StatsCollector struct{}
 
@@ -16,6 +16,11 @@ func (sc *StatsCollector) GetExtendedStats(conn *ConnectionInfo) (*ExtendedStats
 	return nil, nil
 }
 
+func (sc *StatsCollector) PrepareConnection(conn *ConnectionInfo) {}
+func (sc *StatsCollector) EnableExtendedStats(conn *ConnectionInfo) error {
+	return fmt.Errorf("not supported")
+}
+
 func (sc *StatsCollector) Close() {}
 
 func NewService(config ServiceConfig) (*Service, error) {
@@ -0,0 +1,672 @@
+package tcpmonitor
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Throughput test directions, from the client's point of view
+const (
+	DirectionUpload   = "upload"   // Client pushes data to the server
+	DirectionDownload = "download" // Client pulls data from the server
+)
+
+// Wire header sent by the client at the start of every test stream:
+// magic(4) version(1) direction(1) durationMs(4) bufferSize(4)
+var throughputMagic = [4]byte{'T', 'C', 'D', 'T'}
+
+const (
+	throughputVersion    = 1
+	throughputHeaderSize = 14
+
+	// Limits accepted by both client and server
+	maxThroughputDuration = 300 * time.Second
+	maxThroughputStreams  = 64
+	maxThroughputBuffer   = 16 << 20
+
+	// Streams the server serves at once: one test at the stream limit.
+	// Further connections are turned away.
+	maxServerStreams = maxThroughputStreams
+
+	// Recent reports kept by client and server
+	maxThroughputReports = 20
+)
+
+// ThroughputTestConfig describes a client-side throughput test
+type ThroughputTestConfig struct {
+	Target           string // "host:port" of a tcpdoctor throughput server
+	Direction        string // DirectionUpload or DirectionDownload
+	DurationSeconds  int
+	Streams          int // Parallel TCP connections
+	BufferSize       int // Application read/write buffer in bytes
+	SocketBufferSize int // SO_SNDBUF/SO_RCVBUF in bytes, 0 keeps OS autotuning
+	SampleIntervalMs int // How often ExtendedStats are sampled per stream
+}
+
+// DefaultThroughputTestConfig returns sensible defaults for a 10 second upload test
+func DefaultThroughputTestConfig() ThroughputTestConfig {
+	return ThroughputTestConfig{
+		Direction:        DirectionUpload,
+		DurationSeconds:  10,
+		Streams:          1,
+		BufferSize:       128 * 1024,
+		SampleIntervalMs: 100,
+	}
+}
+
+// ThroughputSample is one high-frequency sample of a test stream
+type ThroughputSample struct {
+	Timestamp     time.Time `json:"timestamp"`
+	ElapsedMs     int64     `json:"elapsedMs"`
+	Stream        int       `json:"stream"`
+	Bytes         int64     `json:"bytes"`         // Cumulative payload bytes on this stream
+	ThroughputBps float64   `json:"throughputBps"` // Over the last sample interval
+
+	// From ExtendedStats, zero if unavailable
+	RTT          int64 `json:"rtt"`
+	RTTVariance  int64 `json:"rttVariance"`
+	Cwnd         int64 `json:"cwnd"`
+	Ssthresh     int64 `json:"ssthresh"`
+	SegsRetrans  int64 `json:"segsRetrans"`
+	BytesRetrans int64 `json:"bytesRetrans"`
+	FastRetrans  int64 `json:"fastRetrans"`
+	Timeouts     int64 `json:"timeouts"`
+	CurRwinRcvd  int64 `json:"curRwinRcvd"`
+	CurRwinSent  int64 `json:"curRwinSent"`
+	CurMss       int64 `json:"curMss"`
+}
+
+// ThroughputStreamResult summarizes one stream of a test
+type ThroughputStreamResult struct {
+	Stream        int     `json:"stream"`
+	LocalAddr     string  `json:"localAddr"`
+	LocalPort     int     `json:"localPort"`
+	RemoteAddr    string  `json:"remoteAddr"`
+	RemotePort    int     `json:"remotePort"`
+	Bytes         int64   `json:"bytes"`
+	DurationMs    int64   `json:"durationMs"`
+	ThroughputBps float64 `json:"throughputBps"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// ThroughputTestReport is the outcome of a test, from either side
+type ThroughputTestReport struct {
+	ID            int64                    `json:"id"`
+	Role          string                   `json:"role"` // "client" or "server"
+	Config        ThroughputTestConfig     `json:"config"`
+	StartTime     time.Time                `json:"startTime"`
+	EndTime       time.Time                `json:"endTime"`
+	TotalBytes    int64                    `json:"totalBytes"`
+	ThroughputBps float64                  `json:"throughputBps"` // Aggregate across streams
+	Streams       []ThroughputStreamResult `json:"streams"`
+	Samples       []ThroughputSample       `json:"samples"`
+	Error         string                   `json:"error,omitempty"`
+}
+
+// ThroughputServerStatus describes the local throughput test server
+type ThroughputServerStatus struct {
+	Running       bool                   `json:"running"`
+	ListenAddr    string                 `json:"listenAddr"`
+	ActiveStreams int                    `json:"activeStreams"`
+	Recent        []ThroughputTestReport `json:"recent"` // One report per served stream
+}
+
+// connectionStatsSource provides ExtendedStats for sockets owned by this process
+type connectionStatsSource interface {
+	PrepareConnection(conn *ConnectionInfo)
+	EnableExtendedStats(conn *ConnectionInfo) error
+	GetExtendedStats(conn *ConnectionInfo) (*ExtendedStats, error)
+}
+
+// ThroughputTester runs the iperf-style test server and client
+type ThroughputTester struct {
+	stats connectionStatsSource
+
+	listener      net.Listener
+	activeStreams int32
+	serverConns   map[net.Conn]struct{} // Streams being served, closed on stop
+	serverReports []ThroughputTestReport
+	clientReports []ThroughputTestReport
+	nextReportID  int64
+
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	logger *Logger
+}
+
+// NewThroughputTester creates a tester; stats may be nil when ExtendedStats are unavailable
+func NewThroughputTester(stats connectionStatsSource) *ThroughputTester {
+	return &ThroughputTester{
+		stats:        stats,
+		serverConns:  make(map[net.Conn]struct{}),
+		nextReportID: 1,
+		logger:       GetLogger(),
+	}
+}
+
+// === Server ===
+
+// StartServer listens for test streams on the given address (e.g. ":5201")
+func (t *ThroughputTester) StartServer(listenAddr string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.listener != nil {
+		return fmt.Errorf("throughput server already running on %s", t.listener.Addr())
+	}
+
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to start throughput server: %w", err)
+	}
+	t.listener = listener
+
+	t.wg.Add(1)
+	go t.acceptLoop(listener)
+
+	t.logger.Info("Throughput server listening on %s", listener.Addr())
+	return nil
+}
+
+// StopServer closes the listener, aborts the streams being served and waits
+// for them to finish
+func (t *ThroughputTester) StopServer() {
+	t.mu.Lock()
+	listener := t.listener
+	t.listener = nil
+	if listener != nil {
+		listener.Close()
+		for conn := range t.serverConns {
+			conn.Close()
+		}
+	}
+	t.mu.Unlock()
+
+	if listener == nil {
+		return
+	}
+	t.wg.Wait()
+	t.logger.Info("Throughput server stopped")
+}
+
+// ServerStatus returns the state of the local server
+func (t *ThroughputTester) ServerStatus() ThroughputServerStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	status := ThroughputServerStatus{
+		Running:       t.listener != nil,
+		ActiveStreams: int(atomic.LoadInt32(&t.activeStreams)),
+		Recent:        append([]ThroughputTestReport(nil), t.serverReports...),
+	}
+	if t.listener != nil {
+		status.ListenAddr = t.listener.Addr().String()
+	}
+	return status
+}
+
+// acceptLoop serves incoming streams until the listener is closed
+func (t *ThroughputTester) acceptLoop(listener net.Listener) {
+	defer t.wg.Done()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				t.logger.Error("Throughput server accept failed: %v", err)
+			}
+			return
+		}
+
+		if !t.trackConn(conn) {
+			t.logger.Warn("Rejected throughput stream from %s: %d streams already active", conn.RemoteAddr(), maxServerStreams)
+			conn.Close()
+			continue
+		}
+
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			defer t.untrackConn(conn)
+			atomic.AddInt32(&t.activeStreams, 1)
+			defer atomic.AddInt32(&t.activeStreams, -1)
+			t.serveStream(conn)
+		}()
+	}
+}
+
+// trackConn registers a stream so StopServer can abort it. It reports false
+// when the server is full or stopping.
+func (t *ThroughputTester) trackConn(conn net.Conn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.listener == nil || len(t.serverConns) >= maxServerStreams {
+		return false
+	}
+	t.serverConns[conn] = struct{}{}
+	return true
+}
+
+// untrackConn forgets a stream that finished
+func (t *ThroughputTester) untrackConn(conn net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.serverConns, conn)
+}
+
+// serveStream handles a single test stream from a client
+func (t *ThroughputTester) serveStream(conn net.Conn) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	config, err := readThroughputHeader(conn)
+	if err != nil {
+		t.logger.Warn("Rejected throughput stream from %s: %v", conn.RemoteAddr(), err)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	// The server sends when the client downloads, and vice versa
+	serverConfig := config
+	serverConfig.Target = conn.RemoteAddr().String()
+	serverConfig.Streams = 1
+
+	report := ThroughputTestReport{Role: "server", Config: serverConfig, StartTime: time.Now()}
+	duration := time.Duration(config.DurationSeconds) * time.Second
+
+	stream := newTestStream(0, conn, t.stats)
+	ctx, cancel := context.WithCancel(context.Background())
+	samplesDone := stream.sampleLoop(ctx, time.Duration(config.SampleIntervalMs)*time.Millisecond, report.StartTime)
+
+	if config.Direction == DirectionDownload {
+		err = stream.send(duration, config.BufferSize)
+	} else {
+		// Guard against clients that never stop sending
+		conn.SetReadDeadline(time.Now().Add(duration + 30*time.Second))
+		err = stream.receive(config.BufferSize)
+	}
+
+	cancel()
+	report.Samples = <-samplesDone
+	report.EndTime = time.Now()
+	result := stream.result(report.StartTime, report.EndTime, err)
+	report.Streams = []ThroughputStreamResult{result}
+	report.TotalBytes = result.Bytes
+	report.ThroughputBps = result.ThroughputBps
+	report.Error = result.Error
+
+	t.addReport(&t.serverReports, report)
+}
+
+// === Client ===
+
+// RunTest runs a client test against a remote tcpdoctor server and blocks until done
+func (t *ThroughputTester) RunTest(config ThroughputTestConfig) (*ThroughputTestReport, error) {
+	if err := validateThroughputConfig(&config); err != nil {
+		return nil, err
+	}
+
+	t.logger.Info("Starting throughput test: %s %s for %ds with %d streams",
+		config.Direction, config.Target, config.DurationSeconds, config.Streams)
+
+	report := ThroughputTestReport{Role: "client", Config: config, StartTime: time.Now()}
+	duration := time.Duration(config.DurationSeconds) * time.Second
+	interval := time.Duration(config.SampleIntervalMs) * time.Millisecond
+
+	// Open all streams before starting so they run concurrently
+	streams := make([]*testStream, 0, config.Streams)
+	defer func() {
+		for _, s := range streams {
+			s.conn.Close()
+		}
+	}()
+	for i := 0; i < config.Streams; i++ {
+		conn, err := dialThroughputStream(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open stream %d: %w", i, err)
+		}
+		streams = append(streams, newTestStream(i, conn, t.stats))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	start := time.Now()
+	report.StartTime = start
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(streams))
+	sampleChans := make([]<-chan []ThroughputSample, len(streams))
+
+	for i, s := range streams {
+		sampleChans[i] = s.sampleLoop(ctx, interval, start)
+
+		wg.Add(1)
+		go func(i int, s *testStream) {
+			defer wg.Done()
+			if config.Direction == DirectionUpload {
+				errs[i] = s.upload(duration, config.BufferSize)
+			} else {
+				s.conn.SetReadDeadline(time.Now().Add(duration + 30*time.Second))
+				errs[i] = s.receive(config.BufferSize)
+			}
+		}(i, s)
+	}
+	wg.Wait()
+	cancel()
+
+	report.EndTime = time.Now()
+	for i, s := range streams {
+		report.Samples = append(report.Samples, <-sampleChans[i]...)
+		result := s.result(start, report.EndTime, errs[i])
+		report.Streams = append(report.Streams, result)
+		report.TotalBytes += result.Bytes
+		if result.Error != "" && report.Error == "" {
+			report.Error = result.Error
+		}
+	}
+	if elapsed := report.EndTime.Sub(start).Seconds(); elapsed > 0 {
+		report.ThroughputBps = float64(report.TotalBytes*8) / elapsed
+	}
+
+	t.addReport(&t.clientReports, report)
+	t.logger.Info("Throughput test finished: %d bytes, %.2f Mbit/s", report.TotalBytes, report.ThroughputBps/1e6)
+	return &report, nil
+}
+
+// ClientReports returns recent client-side test reports
+func (t *ThroughputTester) ClientReports() []ThroughputTestReport {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]ThroughputTestReport(nil), t.clientReports...)
+}
+
+// addReport assigns an ID and appends to a bounded report list
+func (t *ThroughputTester) addReport(reports *[]ThroughputTestReport, report ThroughputTestReport) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report.ID = t.nextReportID
+	t.nextReportID++
+	*reports = append(*reports, report)
+	if len(*reports) > maxThroughputReports {
+		*reports = (*reports)[len(*reports)-maxThroughputReports:]
+	}
+}
+
+// validateThroughputConfig fills defaults and checks limits
+func validateThroughputConfig(config *ThroughputTestConfig) error {
+	defaults := DefaultThroughputTestConfig()
+	if config.Target == "" {
+		return fmt.Errorf("throughput test target is required")
+	}
+	if config.Direction == "" {
+		config.Direction = defaults.Direction
+	}
+	if config.Direction != DirectionUpload && config.Direction != DirectionDownload {
+		return fmt.Errorf("invalid direction %q", config.Direction)
+	}
+	if config.DurationSeconds <= 0 {
+		config.DurationSeconds = defaults.DurationSeconds
+	}
+	if config.Streams <= 0 {
+		config.Streams = defaults.Streams
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaults.BufferSize
+	}
+	if config.SampleIntervalMs <= 0 {
+		config.SampleIntervalMs = defaults.SampleIntervalMs
+	}
+
+	if time.Duration(config.DurationSeconds)*time.Second > maxThroughputDuration {
+		return fmt.Errorf("duration exceeds %v", maxThroughputDuration)
+	}
+	if config.Streams > maxThroughputStreams {
+		return fmt.Errorf("at most %d parallel streams are supported", maxThroughputStreams)
+	}
+	if config.BufferSize > maxThroughputBuffer || config.SocketBufferSize > maxThroughputBuffer {
+		return fmt.Errorf("buffer size exceeds %d bytes", maxThroughputBuffer)
+	}
+	return nil
+}
+
+// dialThroughputStream connects to the server and sends the stream header
+func dialThroughputStream(config ThroughputTestConfig) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", config.Target, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	if tcpConn, ok := conn.(*net.TCPConn); ok && config.SocketBufferSize > 0 {
+		tcpConn.SetWriteBuffer(config.SocketBufferSize)
+		tcpConn.SetReadBuffer(config.SocketBufferSize)
+	}
+
+	if err := writeThroughputHeader(conn, config); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// writeThroughputHeader sends the stream parameters to the server
+func writeThroughputHeader(w io.Writer, config ThroughputTestConfig) error {
+	var header [throughputHeaderSize]byte
+	copy(header[0:4], throughputMagic[:])
+	header[4] = throughputVersion
+	if config.Direction == DirectionDownload {
+		header[5] = 'D'
+	} else {
+		header[5] = 'U'
+	}
+	binary.BigEndian.PutUint32(header[6:10], uint32(config.DurationSeconds*1000))
+	binary.BigEndian.PutUint32(header[10:14], uint32(config.BufferSize))
+	_, err := w.Write(header[:])
+	return err
+}
+
+// readThroughputHeader parses and validates a stream header
+func readThroughputHeader(r io.Reader) (ThroughputTestConfig, error) {
+	var header [throughputHeaderSize]byte
+	config := DefaultThroughputTestConfig()
+
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return config, fmt.Errorf("failed to read header: %w", err)
+	}
+	if [4]byte(header[0:4]) != throughputMagic || header[4] != throughputVersion {
+		return config, fmt.Errorf("not a tcpdoctor throughput stream")
+	}
+
+	switch header[5] {
+	case 'U':
+		config.Direction = DirectionUpload
+	case 'D':
+		config.Direction = DirectionDownload
+	default:
+		return config, fmt.Errorf("invalid direction %q", header[5])
+	}
+
+	durationMs := binary.BigEndian.Uint32(header[6:10])
+	config.DurationSeconds = int((durationMs + 999) / 1000)
+	config.BufferSize = int(binary.BigEndian.Uint32(header[10:14]))
+
+	if config.DurationSeconds <= 0 || time.Duration(config.DurationSeconds)*time.Second > maxThroughputDuration {
+		return config, fmt.Errorf("invalid duration %dms", durationMs)
+	}
+	if config.BufferSize <= 0 || config.BufferSize > maxThroughputBuffer {
+		return config, fmt.Errorf("invalid buffer size %d", config.BufferSize)
+	}
+	return config, nil
+}
+
+// === Streams ===
+
+// testStream is one TCP connection of a test with its transfer counter
+type testStream struct {
+	index   int
+	conn    net.Conn
+	info    ConnectionInfo
+	bytes   int64 // Updated atomically
+	stats   connectionStatsSource
+	enabled bool // ExtendedStats collection enabled for this socket
+}
+
+// newTestStream wraps a connection and prepares its identity for stats lookups
+func newTestStream(index int, conn net.Conn, stats connectionStatsSource) *testStream {
+	s := &testStream{index: index, conn: conn}
+
+	local, _ := conn.LocalAddr().(*net.TCPAddr)
+	remote, _ := conn.RemoteAddr().(*net.TCPAddr)
+	if local != nil && remote != nil {
+		s.info = ConnectionInfo{
+			LocalAddr:  local.IP.String(),
+			LocalPort:  uint16(local.Port),
+			RemoteAddr: remote.IP.String(),
+			RemotePort: uint16(remote.Port),
+			State:      StateEstablished,
+			IsIPv6:     local.IP.To4() == nil,
+		}
+		if stats != nil {
+			stats.PrepareConnection(&s.info)
+			s.stats = stats
+		}
+	}
+	return s
+}
+
+// upload sends for the given duration, then half-closes and waits for the
+// server to close so that all sent data is accounted for
+func (s *testStream) upload(duration time.Duration, bufferSize int) error {
+	if err := s.send(duration, bufferSize); err != nil {
+		return err
+	}
+	if tcpConn, ok := s.conn.(*net.TCPConn); ok {
+		tcpConn.CloseWrite()
+	}
+	s.conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	_, err := io.Copy(io.Discard, s.conn)
+	return err
+}
+
+// send writes a buffer repeatedly until the duration elapses
+func (s *testStream) send(duration time.Duration, bufferSize int) error {
+	buf := make([]byte, bufferSize)
+	deadline := time.Now().Add(duration)
+
+	for time.Now().Before(deadline) {
+		n, err := s.conn.Write(buf)
+		atomic.AddInt64(&s.bytes, int64(n))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receive reads and discards until the peer closes
+func (s *testStream) receive(bufferSize int) error {
+	buf := make([]byte, bufferSize)
+	for {
+		n, err := s.conn.Read(buf)
+		atomic.AddInt64(&s.bytes, int64(n))
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sampleLoop records samples until ctx is cancelled, then delivers them on the returned channel
+func (s *testStream) sampleLoop(ctx context.Context, interval time.Duration, start time.Time) <-chan []ThroughputSample {
+	done := make(chan []ThroughputSample, 1)
+
+	go func() {
+		var samples []ThroughputSample
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		lastBytes := int64(0)
+		lastTime := start
+		for {
+			select {
+			case <-ctx.Done():
+				done <- samples
+				return
+			case now := <-ticker.C:
+				bytes := atomic.LoadInt64(&s.bytes)
+				sample := ThroughputSample{
+					Timestamp: now,
+					ElapsedMs: now.Sub(start).Milliseconds(),
+					Stream:    s.index,
+					Bytes:     bytes,
+				}
+				if secs := now.Sub(lastTime).Seconds(); secs > 0 {
+					sample.ThroughputBps = float64((bytes-lastBytes)*8) / secs
+				}
+				s.fillStats(&sample)
+				samples = append(samples, sample)
+				lastBytes, lastTime = bytes, now
+			}
+		}
+	}()
+
+	return done
+}
+
+// fillStats copies ExtendedStats into a sample if the platform provides them
+func (s *testStream) fillStats(sample *ThroughputSample) {
+	if s.stats == nil {
+		return
+	}
+	if !s.enabled {
+		if err := s.stats.EnableExtendedStats(&s.info); err != nil {
+			// Keep sampling throughput only (e.g. not running as administrator)
+			s.stats = nil
+			return
+		}
+		s.enabled = true
+	}
+	stats, err := s.stats.GetExtendedStats(&s.info)
+	if err != nil || stats == nil {
+		return
+	}
+	sample.RTT = int64(stats.SmoothedRTT)
+	sample.RTTVariance = int64(stats.RTTVariance)
+	sample.Cwnd = int64(stats.CurrentCwnd)
+	sample.Ssthresh = int64(stats.CurrentSsthresh)
+	sample.SegsRetrans = int64(stats.SegsRetrans)
+	sample.BytesRetrans = int64(stats.BytesRetrans)
+	sample.FastRetrans = int64(stats.FastRetrans)
+	sample.Timeouts = int64(stats.TimeoutEpisodes)
+	sample.CurRwinRcvd = int64(stats.CurRwinRcvd)
+	sample.CurRwinSent = int64(stats.CurRwinSent)
+	sample.CurMss = int64(stats.CurMss)
+}
+
+// result summarizes the stream after the test
+func (s *testStream) result(start, end time.Time, err error) ThroughputStreamResult {
+	result := ThroughputStreamResult{
+		Stream:     s.index,
+		LocalAddr:  s.info.LocalAddr,
+		LocalPort:  int(s.info.LocalPort),
+		RemoteAddr: s.info.RemoteAddr,
+		RemotePort: int(s.info.RemotePort),
+		Bytes:      atomic.LoadInt64(&s.bytes),
+		DurationMs: end.Sub(start).Milliseconds(),
+	}
+	if secs := end.Sub(start).Seconds(); secs > 0 {
+		result.ThroughputBps = float64(result.Bytes*8) / secs
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -1,6 +1,7 @@
 package tcpmonitor
 
 import (
+	"strings"
 	"time"
 )
 
@@ -41,12 +42,35 @@ type ExtendedStats struct {
 	SlowStartCount  uint32
 	CongAvoidCount  uint32
 
+	// Congestion control algorithm and state (Linux inet_diag; empty on Windows)
+	CongestionAlgorithm string     // e.g. "cubic", "bbr", "dctcp"
+	CAState             string     // open, disorder, cwr, recovery or loss
+	PacingRate          uint64     // Bits per second, 0 if not pacing
+	BBR                 *BBRInfo   // Set when the algorithm is BBR
+	DCTCP               *DCTCPInfo // Set when the algorithm is DCTCP
+	Vegas               *VegasInfo // Set for Vegas and other delay-based algorithms
+
+	// Sender limitation: cumulative milliseconds the sender was limited by
+	// the peer's receive window, its congestion window, or the application
+	SndLimTimeRwin uint32
+	SndLimTimeCwnd uint32
+	SndLimTimeSnd  uint32
+
 	// Buffers
 	CurRetxQueue uint32
 	MaxRetxQueue uint32
 	CurAppWQueue uint32
 	MaxAppWQueue uint32
 
+	// Socket memory (Linux SKMEMINFO; zero on Windows)
+	RmemAlloc  uint32 // Receive queue memory in use
+	RcvBuf     uint32 // Receive buffer limit (SO_RCVBUF or autotuned)
+	WmemAlloc  uint32 // Transmit memory handed to the IP layer
+	WmemQueued uint32 // Send queue memory (unsent and unacknowledged data)
+	SndBuf     uint32 // Send buffer limit (SO_SNDBUF or autotuned)
+	FwdAlloc   uint32 // Memory reserved for the socket but not yet used
+	SockDrops  uint32 // Packets dropped before reaching the receive queue
+
 	// Bandwidth
 	OutboundBandwidth uint64
 	InboundBandwidth  uint64
@@ -72,6 +96,41 @@ type ExtendedStats struct {
 	DsackDups      uint32
 }
 
+// TimerInfo describes the pending TCP timer of a connection
+type TimerInfo struct {
+	Kind        string // retransmit, keepalive, time_wait or zero_window_probe
+	ExpiresMs   uint32 // Time until the timer fires
+	Retransmits uint32 // Consecutive retransmissions without an ACK
+	Probes      uint32 // Unanswered zero-window or keepalive probes
+	Backoff     uint32 // Exponential RTO backoff level
+	RTOMs       uint32 // Current retransmission timeout
+}
+
+// BBRInfo is BBR's model of the path
+type BBRInfo struct {
+	BandwidthBps uint64  // Estimated bottleneck bandwidth, bits per second
+	MinRTTUs     uint32  // Windowed minimum RTT, microseconds
+	PacingGain   float64 // Current pacing gain (1.0 = pace at estimated bandwidth)
+	CwndGain     float64 // Current cwnd gain relative to the estimated BDP
+}
+
+// DCTCPInfo is DCTCP's ECN-based congestion estimate
+type DCTCPInfo struct {
+	Enabled bool
+	CEState uint16
+	Alpha   uint32 // Fraction of ECN-marked bytes, scaled to 1024
+	ABECN   uint32 // Bytes acked with ECN marks in the current window
+	ABTotal uint32 // Bytes acked in the current window
+}
+
+// VegasInfo is the delay estimate of Vegas-style algorithms
+type VegasInfo struct {
+	Enabled  bool
+	RTTCount uint32
+	RTTUs    uint32
+	MinRTTUs uint32
+}
+
 // === Connection Types ===
 
 // ConnectionInfo represents a TCP connection with its statistics
@@ -87,6 +146,15 @@ type ConnectionInfo struct {
 	BasicStats    *BasicStats
 	ExtendedStats *ExtendedStats
 
+	// Enrichment (filled asynchronously, may be empty)
+	RemoteHostname string
+	RemoteCountry  string // ISO country code
+	RemoteCity     string
+	RemoteASN      uint32
+	RemoteASOrg    string
+	ServiceName    string // From the service catalog, e.g. "https" or "kafka-prod"
+	Role           string // RoleClient, RoleServer or RoleListener
+
 	// Raw values from Windows API (for stats API calls)
 	RawLocalPort   uint32
 	RawRemotePort  uint32
@@ -95,9 +163,22 @@ type ConnectionInfo struct {
 	RawLocalAddr6  [16]byte // IPv6 only
 	RawRemoteAddr6 [16]byte // IPv6 only
 
+	// Pending TCP timer (Linux inet_diag), nil if none is armed or unavailable
+	Timer *TimerInfo
+
 	// Health indicators
 	HighRetransmissionWarning bool
 	HighRTTWarning            bool
+	MSSWarning                bool
+	MSSAnomaly                *MSSFinding // Standing MSS/PMTU anomaly, nil if none
+	WindowScaleWarning        bool
+	BufferWarning             bool
+	BufferPressure            *BufferFinding // Standing buffer condition, nil if none
+	StallWarning              bool
+	Stall                     *StallFinding // Retransmission backoff or probing, nil if none
+
+	// Throughput limitation, nil until two samples have been seen
+	WindowAnalysis *WindowAnalysis
 }
 
 // TCPState represents the state of a TCP connection
@@ -149,6 +230,18 @@ func (s TCPState) String() string {
 	return "UNKNOWN"
 }
 
+// parseTCPState returns the state with the given name, ignoring case and
+// treating "-" like "_" (e.g. "established", "time-wait")
+func parseTCPState(name string) (TCPState, bool) {
+	name = strings.ReplaceAll(strings.TrimSpace(name), "-", "_")
+	for state := StateClosed; state <= StateDeleteTCB; state++ {
+		if strings.EqualFold(state.String(), name) {
+			return state, true
+		}
+	}
+	return 0, false
+}
+
 // === Configuration Types ===
 
 // HealthThresholds defines thresholds for health warnings
@@ -0,0 +1,422 @@
+package tcpmonitor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Throughput limitation classes
+const (
+	LimitReceiverWindow   = "rwnd_limited"    // Receiver's advertised window is full
+	LimitCongestionWindow = "cwnd_limited"    // Congestion window is full, path not saturated
+	LimitApplication      = "app_limited"     // Sender has no data queued; windows not full
+	LimitNetwork          = "network_limited" // Path is the bottleneck (queueing, loss, capacity)
+	LimitUnknown          = "unknown"         // Not determinable from this endpoint
+	LimitIdle             = "idle"            // Too little traffic to classify
+)
+
+const (
+	// Below this throughput a connection is considered idle
+	minClassifyBps = 64 * 1000
+
+	// A window counts as full when in-flight data reaches this fraction of it
+	windowFullRatio = 0.8
+
+	// Smoothed RTT above MinRTT by this factor indicates a standing queue
+	queueingRTTRatio = 1.5
+
+	// Largest window without window scaling
+	unscaledWindowMax = 65535
+
+	// Largest valid window scale shift (RFC 7323)
+	maxWindowScale = 14
+)
+
+// WindowAnalysis explains what limits a connection's throughput
+type WindowAnalysis struct {
+	Limitation    string `json:"limitation"`
+	Direction     string `json:"direction"` // "send" or "receive"
+	ThroughputBps uint64 `json:"throughputBps"`
+	RTTMs         uint32 `json:"rttMs"`
+	BDPBytes      uint64 `json:"bdpBytes"`     // Achieved throughput x smoothed RTT
+	PathBDPBytes  uint64 `json:"pathBdpBytes"` // Estimated capacity x min RTT, 0 if unknown
+
+	Cwnd         uint32 `json:"cwnd"`
+	PeerRwnd     uint32 `json:"peerRwnd"`     // Window advertised by the peer (limits sending)
+	LocalRwnd    uint32 `json:"localRwnd"`    // Window advertised by us (limits receiving)
+	WinScaleSent int    `json:"winScaleSent"` // -1 if not negotiated
+	WinScaleRcvd int    `json:"winScaleRcvd"` // -1 if not negotiated
+
+	WindowScaleIssue string `json:"windowScaleIssue,omitempty"`
+	Explanation      string `json:"explanation"`
+}
+
+// windowSample is the subset of stats the analyzer looks at
+type windowSample struct {
+	Timestamp    time.Time
+	Acked        uint64
+	Received     uint64
+	RTT          uint32
+	MinRTT       uint32
+	Cwnd         uint32
+	PeerRwnd     uint32
+	LocalRwnd    uint32
+	WinScaleSent uint32
+	WinScaleRcvd uint32
+	OutBandwidth uint64
+	InBandwidth  uint64
+	AppWQueue    uint32
+	Retrans      uint32
+	SndLimRwin   uint32
+	SndLimCwnd   uint32
+	SndLimSnd    uint32
+}
+
+// windowSampleFromStats extracts analyzer inputs from ExtendedStats
+func windowSampleFromStats(stats *ExtendedStats, at time.Time) windowSample {
+	return windowSample{
+		Timestamp:    at,
+		Acked:        stats.ThruBytesAcked,
+		Received:     stats.ThruBytesReceived,
+		RTT:          stats.SmoothedRTT,
+		MinRTT:       stats.MinRTT,
+		Cwnd:         stats.CurrentCwnd,
+		PeerRwnd:     stats.CurRwinRcvd,
+		LocalRwnd:    stats.CurRwinSent,
+		WinScaleSent: stats.WinScaleSent,
+		WinScaleRcvd: stats.WinScaleRcvd,
+		OutBandwidth: stats.OutboundBandwidth,
+		InBandwidth:  stats.InboundBandwidth,
+		AppWQueue:    stats.CurAppWQueue,
+		Retrans:      stats.SegsRetrans,
+		SndLimRwin:   stats.SndLimTimeRwin,
+		SndLimCwnd:   stats.SndLimTimeCwnd,
+		SndLimSnd:    stats.SndLimTimeSnd,
+	}
+}
+
+// windowSampleFromTimeline extracts analyzer inputs from a recorded snapshot
+func windowSampleFromTimeline(tc *TimelineConnection) windowSample {
+	c := &tc.Connection
+	return windowSample{
+		Timestamp:    tc.Timestamp,
+		Acked:        uint64(c.ThruBytesAcked),
+		Received:     uint64(c.ThruBytesReceived),
+		RTT:          uint32(c.RTT),
+		MinRTT:       uint32(c.MinRTT),
+		Cwnd:         uint32(c.CongestionWin),
+		PeerRwnd:     uint32(c.CurRwinRcvd),
+		LocalRwnd:    uint32(c.CurRwinSent),
+		WinScaleSent: uint32(c.WinScaleSent),
+		WinScaleRcvd: uint32(c.WinScaleRcvd),
+		OutBandwidth: uint64(c.OutBandwidth),
+		InBandwidth:  uint64(c.InBandwidth),
+		AppWQueue:    uint32(c.CurAppWQueue),
+		Retrans:      uint32(c.SegsRetrans),
+		SndLimRwin:   uint32(c.SndLimTimeRwin),
+		SndLimCwnd:   uint32(c.SndLimTimeCwnd),
+		SndLimSnd:    uint32(c.SndLimTimeSnd),
+	}
+}
+
+// analyzeWindow classifies the interval between two samples of one connection
+func analyzeWindow(prev, cur windowSample) WindowAnalysis {
+	a := WindowAnalysis{
+		Limitation:   LimitIdle,
+		Direction:    "send",
+		RTTMs:        cur.RTT,
+		Cwnd:         cur.Cwnd,
+		PeerRwnd:     cur.PeerRwnd,
+		LocalRwnd:    cur.LocalRwnd,
+		WinScaleSent: windowScale(cur.WinScaleSent),
+		WinScaleRcvd: windowScale(cur.WinScaleRcvd),
+	}
+
+	secs := cur.Timestamp.Sub(prev.Timestamp).Seconds()
+	if secs <= 0 {
+		a.Explanation = "Not enough samples to measure throughput"
+		return a
+	}
+
+	sendBps := uint64(float64(counterDelta64(prev.Acked, cur.Acked)*8) / secs)
+	recvBps := uint64(float64(counterDelta64(prev.Received, cur.Received)*8) / secs)
+	capacity := cur.OutBandwidth
+	a.ThroughputBps = sendBps
+	if recvBps > sendBps {
+		a.Direction = "receive"
+		a.ThroughputBps = recvBps
+		capacity = cur.InBandwidth
+	}
+
+	rtt := cur.RTT
+	if rtt == 0 {
+		rtt = cur.MinRTT
+	}
+	minRTT := cur.MinRTT
+	if minRTT == 0 {
+		minRTT = rtt
+	}
+	a.BDPBytes = bdpBytes(a.ThroughputBps, rtt)
+	a.PathBDPBytes = bdpBytes(capacity, minRTT)
+
+	a.WindowScaleIssue = windowScaleIssue(a)
+
+	if a.ThroughputBps < minClassifyBps || rtt == 0 {
+		a.Explanation = "Too little traffic to classify"
+		return a
+	}
+
+	queueing := cur.MinRTT > 0 && float64(cur.RTT) > queueingRTTRatio*float64(cur.MinRTT)
+	saturated := capacity > 0 && float64(a.ThroughputBps) >= windowFullRatio*float64(capacity)
+	lossy := cur.Retrans > prev.Retrans
+
+	if a.Direction == "receive" {
+		switch {
+		case windowFull(a.BDPBytes, cur.LocalRwnd):
+			a.Limitation = LimitReceiverWindow
+			a.Explanation = fmt.Sprintf("Our advertised receive window (%s) is full: at %d ms RTT it allows at most %s",
+				formatByteSize(uint64(cur.LocalRwnd)), rtt, formatBitRate(windowRate(cur.LocalRwnd, rtt)))
+		case queueing || saturated:
+			a.Limitation = LimitNetwork
+			a.Explanation = networkExplanation(cur, saturated, false)
+		default:
+			a.Limitation = LimitUnknown
+			a.Explanation = "Receive window not full; the peer's congestion window or application is limiting, which is not visible from the receiver"
+		}
+		return a
+	}
+
+	// Prefer the stack's own sender-limitation accounting when present
+	rwin := counterDelta32(prev.SndLimRwin, cur.SndLimRwin)
+	cwin := counterDelta32(prev.SndLimCwnd, cur.SndLimCwnd)
+	snd := counterDelta32(prev.SndLimSnd, cur.SndLimSnd)
+
+	switch {
+	case rwin+cwin+snd > 0 && rwin >= cwin && rwin >= snd,
+		rwin+cwin+snd == 0 && windowFull(a.BDPBytes, cur.PeerRwnd) && cur.PeerRwnd <= cur.Cwnd:
+		a.Limitation = LimitReceiverWindow
+		a.Explanation = fmt.Sprintf("The peer's receive window (%s) is full: at %d ms RTT it allows at most %s",
+			formatByteSize(uint64(cur.PeerRwnd)), rtt, formatBitRate(windowRate(cur.PeerRwnd, rtt)))
+	case rwin+cwin+snd > 0 && snd >= cwin,
+		rwin+cwin+snd == 0 && !windowFull(a.BDPBytes, cur.Cwnd) && cur.AppWQueue == 0:
+		a.Limitation = LimitApplication
+		a.Explanation = "The application is not writing fast enough to fill the windows"
+	case queueing || saturated || lossy:
+		a.Limitation = LimitNetwork
+		a.Explanation = networkExplanation(cur, saturated, lossy)
+	default:
+		a.Limitation = LimitCongestionWindow
+		a.Explanation = fmt.Sprintf("The congestion window (%s) is full without loss or queueing: at %d ms RTT it allows at most %s",
+			formatByteSize(uint64(cur.Cwnd)), rtt, formatBitRate(windowRate(cur.Cwnd, rtt)))
+	}
+	return a
+}
+
+// networkExplanation describes why the path is considered the bottleneck
+func networkExplanation(cur windowSample, saturated, lossy bool) string {
+	switch {
+	case saturated:
+		return "Throughput is close to the estimated path capacity"
+	case lossy:
+		return "Retransmissions are limiting the congestion window"
+	default:
+		return fmt.Sprintf("RTT inflated from %d ms to %d ms: a bottleneck queue is building", cur.MinRTT, cur.RTT)
+	}
+}
+
+// windowScaleIssue calls out missing window scaling on a high-BDP path
+func windowScaleIssue(a WindowAnalysis) string {
+	bdp := a.PathBDPBytes
+	if a.BDPBytes > bdp {
+		bdp = a.BDPBytes
+	}
+
+	// The window that bounds the analyzed direction is advertised by the receiver
+	side, scale, window := "peer", a.WinScaleRcvd, a.PeerRwnd
+	if a.Direction == "receive" {
+		side, scale, window = "local", a.WinScaleSent, a.LocalRwnd
+	}
+	if a.WinScaleSent < 0 || a.WinScaleRcvd < 0 {
+		side, scale = "both", -1
+	}
+	if scale > 0 {
+		return ""
+	}
+
+	// Either the path needs more than 64 KB, or a capped window is already full
+	capped := window > 0 && window <= unscaledWindowMax && windowFull(a.BDPBytes, window)
+	if bdp <= unscaledWindowMax && !capped {
+		return ""
+	}
+
+	what := "window scale 0"
+	if scale < 0 {
+		what = "window scaling not negotiated"
+	}
+	return fmt.Sprintf("%s (%s) on a path with %s BDP: windows are capped at 64 KB, at most %s at %d ms RTT",
+		what, side, formatByteSize(bdp), formatBitRate(windowRate(unscaledWindowMax, a.RTTMs)), a.RTTMs)
+}
+
+// windowScale converts a raw window scale to -1 when not negotiated
+func windowScale(raw uint32) int {
+	if raw > maxWindowScale {
+		return -1
+	}
+	return int(raw)
+}
+
+// windowFull checks whether in-flight data fills a window
+func windowFull(bdp uint64, window uint32) bool {
+	return window > 0 && float64(bdp) >= windowFullRatio*float64(window)
+}
+
+// bdpBytes computes a bandwidth-delay product
+func bdpBytes(bps uint64, rttMs uint32) uint64 {
+	return bps / 8 * uint64(rttMs) / 1000
+}
+
+// windowRate is the maximum throughput a window allows at an RTT
+func windowRate(window, rttMs uint32) uint64 {
+	if rttMs == 0 {
+		return 0
+	}
+	return uint64(window) * 8 * 1000 / uint64(rttMs)
+}
+
+// counterDelta64 returns the increase of a cumulative counter, 0 on reset
+func counterDelta64(prev, cur uint64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
+
+// counterDelta32 returns the increase of a cumulative counter, 0 on reset
+func counterDelta32(prev, cur uint32) uint32 {
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
+
+// formatByteSize formats a byte count for explanations
+func formatByteSize(b uint64) string {
+	switch {
+	case b >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(b)/(1<<20))
+	case b >= 1<<10:
+		return fmt.Sprintf("%.0f KB", float64(b)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", b)
+	}
+}
+
+// formatBitRate formats bits per second for explanations
+func formatBitRate(bps uint64) string {
+	switch {
+	case bps >= 1e9:
+		return fmt.Sprintf("%.2f Gbit/s", float64(bps)/1e9)
+	case bps >= 1e6:
+		return fmt.Sprintf("%.1f Mbit/s", float64(bps)/1e6)
+	default:
+		return fmt.Sprintf("%.0f kbit/s", float64(bps)/1e3)
+	}
+}
+
+// AnalyzeSessionWindows classifies every interval of a recorded connection
+// and returns the most frequent limitation with the latest analysis for it
+func AnalyzeSessionWindows(snapshots []TimelineConnection) *WindowAnalysis {
+	counts := make(map[string]int)
+	latest := make(map[string]WindowAnalysis)
+	scaleIssue := ""
+
+	for i := 1; i < len(snapshots); i++ {
+		if TCPState(snapshots[i].Connection.State) != StateEstablished {
+			continue
+		}
+		a := analyzeWindow(windowSampleFromTimeline(&snapshots[i-1]), windowSampleFromTimeline(&snapshots[i]))
+		if a.WindowScaleIssue != "" {
+			scaleIssue = a.WindowScaleIssue
+		}
+		if a.Limitation == LimitIdle {
+			continue
+		}
+		counts[a.Limitation]++
+		latest[a.Limitation] = a
+	}
+
+	best := ""
+	for limitation, n := range counts {
+		if best == "" || n > counts[best] || (n == counts[best] && limitation < best) {
+			best = limitation
+		}
+	}
+	if best == "" {
+		if scaleIssue == "" {
+			return nil
+		}
+		return &WindowAnalysis{Limitation: LimitIdle, WindowScaleIssue: scaleIssue, Explanation: "Too little traffic to classify"}
+	}
+
+	result := latest[best]
+	if result.WindowScaleIssue == "" {
+		result.WindowScaleIssue = scaleIssue
+	}
+	return &result
+}
+
+// WindowAnalyzer classifies live connections using consecutive samples
+type WindowAnalyzer struct {
+	previous map[ConnectionKey]windowSample
+	mu       sync.Mutex
+}
+
+// NewWindowAnalyzer creates a new analyzer
+func NewWindowAnalyzer() *WindowAnalyzer {
+	return &WindowAnalyzer{
+		previous: make(map[ConnectionKey]windowSample),
+	}
+}
+
+// Analyze sets WindowAnalysis and WindowScaleWarning on connections with
+// extended stats and forgets connections that are gone
+func (w *WindowAnalyzer) Analyze(connections []ConnectionInfo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	seen := make(map[ConnectionKey]bool, len(connections))
+	for i := range connections {
+		conn := &connections[i]
+		conn.WindowAnalysis = nil
+		conn.WindowScaleWarning = false
+
+		if conn.ExtendedStats == nil || conn.State != StateEstablished {
+			continue
+		}
+
+		key := ConnectionKey{
+			LocalAddr:  conn.LocalAddr,
+			LocalPort:  conn.LocalPort,
+			RemoteAddr: conn.RemoteAddr,
+			RemotePort: conn.RemotePort,
+			IsIPv6:     conn.IsIPv6,
+		}
+		seen[key] = true
+
+		cur := windowSampleFromStats(conn.ExtendedStats, now)
+		if prev, ok := w.previous[key]; ok {
+			a := analyzeWindow(prev, cur)
+			conn.WindowAnalysis = &a
+			conn.WindowScaleWarning = a.WindowScaleIssue != ""
+		}
+		w.previous[key] = cur
+	}
+
+	for key := range w.previous {
+		if !seen[key] {
+			delete(w.previous, key)
+		}
+	}
+}